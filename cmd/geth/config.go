@@ -44,6 +44,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/paircache"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/naoina/toml"
 	"github.com/urfave/cli/v2"
@@ -98,6 +99,7 @@ type gethConfig struct {
 	Ethstats   ethstatsConfig
 	Metrics    metrics.Config
 	FakeBeacon fakebeacon.Config
+	Pair       paircache.Config
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -134,6 +136,7 @@ func loadBaseConfig(ctx *cli.Context) gethConfig {
 		Eth:     ethconfig.Defaults,
 		Node:    defaultNodeConfig(),
 		Metrics: metrics.DefaultConfig,
+		Pair:    paircache.DefaultConfig,
 	}
 
 	// Load config file.
@@ -175,6 +178,8 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 		cfg.Ethstats.URL = ctx.String(utils.EthStatsURLFlag.Name)
 	}
 	applyMetricConfig(ctx, &cfg)
+	applyPairConfig(ctx, &cfg)
+	applyIndexConfig(ctx, &cfg)
 
 	return stack, cfg
 }
@@ -232,6 +237,11 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		})
 	}
 
+	// Record the head block number with every recorded RPC call.
+	if recorder := stack.RPCRecorder(); recorder != nil {
+		recorder.SetHeadFunc(func() uint64 { return backend.CurrentHeader().Number.Uint64() })
+	}
+
 	// Configure log filter RPC API.
 	filterSystem := utils.RegisterFilterAPI(stack, backend, &cfg.Eth)
 
@@ -254,6 +264,20 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		go fakebeacon.NewService(&cfg.FakeBeacon, backend).Run()
 	}
 
+	// Index the traces of new blocks if requested.
+	if cfg.Eth.TraceIndex.Enabled {
+		utils.RegisterTraceIndexService(stack, eth.APIBackend, &cfg.Eth.TraceIndex)
+	}
+	// Index the token transfers of new blocks if requested.
+	if cfg.Eth.TokenIndex.Enabled {
+		utils.RegisterTokenIndexService(stack, eth.APIBackend, &cfg.Eth.TokenIndex)
+	}
+
+	// Start the arbitrage pair cache if a triangle source is configured.
+	if cfg.Pair.Enabled() {
+		utils.RegisterPairService(stack, backend, &cfg.Pair)
+	}
+
 	git, _ := version.VCS()
 	utils.SetupMetrics(ctx,
 		utils.EnableBuildInfo(git.Commit, git.Date),
@@ -338,6 +362,107 @@ func applyMetricConfig(ctx *cli.Context, cfg *gethConfig) {
 	}
 }
 
+func applyPairConfig(ctx *cli.Context, cfg *gethConfig) {
+	if ctx.IsSet(utils.PairSourceFlag.Name) {
+		cfg.Pair.Source = ctx.String(utils.PairSourceFlag.Name)
+	}
+	if ctx.IsSet(utils.PairMySQLDSNFlag.Name) {
+		cfg.Pair.MySQLDSN = ctx.String(utils.PairMySQLDSNFlag.Name)
+	}
+	if ctx.IsSet(utils.PairTriangleFileFlag.Name) {
+		cfg.Pair.TriangleFile = ctx.String(utils.PairTriangleFileFlag.Name)
+	}
+	if ctx.IsSet(utils.PairTopicFileFlag.Name) {
+		cfg.Pair.TopicFile = ctx.String(utils.PairTopicFileFlag.Name)
+	}
+	if ctx.IsSet(utils.PairTriangleRefreshFlag.Name) {
+		cfg.Pair.TriangleRefresh = ctx.Duration(utils.PairTriangleRefreshFlag.Name)
+	}
+	if ctx.IsSet(utils.PairTopicRefreshFlag.Name) {
+		cfg.Pair.TopicRefresh = ctx.Duration(utils.PairTopicRefreshFlag.Name)
+	}
+	if ctx.IsSet(utils.PairQueueSizeFlag.Name) {
+		cfg.Pair.QueueSize = ctx.Int(utils.PairQueueSizeFlag.Name)
+	}
+	if ctx.IsSet(utils.PairMaxTrianglesFlag.Name) {
+		cfg.Pair.MaxTriangles = ctx.Int(utils.PairMaxTrianglesFlag.Name)
+	}
+	if ctx.IsSet(utils.PairBudgetFlag.Name) {
+		cfg.Pair.Budget = ctx.Duration(utils.PairBudgetFlag.Name)
+	}
+	if ctx.IsSet(utils.PairMempoolFlag.Name) {
+		cfg.Pair.Mempool = ctx.Bool(utils.PairMempoolFlag.Name)
+	}
+	if ctx.IsSet(utils.PairMempoolWorkersFlag.Name) {
+		cfg.Pair.MempoolWorkers = ctx.Int(utils.PairMempoolWorkersFlag.Name)
+	}
+	if ctx.IsSet(utils.PairMempoolBudgetFlag.Name) {
+		cfg.Pair.MempoolBudget = ctx.Duration(utils.PairMempoolBudgetFlag.Name)
+	}
+	if ctx.IsSet(utils.PairRunHistoryFlag.Name) {
+		cfg.Pair.RunHistory = ctx.Uint64(utils.PairRunHistoryFlag.Name)
+	}
+	if ctx.IsSet(utils.PairDiscoverHopsFlag.Name) {
+		cfg.Pair.DiscoverHops = ctx.Int(utils.PairDiscoverHopsFlag.Name)
+	}
+	if ctx.IsSet(utils.PairDiscoverBasesFlag.Name) {
+		var bases []common.Address
+		for _, base := range strings.Split(ctx.String(utils.PairDiscoverBasesFlag.Name), ",") {
+			if !common.IsHexAddress(base) {
+				utils.Fatalf("Invalid route discovery base token %q", base)
+			}
+			bases = append(bases, common.HexToAddress(base))
+		}
+		cfg.Pair.DiscoverBases = bases
+	}
+	if ctx.IsSet(utils.PairDiscoverLimitFlag.Name) {
+		cfg.Pair.DiscoverLimit = ctx.Int(utils.PairDiscoverLimitFlag.Name)
+	}
+	if ctx.IsSet(utils.PairSubmitModeFlag.Name) {
+		cfg.Pair.Submit.Mode = ctx.String(utils.PairSubmitModeFlag.Name)
+	}
+	if ctx.IsSet(utils.PairSubmitAccountFlag.Name) {
+		account := ctx.String(utils.PairSubmitAccountFlag.Name)
+		if !common.IsHexAddress(account) {
+			utils.Fatalf("Invalid arbitrage submission account %q", account)
+		}
+		cfg.Pair.Submit.Account = common.HexToAddress(account)
+	}
+	if ctx.IsSet(utils.PairSubmitEndpointsFlag.Name) {
+		cfg.Pair.Submit.Endpoints = strings.Split(ctx.String(utils.PairSubmitEndpointsFlag.Name), ",")
+	}
+	if ctx.IsSet(utils.PairSubmitGasPriceFlag.Name) {
+		cfg.Pair.Submit.GasPrice = flags.GlobalBig(ctx, utils.PairSubmitGasPriceFlag.Name)
+	}
+	if ctx.IsSet(utils.PairSubmitGasMarginFlag.Name) {
+		cfg.Pair.Submit.GasMargin = ctx.Uint64(utils.PairSubmitGasMarginFlag.Name)
+	}
+	if ctx.IsSet(utils.PairSubmitDryRunFlag.Name) {
+		cfg.Pair.Submit.DryRun = ctx.Bool(utils.PairSubmitDryRunFlag.Name)
+	}
+	if ctx.IsSet(utils.PairSubmitJournalFlag.Name) {
+		cfg.Pair.Submit.Journal = ctx.String(utils.PairSubmitJournalFlag.Name)
+	}
+}
+
+func applyIndexConfig(ctx *cli.Context, cfg *gethConfig) {
+	if ctx.IsSet(utils.AddressIndexFlag.Name) {
+		cfg.Eth.AddressIndex = ctx.Bool(utils.AddressIndexFlag.Name)
+	}
+	if ctx.IsSet(utils.TraceIndexFlag.Name) {
+		cfg.Eth.TraceIndex.Enabled = ctx.Bool(utils.TraceIndexFlag.Name)
+	}
+	if ctx.IsSet(utils.TraceIndexHistoryFlag.Name) {
+		cfg.Eth.TraceIndex.History = ctx.Uint64(utils.TraceIndexHistoryFlag.Name)
+	}
+	if ctx.IsSet(utils.TokenIndexFlag.Name) {
+		cfg.Eth.TokenIndex.Enabled = ctx.Bool(utils.TokenIndexFlag.Name)
+	}
+	if ctx.IsSet(utils.TokenIndexFromFlag.Name) {
+		cfg.Eth.TokenIndex.From = ctx.Uint64(utils.TokenIndexFromFlag.Name)
+	}
+}
+
 func deprecated(field string) bool {
 	switch field {
 	case "ethconfig.Config.EVMInterpreter":
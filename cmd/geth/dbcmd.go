@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/console/prompt"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/flags"
@@ -91,6 +92,8 @@ Remove blockchain and state databases`,
 			dbHbss2PbssCmd,
 			dbTrieGetCmd,
 			dbTrieDeleteCmd,
+			dbBuildAddressIndexCmd,
+			dbDropAddressIndexCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -276,6 +279,27 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "Shows metadata about the chain status.",
 	}
+	dbBuildAddressIndexCmd = &cli.Command{
+		Action: buildAddressIndex,
+		Name:   "build-address-index",
+		Usage:  "Build the index of transactions by sender and recipient",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			utils.TransactionHistoryFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command indexes the transactions of the recent blocks retained by
+--history.transactions by sender and recipient. The index is only kept up to date
+by nodes running with --index.addresses. Indexing the whole chain takes a long time.`,
+	}
+	dbDropAddressIndexCmd = &cli.Command{
+		Action: dropAddressIndex,
+		Name:   "drop-address-index",
+		Usage:  "Delete the index of transactions by sender and recipient",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: "This command deletes the address index built by --index.addresses.",
+	}
 	ancientInspectCmd = &cli.Command{
 		Action: ancientInspect,
 		Name:   "inspect-reserved-oldest-blocks",
@@ -1351,3 +1375,52 @@ func hbss2pbss(ctx *cli.Context) error {
 	}
 	return nil
 }
+
+// buildAddressIndex indexes the transactions of the retained transaction
+// history by address, unindexing the blocks that fell out of it.
+func buildAddressIndex(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false, false)
+	defer db.Close()
+
+	head := rawdb.ReadHeadBlock(db)
+	if head == nil {
+		return errors.New("no head block found")
+	}
+	config := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if config == nil {
+		return errors.New("no chain config found")
+	}
+	var (
+		signer = types.LatestSigner(config)
+		limit  = ctx.Uint64(utils.TransactionHistoryFlag.Name)
+		number = head.NumberU64()
+		from   uint64
+	)
+	if limit != 0 && number >= limit {
+		from = number - limit + 1
+	}
+	if tail := rawdb.ReadAddressIndexTail(db); tail != nil && *tail < from {
+		rawdb.UnindexAddresses(db, signer, *tail, from, nil, true)
+	}
+	rawdb.IndexAddresses(db, signer, from, number+1, nil, true)
+	return nil
+}
+
+// dropAddressIndex deletes the address index.
+func dropAddressIndex(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false, false)
+	defer db.Close()
+
+	start := time.Now()
+	if err := rawdb.DeleteAddressIndex(db); err != nil {
+		return err
+	}
+	log.Info("Deleted address index", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
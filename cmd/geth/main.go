@@ -104,7 +104,12 @@ var (
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag, // deprecated
 		utils.TransactionHistoryFlag,
+		utils.AddressIndexFlag,
 		utils.StateHistoryFlag,
+		utils.TraceIndexFlag,
+		utils.TraceIndexHistoryFlag,
+		utils.TokenIndexFlag,
+		utils.TokenIndexFromFlag,
 		utils.PathDBSyncFlag,
 		utils.JournalFileFlag,
 		utils.LightServeFlag,       // deprecated
@@ -173,9 +178,13 @@ var (
 		utils.BlockAmountReserved,
 		utils.CheckSnapshotWithMPT,
 		utils.EnableDoubleSignMonitorFlag,
+		utils.DoubleSignReporterFlag,
+		utils.DoubleSignReportDryRunFlag,
 		utils.VotingEnabledFlag,
 		utils.DisableVoteAttestationFlag,
 		utils.EnableMaliciousVoteMonitorFlag,
+		utils.MaliciousVoteReporterFlag,
+		utils.MaliciousVoteReportDryRunFlag,
 		utils.BLSPasswordFileFlag,
 		utils.BLSWalletDirFlag,
 		utils.VoteJournalDirFlag,
@@ -214,6 +223,17 @@ var (
 		utils.AllowUnprotectedTxs,
 		utils.BatchRequestLimit,
 		utils.BatchResponseMaxSize,
+		utils.RPCRecordFlag,
+		utils.RPCRecordMaxSizeFlag,
+		utils.RPCRecordMaxBackupsFlag,
+		utils.RPCRecordMethodsFlag,
+		utils.RPCAPIKeysFlag,
+		utils.RPCAllowAnonymousFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateLimitBurstFlag,
+		utils.RPCMethodRateLimitsFlag,
+		utils.RPCConcurrencyFlag,
+		utils.RPCConcurrencyTimeoutFlag,
 	}
 
 	metricsFlags = []cli.Flag{
@@ -238,6 +258,32 @@ var (
 		utils.FakeBeaconAddrFlag,
 		utils.FakeBeaconPortFlag,
 	}
+
+	pairFlags = []cli.Flag{
+		utils.PairSourceFlag,
+		utils.PairMySQLDSNFlag,
+		utils.PairTriangleFileFlag,
+		utils.PairTopicFileFlag,
+		utils.PairTriangleRefreshFlag,
+		utils.PairTopicRefreshFlag,
+		utils.PairQueueSizeFlag,
+		utils.PairMaxTrianglesFlag,
+		utils.PairBudgetFlag,
+		utils.PairMempoolFlag,
+		utils.PairMempoolWorkersFlag,
+		utils.PairMempoolBudgetFlag,
+		utils.PairRunHistoryFlag,
+		utils.PairDiscoverHopsFlag,
+		utils.PairDiscoverBasesFlag,
+		utils.PairDiscoverLimitFlag,
+		utils.PairSubmitModeFlag,
+		utils.PairSubmitAccountFlag,
+		utils.PairSubmitEndpointsFlag,
+		utils.PairSubmitGasPriceFlag,
+		utils.PairSubmitGasMarginFlag,
+		utils.PairSubmitDryRunFlag,
+		utils.PairSubmitJournalFlag,
+	}
 )
 
 var app = flags.NewApp("the go-ethereum command line interface")
@@ -280,6 +326,12 @@ func init() {
 		blsCommand,
 		// See verkle.go
 		verkleCommand,
+		// See rpcreplaycmd.go
+		rpcReplayCommand,
+		// See traceindexcmd.go
+		traceIndexCommand,
+		// See parliacmd.go
+		parliaCommand,
 	}
 	if logTestCommand != nil {
 		app.Commands = append(app.Commands, logTestCommand)
@@ -293,6 +345,7 @@ func init() {
 		debug.Flags,
 		metricsFlags,
 		fakeBeaconFlags,
+		pairFlags,
 	)
 	flags.AutoEnvVars(app.Flags, "GETH")
 
@@ -0,0 +1,259 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	parliaVerifyFromStoredFlag = &cli.BoolFlag{
+		Name:  "from-stored",
+		Usage: "Rebuild the snapshot from the closest checkpoint snapshot stored in the database instead of the genesis, trusting it as is",
+	}
+)
+
+var parliaCommand = &cli.Command{
+	Name:  "parlia",
+	Usage: "A set of commands based on the Parlia consensus",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "snapshot",
+			Usage: "A set of commands transferring Parlia snapshots between nodes",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "export",
+					Usage:     "Export the Parlia snapshot at a block into a file",
+					ArgsUsage: "<file> [<block>]",
+					Action:    exportParliaSnapshot,
+					Flags:     flags.Merge(utils.NetworkFlags, utils.DatabaseFlags),
+					Description: `
+geth parlia snapshot export <file> [<block>]
+writes the Parlia snapshot (validators, vote addresses, recent signers and turn
+length) at the given canonical block into a portable JSON file. The block must
+be a checkpoint, a multiple of 1024, as the node only loads the snapshots of
+those from its database. It defaults to the latest checkpoint of the chain.
+
+The node must not be running. Checkpoint snapshots rebuilt from the headers are
+stored into the database along the way.`,
+				},
+				{
+					Name:      "import",
+					Usage:     "Import a Parlia snapshot from a file",
+					ArgsUsage: "<file>",
+					Action:    importParliaSnapshot,
+					Flags:     flags.Merge(utils.NetworkFlags, utils.DatabaseFlags),
+					Description: `
+geth parlia snapshot import <file>
+stores the Parlia snapshot of an export file into the database, so the node
+builds the snapshots of the following blocks from it instead of from older
+headers, which may have been pruned. The datadir may be fresh, initialized with
+geth init only, but the snapshot must belong to its network and its block must
+be canonical if already known.
+
+The node must not be running. Verify the snapshot against the headers of a
+trusted node before importing it.`,
+				},
+				{
+					Name:      "verify",
+					Usage:     "Verify a Parlia snapshot file against the headers",
+					ArgsUsage: "<file>",
+					Action:    verifyParliaSnapshot,
+					Flags: flags.Merge([]cli.Flag{
+						parliaVerifyFromStoredFlag,
+					}, utils.NetworkFlags, utils.DatabaseFlags),
+					Description: `
+geth parlia snapshot verify [--from-stored] <file>
+rebuilds the Parlia snapshot at the block of an export file from the headers of
+the database and checks that it matches the one of the file. The rebuild starts
+at the genesis, whose snapshot is derived from its header, so nothing but the
+headers is trusted.
+
+With --from-stored the rebuild starts at the closest checkpoint snapshot stored
+below the block instead, which is much faster far from the genesis. The stored
+snapshot is trusted as is though: it may have been imported, or derived from an
+imported one, without being verified. The base used is logged.
+
+The node must not be running. All the headers from the starting point up to the
+block of the snapshot must be available.`,
+				},
+			},
+		},
+	},
+}
+
+// parliaSnapshotFile is the portable encoding of a Parlia snapshot.
+type parliaSnapshotFile struct {
+	Genesis  common.Hash      `json:"genesis"`
+	Snapshot *parlia.Snapshot `json:"snapshot"`
+}
+
+// makeParliaChain opens the header chain of the datadir along with its Parlia
+// engine.
+func makeParliaChain(ctx *cli.Context, db ethdb.Database) (*core.HeaderChain, *parlia.Parlia, common.Hash, error) {
+	config, genesisHash, err := core.LoadChainConfig(db, utils.MakeGenesis(ctx))
+	if err != nil {
+		return nil, nil, common.Hash{}, err
+	}
+	if config.Parlia == nil {
+		return nil, nil, common.Hash{}, errors.New("the network is not based on Parlia")
+	}
+	engine, err := ethconfig.CreateConsensusEngine(config, db, nil, genesisHash)
+	if err != nil {
+		return nil, nil, common.Hash{}, err
+	}
+	chain, err := core.NewHeaderChain(db, config, engine, func() bool { return false })
+	if errors.Is(err, core.ErrNoGenesis) {
+		return nil, nil, common.Hash{}, errors.New("datadir not initialized, run geth init first")
+	}
+	if err != nil {
+		return nil, nil, common.Hash{}, err
+	}
+	return chain, engine.(*parlia.Parlia), genesisHash, nil
+}
+
+// readParliaSnapshot decodes a snapshot export file, checking that it belongs
+// to the network.
+func readParliaSnapshot(path string, genesis common.Hash) (*parlia.Snapshot, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file parliaSnapshotFile
+	if err := json.Unmarshal(blob, &file); err != nil {
+		return nil, fmt.Errorf("invalid snapshot file: %v", err)
+	}
+	if file.Snapshot == nil {
+		return nil, errors.New("snapshot file without snapshot")
+	}
+	if file.Genesis != genesis {
+		return nil, fmt.Errorf("snapshot of network with genesis %x, have %x", file.Genesis, genesis)
+	}
+	return file.Snapshot, nil
+}
+
+func exportParliaSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		return errors.New("expected the file to export to and optionally the block number")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false, false)
+	defer db.Close()
+
+	chain, engine, genesis, err := makeParliaChain(ctx, db)
+	if err != nil {
+		return err
+	}
+	number := parlia.LastSnapshotCheckpoint(chain.CurrentHeader().Number.Uint64())
+	if ctx.NArg() == 2 {
+		if number, err = strconv.ParseUint(ctx.Args().Get(1), 0, 64); err != nil {
+			return err
+		}
+		if !parlia.IsSnapshotCheckpoint(number) {
+			return fmt.Errorf("block #%d is not a checkpoint, nearest one below is #%d", number, parlia.LastSnapshotCheckpoint(number))
+		}
+	}
+	header := chain.GetHeaderByNumber(number)
+	if header == nil {
+		return fmt.Errorf("block #%d not found", number)
+	}
+	snap, err := engine.SnapshotAt(chain, header)
+	if err != nil {
+		return err
+	}
+	blob, err := json.MarshalIndent(&parliaSnapshotFile{Genesis: genesis, Snapshot: snap}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(ctx.Args().First(), blob, 0644); err != nil {
+		return err
+	}
+	log.Info("Exported Parlia snapshot", "number", snap.Number, "hash", snap.Hash, "validators", len(snap.Validators), "file", ctx.Args().First())
+	return nil
+}
+
+func importParliaSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("expected the file to import from")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false, false)
+	defer db.Close()
+
+	chain, engine, genesis, err := makeParliaChain(ctx, db)
+	if err != nil {
+		return err
+	}
+	snap, err := readParliaSnapshot(ctx.Args().First(), genesis)
+	if err != nil {
+		return err
+	}
+	if err := engine.ImportSnapshot(chain, snap); err != nil {
+		return err
+	}
+	log.Info("Imported Parlia snapshot", "number", snap.Number, "hash", snap.Hash, "validators", len(snap.Validators))
+	return nil
+}
+
+func verifyParliaSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("expected the file to verify")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false, false)
+	defer db.Close()
+
+	chain, engine, genesis, err := makeParliaChain(ctx, db)
+	if err != nil {
+		return err
+	}
+	snap, err := readParliaSnapshot(ctx.Args().First(), genesis)
+	if err != nil {
+		return err
+	}
+	fromStored := ctx.Bool(parliaVerifyFromStoredFlag.Name)
+	base, err := engine.VerifySnapshot(chain, snap, fromStored)
+	if err != nil {
+		return err
+	}
+	if base == 0 {
+		log.Info("Verified Parlia snapshot", "number", snap.Number, "hash", snap.Hash, "base", "genesis header")
+	} else {
+		log.Info("Verified Parlia snapshot", "number", snap.Number, "hash", snap.Hash, "base", fmt.Sprintf("stored snapshot #%d", base))
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	replayEndpointFlag = &cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "RPC endpoint of the node to replay the recording against",
+		Value: "http://127.0.0.1:8545",
+	}
+	replayPinFlag = &cli.BoolFlag{
+		Name:  "pin",
+		Usage: "Replace \"latest\" block parameters by the head block number at the time of recording, skipping the calls of other moving block tags",
+		Value: true,
+	}
+	replayMethodsFlag = &cli.StringFlag{
+		Name:  "methods",
+		Usage: "Comma separated list of the methods to replay, all if empty",
+	}
+	replayStateChangingFlag = &cli.BoolFlag{
+		Name:  "state-changing",
+		Usage: "Also replay the calls sending transactions or changing the node state (personal_*, admin_*, miner_*, eth_send*, ...)",
+	}
+
+	rpcReplayCommand = &cli.Command{
+		Name:      "rpc-replay",
+		Usage:     "Replay an RPC recording against a node and diff the responses",
+		ArgsUsage: "<recording> [<recording> ...]",
+		Action:    rpcReplay,
+		Flags:     []cli.Flag{replayEndpointFlag, replayPinFlag, replayMethodsFlag, replayStateChangingFlag},
+		Description: `
+geth rpc-replay [--endpoint <url>] <recording> [<recording> ...]
+
+Replays the calls of recordings written by a node running with --rpc.record
+against the given endpoint and reports every call whose response differs from
+the recorded one. Calls of methods taking a block parameter are deterministic
+if the parameter is given, which is made sure of for "latest" parameters by
+--pin. The block of "pending", "safe" and "finalized" parameters is not part of
+the recording, so --pin skips those calls. Subscriptions are not replayed.
+
+Calls sending transactions or changing the state of the node, such as
+eth_sendRawTransaction or the personal, admin and miner namespaces, are skipped
+unless --state-changing is given, so that replaying a recording never
+rebroadcasts its transactions.`,
+	}
+)
+
+// stateChangingNamespaces are the namespaces whose methods change the state of
+// the node or sign with its keys.
+var stateChangingNamespaces = []string{"personal_", "admin_", "miner_", "engine_"}
+
+// stateChangingMethods are the methods outside of those namespaces sending
+// transactions or changing the state of the node.
+var stateChangingMethods = map[string]bool{
+	"eth_sendRawTransaction":            true,
+	"eth_sendRawTransactionConditional": true,
+	"eth_sendTransaction":               true,
+	"eth_sign":                          true,
+	"eth_signTransaction":               true,
+	"eth_submitWork":                    true,
+	"eth_submitHashrate":                true,
+	"mev_sendBid":                       true,
+	"pair_reload":                       true,
+}
+
+// stateChanging reports whether replaying a call of the method may send a
+// transaction or change the state of the node.
+func stateChanging(method string) bool {
+	for _, namespace := range stateChangingNamespaces {
+		if strings.HasPrefix(method, namespace) {
+			return true
+		}
+	}
+	// Debug setters and profilers, e.g. debug_setHead or debug_startCPUProfile
+	if name, ok := strings.CutPrefix(method, "debug_"); ok {
+		for _, prefix := range []string{"set", "start", "stop", "write", "free", "chaindbCompact"} {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+	}
+	return stateChangingMethods[method]
+}
+
+// replayOptions select the calls of a recording that are replayed.
+type replayOptions struct {
+	pin           bool            // Pin "latest" block parameters, skip the other moving tags
+	methods       map[string]bool // Methods to replay, all if nil
+	stateChanging bool            // Replay the state changing methods too
+}
+
+// replayStats counts the calls of a replay.
+type replayStats struct {
+	total    int // Calls replayed
+	skipped  int // Calls not replayed
+	mismatch int // Calls whose response differs from the recorded one
+}
+
+// replayResponse is the part of a JSON-RPC response compared by the replay.
+type replayResponse struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *replayError    `json:"error,omitempty"`
+}
+
+type replayError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func rpcReplay(ctx *cli.Context) error {
+	if ctx.Args().Len() == 0 {
+		return errors.New("no recording given")
+	}
+	client, err := rpc.DialContext(ctx.Context, ctx.String(replayEndpointFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	opts := replayOptions{
+		pin:           ctx.Bool(replayPinFlag.Name),
+		stateChanging: ctx.Bool(replayStateChangingFlag.Name),
+	}
+	if list := ctx.String(replayMethodsFlag.Name); list != "" {
+		opts.methods = make(map[string]bool)
+		for _, method := range strings.Split(list, ",") {
+			opts.methods[strings.TrimSpace(method)] = true
+		}
+	}
+	var stats replayStats
+	for _, path := range ctx.Args().Slice() {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		err = replayRecording(ctx.Context, client, file, opts, os.Stdout, &stats)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	fmt.Printf("Replayed %d calls, skipped %d, %d responses differ\n", stats.total, stats.skipped, stats.mismatch)
+	if stats.mismatch > 0 {
+		return fmt.Errorf("%d of %d responses differ", stats.mismatch, stats.total)
+	}
+	return nil
+}
+
+// replayRecording replays the calls of a recording and writes the differing
+// responses to out.
+func replayRecording(ctx context.Context, client *rpc.Client, r io.Reader, opts replayOptions, out io.Writer, stats *replayStats) error {
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		blob, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(blob)) > 0 {
+			if err := replayEntry(ctx, client, line, blob, opts, out, stats); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// replayEntry replays a single call of a recording.
+func replayEntry(ctx context.Context, client *rpc.Client, line int, blob []byte, opts replayOptions, out io.Writer, stats *replayStats) error {
+	var (
+		entry rpc.RecordEntry
+		req   struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+	)
+	if err := json.Unmarshal(blob, &entry); err != nil {
+		return fmt.Errorf("line %d: %w", line, err)
+	}
+	if err := json.Unmarshal(entry.Request, &req); err != nil {
+		// Requests with named parameters can't be replayed by the client
+		stats.skipped++
+		return nil
+	}
+	if (opts.methods != nil && !opts.methods[req.Method]) || strings.HasSuffix(req.Method, "_subscribe") || strings.HasSuffix(req.Method, "_unsubscribe") {
+		stats.skipped++
+		return nil
+	}
+	if !opts.stateChanging && stateChanging(req.Method) {
+		stats.skipped++
+		return nil
+	}
+	if opts.pin {
+		if err := pinParams(req.Params, entry.Head); err != nil {
+			// The block the call was served at is unknown, the response can't match
+			stats.skipped++
+			return nil
+		}
+	}
+	args := make([]interface{}, len(req.Params))
+	for i, param := range req.Params {
+		args[i] = param
+	}
+	var (
+		have   replayResponse
+		result json.RawMessage
+	)
+	if err := client.CallContext(ctx, &result, req.Method, args...); err != nil {
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) {
+			return fmt.Errorf("line %d: %s: %w", line, req.Method, err)
+		}
+		have.Error = &replayError{Code: rpcErr.ErrorCode(), Message: rpcErr.Error()}
+		var dataErr rpc.DataError
+		if errors.As(err, &dataErr) {
+			have.Error.Data = dataErr.ErrorData()
+		}
+	} else {
+		have.Result = result
+	}
+	stats.total++
+
+	var want replayResponse
+	if err := json.Unmarshal(entry.Response, &want); err != nil {
+		return fmt.Errorf("line %d: %w", line, err)
+	}
+	if equalResponses(have, want) {
+		return nil
+	}
+	stats.mismatch++
+	haveJSON, _ := json.Marshal(have)
+	fmt.Fprintf(out, "line %d: %s at block %d differs\n  params:   %s\n  recorded: %s\n  replayed: %s\n",
+		line, req.Method, entry.Head, joinParams(req.Params), entry.Response, haveJSON)
+	return nil
+}
+
+// errUnpinnable is returned if a block parameter refers to a block that is not
+// part of the recording.
+var errUnpinnable = errors.New("block parameter can't be pinned")
+
+// pinnedFields are the fields of object parameters holding a block parameter,
+// e.g. the block number or hash object of EIP-1898 and the range of a filter.
+var pinnedFields = []string{"blockNumber", "fromBlock", "toBlock"}
+
+// pinParams replaces the "latest" block parameters by the given block number,
+// both as plain parameters and within object parameters. The "pending", "safe"
+// and "finalized" tags can't be pinned as the recording only knows the head.
+func pinParams(params []json.RawMessage, head uint64) error {
+	for i, param := range params {
+		pinned, err := pinParam(param, head)
+		if err != nil {
+			return err
+		}
+		params[i] = pinned
+	}
+	return nil
+}
+
+// pinParam pins a single parameter, returning it as is if it holds no block tag.
+func pinParam(param json.RawMessage, head uint64) (json.RawMessage, error) {
+	switch string(param) {
+	case `"latest"`:
+		return json.RawMessage(`"` + hexutil.EncodeUint64(head) + `"`), nil
+	case `"pending"`, `"safe"`, `"finalized"`:
+		return nil, fmt.Errorf("%w: %s", errUnpinnable, param)
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(param, &fields) != nil {
+		return param, nil
+	}
+	changed := false
+	for _, name := range pinnedFields {
+		field, ok := fields[name]
+		if !ok {
+			continue
+		}
+		pinned, err := pinParam(field, head)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(pinned, field) {
+			fields[name] = pinned
+			changed = true
+		}
+	}
+	if !changed {
+		return param, nil
+	}
+	return json.Marshal(fields)
+}
+
+// equalResponses reports whether two responses carry the same result or error.
+func equalResponses(a, b replayResponse) bool {
+	if (a.Error == nil) != (b.Error == nil) {
+		return false
+	}
+	if a.Error != nil {
+		ad, _ := json.Marshal(a.Error.Data)
+		bd, _ := json.Marshal(b.Error.Data)
+		return a.Error.Code == b.Error.Code && a.Error.Message == b.Error.Message && bytes.Equal(ad, bd)
+	}
+	var av, bv interface{}
+	if err := json.Unmarshal(nonNull(a.Result), &av); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(nonNull(b.Result), &bv); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+// nonNull returns a JSON null for empty results.
+func nonNull(blob json.RawMessage) json.RawMessage {
+	if len(blob) == 0 {
+		return json.RawMessage("null")
+	}
+	return blob
+}
+
+func joinParams(params []json.RawMessage) string {
+	parts := make([]string, len(params))
+	for i, param := range params {
+		parts[i] = string(param)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type replayService struct {
+	values map[string]int
+}
+
+func (s *replayService) Value(block string) (int, error) {
+	value, ok := s.values[block]
+	if !ok {
+		return 0, errors.New("unknown block")
+	}
+	return value, nil
+}
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestRPCReplay(t *testing.T) {
+	service := &replayService{values: map[string]int{"latest": 1, "0x5": 1, "0x6": 2}}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("test", service); err != nil {
+		t.Fatal(err)
+	}
+	recording := new(bytes.Buffer)
+	recorder := rpc.NewRecorder(nopCloser{recording})
+	recorder.SetHeadFunc(func() uint64 { return 5 })
+	server.SetRecorder(recorder)
+
+	recorded := rpc.DialInProc(server)
+	for _, block := range []string{"latest", "0x6", "0x7"} {
+		recorded.Call(nil, "test_value", block)
+	}
+	recorded.Close()
+
+	// Replay over a connection that is not recorded
+	server.SetRecorder(nil)
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	// Replaying against the same state matches, with the head pinned too
+	for _, pin := range []bool{false, true} {
+		var (
+			stats replayStats
+			out   = new(bytes.Buffer)
+		)
+		if err := replayRecording(context.Background(), client, bytes.NewReader(recording.Bytes()), replayOptions{pin: pin}, out, &stats); err != nil {
+			t.Fatal(err)
+		}
+		if stats.total != 3 || stats.mismatch != 0 {
+			t.Fatalf("pin %v: wrong stats %+v, output:\n%s", pin, stats, out)
+		}
+	}
+	// Changes after the recording are reported, but not for pinned calls
+	service.values["latest"] = 3
+	service.values["0x6"] = 4
+	for pin, want := range map[bool]int{false: 2, true: 1} {
+		var (
+			stats replayStats
+			out   = new(bytes.Buffer)
+		)
+		if err := replayRecording(context.Background(), client, bytes.NewReader(recording.Bytes()), replayOptions{pin: pin}, out, &stats); err != nil {
+			t.Fatal(err)
+		}
+		if stats.mismatch != want {
+			t.Fatalf("pin %v: wrong mismatch count %d, want %d, output:\n%s", pin, stats.mismatch, want, out)
+		}
+	}
+	// Method filters skip the other calls
+	var stats replayStats
+	if err := replayRecording(context.Background(), client, bytes.NewReader(recording.Bytes()), replayOptions{pin: true, methods: map[string]bool{"eth_call": true}}, new(bytes.Buffer), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.total != 0 || stats.skipped != 3 {
+		t.Fatalf("wrong stats %+v", stats)
+	}
+}
+
+func TestPinParams(t *testing.T) {
+	params := []json.RawMessage{
+		json.RawMessage(`{"to":"0x01"}`),
+		json.RawMessage(`"latest"`),
+		json.RawMessage(`{"blockNumber":"latest","requireCanonical":true}`),
+		json.RawMessage(`{"fromBlock":"0x1","toBlock":"latest"}`),
+		json.RawMessage(`"earliest"`),
+	}
+	if err := pinParams(params, 255); err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"to":"0x01"},"0xff",{"blockNumber":"0xff","requireCanonical":true},{"fromBlock":"0x1","toBlock":"0xff"},"earliest"]`
+	if have := joinParams(params); have != want {
+		t.Fatalf("wrong pinned params %s", have)
+	}
+	// Tags whose block is not recorded are rejected, plain or within objects
+	for _, param := range []string{`"pending"`, `"safe"`, `"finalized"`, `{"blockNumber":"safe"}`, `{"toBlock":"pending"}`} {
+		if err := pinParams([]json.RawMessage{json.RawMessage(param)}, 255); !errors.Is(err, errUnpinnable) {
+			t.Errorf("%s: expected unpinnable error, have %v", param, err)
+		}
+	}
+}
+
+func TestReplaySkipsStateChanging(t *testing.T) {
+	service := &replayService{values: map[string]int{"latest": 1}}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("test", service); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	var recording bytes.Buffer
+	for _, method := range []string{"eth_sendRawTransaction", "personal_unlockAccount", "admin_addPeer", "miner_start", "debug_setHead", "test_value"} {
+		request, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": []string{"latest"}})
+		entry, _ := json.Marshal(rpc.RecordEntry{Head: 5, Request: request, Response: json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":1}`)})
+		recording.Write(append(entry, '\n'))
+	}
+	var stats replayStats
+	if err := replayRecording(context.Background(), client, bytes.NewReader(recording.Bytes()), replayOptions{}, new(bytes.Buffer), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.total != 1 || stats.skipped != 5 || stats.mismatch != 0 {
+		t.Fatalf("wrong stats %+v", stats)
+	}
+	// Opting in replays them, the test node fails them as unknown methods
+	stats = replayStats{}
+	if err := replayRecording(context.Background(), client, bytes.NewReader(recording.Bytes()), replayOptions{stateChanging: true}, new(bytes.Buffer), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.total != 6 || stats.mismatch != 5 {
+		t.Fatalf("wrong opted in stats %+v", stats)
+	}
+}
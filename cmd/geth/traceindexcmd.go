@@ -0,0 +1,68 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/urfave/cli/v2"
+)
+
+var traceIndexCommand = &cli.Command{
+	Name:  "traceindex",
+	Usage: "A set of commands maintaining the trace index",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "backfill",
+			Usage:     "Index the traces of the blocks below the trace index",
+			ArgsUsage: "<from>",
+			Action:    backfillTraceIndex,
+			Flags: flags.Merge([]cli.Flag{
+				utils.TraceIndexHistoryFlag,
+			}, utils.NetworkFlags, utils.DatabaseFlags),
+			Description: `
+geth traceindex backfill <from>
+traces the canonical blocks from the given block number up to the trace index
+and indexes them, extending the index to the current head first. Blocks outside
+of the retained history (--history.traces) are not indexed.
+
+The node must not be running. Blocks are traced on top of the state of their
+parent, which is regenerated from a stored state at most 128 blocks below it if
+not available. Beyond that the backfill stops, so blocks far behind the head
+can only be backfilled on an archive node (--gcmode=archive).`,
+		},
+	},
+}
+
+func backfillTraceIndex(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("expected the block number to backfill from")
+	}
+	from, err := strconv.ParseUint(ctx.Args().First(), 0, 64)
+	if err != nil {
+		return err
+	}
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	_, eth := utils.RegisterEthService(stack, &cfg.Eth)
+	indexer := utils.RegisterTraceIndexService(stack, eth.APIBackend, &cfg.Eth.TraceIndex)
+	return indexer.Backfill(ctx.Context, from)
+}
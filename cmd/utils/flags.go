@@ -53,7 +53,9 @@ import (
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/tokenindex"
 	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/tracers/traceindex"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/remotedb"
 	"github.com/ethereum/go-ethereum/ethstats"
@@ -70,6 +72,10 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/paircache"
+	"github.com/ethereum/go-ethereum/paircache/pipeline"
+	"github.com/ethereum/go-ethereum/paircache/runstore"
+	"github.com/ethereum/go-ethereum/paircache/submit"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/triedb"
@@ -394,6 +400,32 @@ var (
 		Value:    ethconfig.Defaults.TransactionHistory,
 		Category: flags.StateCategory,
 	}
+	AddressIndexFlag = &cli.BoolFlag{
+		Name:     "index.addresses",
+		Usage:    "Index transactions by sender and recipient over the transaction history (enables eth_getTransactionsByAddress)",
+		Category: flags.StateCategory,
+	}
+	TraceIndexFlag = &cli.BoolFlag{
+		Name:     "traceindex",
+		Usage:    "Index the call traces and internal value transfers of imported blocks",
+		Category: flags.StateCategory,
+	}
+	TraceIndexHistoryFlag = &cli.Uint64Flag{
+		Name:     "history.traces",
+		Usage:    "Number of recent blocks to maintain the trace index for (0 = entire chain)",
+		Value:    ethconfig.Defaults.TraceIndex.History,
+		Category: flags.StateCategory,
+	}
+	TokenIndexFlag = &cli.BoolFlag{
+		Name:     "tokenindex",
+		Usage:    "Index the ERC20/BEP20 token transfers and balances of imported blocks",
+		Category: flags.StateCategory,
+	}
+	TokenIndexFromFlag = &cli.Uint64Flag{
+		Name:     "tokenindex.from",
+		Usage:    "Block to start the token index at, balances only sum up the transfers from there on (0 = head at the time the index is first enabled)",
+		Category: flags.StateCategory,
+	}
 	// Transaction pool settings
 	TxPoolLocalsFlag = &cli.StringFlag{
 		Name:     "txpool.locals",
@@ -833,6 +865,64 @@ var (
 		Value:    node.DefaultConfig.BatchResponseMaxSize,
 		Category: flags.APICategory,
 	}
+	RPCRecordFlag = &cli.StringFlag{
+		Name:     "rpc.record",
+		Usage:    "File to record the calls served over HTTP and WebSocket and their responses to (JSONL)",
+		Category: flags.APICategory,
+	}
+	RPCRecordMaxSizeFlag = &cli.IntFlag{
+		Name:     "rpc.record.maxsize",
+		Usage:    "Size in megabytes the RPC recording is rotated at",
+		Value:    node.DefaultConfig.RPCRecordMaxSize,
+		Category: flags.APICategory,
+	}
+	RPCRecordMaxBackupsFlag = &cli.IntFlag{
+		Name:     "rpc.record.maxbackups",
+		Usage:    "Maximum number of rotated RPC recordings to keep (0 = keep all)",
+		Value:    node.DefaultConfig.RPCRecordMaxBackups,
+		Category: flags.APICategory,
+	}
+	RPCRecordMethodsFlag = &cli.StringFlag{
+		Name:     "rpc.record.methods",
+		Usage:    "Comma separated list of the methods or namespaces to record (default = all, personal and signing methods are never recorded)",
+		Category: flags.APICategory,
+	}
+	RPCAPIKeysFlag = &cli.StringFlag{
+		Name:     "rpc.apikeys",
+		Usage:    "File with the API keys accepted over HTTP and WebSocket, one <client>=<key> per line",
+		Category: flags.APICategory,
+	}
+	RPCAllowAnonymousFlag = &cli.BoolFlag{
+		Name:     "rpc.allowanonymous",
+		Usage:    "Serve clients without API key if API keys are configured",
+		Category: flags.APICategory,
+	}
+	RPCRateLimitFlag = &cli.Float64Flag{
+		Name:     "rpc.ratelimit",
+		Usage:    "Requests per second each HTTP and WebSocket client may send (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	RPCRateLimitBurstFlag = &cli.IntFlag{
+		Name:     "rpc.ratelimit.burst",
+		Usage:    "Maximum number of requests a client may send at once (0 = rate limit rounded up)",
+		Category: flags.APICategory,
+	}
+	RPCMethodRateLimitsFlag = &cli.StringFlag{
+		Name:     "rpc.methodratelimits",
+		Usage:    "Comma separated list of per client request rates by method or namespace (e.g. eth_getLogs=10,debug=1)",
+		Category: flags.APICategory,
+	}
+	RPCConcurrencyFlag = &cli.StringFlag{
+		Name:     "rpc.concurrency",
+		Usage:    "Comma separated list of the maximum number of requests in progress by method or namespace (e.g. debug=2)",
+		Category: flags.APICategory,
+	}
+	RPCConcurrencyTimeoutFlag = &cli.DurationFlag{
+		Name:     "rpc.concurrency.timeout",
+		Usage:    "Maximum time a request waits for a free concurrency slot",
+		Value:    5 * time.Second,
+		Category: flags.APICategory,
+	}
 	EnablePersonal = &cli.BoolFlag{
 		Name:     "rpc.enabledeprecatedpersonal",
 		Usage:    "Enables the (deprecated) personal namespace",
@@ -1105,6 +1195,16 @@ Please note that --` + MetricsHTTPFlag.Name + ` must be set to start the server.
 		Usage:    "Enable double sign monitor to check whether any validator signs multiple blocks",
 		Category: flags.MinerCategory,
 	}
+	DoubleSignReporterFlag = &cli.StringFlag{
+		Name:     "monitor.doublesign.reporter",
+		Usage:    "Unlocked account submitting the evidence found by the double sign monitor to the slash contract",
+		Category: flags.MinerCategory,
+	}
+	DoubleSignReportDryRunFlag = &cli.BoolFlag{
+		Name:     "monitor.doublesign.dryrun",
+		Usage:    "Only estimate and log the double sign evidence transactions instead of sending them",
+		Category: flags.MinerCategory,
+	}
 
 	VotingEnabledFlag = &cli.BoolFlag{
 		Name:     "vote",
@@ -1123,6 +1223,16 @@ Please note that --` + MetricsHTTPFlag.Name + ` must be set to start the server.
 		Usage:    "Enable malicious vote monitor to check whether any validator violates the voting rules of fast finality",
 		Category: flags.FastFinalityCategory,
 	}
+	MaliciousVoteReporterFlag = &cli.StringFlag{
+		Name:     "monitor.maliciousvote.reporter",
+		Usage:    "Unlocked account submitting the evidence found by the malicious vote monitor to the slash contract",
+		Category: flags.FastFinalityCategory,
+	}
+	MaliciousVoteReportDryRunFlag = &cli.BoolFlag{
+		Name:     "monitor.maliciousvote.dryrun",
+		Usage:    "Only estimate and log the malicious vote evidence transactions instead of sending them",
+		Category: flags.FastFinalityCategory,
+	}
 
 	BLSPasswordFileFlag = &cli.StringFlag{
 		Name:     "blspassword",
@@ -1168,6 +1278,136 @@ Please note that --` + MetricsHTTPFlag.Name + ` must be set to start the server.
 		Value:    fakebeacon.DefaultPort,
 		Category: flags.APICategory,
 	}
+
+	// Arbitrage pair cache settings
+	PairSourceFlag = &cli.StringFlag{
+		Name:     "pair.source",
+		Usage:    "Triangle source of the arbitrage pair cache (mysql, file, memory), the pair cache is disabled if empty",
+		Category: flags.ArbitrageCategory,
+	}
+	PairMySQLDSNFlag = &cli.StringFlag{
+		Name:     "pair.mysql.dsn",
+		Usage:    "MySQL data source name of the mysql triangle source (user:password@tcp(host:port)/dbname)",
+		Category: flags.ArbitrageCategory,
+	}
+	PairTriangleFileFlag = &cli.StringFlag{
+		Name:     "pair.triangles",
+		Usage:    "JSON or CSV triangle file of the file triangle source",
+		Category: flags.ArbitrageCategory,
+	}
+	PairTopicFileFlag = &cli.StringFlag{
+		Name:     "pair.topics",
+		Usage:    "JSON file mapping log topics to pair operations",
+		Category: flags.ArbitrageCategory,
+	}
+	PairTriangleRefreshFlag = &cli.DurationFlag{
+		Name:     "pair.triangles.refresh",
+		Usage:    "Time interval to reload triangles from the triangle source",
+		Value:    paircache.DefaultConfig.TriangleRefresh,
+		Category: flags.ArbitrageCategory,
+	}
+	PairTopicRefreshFlag = &cli.DurationFlag{
+		Name:     "pair.topics.refresh",
+		Usage:    "Time interval to reload topics from the triangle source",
+		Value:    paircache.DefaultConfig.TopicRefresh,
+		Category: flags.ArbitrageCategory,
+	}
+	PairQueueSizeFlag = &cli.IntFlag{
+		Name:     "pair.queue",
+		Usage:    "Maximum number of blocks waiting for arbitrage evaluation",
+		Value:    paircache.DefaultConfig.QueueSize,
+		Category: flags.ArbitrageCategory,
+	}
+	PairMaxTrianglesFlag = &cli.IntFlag{
+		Name:     "pair.maxtriangles",
+		Usage:    "Maximum number of routes evaluated per block on top of the time budget (0 = budget only)",
+		Value:    paircache.DefaultConfig.MaxTriangles,
+		Category: flags.ArbitrageCategory,
+	}
+	PairBudgetFlag = &cli.DurationFlag{
+		Name:     "pair.budget",
+		Usage:    "Time budget of the arbitrage evaluation of a block, the most promising routes that fit are evaluated and cut off once it expires or the next block is due",
+		Value:    paircache.DefaultConfig.Budget,
+		Category: flags.ArbitrageCategory,
+	}
+	PairMempoolFlag = &cli.BoolFlag{
+		Name:     "pair.mempool",
+		Usage:    "Simulate pending transactions and evaluate backrun arbitrage behind them",
+		Category: flags.ArbitrageCategory,
+	}
+	PairMempoolWorkersFlag = &cli.IntFlag{
+		Name:     "pair.mempool.workers",
+		Usage:    "Number of pending transactions simulated and evaluated concurrently",
+		Value:    paircache.DefaultConfig.MempoolWorkers,
+		Category: flags.ArbitrageCategory,
+	}
+	PairMempoolBudgetFlag = &cli.DurationFlag{
+		Name:     "pair.mempool.budget",
+		Usage:    "Time budget of the backrun arbitrage evaluation behind a single pending transaction",
+		Value:    paircache.DefaultConfig.MempoolBudget,
+		Category: flags.ArbitrageCategory,
+	}
+	PairRunHistoryFlag = &cli.Uint64Flag{
+		Name:     "pair.runs.history",
+		Usage:    "Number of recent blocks to keep arbitrage evaluation results for (0 = entire chain)",
+		Value:    paircache.DefaultConfig.RunHistory,
+		Category: flags.ArbitrageCategory,
+	}
+	PairDiscoverHopsFlag = &cli.IntFlag{
+		Name:     "pair.discover.hops",
+		Usage:    "Maximum number of hops of the routes discovered from the pair graph (2-4), discovery is disabled if 0",
+		Value:    paircache.DefaultConfig.DiscoverHops,
+		Category: flags.ArbitrageCategory,
+	}
+	PairDiscoverBasesFlag = &cli.StringFlag{
+		Name:     "pair.discover.bases",
+		Usage:    "Comma separated tokens the discovered routes start and end with (default WBNB)",
+		Category: flags.ArbitrageCategory,
+	}
+	PairDiscoverLimitFlag = &cli.IntFlag{
+		Name:     "pair.discover.limit",
+		Usage:    "Maximum number of routes discovered from the pair graph",
+		Value:    paircache.DefaultConfig.DiscoverLimit,
+		Category: flags.ArbitrageCategory,
+	}
+	PairSubmitModeFlag = &cli.StringFlag{
+		Name:     "pair.submit",
+		Usage:    "Route of the arbitrage bundles (local, builder, private), nothing is submitted if empty",
+		Category: flags.ArbitrageCategory,
+	}
+	PairSubmitAccountFlag = &cli.StringFlag{
+		Name:     "pair.submit.account",
+		Usage:    "Keystore account signing the arbitrage bundles, it must be unlocked",
+		Category: flags.ArbitrageCategory,
+	}
+	PairSubmitEndpointsFlag = &cli.StringFlag{
+		Name:     "pair.submit.endpoints",
+		Usage:    "Comma separated RPC endpoints of the builder and private submission routes",
+		Category: flags.ArbitrageCategory,
+	}
+	PairSubmitGasPriceFlag = &flags.BigFlag{
+		Name:     "pair.submit.gasprice",
+		Usage:    "Gas price of the arbitrage transactions",
+		Value:    submit.DefaultConfig.GasPrice,
+		Category: flags.ArbitrageCategory,
+	}
+	PairSubmitGasMarginFlag = &cli.Uint64Flag{
+		Name:     "pair.submit.gasmargin",
+		Usage:    "Extra gas limit on top of the estimate of the arbitrage transactions, in percent",
+		Value:    submit.DefaultConfig.GasMargin,
+		Category: flags.ArbitrageCategory,
+	}
+	PairSubmitDryRunFlag = &cli.BoolFlag{
+		Name:     "pair.submit.dryrun",
+		Usage:    "Build, sign and journal the arbitrage bundles without sending them",
+		Category: flags.ArbitrageCategory,
+	}
+	PairSubmitJournalFlag = &cli.StringFlag{
+		Name:     "pair.submit.journal",
+		Usage:    "Journal file recording the arbitrage bundle of every block (relative to datadir)",
+		Value:    submit.DefaultConfig.Journal,
+		Category: flags.ArbitrageCategory,
+	}
 )
 
 var (
@@ -1380,6 +1620,107 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(BatchResponseMaxSize.Name) {
 		cfg.BatchResponseMaxSize = ctx.Int(BatchResponseMaxSize.Name)
 	}
+
+	if ctx.IsSet(RPCRecordFlag.Name) {
+		cfg.RPCRecordFile = ctx.String(RPCRecordFlag.Name)
+	}
+	if ctx.IsSet(RPCRecordMaxSizeFlag.Name) {
+		cfg.RPCRecordMaxSize = ctx.Int(RPCRecordMaxSizeFlag.Name)
+	}
+	if ctx.IsSet(RPCRecordMaxBackupsFlag.Name) {
+		cfg.RPCRecordMaxBackups = ctx.Int(RPCRecordMaxBackupsFlag.Name)
+	}
+	if ctx.IsSet(RPCRecordMethodsFlag.Name) {
+		cfg.RPCRecordMethods = SplitAndTrim(ctx.String(RPCRecordMethodsFlag.Name))
+	}
+}
+
+// setRPCLimits applies the API key and request limit flags to the RPC limits
+// of the node, on top of those loaded from the config file.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	limitFlags := []cli.Flag{
+		RPCAPIKeysFlag, RPCAllowAnonymousFlag, RPCRateLimitFlag, RPCRateLimitBurstFlag,
+		RPCMethodRateLimitsFlag, RPCConcurrencyFlag, RPCConcurrencyTimeoutFlag,
+	}
+	set := false
+	for _, flag := range limitFlags {
+		set = set || ctx.IsSet(flag.Names()[0])
+	}
+	if !set {
+		return
+	}
+	if cfg.RPCLimits == nil {
+		cfg.RPCLimits = new(rpc.LimitConfig)
+	}
+	limits := cfg.RPCLimits
+
+	if ctx.IsSet(RPCAPIKeysFlag.Name) {
+		keys, err := readAPIKeys(ctx.String(RPCAPIKeysFlag.Name))
+		if err != nil {
+			Fatalf("Failed to read API keys: %v", err)
+		}
+		limits.APIKeys = keys
+	}
+	if ctx.IsSet(RPCAllowAnonymousFlag.Name) {
+		limits.AllowAnonymous = ctx.Bool(RPCAllowAnonymousFlag.Name)
+	}
+	if ctx.IsSet(RPCRateLimitFlag.Name) {
+		limits.Rate.Rate = ctx.Float64(RPCRateLimitFlag.Name)
+	}
+	if ctx.IsSet(RPCRateLimitBurstFlag.Name) {
+		limits.Rate.Burst = ctx.Int(RPCRateLimitBurstFlag.Name)
+	}
+	if ctx.IsSet(RPCMethodRateLimitsFlag.Name) {
+		limits.MethodRates = make(map[string]rpc.RateLimit)
+		for _, entry := range SplitAndTrim(ctx.String(RPCMethodRateLimitsFlag.Name)) {
+			name, value, ok := strings.Cut(entry, "=")
+			r, err := strconv.ParseFloat(value, 64)
+			if !ok || err != nil || r < 0 {
+				Fatalf("Invalid --%s entry %q, want <method>=<rate>", RPCMethodRateLimitsFlag.Name, entry)
+			}
+			limits.MethodRates[strings.TrimSpace(name)] = rpc.RateLimit{Rate: r}
+		}
+	}
+	if ctx.IsSet(RPCConcurrencyFlag.Name) {
+		limits.Concurrency = make(map[string]int)
+		for _, entry := range SplitAndTrim(ctx.String(RPCConcurrencyFlag.Name)) {
+			name, value, ok := strings.Cut(entry, "=")
+			n, err := strconv.Atoi(value)
+			if !ok || err != nil || n <= 0 {
+				Fatalf("Invalid --%s entry %q, want <method>=<count>", RPCConcurrencyFlag.Name, entry)
+			}
+			limits.Concurrency[strings.TrimSpace(name)] = n
+		}
+	}
+	if ctx.IsSet(RPCConcurrencyTimeoutFlag.Name) {
+		limits.ConcurrencyTimeout = ctx.Duration(RPCConcurrencyTimeoutFlag.Name)
+	}
+}
+
+// readAPIKeys reads a file of <client>=<key> lines into a key to client name
+// map. Blank lines and lines starting with # are skipped.
+func readAPIKeys(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, key, ok := strings.Cut(line, "=")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !ok || name == "" || key == "" {
+			return nil, fmt.Errorf("line %d: want <client>=<key>", i+1)
+		}
+		if _, dup := keys[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key", i+1)
+		}
+		keys[key] = name
+	}
+	return keys, nil
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -1616,6 +1957,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	SetP2PConfig(ctx, &cfg.P2P)
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
@@ -2003,6 +2345,26 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Warn("The flag --txlookuplimit is deprecated and will be removed, please use --history.transactions")
 		cfg.TransactionHistory = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(DoubleSignReporterFlag.Name) {
+		addr := ctx.String(DoubleSignReporterFlag.Name)
+		if !common.IsHexAddress(addr) {
+			Fatalf("-%s: invalid reporter address %q", DoubleSignReporterFlag.Name, addr)
+		}
+		cfg.DoubleSignReporter = common.HexToAddress(addr)
+	}
+	if ctx.IsSet(DoubleSignReportDryRunFlag.Name) {
+		cfg.DoubleSignReportDryRun = ctx.Bool(DoubleSignReportDryRunFlag.Name)
+	}
+	if ctx.IsSet(MaliciousVoteReporterFlag.Name) {
+		addr := ctx.String(MaliciousVoteReporterFlag.Name)
+		if !common.IsHexAddress(addr) {
+			Fatalf("-%s: invalid reporter address %q", MaliciousVoteReporterFlag.Name, addr)
+		}
+		cfg.MaliciousVoteReporter = common.HexToAddress(addr)
+	}
+	if ctx.IsSet(MaliciousVoteReportDryRunFlag.Name) {
+		cfg.MaliciousVoteReportDryRun = ctx.Bool(MaliciousVoteReportDryRunFlag.Name)
+	}
 	if ctx.IsSet(PathDBSyncFlag.Name) {
 		cfg.PathSyncFlush = true
 	}
@@ -2254,6 +2616,89 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 	}
 }
 
+// RegisterPairService configures the arbitrage pair cache together with the
+// pipeline evaluating the routes touched by new blocks, and optionally by pending
+// transactions, and adds them to the node.
+func RegisterPairService(stack *node.Node, backend ethapi.Backend, cfg *paircache.Config) {
+	service, err := paircache.NewService(cfg)
+	if err != nil {
+		Fatalf("Failed to register the pair cache service: %v", err)
+	}
+	stack.RegisterAPIs(service.APIs())
+	stack.RegisterLifecycle(service)
+
+	var (
+		submitter        pipeline.Submitter
+		backrunSubmitter pipeline.BackrunSubmitter
+	)
+	if cfg.Submit.Enabled() {
+		var ks *keystore.KeyStore
+		if keystores := stack.AccountManager().Backends(keystore.KeyStoreType); len(keystores) > 0 {
+			ks = keystores[0].(*keystore.KeyStore)
+		}
+		if ks == nil || !ks.HasAddress(cfg.Submit.Account) {
+			Fatalf("Arbitrage submission account %v not found in the keystore", cfg.Submit.Account)
+		}
+		submitCfg := cfg.Submit
+		if submitCfg.Journal != "" {
+			submitCfg.Journal = stack.ResolvePath(submitCfg.Journal)
+		}
+		s, err := submit.New(submitCfg, backend, ethapi.NewMevAPI(backend), ks)
+		if err != nil {
+			Fatalf("Failed to register the arbitrage submission service: %v", err)
+		}
+		stack.RegisterLifecycle(s)
+		submitter, backrunSubmitter = s, s
+	}
+	db, err := stack.OpenDatabase("pairruns", 16, 16, "pair/runs/", false)
+	if err != nil {
+		Fatalf("Failed to open the arbitrage run database: %v", err)
+	}
+	runs := runstore.New(db, cfg.RunHistory)
+	stack.RegisterAPIs(runstore.APIs(runs))
+
+	evaluator := pipeline.New(backend, ethapi.NewBlockChainAPI(backend), submitter, runs, paircache.GetPairControl(), pipeline.Config{
+		QueueSize:    cfg.QueueSize,
+		MaxTriangles: cfg.MaxTriangles,
+		Budget:       cfg.Budget,
+	})
+	stack.RegisterLifecycle(evaluator)
+
+	if cfg.Mempool {
+		mempool := pipeline.NewMempool(backend, ethapi.NewBlockChainAPI(backend), backrunSubmitter, paircache.GetPairControl(), pipeline.MempoolConfig{
+			Workers: cfg.MempoolWorkers,
+			Budget:  cfg.MempoolBudget,
+		})
+		stack.RegisterLifecycle(mempool)
+	}
+}
+
+// RegisterTraceIndexService configures the trace index and adds its indexer and
+// query API to the node.
+func RegisterTraceIndexService(stack *node.Node, backend traceindex.Backend, cfg *traceindex.Config) *traceindex.Indexer {
+	db, err := stack.OpenDatabase("traceindex", 16, 16, "traceindex/", false)
+	if err != nil {
+		Fatalf("Failed to open the trace index database: %v", err)
+	}
+	indexer := traceindex.NewIndexer(backend, traceindex.NewStore(db), cfg.History)
+	stack.RegisterAPIs(traceindex.APIs(indexer.Store()))
+	stack.RegisterLifecycle(indexer)
+	return indexer
+}
+
+// RegisterTokenIndexService configures the token index and adds its indexer and
+// query API to the node.
+func RegisterTokenIndexService(stack *node.Node, backend tokenindex.Backend, cfg *tokenindex.Config) *tokenindex.Indexer {
+	db, err := stack.OpenDatabase("tokenindex", 16, 16, "tokenindex/", false)
+	if err != nil {
+		Fatalf("Failed to open the token index database: %v", err)
+	}
+	indexer := tokenindex.NewIndexer(backend, tokenindex.NewStore(db), cfg.From)
+	stack.RegisterAPIs(tokenindex.APIs(indexer.Store()))
+	stack.RegisterLifecycle(indexer)
+	return indexer
+}
+
 // RegisterGraphQLService adds the GraphQL API to the node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cfg *node.Config) {
 	err := graphql.New(stack, backend, filterSystem, cfg.GraphQLCors, cfg.GraphQLVirtualHosts)
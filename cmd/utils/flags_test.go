@@ -18,6 +18,8 @@
 package utils
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -65,3 +67,27 @@ func Test_SplitTagsFlag(t *testing.T) {
 		})
 	}
 }
+
+func TestReadAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "apikeys")
+	if err := os.WriteFile(path, []byte("# clients\nalice = key1\n\nbob=key2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := readAPIKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"key1": "alice", "key2": "bob"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("readAPIKeys() = %v, want %v", keys, want)
+	}
+	for _, bad := range []string{"alice\n", "alice=\n", "alice=key1\nbob=key1\n"} {
+		if err := os.WriteFile(path, []byte(bad), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readAPIKeys(path); err == nil {
+			t.Errorf("readAPIKeys(%q) succeeded, want error", bad)
+		}
+	}
+}
@@ -114,6 +114,40 @@ func (api *API) GetFinalizedNumber(number *rpc.BlockNumber) (uint64, error) {
 	return snap.Attestation.SourceNumber, nil
 }
 
+// GetValidatorStats returns the in-turn and out-of-turn block production,
+// missed slots, back offs and recently signed violations of the validators
+// within the block range, which may span at most as many blocks as the block
+// records kept in memory.
+func (api *API) GetValidatorStats(fromBlock, toBlock *rpc.BlockNumber) (*ValidatorStatsResult, error) {
+	from, to := api.getHeader(fromBlock), api.getHeader(toBlock)
+	if from == nil || to == nil {
+		return nil, errUnknownBlock
+	}
+	return api.parlia.validatorStats(api.chain, from.Number.Uint64(), to.Number.Uint64())
+}
+
+// GetVoteAttestation returns the decoded vote attestation of a block, with the
+// validators whose votes were aggregated. It returns nil if the block carries
+// no attestation.
+func (api *API) GetVoteAttestation(number *rpc.BlockNumber) (*VoteAttestationInfo, error) {
+	header := api.getHeader(number)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.parlia.voteAttestation(api.chain, header)
+}
+
+// GetVoteStats returns the vote inclusion rates of the validators and the
+// justification and finalization lag within the block range, which may span at
+// most as many blocks as the vote records kept in memory.
+func (api *API) GetVoteStats(fromBlock, toBlock *rpc.BlockNumber) (*VoteStatsResult, error) {
+	from, to := api.getHeader(fromBlock), api.getHeader(toBlock)
+	if from == nil || to == nil {
+		return nil, errUnknownBlock
+	}
+	return api.parlia.voteStats(api.chain, from.Number.Uint64(), to.Number.Uint64())
+}
+
 func (api *API) getHeader(number *rpc.BlockNumber) (header *types.Header) {
 	currentHeader := api.chain.CurrentHeader()
 
@@ -70,12 +70,12 @@ func (p *Parlia) getTurnLengthFromContract(header *types.Header) (turnLength *bi
 		Gas:  &gas,
 		To:   &toAddress,
 		Data: &msgData,
-	}, &blockNr, nil, nil)
+	}, &blockNr, nil, nil, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := p.validatorSetABI.UnpackIntoInterface(&turnLength, method, result); err != nil {
+	if err := p.validatorSetABI.UnpackIntoInterface(&turnLength, method, result.(hexutil.Bytes)); err != nil {
 		return nil, err
 	}
 
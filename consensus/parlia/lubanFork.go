@@ -38,12 +38,12 @@ func (p *Parlia) getCurrentValidatorsBeforeLuban(blockHash common.Hash, blockNum
 		Gas:  &gas,
 		To:   &toAddress,
 		Data: &msgData,
-	}, &blockNr, nil, nil)
+	}, &blockNr, nil, nil, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	var valSet []common.Address
-	err = p.validatorSetABIBeforeLuban.UnpackIntoInterface(&valSet, method, result)
+	err = p.validatorSetABIBeforeLuban.UnpackIntoInterface(&valSet, method, result.(hexutil.Bytes))
 	return valSet, err
 }
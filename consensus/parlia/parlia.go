@@ -249,6 +249,8 @@ type Parlia struct {
 	slashABI                   abi.ABI
 	stakeHubABI                abi.ABI
 
+	tracker *validatorTracker // Block production of the validators
+
 	// The fields below are for testing only
 	fakeDiff bool // Skip difficulty verifications
 }
@@ -312,6 +314,7 @@ func New(
 		slashABI:                   sABI,
 		stakeHubABI:                stABI,
 		signer:                     types.LatestSigner(chainConfig),
+		tracker:                    newValidatorTracker(),
 	}
 
 	return c
@@ -895,6 +898,7 @@ func (p *Parlia) verifySeal(chain consensus.ChainHeaderReader, header *types.Hea
 	}
 
 	if snap.SignRecently(signer) {
+		p.tracker.logRecentlySigned(header, signer)
 		return errRecentlySigned
 	}
 
@@ -1784,14 +1788,14 @@ func (p *Parlia) getCurrentValidators(blockHash common.Hash, blockNum *big.Int)
 		Gas:  &gas,
 		To:   &toAddress,
 		Data: &msgData,
-	}, &blockNr, nil, nil)
+	}, &blockNr, nil, nil, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var valSet []common.Address
 	var voteAddrSet []types.BLSPublicKey
-	if err := p.validatorSetABI.UnpackIntoInterface(&[]interface{}{&valSet, &voteAddrSet}, method, result); err != nil {
+	if err := p.validatorSetABI.UnpackIntoInterface(&[]interface{}{&valSet, &voteAddrSet}, method, result.(hexutil.Bytes)); err != nil {
 		return nil, nil, err
 	}
 
@@ -1851,6 +1855,43 @@ func (p *Parlia) slash(spoiledVal common.Address, state *state.StateDB, header *
 	return p.applyTransaction(msg, state, header, chain, txs, receipts, receivedTxs, usedGas, mining)
 }
 
+// DoubleSignEvidenceData packs the call of the slash contract submitting the
+// proof of a validator sealing both of the given RLP encoded headers.
+func (p *Parlia) DoubleSignEvidenceData(header1, header2 []byte) ([]byte, error) {
+	return p.slashABI.Pack("submitDoubleSignEvidence", header1, header2)
+}
+
+// FinalityViolationEvidenceData packs the call of the slash contract submitting
+// the proof of a validator casting both of the given conflicting votes.
+func (p *Parlia) FinalityViolationEvidenceData(voteA, voteB *types.VoteEnvelope) ([]byte, error) {
+	type voteData struct {
+		SrcNum  *big.Int
+		SrcHash [32]byte
+		TarNum  *big.Int
+		TarHash [32]byte
+		Sig     []byte
+	}
+	convert := func(vote *types.VoteEnvelope) voteData {
+		return voteData{
+			SrcNum:  new(big.Int).SetUint64(vote.Data.SourceNumber),
+			SrcHash: vote.Data.SourceHash,
+			TarNum:  new(big.Int).SetUint64(vote.Data.TargetNumber),
+			TarHash: vote.Data.TargetHash,
+			Sig:     vote.Signature[:],
+		}
+	}
+	evidence := struct {
+		VoteA    voteData
+		VoteB    voteData
+		VoteAddr []byte
+	}{
+		VoteA:    convert(voteA),
+		VoteB:    convert(voteB),
+		VoteAddr: voteA.VoteAddress[:],
+	}
+	return p.slashABI.Pack("submitFinalityViolationEvidence", evidence)
+}
+
 // init contract
 func (p *Parlia) initContract(state *state.StateDB, header *types.Header, chain core.ChainContext,
 	txs *[]*types.Transaction, receipts *[]*types.Receipt, receivedTxs *[]*types.Transaction, usedGas *uint64, mining bool) error {
@@ -1,13 +1,16 @@
 package parlia
 
 import (
+	"bytes"
 	"crypto/rand"
 	"fmt"
 	mrand "math/rand"
+	"strings"
 	"testing"
 
 	"golang.org/x/crypto/sha3"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	cmath "github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -615,3 +618,34 @@ func TestSimulateP2P(t *testing.T) {
 		}
 	}
 }
+
+func TestEvidenceData(t *testing.T) {
+	sABI, err := abi.JSON(strings.NewReader(slashABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Parlia{slashABI: sABI}
+
+	data, err := p.DoubleSignEvidenceData([]byte{1}, []byte{2})
+	if err != nil {
+		t.Fatalf("failed to pack double sign evidence: %v", err)
+	}
+	args, err := sABI.Methods["submitDoubleSignEvidence"].Inputs.Unpack(data[4:])
+	if err != nil {
+		t.Fatalf("failed to unpack double sign evidence: %v", err)
+	}
+	if !bytes.Equal(args[0].([]byte), []byte{1}) || !bytes.Equal(args[1].([]byte), []byte{2}) {
+		t.Fatalf("double sign evidence mismatch: %v", args)
+	}
+
+	voteA := &types.VoteEnvelope{Data: &types.VoteData{SourceNumber: 1, TargetNumber: 3, TargetHash: common.Hash{3}}}
+	voteB := &types.VoteEnvelope{Data: &types.VoteData{SourceNumber: 1, TargetNumber: 3, TargetHash: common.Hash{4}}}
+	voteA.VoteAddress[0], voteB.VoteAddress[0] = 5, 5
+	data, err = p.FinalityViolationEvidenceData(voteA, voteB)
+	if err != nil {
+		t.Fatalf("failed to pack finality violation evidence: %v", err)
+	}
+	if _, err := sABI.Methods["submitFinalityViolationEvidence"].Inputs.Unpack(data[4:]); err != nil {
+		t.Fatalf("failed to unpack finality violation evidence: %v", err)
+	}
+}
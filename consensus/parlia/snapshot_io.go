@@ -0,0 +1,172 @@
+package parlia
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// IsSnapshotCheckpoint reports whether snapshots of the given block are loaded
+// from the database instead of being rebuilt from the headers.
+func IsSnapshotCheckpoint(number uint64) bool {
+	return number%checkpointInterval == 0
+}
+
+// LastSnapshotCheckpoint returns the highest checkpoint block not above the
+// given block.
+func LastSnapshotCheckpoint(number uint64) uint64 {
+	return number - number%checkpointInterval
+}
+
+// SnapshotAt returns the snapshot at a block of the chain.
+func (p *Parlia) SnapshotAt(chain consensus.ChainHeaderReader, header *types.Header) (*Snapshot, error) {
+	return p.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+}
+
+// ImportSnapshot stores the snapshot of a checkpoint block, so the snapshots of
+// the following blocks are built from it instead of walking the headers back.
+// The block must be canonical if it is already known.
+func (p *Parlia) ImportSnapshot(chain consensus.ChainHeaderReader, snap *Snapshot) error {
+	if !IsSnapshotCheckpoint(snap.Number) {
+		return fmt.Errorf("snapshot #%d is not at a checkpoint, only snapshots of every %d blocks are loaded", snap.Number, checkpointInterval)
+	}
+	if len(snap.Validators) == 0 {
+		return errors.New("snapshot has no validators")
+	}
+	if header := chain.GetHeaderByNumber(snap.Number); header == nil {
+		log.Warn("Snapshot block not in the database yet", "number", snap.Number, "hash", snap.Hash)
+	} else if header.Hash() != snap.Hash {
+		return fmt.Errorf("snapshot of block %x, canonical block #%d is %x", snap.Hash, snap.Number, header.Hash())
+	}
+	if snap.TurnLength == 0 {
+		snap.TurnLength = defaultTurnLength
+	}
+	if snap.Recents == nil {
+		snap.Recents = make(map[uint64]common.Address)
+	}
+	if snap.RecentForkHashes == nil {
+		snap.RecentForkHashes = make(map[uint64]string)
+	}
+	snap.config = p.config
+	snap.sigCache = p.signatures
+	snap.ethAPI = p.ethAPI
+	p.recentSnaps.Remove(snap.Hash)
+	return snap.store(p.db)
+}
+
+// VerifySnapshot rebuilds the snapshot at the block of the given one from the
+// headers and checks that both match. The rebuild starts at the genesis, whose
+// snapshot is derived from its header. If fromStored is set it starts at the
+// closest checkpoint snapshot stored below the block instead, which is trusted
+// as is even if it was imported. The number of the starting block is returned.
+func (p *Parlia) VerifySnapshot(chain consensus.ChainHeaderReader, snap *Snapshot, fromStored bool) (uint64, error) {
+	header := chain.GetHeaderByNumber(snap.Number)
+	if header == nil {
+		return 0, fmt.Errorf("block #%d not found", snap.Number)
+	}
+	if header.Hash() != snap.Hash {
+		return 0, fmt.Errorf("snapshot of block %x, canonical block #%d is %x", snap.Hash, snap.Number, header.Hash())
+	}
+	if snap.Number == 0 {
+		return 0, errors.New("the genesis snapshot is derived from the genesis header only")
+	}
+	var base *Snapshot
+	if fromStored {
+		for number := LastSnapshotCheckpoint(snap.Number - 1); number > 0; number -= checkpointInterval {
+			checkpoint := chain.GetHeaderByNumber(number)
+			if checkpoint == nil {
+				return 0, fmt.Errorf("block #%d not found", number)
+			}
+			if stored, err := loadSnapshot(p.config, p.signatures, p.db, checkpoint.Hash(), p.ethAPI); err == nil {
+				base = stored
+				break
+			}
+		}
+	}
+	if base == nil {
+		genesis, err := p.genesisSnapshot(chain)
+		if err != nil {
+			return 0, err
+		}
+		base = genesis
+	}
+	var (
+		rebuilt = base
+		start   = time.Now()
+		logged  = time.Now()
+	)
+	for rebuilt.Number < snap.Number {
+		// Apply the headers in batches to not hold the whole chain in memory
+		last := min(rebuilt.Number+checkpointInterval, snap.Number)
+		headers := make([]*types.Header, 0, last-rebuilt.Number)
+		for number := rebuilt.Number + 1; number <= last; number++ {
+			header := chain.GetHeaderByNumber(number)
+			if header == nil {
+				return 0, fmt.Errorf("block #%d not found", number)
+			}
+			headers = append(headers, header)
+		}
+		next, err := rebuilt.apply(headers, chain, nil, p.chainConfig)
+		if err != nil {
+			return 0, fmt.Errorf("failed to rebuild the snapshot from block #%d: %v", base.Number, err)
+		}
+		rebuilt = next
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Rebuilding Parlia snapshot", "number", rebuilt.Number, "target", snap.Number, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	// Compare the JSON encodings, which are independent of the map orders
+	for _, field := range []struct {
+		name     string
+		got, exp interface{}
+	}{
+		{"turn length", snap.TurnLength, rebuilt.TurnLength},
+		{"validators", snap.Validators, rebuilt.Validators},
+		{"recents", snap.Recents, rebuilt.Recents},
+		{"recent fork hashes", snap.RecentForkHashes, rebuilt.RecentForkHashes},
+		{"attestation", snap.Attestation, rebuilt.Attestation},
+	} {
+		got, err := json.Marshal(field.got)
+		if err != nil {
+			return 0, err
+		}
+		exp, err := json.Marshal(field.exp)
+		if err != nil {
+			return 0, err
+		}
+		if string(got) != string(exp) {
+			return 0, fmt.Errorf("snapshot %s mismatch: have %s, want %s", field.name, got, exp)
+		}
+	}
+	return base.Number, nil
+}
+
+// genesisSnapshot derives the snapshot of the genesis block from its header,
+// ignoring any snapshot stored for it.
+func (p *Parlia) genesisSnapshot(chain consensus.ChainHeaderReader) (*Snapshot, error) {
+	genesis := chain.GetHeaderByNumber(0)
+	if genesis == nil {
+		return nil, errors.New("genesis block not found")
+	}
+	validators, voteAddrs, err := parseValidators(genesis, p.chainConfig, p.config)
+	if err != nil {
+		return nil, err
+	}
+	snap := newSnapshot(p.config, p.signatures, 0, genesis.Hash(), validators, voteAddrs, p.ethAPI)
+	turnLength, err := parseTurnLength(genesis, p.chainConfig, p.config)
+	if err != nil {
+		return nil, err
+	}
+	if turnLength != nil {
+		snap.TurnLength = *turnLength
+	}
+	return snap, nil
+}
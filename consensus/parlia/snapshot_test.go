@@ -2,12 +2,18 @@ package parlia
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"math/big"
 	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestValidatorSetSort(t *testing.T) {
@@ -21,3 +27,110 @@ func TestValidatorSetSort(t *testing.T) {
 		assert.True(t, bytes.Compare(validators[i][:], validators[i+1][:]) < 0)
 	}
 }
+
+func TestImportSnapshot(t *testing.T) {
+	p := New(params.ParliaTestChainConfig, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+	chain := &trackerTestChain{headers: []*types.Header{{Number: big.NewInt(0)}}}
+	snap := &Snapshot{
+		Number:     0,
+		Hash:       chain.headers[0].Hash(),
+		Validators: map[common.Address]*ValidatorInfo{{1}: {}},
+	}
+	assert.NoError(t, p.ImportSnapshot(chain, snap))
+
+	stored, err := loadSnapshot(p.config, p.signatures, p.db, snap.Hash, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultTurnLength, stored.TurnLength)
+	assert.Equal(t, snap.Validators, stored.Validators)
+
+	// Only canonical checkpoint snapshots are imported
+	snap.Hash = common.Hash{1}
+	assert.Error(t, p.ImportSnapshot(chain, snap))
+	snap.Number = checkpointInterval + 1
+	assert.Error(t, p.ImportSnapshot(chain, snap))
+
+	// Unknown checkpoint blocks are accepted ahead of the headers
+	snap.Number = checkpointInterval
+	assert.NoError(t, p.ImportSnapshot(chain, snap))
+
+	_, err = p.VerifySnapshot(chain, snap, false)
+	assert.Error(t, err)
+}
+
+// newSealedTestChain creates a chain of the given length whose blocks are
+// sealed by the validators in turn. The genesis and epoch headers list the
+// validators.
+func newSealedTestChain(t *testing.T, length int, keys []*ecdsa.PrivateKey) *trackerTestChain {
+	config := params.ParliaTestChainConfig
+	validators := []byte{byte(len(keys))}
+	for _, key := range keys {
+		validators = append(validators, crypto.PubkeyToAddress(key.PublicKey).Bytes()...)
+		validators = append(validators, make([]byte, types.BLSPublicKeyLength)...)
+	}
+	chain := &trackerTestChain{}
+	for number := uint64(0); number < uint64(length); number++ {
+		header := &types.Header{
+			Number:     new(big.Int).SetUint64(number),
+			Difficulty: big.NewInt(1),
+			Extra:      make([]byte, extraVanity),
+		}
+		if number > 0 {
+			header.ParentHash = chain.headers[number-1].Hash()
+		}
+		if number%config.Parlia.Epoch == 0 {
+			header.Extra = append(header.Extra, validators...)
+		}
+		header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+		if number > 0 {
+			sig, err := crypto.Sign(types.SealHash(header, config.ChainID).Bytes(), keys[number%uint64(len(keys))])
+			assert.NoError(t, err)
+			copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	return chain
+}
+
+func TestVerifySnapshot(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	var (
+		p          = New(params.ParliaTestChainConfig, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+		chain      = newSealedTestChain(t, checkpointInterval+8, keys)
+		head       = chain.headers[len(chain.headers)-1]
+		checkpoint = chain.headers[checkpointInterval]
+	)
+	// Snapshots rebuilt from the headers are verified from the genesis, or
+	// from the checkpoint snapshot stored along the way if asked for
+	_, err := p.SnapshotAt(chain, checkpoint)
+	assert.NoError(t, err)
+	snap, err := p.SnapshotAt(chain, head)
+	assert.NoError(t, err)
+
+	base, err := p.VerifySnapshot(chain, snap, false)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), base)
+	base, err = p.VerifySnapshot(chain, snap, true)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(checkpointInterval), base)
+
+	// Tampered snapshots are rejected
+	tampered := snap.copy()
+	tampered.Recents[snap.Number] = crypto.PubkeyToAddress(keys[(snap.Number+1)%3].PublicKey)
+	_, err = p.VerifySnapshot(chain, tampered, false)
+	assert.ErrorContains(t, err, "recents mismatch")
+
+	// Imported checkpoint snapshots are not trusted unless asked for
+	imported, err := loadSnapshot(p.config, p.signatures, p.db, checkpoint.Hash(), nil)
+	assert.NoError(t, err)
+	imported.Validators[common.Address{1}] = &ValidatorInfo{}
+	assert.NoError(t, p.ImportSnapshot(chain, imported))
+
+	base, err = p.VerifySnapshot(chain, snap, false)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), base)
+	_, err = p.VerifySnapshot(chain, snap, true)
+	assert.ErrorContains(t, err, "validators mismatch")
+}
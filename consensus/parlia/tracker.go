@@ -0,0 +1,249 @@
+package parlia
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	inMemoryBlockRecords  = 4096 // Number of block production and vote records to keep in memory
+	maxRecentlySignedLogs = 1024 // Number of rejected recently signed headers to keep in memory
+
+	// maxValidatorStatsRange and maxVoteStatsRange are the maximum numbers of
+	// blocks parlia_getValidatorStats and parlia_getVoteStats aggregate.
+	// Uncached records are rebuilt from the snapshots, so the ranges are capped
+	// to the cache to keep them from evicting their own records.
+	maxValidatorStatsRange = inMemoryBlockRecords
+	maxVoteStatsRange      = inMemoryBlockRecords
+)
+
+// ValidatorStats is the block production of a validator within a block range.
+type ValidatorStats struct {
+	InTurn         uint64 `json:"inTurn"`         // Blocks sealed in turn
+	OutOfTurn      uint64 `json:"outOfTurn"`      // Blocks sealed out of turn, with diffNoTurn
+	Missed         uint64 `json:"missed"`         // In-turn slots sealed by another validator although not recently signed
+	BackedOff      uint64 `json:"backedOff"`      // Out-of-turn blocks sealed after a back off delay
+	BackOffTime    uint64 `json:"backOffTime"`    // Total back off delay of those blocks, in seconds
+	RecentlySigned uint64 `json:"recentlySigned"` // Headers seen by this node rejected for sealing again within the recent window
+}
+
+// ValidatorStatsResult is the block production of the validators within a
+// block range.
+type ValidatorStatsResult struct {
+	FromBlock  uint64                             `json:"fromBlock"`
+	ToBlock    uint64                             `json:"toBlock"`
+	Validators map[common.Address]*ValidatorStats `json:"validators"`
+}
+
+// blockRecord is the production of a single block, derived from the snapshot
+// of its parent.
+type blockRecord struct {
+	signer  common.Address // Validator sealing the block
+	inturn  common.Address // Validator whose turn it was
+	missed  bool           // Whether the in-turn validator missed its slot
+	backOff uint64         // Back off delay of an out-of-turn signer, in seconds
+	counted bool           // Whether the block was added to the metrics
+}
+
+// recentlySignedLog is a header rejected as its signer sealed one of the recent
+// blocks.
+type recentlySignedLog struct {
+	number    uint64
+	hash      common.Hash
+	validator common.Address
+}
+
+// validatorTracker keeps the production and vote records of recent blocks and
+// the recently signed violations seen by the node.
+type validatorTracker struct {
+	records        *lru.ARCCache // Block production records by block hash
+	votes          *lru.ARCCache // Vote records by block hash
+	lock           sync.Mutex    // Protects the fields below and the counted flag of the records
+	recentlySigned []recentlySignedLog
+}
+
+func newValidatorTracker() *validatorTracker {
+	records, err := lru.NewARC(inMemoryBlockRecords)
+	if err != nil {
+		panic(err)
+	}
+	votes, err := lru.NewARC(inMemoryBlockRecords)
+	if err != nil {
+		panic(err)
+	}
+	return &validatorTracker{records: records, votes: votes}
+}
+
+// logRecentlySigned records a header violating the recently signed rule.
+func (t *validatorTracker) logRecentlySigned(header *types.Header, validator common.Address) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	hash := header.Hash()
+	for _, l := range t.recentlySigned {
+		if l.hash == hash {
+			return
+		}
+	}
+	t.recentlySigned = append(t.recentlySigned, recentlySignedLog{number: header.Number.Uint64(), hash: hash, validator: validator})
+	if len(t.recentlySigned) > maxRecentlySignedLogs {
+		t.recentlySigned = t.recentlySigned[len(t.recentlySigned)-maxRecentlySignedLogs:]
+	}
+	validatorCounter(validator, "recentlysigned").Inc(1)
+}
+
+// validatorCounter returns the metrics counter of a validator.
+func validatorCounter(validator common.Address, name string) metrics.Counter {
+	return metrics.GetOrRegisterCounter("parlia/validator/"+strings.ToLower(validator.Hex())+"/"+name, nil)
+}
+
+// blockRecord returns the production record of a block, deriving it from the
+// snapshot of the parent if it is not cached.
+func (p *Parlia) blockRecord(chain consensus.ChainHeaderReader, header *types.Header) (*blockRecord, error) {
+	hash := header.Hash()
+	if rec, ok := p.tracker.records.Get(hash); ok {
+		return rec.(*blockRecord), nil
+	}
+	number := header.Number.Uint64()
+	snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	rec := &blockRecord{
+		signer: header.Coinbase,
+		inturn: snap.inturnValidator(),
+	}
+	if rec.signer != rec.inturn {
+		// The in-turn validator is not to blame if it was not allowed to seal
+		rec.missed = !snap.SignRecently(rec.inturn)
+		rec.backOff = p.backOffTime(snap, header, rec.signer)
+	}
+	p.tracker.records.Add(hash, rec)
+	return rec, nil
+}
+
+// trackHeader adds an imported header to the validator metrics and indexes
+// its vote attestation.
+func (p *Parlia) trackHeader(chain consensus.ChainHeaderReader, header *types.Header) {
+	if header.Number.Sign() == 0 {
+		return
+	}
+	if _, err := p.voteRecord(chain, header); err != nil {
+		log.Debug("Failed to index vote attestation", "number", header.Number, "hash", header.Hash(), "err", err)
+	}
+	rec, err := p.blockRecord(chain, header)
+	if err != nil {
+		log.Debug("Failed to track block production", "number", header.Number, "hash", header.Hash(), "err", err)
+		return
+	}
+	p.tracker.lock.Lock()
+	defer p.tracker.lock.Unlock()
+
+	if rec.counted {
+		return
+	}
+	rec.counted = true
+	if rec.signer == rec.inturn {
+		validatorCounter(rec.signer, "inturn").Inc(1)
+		return
+	}
+	validatorCounter(rec.signer, "outofturn").Inc(1)
+	if rec.backOff > 0 {
+		validatorCounter(rec.signer, "backedoff").Inc(1)
+	}
+	if rec.missed {
+		validatorCounter(rec.inturn, "missed").Inc(1)
+	}
+}
+
+// TrackValidators adds the production of every block imported into the chain
+// to the validator metrics and indexes its vote attestation, until the returned
+// subscription is unsubscribed.
+func (p *Parlia) TrackValidators(chain interface {
+	consensus.ChainHeaderReader
+	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+}) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		chainCh := make(chan core.ChainEvent, 64)
+		sub := chain.SubscribeChainEvent(chainCh)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-chainCh:
+				p.trackHeader(chain, ev.Block.Header())
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	})
+}
+
+// validatorStats aggregates the production of the canonical blocks within the
+// block range.
+func (p *Parlia) validatorStats(chain consensus.ChainHeaderReader, from, to uint64) (*ValidatorStatsResult, error) {
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if to-from >= maxValidatorStatsRange {
+		return nil, fmt.Errorf("block range %d-%d exceeds the limit of %d blocks", from, to, maxValidatorStatsRange)
+	}
+	res := &ValidatorStatsResult{
+		FromBlock:  from,
+		ToBlock:    to,
+		Validators: make(map[common.Address]*ValidatorStats),
+	}
+	stats := func(validator common.Address) *ValidatorStats {
+		if s, ok := res.Validators[validator]; ok {
+			return s
+		}
+		s := new(ValidatorStats)
+		res.Validators[validator] = s
+		return s
+	}
+	for number := max(from, 1); number <= to; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		rec, err := p.blockRecord(chain, header)
+		if err != nil {
+			return nil, err
+		}
+		signer := stats(rec.signer)
+		if rec.signer == rec.inturn {
+			signer.InTurn++
+			continue
+		}
+		signer.OutOfTurn++
+		if rec.backOff > 0 {
+			signer.BackedOff++
+			signer.BackOffTime += rec.backOff
+		}
+		if rec.missed {
+			stats(rec.inturn).Missed++
+		}
+	}
+	p.tracker.lock.Lock()
+	defer p.tracker.lock.Unlock()
+
+	for _, l := range p.tracker.recentlySigned {
+		if l.number >= from && l.number <= to {
+			stats(l.validator).RecentlySigned++
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,198 @@
+package parlia
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// trackerTestChain serves the canonical headers of the tracker tests.
+type trackerTestChain struct {
+	consensus.ChainHeaderReader
+	headers []*types.Header
+}
+
+func (c *trackerTestChain) Config() *params.ChainConfig { return params.ParliaTestChainConfig }
+
+func (c *trackerTestChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (c *trackerTestChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+
+func TestValidatorStats(t *testing.T) {
+	p := New(params.ParliaTestChainConfig, nil, nil, common.Hash{})
+	var (
+		vals    = []common.Address{{1}, {2}, {3}}
+		signers = []common.Address{{}, vals[1], vals[0], vals[1]}
+		recents = []map[uint64]common.Address{{}, {}, {2: vals[0]}}
+		chain   = &trackerTestChain{headers: []*types.Header{{Number: big.NewInt(0)}}}
+	)
+	for number := uint64(1); number < uint64(len(signers)); number++ {
+		parent := chain.headers[number-1]
+		snap := &Snapshot{
+			config:     p.config,
+			Number:     number - 1,
+			Hash:       parent.Hash(),
+			TurnLength: 1,
+			Validators: make(map[common.Address]*ValidatorInfo),
+			Recents:    recents[number-1],
+		}
+		for _, val := range vals {
+			snap.Validators[val] = &ValidatorInfo{}
+		}
+		p.recentSnaps.Add(snap.Hash, snap)
+		chain.headers = append(chain.headers, &types.Header{
+			Number:     new(big.Int).SetUint64(number),
+			ParentHash: parent.Hash(),
+			Coinbase:   signers[number],
+		})
+	}
+	p.tracker.logRecentlySigned(chain.headers[3], vals[2])
+	p.tracker.logRecentlySigned(chain.headers[3], vals[2])
+
+	res, err := p.validatorStats(chain, 0, 3)
+	assert.NoError(t, err)
+	// Block 1 is sealed in turn, block 2 out of turn instead of the third
+	// validator, block 3 out of turn as the in-turn validator signed recently
+	assert.Equal(t, uint64(1), res.Validators[vals[1]].InTurn)
+	assert.Equal(t, uint64(1), res.Validators[vals[1]].OutOfTurn)
+	assert.Equal(t, uint64(1), res.Validators[vals[0]].OutOfTurn)
+	assert.Equal(t, uint64(1), res.Validators[vals[0]].BackedOff)
+	assert.NotZero(t, res.Validators[vals[0]].BackOffTime)
+	assert.Zero(t, res.Validators[vals[0]].Missed)
+	assert.Equal(t, uint64(1), res.Validators[vals[2]].Missed)
+	assert.Equal(t, uint64(1), res.Validators[vals[2]].RecentlySigned)
+
+	res, err = p.validatorStats(chain, 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, res.Validators, 1)
+
+	_, err = p.validatorStats(chain, 2, 1)
+	assert.Error(t, err)
+	_, err = p.validatorStats(chain, 0, maxValidatorStatsRange)
+	assert.Error(t, err)
+
+	// Imported headers are only added to the metrics once
+	p.trackHeader(chain, chain.headers[2])
+	rec, err := p.blockRecord(chain, chain.headers[2])
+	assert.NoError(t, err)
+	assert.True(t, rec.counted)
+}
+
+// newVoteTestChain creates a chain whose blocks carry the vote attestations of
+// the given validator bit sets, along with the snapshots of the blocks.
+func newVoteTestChain(t *testing.T, p *Parlia, vals []common.Address, voted []types.ValidatorsBitSet, attestations []*types.VoteData) *trackerTestChain {
+	chain := &trackerTestChain{}
+	for number := uint64(0); number < uint64(len(voted)); number++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(number)}
+		if number > 0 {
+			header.ParentHash = chain.headers[number-1].Hash()
+		}
+		if voted[number] != 0 {
+			attestation := &types.VoteAttestation{VoteAddressSet: voted[number], Data: &types.VoteData{TargetNumber: number - 1, TargetHash: header.ParentHash}}
+			blob, err := rlp.EncodeToBytes(attestation)
+			assert.NoError(t, err)
+			header.Extra = append(append(make([]byte, extraVanity), blob...), make([]byte, extraSeal)...)
+		}
+		chain.headers = append(chain.headers, header)
+
+		snap := &Snapshot{
+			config:      p.config,
+			Number:      number,
+			Hash:        header.Hash(),
+			TurnLength:  1,
+			Validators:  make(map[common.Address]*ValidatorInfo),
+			Recents:     make(map[uint64]common.Address),
+			Attestation: attestations[number],
+		}
+		for _, val := range vals {
+			snap.Validators[val] = &ValidatorInfo{}
+		}
+		p.recentSnaps.Add(snap.Hash, snap)
+	}
+	return chain
+}
+
+func TestVoteRecord(t *testing.T) {
+	p := New(params.ParliaTestChainConfig, nil, nil, common.Hash{})
+	var (
+		vals         = []common.Address{{1}, {2}, {3}, {4}, {5}}
+		voted        = []types.ValidatorsBitSet{0, 0, 0, 0, 0, 0b10101}
+		attestations = []*types.VoteData{nil, nil, nil, nil, nil, {SourceNumber: 2, TargetNumber: 4}}
+		chain        = newVoteTestChain(t, p, vals, voted, attestations)
+	)
+	// The bits of the set index the validators of the parent snapshot in
+	// ascending address order
+	rec, err := p.voteRecord(chain, chain.headers[5])
+	assert.NoError(t, err)
+	assert.Equal(t, types.ValidatorsBitSet(0b10101), rec.attestation.VoteAddressSet)
+	assert.Equal(t, []common.Address{vals[0], vals[2], vals[4]}, rec.voters)
+	assert.Equal(t, vals, rec.eligible)
+	assert.True(t, rec.finality)
+	assert.Equal(t, uint64(4), rec.justified)
+	assert.Equal(t, uint64(2), rec.finalized)
+
+	cached, err := p.voteRecord(chain, chain.headers[5])
+	assert.NoError(t, err)
+	assert.Same(t, rec, cached)
+
+	info, err := p.voteAttestation(chain, chain.headers[5])
+	assert.NoError(t, err)
+	assert.Equal(t, rec.voters, info.Voters)
+	assert.Equal(t, len(vals), info.Validators)
+
+	res, err := p.voteStats(chain, 5, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), res.Validators[vals[2]].Included)
+	assert.Equal(t, uint64(0), res.Validators[vals[3]].Included)
+	assert.Equal(t, FinalityLag{Average: 1, Max: 1}, res.JustificationLag)
+	assert.Equal(t, FinalityLag{Average: 3, Max: 3}, res.FinalizationLag)
+
+	// Ranges beyond the cached records are rejected
+	_, err = p.voteStats(chain, 0, maxVoteStatsRange)
+	assert.Error(t, err)
+}
+
+func TestVoteStats(t *testing.T) {
+	p := New(params.ParliaTestChainConfig, nil, nil, common.Hash{})
+	var (
+		vals         = []common.Address{{1}, {2}, {3}}
+		voted        = []types.ValidatorsBitSet{0, 0, 0b011, 0b111}
+		attestations = []*types.VoteData{nil, nil, {SourceNumber: 0, TargetNumber: 1}, {SourceNumber: 1, TargetNumber: 2}}
+		chain        = newVoteTestChain(t, p, vals, voted, attestations)
+	)
+	info, err := p.voteAttestation(chain, chain.headers[2])
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), info.TargetNumber)
+	assert.Equal(t, chain.headers[1].Hash(), info.TargetHash)
+	assert.Equal(t, []common.Address{vals[0], vals[1]}, info.Voters)
+	assert.Equal(t, 3, info.Validators)
+
+	info, err = p.voteAttestation(chain, chain.headers[1])
+	assert.NoError(t, err)
+	assert.Nil(t, info)
+
+	res, err := p.voteStats(chain, 1, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), res.Attested)
+	assert.Equal(t, &ValidatorVoteStats{Eligible: 2, Included: 2, InclusionRate: 1}, res.Validators[vals[0]])
+	assert.Equal(t, &ValidatorVoteStats{Eligible: 2, Included: 1, InclusionRate: 0.5}, res.Validators[vals[2]])
+	assert.Equal(t, FinalityLag{Average: 1, Max: 1}, res.JustificationLag)
+	assert.Equal(t, FinalityLag{Average: 2, Max: 2}, res.FinalizationLag)
+}
@@ -0,0 +1,194 @@
+package parlia
+
+import (
+	"fmt"
+
+	"github.com/willf/bitset"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// VoteAttestationInfo is the decoded vote attestation of a block.
+type VoteAttestationInfo struct {
+	BlockNumber  uint64           `json:"blockNumber"`
+	BlockHash    common.Hash      `json:"blockHash"`
+	SourceNumber uint64           `json:"sourceNumber"`
+	SourceHash   common.Hash      `json:"sourceHash"`
+	TargetNumber uint64           `json:"targetNumber"`
+	TargetHash   common.Hash      `json:"targetHash"`
+	Voters       []common.Address `json:"voters"`     // Validators whose votes were aggregated
+	Validators   int              `json:"validators"` // Number of validators entitled to vote
+}
+
+// ValidatorVoteStats is the vote participation of a validator within a block
+// range.
+type ValidatorVoteStats struct {
+	Eligible      uint64  `json:"eligible"`      // Blocks the validator was entitled to vote for
+	Included      uint64  `json:"included"`      // Blocks whose attestation aggregated the vote of the validator
+	InclusionRate float64 `json:"inclusionRate"` // Included divided by eligible
+}
+
+// FinalityLag is the distance of blocks to their highest justified or
+// finalized ancestor within a block range.
+type FinalityLag struct {
+	Average float64 `json:"average"`
+	Max     uint64  `json:"max"`
+}
+
+// VoteStatsResult is the vote participation of the validators within a block
+// range.
+type VoteStatsResult struct {
+	FromBlock        uint64                                 `json:"fromBlock"`
+	ToBlock          uint64                                 `json:"toBlock"`
+	Attested         uint64                                 `json:"attested"` // Blocks carrying a vote attestation
+	Validators       map[common.Address]*ValidatorVoteStats `json:"validators"`
+	JustificationLag FinalityLag                            `json:"justificationLag"`
+	FinalizationLag  FinalityLag                            `json:"finalizationLag"`
+}
+
+// voteRecord is the vote attestation of a single block along with the
+// finality reached at it.
+type voteRecord struct {
+	attestation *types.VoteAttestation // Attestation of the parent block, nil if none
+	voters      []common.Address       // Validators whose votes were aggregated
+	eligible    []common.Address       // Validators entitled to vote for the parent block, nil before fast finality
+	finality    bool                   // Whether fast finality reached the block
+	justified   uint64                 // Highest justified block at the block
+	finalized   uint64                 // Highest finalized block at the block
+}
+
+// voteRecord returns the vote record of a block, decoding it from the header
+// and the snapshots if it is not cached.
+func (p *Parlia) voteRecord(chain consensus.ChainHeaderReader, header *types.Header) (*voteRecord, error) {
+	hash := header.Hash()
+	if rec, ok := p.tracker.votes.Get(hash); ok {
+		return rec.(*voteRecord), nil
+	}
+	attestation, err := getVoteAttestationFromHeader(header, p.chainConfig, p.config)
+	if err != nil {
+		return nil, err
+	}
+	rec := &voteRecord{attestation: attestation}
+
+	number := header.Number.Uint64()
+	if number >= 2 && p.chainConfig.IsPlato(header.Number) {
+		// The votes for the parent are cast by the validators of its snapshot
+		parent := chain.GetHeader(header.ParentHash, number-1)
+		if parent == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		snap, err := p.snapshot(chain, number-2, parent.ParentHash, nil)
+		if err != nil {
+			return nil, err
+		}
+		rec.eligible = snap.validators()
+		if attestation != nil {
+			voted := bitset.From([]uint64{uint64(attestation.VoteAddressSet)})
+			for index, val := range rec.eligible {
+				if voted.Test(uint(index)) {
+					rec.voters = append(rec.voters, val)
+				}
+			}
+		}
+	}
+	snap, err := p.snapshot(chain, number, hash, nil)
+	if err != nil {
+		return nil, err
+	}
+	if snap.Attestation != nil {
+		rec.finality = true
+		rec.justified = snap.Attestation.TargetNumber
+		rec.finalized = snap.Attestation.SourceNumber
+	}
+	p.tracker.votes.Add(hash, rec)
+	return rec, nil
+}
+
+// voteAttestation decodes the vote attestation of a block, nil if it carries
+// none.
+func (p *Parlia) voteAttestation(chain consensus.ChainHeaderReader, header *types.Header) (*VoteAttestationInfo, error) {
+	rec, err := p.voteRecord(chain, header)
+	if err != nil {
+		return nil, err
+	}
+	if rec.attestation == nil || rec.attestation.Data == nil {
+		return nil, nil
+	}
+	info := &VoteAttestationInfo{
+		BlockNumber:  header.Number.Uint64(),
+		BlockHash:    header.Hash(),
+		SourceNumber: rec.attestation.Data.SourceNumber,
+		SourceHash:   rec.attestation.Data.SourceHash,
+		TargetNumber: rec.attestation.Data.TargetNumber,
+		TargetHash:   rec.attestation.Data.TargetHash,
+		Voters:       rec.voters,
+		Validators:   len(rec.eligible),
+	}
+	if info.Voters == nil {
+		info.Voters = []common.Address{}
+	}
+	return info, nil
+}
+
+// voteStats aggregates the vote participation of the canonical blocks within
+// the block range.
+func (p *Parlia) voteStats(chain consensus.ChainHeaderReader, from, to uint64) (*VoteStatsResult, error) {
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if to-from >= maxVoteStatsRange {
+		return nil, fmt.Errorf("block range %d-%d exceeds the limit of %d blocks", from, to, maxVoteStatsRange)
+	}
+	res := &VoteStatsResult{
+		FromBlock:  from,
+		ToBlock:    to,
+		Validators: make(map[common.Address]*ValidatorVoteStats),
+	}
+	var (
+		finalityBlocks uint64
+		justifiedSum   uint64
+		finalizedSum   uint64
+	)
+	for number := from; number <= to; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		rec, err := p.voteRecord(chain, header)
+		if err != nil {
+			return nil, err
+		}
+		if rec.attestation != nil {
+			res.Attested++
+		}
+		for _, val := range rec.eligible {
+			stats, ok := res.Validators[val]
+			if !ok {
+				stats = new(ValidatorVoteStats)
+				res.Validators[val] = stats
+			}
+			stats.Eligible++
+		}
+		for _, val := range rec.voters {
+			res.Validators[val].Included++
+		}
+		if rec.finality {
+			finalityBlocks++
+			justified, finalized := number-rec.justified, number-rec.finalized
+			justifiedSum += justified
+			finalizedSum += finalized
+			res.JustificationLag.Max = max(res.JustificationLag.Max, justified)
+			res.FinalizationLag.Max = max(res.FinalizationLag.Max, finalized)
+		}
+	}
+	for _, stats := range res.Validators {
+		stats.InclusionRate = float64(stats.Included) / float64(stats.Eligible)
+	}
+	if finalityBlocks > 0 {
+		res.JustificationLag.Average = float64(justifiedSum) / float64(finalityBlocks)
+		res.FinalizationLag.Average = float64(finalizedSum) / float64(finalityBlocks)
+	}
+	return res, nil
+}
@@ -18,16 +18,12 @@
 package core
 
 import (
-	"encoding/hex"
 	"errors"
 	"fmt"
-	"github.com/ethereum/go-ethereum/paircache"
-	"github.com/ethereum/go-ethereum/paircache/pairtypes"
 	"io"
 	"math/big"
 	"runtime"
 	"sort"
-	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -263,7 +259,10 @@ type BlockChain struct {
 	triedb        *triedb.Database                 // The database handler for maintaining trie nodes.
 	stateCache    state.Database                   // State database to reuse between imports (contains state cache)
 	triesInMemory uint64
-	txIndexer     *txIndexer // Transaction indexer, might be nil if not enabled
+	txIndexer     *txIndexer   // Transaction indexer, might be nil if not enabled
+	addressSigner types.Signer // Sender recovery of the address index, nil if the index is disabled
+
+	sharedStateCache *state.SharedCache // Read-through cache of the head state shared by RPC calls
 
 	hc                       *HeaderChain
 	rmLogsFeed               event.Feed
@@ -324,8 +323,6 @@ type BlockChain struct {
 
 	// monitor
 	doubleSignMonitor *monitor.DoubleSignMonitor
-
-	ethAPI pairtypes.PairAPI
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -395,6 +392,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	bc.flushInterval.Store(int64(cacheConfig.TrieTimeLimit))
 	bc.forker = NewForkChoice(bc, shouldPreserve)
 	bc.stateCache = state.NewDatabaseWithNodeDB(bc.db, bc.triedb)
+	bc.sharedStateCache = state.NewSharedCache(0)
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
 	bc.prefetcher = NewStatePrefetcher(chainConfig, bc, engine)
 	bc.processor = NewStateProcessor(chainConfig, bc, engine)
@@ -726,6 +724,7 @@ func (bc *BlockChain) loadLastState() error {
 
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock.Store(headBlock.Header())
+	bc.sharedStateCache.Reset(headBlock.Root())
 	headBlockGauge.Update(int64(headBlock.NumberU64()))
 	justifiedBlockGauge.Update(int64(bc.GetJustifiedNumber(headBlock.Header())))
 	finalizedBlockGauge.Update(int64(bc.getFinalizedNumber(headBlock.Header())))
@@ -1073,6 +1072,7 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 			// last step, however the direction of SetHead is from high
 			// to low, so it's safe to update in-memory markers directly.
 			bc.currentBlock.Store(newHeadBlock)
+			bc.sharedStateCache.Reset(newHeadBlock.Root)
 			headBlockGauge.Update(int64(newHeadBlock.Number.Uint64()))
 
 			// The head state is missing, which is only possible in the path-based
@@ -1196,6 +1196,7 @@ func (bc *BlockChain) SnapSyncCommitHead(hash common.Hash) error {
 		return errChainStopped
 	}
 	bc.currentBlock.Store(block.Header())
+	bc.sharedStateCache.Reset(block.Root())
 	headBlockGauge.Update(int64(block.NumberU64()))
 	justifiedBlockGauge.Update(int64(bc.GetJustifiedNumber(block.Header())))
 	finalizedBlockGauge.Update(int64(bc.getFinalizedNumber(block.Header())))
@@ -1249,6 +1250,7 @@ func (bc *BlockChain) ResetWithGenesisBlock(genesis *types.Block) error {
 	// Last update all in-memory chain markers
 	bc.genesisBlock = genesis
 	bc.currentBlock.Store(bc.genesisBlock.Header())
+	bc.sharedStateCache.Reset(bc.genesisBlock.Root())
 	headBlockGauge.Update(int64(bc.genesisBlock.NumberU64()))
 	justifiedBlockGauge.Update(int64(bc.genesisBlock.NumberU64()))
 	finalizedBlockGauge.Update(int64(bc.genesisBlock.NumberU64()))
@@ -1323,6 +1325,9 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 
 		batch := bc.db.NewBatch()
 		rawdb.WriteTxLookupEntriesByBlock(batch, block)
+		if bc.addressSigner != nil {
+			rawdb.WriteAddressTxEntriesByBlock(batch, bc.addressSigner, block)
+		}
 
 		// Flush the whole batch into the disk, exit the node if failed
 		if err := batch.Write(); err != nil {
@@ -1337,6 +1342,7 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 	headFastBlockGauge.Update(int64(block.NumberU64()))
 
 	bc.currentBlock.Store(block.Header())
+	bc.sharedStateCache.Reset(block.Root())
 	headBlockGauge.Update(int64(block.NumberU64()))
 	justifiedBlockGauge.Update(int64(bc.GetJustifiedNumber(block.Header())))
 	finalizedBlockGauge.Update(int64(bc.getFinalizedNumber(block.Header())))
@@ -2385,73 +2391,6 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 				"root", block.Root())
 		}
 		bc.chainBlockFeed.Send(ChainHeadEvent{block})
-
-		// 根据receipts获取pair
-		pairCache := paircache.GetPairControl()
-		// log.Info("获取pairCache成功", "triange总数", pairCache.TriangleMap.Count(), "pair总数", pairCache.PairTriangleMap.Count())
-		pairAddrMap := make(map[string]*pairtypes.Set)
-		pairOccurTimes := 0
-		for _, receipt := range receipts {
-			for _, reLog := range receipt.Logs {
-				// marshalLog, _ := json.Marshal(reLog)
-				// log.Debug("收据日志打印，", "logBlockNum", reLog.BlockNumber, "区块对应的收据receipt.Logs", marshalLog)
-				topics := reLog.Topics
-				if len(topics) > 0 {
-					topic0Str := "0x" + hex.EncodeToString(topics[0][:])
-					topicOper := pairCache.TopicMap[topic0Str]
-					if topicOper != "" {
-						var address string
-						if topicOper == "Balancer" {
-							address = "0x" + hex.EncodeToString(topics[1][0:20])
-						} else {
-							address = "0x" + hex.EncodeToString(reLog.Address[:])
-						}
-						pairOccurTimes++
-						address = common.HexToAddress(address).Hex()
-						pairAddrMap[address] = pairCache.GetPairSet(address)
-						log.Debug("交易收据日志打印，", "logBlockNum", reLog.BlockNumber, "Log.Index", reLog.Index, "topic", topic0Str, "topicOper", topicOper, "address", address)
-					}
-				}
-			}
-		}
-		// log.Info("pair统计信息，", "logBlockNum", block.Number().Uint64(), "pairAddrNum", len(pairAddrMap), "addrOccurTimes", pairOccurTimes, "pairMap", pairAddrMap)
-		// log.Info("pair统计信息，", "logBlockNum", block.Number().Uint64(), "pairAddrNum", len(pairAddrMap), "addrOccurTimes", pairOccurTimes)
-
-		// 根据pair获取triangle，一个pair对应一组triangleId，多个pair又可能对应同一个triangleId，所以循环每组triangleId去重
-		var triangles []pairtypes.Triangle
-		filterMap := make(map[string]bool)
-		for _, triangleIdSet := range pairAddrMap {
-			for _, triangleId := range triangleIdSet.GetData().Keys() {
-				if filterMap[triangleId] {
-					continue
-				}
-
-				if triangle, exists := pairCache.GetTriangle(triangleId); exists {
-					triangles = append(triangles, triangle)
-					filterMap[triangleId] = true
-					if triangleId != strconv.FormatInt(triangle.ID, 10) {
-						log.Info("triangleId和triangle.ID比较不相同", "triangleId", triangleId, "triangle.ID", triangle.ID)
-					}
-				}
-			}
-		}
-		lenth := len(triangles)
-		lenthfilter := len(filterMap)
-		log.Info("去重获取triangles", "triangles个数", lenth, "去重获取triangleId个数", lenthfilter)
-		if lenth > 0 {
-			filterLenth := 100
-			if lenth <= filterLenth {
-				log.Info("获取triangle数量", "lenth", lenth)
-				bc.ethAPI.PairCallBatch(triangles)
-			} else {
-				log.Info("过滤获取triangle数量", "lenth", filterLenth)
-				bc.ethAPI.PairCallBatch(selectRandomElements(triangles, filterLenth))
-				if err != nil {
-					log.Error("triangles执行eth_call失败", "err", err)
-				}
-			}
-		}
-
 	}
 
 	// Any blocks remaining here? The only ones we care about are the future ones
@@ -2826,6 +2765,9 @@ func (bc *BlockChain) reorg(oldHead *types.Header, newHead *types.Block) error {
 	for _, tx := range diffs {
 		rawdb.DeleteTxLookupEntry(indexesBatch, tx)
 	}
+	if bc.addressSigner != nil {
+		bc.deleteStaleAddressEntries(indexesBatch, oldChain, newChain)
+	}
 	// Delete all hash markers that are not part of the new canonical chain.
 	// Because the reorg function does not handle new chain head, all hash
 	// markers greater than or equal to new chain head should be deleted.
@@ -3218,6 +3160,46 @@ func EnableDoubleSignChecker(bc *BlockChain) (*BlockChain, error) {
 	return bc, nil
 }
 
+// DoubleSignMonitor returns the double sign monitor of the chain, nil if it is
+// not enabled.
+func (bc *BlockChain) DoubleSignMonitor() *monitor.DoubleSignMonitor {
+	return bc.doubleSignMonitor
+}
+
+// EnableAddressIndex maintains the index of transactions by sender and
+// recipient along with the transaction index.
+func EnableAddressIndex(bc *BlockChain) (*BlockChain, error) {
+	bc.addressSigner = types.LatestSigner(bc.chainConfig)
+	return bc, nil
+}
+
+// deleteStaleAddressEntries removes the address index entries of the blocks
+// dropped by a reorg which are not rewritten by the new canonical blocks.
+func (bc *BlockChain) deleteStaleAddressEntries(db ethdb.KeyValueWriter, oldChain, newChain []*types.Block) {
+	type entry struct {
+		address common.Address
+		number  uint64
+		index   uint32
+	}
+	added := make(map[entry]struct{})
+	for _, block := range newChain {
+		for i, tx := range block.Transactions() {
+			for _, addr := range rawdb.TxAddresses(bc.addressSigner, tx) {
+				added[entry{addr, block.NumberU64(), uint32(i)}] = struct{}{}
+			}
+		}
+	}
+	for _, block := range oldChain {
+		for i, tx := range block.Transactions() {
+			for _, addr := range rawdb.TxAddresses(bc.addressSigner, tx) {
+				if _, ok := added[entry{addr, block.NumberU64(), uint32(i)}]; !ok {
+					rawdb.DeleteAddressTxEntry(db, addr, block.NumberU64(), uint32(i))
+				}
+			}
+		}
+	}
+}
+
 func (bc *BlockChain) GetVerifyResult(blockNumber uint64, blockHash common.Hash, diffHash common.Hash) *VerifyResult {
 	var res VerifyResult
 	res.BlockNumber = blockNumber
@@ -403,6 +403,13 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 	return bc.StateAt(bc.CurrentBlock().Root)
 }
 
+// SharedStateCache returns the read-through cache of the current head state,
+// reset on every head change. States created on top of the head may attach
+// it with StateDB.UseSharedCache.
+func (bc *BlockChain) SharedStateCache() *state.SharedCache {
+	return bc.sharedStateCache
+}
+
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	stateDb, err := state.New(root, bc.stateCache, bc.snaps)
@@ -470,6 +477,55 @@ func (bc *BlockChain) TxIndexProgress() (TxIndexProgress, error) {
 	return bc.txIndexer.txIndexProgress()
 }
 
+// AddressIndexTail returns the oldest block covered by the address index. It
+// fails if the index is disabled, and reports false while nothing is indexed.
+func (bc *BlockChain) AddressIndexTail() (uint64, bool, error) {
+	if bc.addressSigner == nil {
+		return 0, false, errors.New("address index is not enabled")
+	}
+	tail := rawdb.ReadAddressIndexTail(bc.db)
+	if tail == nil {
+		return 0, false, nil
+	}
+	return *tail, true, nil
+}
+
+// GetAddressTransactions retrieves at most limit canonical transactions sent
+// or received by an address, starting at the given transaction position and
+// ending with the transactions of block to. Index entries left behind by
+// blocks which are no longer canonical are skipped.
+func (bc *BlockChain) GetAddressTransactions(address common.Address, number uint64, index uint32, to uint64, limit int) ([]rawdb.AddressTxEntry, error) {
+	if bc.addressSigner == nil {
+		return nil, errors.New("address index is not enabled")
+	}
+	var (
+		entries []rawdb.AddressTxEntry
+		bodies  = make(map[uint64]*types.Body)
+	)
+	for len(entries) < limit {
+		want := limit - len(entries)
+		batch := rawdb.ReadAddressTxEntries(bc.db, address, number, index, to, want)
+		for _, entry := range batch {
+			body, ok := bodies[entry.BlockNumber]
+			if !ok {
+				if hash := rawdb.ReadCanonicalHash(bc.db, entry.BlockNumber); hash != (common.Hash{}) {
+					body = bc.GetBody(hash)
+				}
+				bodies[entry.BlockNumber] = body
+			}
+			if body != nil && int(entry.Index) < len(body.Transactions) && body.Transactions[entry.Index].Hash() == entry.Hash {
+				entries = append(entries, entry)
+			}
+		}
+		if len(batch) < want {
+			break
+		}
+		last := batch[len(batch)-1]
+		number, index = last.BlockNumber, last.Index+1
+	}
+	return entries, nil
+}
+
 // TrieDB retrieves the low level trie database used for data storage.
 func (bc *BlockChain) TrieDB() *triedb.Database {
 	return bc.triedb
@@ -2,18 +2,43 @@ package monitor
 
 import (
 	"bytes"
+	"errors"
+	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
 const (
 	MaxCacheHeader = 100
+
+	// maxDoubleSignEvidence is the number of evidence records kept, the oldest
+	// ones are dropped beyond it.
+	maxDoubleSignEvidence = 256
 )
 
+var errUnknownEvidence = errors.New("unknown double sign evidence")
+
+// DoubleSignEvidence is the proof of a validator sealing two different headers
+// at the same height, along with the hash of the transaction submitting it to
+// the slash contract if any.
+type DoubleSignEvidence struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Coinbase   common.Address `json:"coinbase"`
+	Hash1      common.Hash    `json:"hash1"`
+	Hash2      common.Hash    `json:"hash2"`
+	Header1    hexutil.Bytes  `json:"header1"`
+	Header2    hexutil.Bytes  `json:"header2"`
+	DetectedAt time.Time      `json:"detectedAt"`
+	Submission *common.Hash   `json:"submission,omitempty"`
+}
+
 func NewDoubleSignMonitor() *DoubleSignMonitor {
 	return &DoubleSignMonitor{
 		headerNumbers: prque.New[int64, *types.Header](nil),
@@ -24,6 +49,95 @@ func NewDoubleSignMonitor() *DoubleSignMonitor {
 type DoubleSignMonitor struct {
 	headerNumbers *prque.Prque[int64, *types.Header]
 	headers       map[uint64]*types.Header
+
+	lock         sync.Mutex
+	journal      string // Path of the evidence journal, empty if not persisted
+	evidence     []*DoubleSignEvidence
+	evidenceFeed event.Feed
+}
+
+// OpenJournal loads the evidence recorded at path by a previous run and
+// persists the evidence found from now on there.
+func (m *DoubleSignMonitor) OpenJournal(path string) error {
+	var evidence []*DoubleSignEvidence
+	if err := loadJournal(path, &evidence); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.journal = path
+	m.evidence = append(evidence, m.evidence...)
+	return nil
+}
+
+// Evidence returns the double sign evidence found so far, oldest first.
+func (m *DoubleSignMonitor) Evidence() []*DoubleSignEvidence {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	evidence := make([]*DoubleSignEvidence, 0, len(m.evidence))
+	for _, e := range m.evidence {
+		cpy := *e
+		evidence = append(evidence, &cpy)
+	}
+	return evidence
+}
+
+// SubscribeEvidence registers a subscription for newly found double sign
+// evidence.
+func (m *DoubleSignMonitor) SubscribeEvidence(ch chan<- *DoubleSignEvidence) event.Subscription {
+	return m.evidenceFeed.Subscribe(ch)
+}
+
+// MarkSubmitted records the transaction submitting the evidence of the given
+// validator at the given height.
+func (m *DoubleSignMonitor) MarkSubmitted(number uint64, coinbase common.Address, tx common.Hash) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	e := m.lookup(number, coinbase)
+	if e == nil {
+		return errUnknownEvidence
+	}
+	e.Submission = &tx
+	return m.flush()
+}
+
+// record stores new evidence, returning false if the double sign was already
+// known.
+func (m *DoubleSignMonitor) record(e *DoubleSignEvidence) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.lookup(uint64(e.Number), e.Coinbase) != nil {
+		return false
+	}
+	m.evidence = append(m.evidence, e)
+	if len(m.evidence) > maxDoubleSignEvidence {
+		m.evidence = m.evidence[len(m.evidence)-maxDoubleSignEvidence:]
+	}
+	if err := m.flush(); err != nil {
+		log.Error("Failed to write double sign journal", "path", m.journal, "err", err)
+	}
+	return true
+}
+
+func (m *DoubleSignMonitor) lookup(number uint64, coinbase common.Address) *DoubleSignEvidence {
+	for _, e := range m.evidence {
+		if uint64(e.Number) == number && e.Coinbase == coinbase {
+			return e
+		}
+	}
+	return nil
+}
+
+// flush rewrites the journal, the caller must hold the lock.
+func (m *DoubleSignMonitor) flush() error {
+	if m.journal == "" {
+		return nil
+	}
+	return writeJournal(m.journal, m.evidence)
 }
 
 func (m *DoubleSignMonitor) isDoubleSignHeaders(h1, h2 *types.Header) (bool, error) {
@@ -98,5 +212,21 @@ func (m *DoubleSignMonitor) Verify(h *types.Header) {
 		log.Warn("double sign header content",
 			"header1", hexutil.Encode(h1Bytes),
 			"header2", hexutil.Encode(h2Bytes))
+		if h1Bytes == nil || h2Bytes == nil {
+			return
+		}
+		evidence := &DoubleSignEvidence{
+			Number:     hexutil.Uint64(h.Number.Uint64()),
+			Coinbase:   h.Coinbase,
+			Hash1:      h.Hash(),
+			Hash2:      h2.Hash(),
+			Header1:    h1Bytes,
+			Header2:    h2Bytes,
+			DetectedAt: time.Now(),
+		}
+		if m.record(evidence) {
+			cpy := *evidence
+			m.evidenceFeed.Send(&cpy)
+		}
 	}
 }
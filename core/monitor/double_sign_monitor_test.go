@@ -0,0 +1,61 @@
+package monitor
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoubleSignEvidence(t *testing.T) {
+	var (
+		journal  = filepath.Join(t.TempDir(), "doublesign.journal")
+		coinbase = common.HexToAddress("0x01")
+		parent   = common.HexToHash("0x02")
+		header1  = &types.Header{Number: big.NewInt(10), ParentHash: parent, Coinbase: coinbase, Extra: []byte{1}}
+		header2  = &types.Header{Number: big.NewInt(10), ParentHash: parent, Coinbase: coinbase, Extra: []byte{2}}
+		header3  = &types.Header{Number: big.NewInt(10), ParentHash: parent, Coinbase: coinbase, Extra: []byte{3}}
+	)
+	m := NewDoubleSignMonitor()
+	assert.NoError(t, m.OpenJournal(journal))
+
+	evidenceCh := make(chan *DoubleSignEvidence, 2)
+	sub := m.SubscribeEvidence(evidenceCh)
+	defer sub.Unsubscribe()
+
+	m.Verify(header1)
+	assert.Empty(t, m.Evidence())
+
+	// A second header at the same height is recorded and announced once
+	m.Verify(header2)
+	m.Verify(header3)
+	evidence := m.Evidence()
+	assert.Len(t, evidence, 1)
+	assert.Equal(t, uint64(10), uint64(evidence[0].Number))
+	assert.Equal(t, coinbase, evidence[0].Coinbase)
+	assert.Equal(t, header2.Hash(), evidence[0].Hash1)
+	assert.Equal(t, header1.Hash(), evidence[0].Hash2)
+	assert.Nil(t, evidence[0].Submission)
+	assert.Len(t, evidenceCh, 1)
+	assert.Equal(t, header2.Hash(), (<-evidenceCh).Hash1)
+
+	// Submissions are journaled along with the evidence
+	tx := common.HexToHash("0x03")
+	assert.NoError(t, m.MarkSubmitted(10, coinbase, tx))
+	assert.Equal(t, errUnknownEvidence, m.MarkSubmitted(11, coinbase, tx))
+
+	reopened := NewDoubleSignMonitor()
+	assert.NoError(t, reopened.OpenJournal(journal))
+	evidence = reopened.Evidence()
+	assert.Len(t, evidence, 1)
+	assert.Equal(t, header2.Hash(), evidence[0].Hash1)
+	assert.Equal(t, &tx, evidence[0].Submission)
+
+	// Known double signs are not recorded again after a restart
+	reopened.Verify(header1)
+	reopened.Verify(header2)
+	assert.Len(t, reopened.Evidence(), 1)
+}
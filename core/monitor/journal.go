@@ -0,0 +1,40 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// loadJournal decodes the JSON journal at path into v. A missing journal is
+// not an error, v is left untouched.
+func loadJournal(path string, v interface{}) error {
+	blob, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(blob, v)
+}
+
+// writeJournal replaces the JSON journal at path with the encoding of v. The
+// journal is written to a temporary file first, so a crash never leaves it
+// half written.
+func writeJournal(path string, v interface{}) error {
+	blob, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
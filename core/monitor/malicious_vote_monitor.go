@@ -2,11 +2,17 @@ package monitor
 
 import (
 	"encoding/json"
+	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	lru "github.com/hashicorp/golang-lru"
+	"github.com/tidwall/wal"
 )
 
 // follow define in core/vote
@@ -21,11 +27,27 @@ var (
 	violateRule2Counter = metrics.NewRegisteredCounter("monitor/maliciousVote/violateRule2", nil)
 )
 
+// MaliciousVoteEvidence is a pair of conflicting votes of the same validator,
+// along with the hash of the transaction submitting it to the slash contract if
+// any. ID identifies the pair.
+type MaliciousVoteEvidence struct {
+	ID         common.Hash         `json:"id"`
+	VoteA      *types.VoteEnvelope `json:"voteA"`
+	VoteB      *types.VoteEnvelope `json:"voteB"`
+	DetectedAt time.Time           `json:"detectedAt"`
+	Submission *common.Hash        `json:"submission,omitempty"`
+}
+
 // two purposes
 // 1. monitor whether there are bugs in the voting mechanism, so add metrics to observe it.
-// 2. do malicious vote slashing. TODO
+// 2. do malicious vote slashing, the evidence is journaled and announced to the reporter.
 type MaliciousVoteMonitor struct {
 	curVotes map[types.BLSPublicKey]*lru.Cache
+
+	lock         sync.Mutex
+	journal      *wal.Log // Write-ahead log of the evidence, nil if not persisted
+	evidence     []*MaliciousVoteEvidence
+	evidenceFeed event.Feed
 }
 
 func NewMaliciousVoteMonitor() *MaliciousVoteMonitor {
@@ -76,6 +98,7 @@ func (m *MaliciousVoteMonitor) ConflictDetect(newVote *types.VoteEnvelope, pendi
 				maliciousVote = true
 			}
 			if maliciousVote {
+				m.record(voteEnvelope.(*types.VoteEnvelope), newVote)
 				evidence := types.NewSlashIndicatorFinalityEvidenceWrapper(voteEnvelope.(*types.VoteEnvelope), newVote)
 				if evidence != nil {
 					if evidenceJson, err := json.Marshal(evidence); err == nil {
@@ -95,3 +118,168 @@ func (m *MaliciousVoteMonitor) ConflictDetect(newVote *types.VoteEnvelope, pendi
 	voteDataBuffer.Add(newVote.Data.TargetNumber, newVote)
 	return false
 }
+
+// OpenJournal loads the evidence recorded at path by a previous run and
+// persists the evidence found from now on there. The journal uses the same
+// write-ahead log format as the vote journal and keeps the most recent
+// entries only.
+func (m *MaliciousVoteMonitor) OpenJournal(path string) error {
+	journal, err := wal.Open(path, &wal.Options{
+		LogFormat:        wal.JSON,
+		SegmentCacheSize: maxSizeOfRecentEntry,
+	})
+	if err != nil {
+		return err
+	}
+	first, err := journal.FirstIndex()
+	if err != nil {
+		journal.Close()
+		return err
+	}
+	last, err := journal.LastIndex()
+	if err != nil {
+		journal.Close()
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for index := first; index <= last && index > 0; index++ {
+		blob, err := journal.Read(index)
+		if err != nil {
+			journal.Close()
+			return err
+		}
+		e := new(MaliciousVoteEvidence)
+		if err := json.Unmarshal(blob, e); err != nil {
+			log.Warn("Skipping malformed malicious vote journal entry", "index", index, "err", err)
+			continue
+		}
+		// Later entries of the same evidence record its submission
+		if known := m.lookup(e.ID); known != nil {
+			*known = *e
+			continue
+		}
+		m.evidence = append(m.evidence, e)
+	}
+	m.journal = journal
+	m.truncate()
+	return nil
+}
+
+// Close closes the evidence journal.
+func (m *MaliciousVoteMonitor) Close() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.journal == nil {
+		return nil
+	}
+	err := m.journal.Close()
+	m.journal = nil
+	return err
+}
+
+// Evidence returns the malicious vote evidence found so far, oldest first.
+func (m *MaliciousVoteMonitor) Evidence() []*MaliciousVoteEvidence {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	evidence := make([]*MaliciousVoteEvidence, 0, len(m.evidence))
+	for _, e := range m.evidence {
+		cpy := *e
+		evidence = append(evidence, &cpy)
+	}
+	return evidence
+}
+
+// SubscribeEvidence registers a subscription for newly found malicious vote
+// evidence.
+func (m *MaliciousVoteMonitor) SubscribeEvidence(ch chan<- *MaliciousVoteEvidence) event.Subscription {
+	return m.evidenceFeed.Subscribe(ch)
+}
+
+// MarkSubmitted records the transaction submitting the evidence with the given
+// id.
+func (m *MaliciousVoteMonitor) MarkSubmitted(id common.Hash, tx common.Hash) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	e := m.lookup(id)
+	if e == nil {
+		return errUnknownEvidence
+	}
+	e.Submission = &tx
+	return m.write(e)
+}
+
+// record stores the evidence of two conflicting votes and announces it, unless
+// the pair was already known.
+func (m *MaliciousVoteMonitor) record(voteA, voteB *types.VoteEnvelope) {
+	id := crypto.Keccak256Hash(voteA.Hash().Bytes(), voteB.Hash().Bytes())
+
+	m.lock.Lock()
+	if m.lookup(id) != nil {
+		m.lock.Unlock()
+		return
+	}
+	e := &MaliciousVoteEvidence{
+		ID:         id,
+		VoteA:      voteA,
+		VoteB:      voteB,
+		DetectedAt: time.Now(),
+	}
+	m.evidence = append(m.evidence, e)
+	m.truncate()
+	if err := m.write(e); err != nil {
+		log.Error("Failed to write malicious vote journal", "err", err)
+	}
+	cpy := *e
+	m.lock.Unlock()
+
+	m.evidenceFeed.Send(&cpy)
+}
+
+func (m *MaliciousVoteMonitor) lookup(id common.Hash) *MaliciousVoteEvidence {
+	for _, e := range m.evidence {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// truncate drops the oldest evidence beyond the retained amount, the caller
+// must hold the lock.
+func (m *MaliciousVoteMonitor) truncate() {
+	if len(m.evidence) > maxSizeOfRecentEntry {
+		m.evidence = m.evidence[len(m.evidence)-maxSizeOfRecentEntry:]
+	}
+}
+
+// write appends an evidence entry to the journal and drops the oldest entries
+// beyond the retained amount, the caller must hold the lock.
+func (m *MaliciousVoteMonitor) write(e *MaliciousVoteEvidence) error {
+	if m.journal == nil {
+		return nil
+	}
+	blob, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	last, err := m.journal.LastIndex()
+	if err != nil {
+		return err
+	}
+	if err := m.journal.Write(last+1, blob); err != nil {
+		return err
+	}
+	first, err := m.journal.FirstIndex()
+	if err != nil {
+		return err
+	}
+	if last+1-first+1 > maxSizeOfRecentEntry {
+		return m.journal.TruncateFront(last + 1 - maxSizeOfRecentEntry + 1)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -208,3 +209,55 @@ func TestMaliciousVoteMonitor(t *testing.T) {
 		assert.Equal(t, false, maliciousVoteMonitor.ConflictDetect(vote3, pendingBlockNumber))
 	}
 }
+
+func TestMaliciousVoteEvidence(t *testing.T) {
+	var (
+		journal            = filepath.Join(t.TempDir(), "maliciousvote.journal")
+		pendingBlockNumber = uint64(1000)
+		voteAddress        = types.BLSPublicKey{1}
+	)
+	newVote := func(target byte) *types.VoteEnvelope {
+		return &types.VoteEnvelope{
+			VoteAddress: voteAddress,
+			Data: &types.VoteData{
+				SourceNumber: pendingBlockNumber - 2,
+				TargetNumber: pendingBlockNumber - 1,
+				TargetHash:   common.Hash{target},
+			},
+		}
+	}
+	m := NewMaliciousVoteMonitor()
+	assert.NoError(t, m.OpenJournal(journal))
+
+	evidenceCh := make(chan *MaliciousVoteEvidence, 2)
+	sub := m.SubscribeEvidence(evidenceCh)
+	defer sub.Unsubscribe()
+
+	voteA, voteB := newVote(1), newVote(2)
+	assert.False(t, m.ConflictDetect(voteA, pendingBlockNumber))
+	assert.True(t, m.ConflictDetect(voteB, pendingBlockNumber))
+	assert.True(t, m.ConflictDetect(voteB, pendingBlockNumber))
+
+	evidence := m.Evidence()
+	assert.Len(t, evidence, 1)
+	assert.Equal(t, voteA.Hash(), evidence[0].VoteA.Hash())
+	assert.Equal(t, voteB.Hash(), evidence[0].VoteB.Hash())
+	assert.Len(t, evidenceCh, 1)
+	assert.Equal(t, evidence[0].ID, (<-evidenceCh).ID)
+
+	tx := common.HexToHash("0x01")
+	assert.NoError(t, m.MarkSubmitted(evidence[0].ID, tx))
+	assert.Equal(t, errUnknownEvidence, m.MarkSubmitted(common.Hash{}, tx))
+	assert.NoError(t, m.Close())
+
+	// The evidence and its submission survive a restart
+	reopened := NewMaliciousVoteMonitor()
+	assert.NoError(t, reopened.OpenJournal(journal))
+	defer reopened.Close()
+
+	reloaded := reopened.Evidence()
+	assert.Len(t, reloaded, 1)
+	assert.Equal(t, evidence[0].ID, reloaded[0].ID)
+	assert.Equal(t, voteB.Hash(), reloaded[0].VoteB.Hash())
+	assert.Equal(t, &tx, reloaded[0].Submission)
+}
@@ -18,10 +18,12 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -93,6 +95,132 @@ func DeleteTxLookupEntries(db ethdb.KeyValueWriter, hashes []common.Hash) {
 	}
 }
 
+// AddressTxEntry is the position of a transaction in the address index.
+type AddressTxEntry struct {
+	BlockNumber uint64
+	Index       uint32
+	Hash        common.Hash
+}
+
+// TxAddresses returns the addresses a transaction is indexed under: its sender
+// and its recipient, or the contract it creates.
+func TxAddresses(signer types.Signer, tx *types.Transaction) []common.Address {
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil
+	}
+	var to common.Address
+	if tx.To() != nil {
+		to = *tx.To()
+	} else {
+		to = crypto.CreateAddress(from, tx.Nonce())
+	}
+	if to == from {
+		return []common.Address{from}
+	}
+	return []common.Address{from, to}
+}
+
+// WriteAddressTxEntries stores the address index entries of the transactions
+// of a block, addrs holding the indexed addresses of every transaction.
+func WriteAddressTxEntries(db ethdb.KeyValueWriter, number uint64, hashes []common.Hash, addrs [][]common.Address) {
+	for i, hash := range hashes {
+		for _, addr := range addrs[i] {
+			if err := db.Put(addressTxKey(addr, number, uint32(i)), hash.Bytes()); err != nil {
+				log.Crit("Failed to store address index entry", "err", err)
+			}
+		}
+	}
+}
+
+// WriteAddressTxEntriesByBlock stores the address index entries of the
+// transactions of a block.
+func WriteAddressTxEntriesByBlock(db ethdb.KeyValueWriter, signer types.Signer, block *types.Block) {
+	number := block.NumberU64()
+	for i, tx := range block.Transactions() {
+		for _, addr := range TxAddresses(signer, tx) {
+			if err := db.Put(addressTxKey(addr, number, uint32(i)), tx.Hash().Bytes()); err != nil {
+				log.Crit("Failed to store address index entry", "err", err)
+			}
+		}
+	}
+}
+
+// DeleteAddressTxEntry removes an address index entry.
+func DeleteAddressTxEntry(db ethdb.KeyValueWriter, address common.Address, number uint64, index uint32) {
+	if err := db.Delete(addressTxKey(address, number, index)); err != nil {
+		log.Crit("Failed to delete address index entry", "err", err)
+	}
+}
+
+// DeleteAddressTxEntries removes the address index entries of the transactions
+// of a block, addrs holding the indexed addresses of every transaction.
+func DeleteAddressTxEntries(db ethdb.KeyValueWriter, number uint64, addrs [][]common.Address) {
+	for i := range addrs {
+		for _, addr := range addrs[i] {
+			DeleteAddressTxEntry(db, addr, number, uint32(i))
+		}
+	}
+}
+
+// ReadAddressTxEntries retrieves at most limit address index entries of an
+// address, starting at the given transaction position and ending with the
+// transactions of block to. The entries are ordered by position.
+func ReadAddressTxEntries(db ethdb.Iteratee, address common.Address, number uint64, index uint32, to uint64, limit int) []AddressTxEntry {
+	prefix := append(append([]byte{}, addressTxPrefix...), address.Bytes()...)
+	start := addressTxKey(address, number, index)[len(prefix):]
+
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	var entries []AddressTxEntry
+	for it.Next() && (limit <= 0 || len(entries) < limit) {
+		key := it.Key()
+		if len(key) != len(prefix)+12 || len(it.Value()) != common.HashLength {
+			continue
+		}
+		entry := AddressTxEntry{
+			BlockNumber: binary.BigEndian.Uint64(key[len(prefix):]),
+			Index:       binary.BigEndian.Uint32(key[len(prefix)+8:]),
+			Hash:        common.BytesToHash(it.Value()),
+		}
+		if entry.BlockNumber > to {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// DeleteAddressIndex removes the whole address index together with its tail
+// marker.
+func DeleteAddressIndex(db ethdb.KeyValueStore) error {
+	DeleteAddressIndexTail(db)
+
+	it := db.NewIterator(addressTxPrefix, nil)
+	defer it.Release()
+
+	batch := db.NewBatch()
+	for it.Next() {
+		if len(it.Key()) != len(addressTxPrefix)+common.AddressLength+12 {
+			continue
+		}
+		if err := batch.Delete(common.CopyBytes(it.Key())); err != nil {
+			return err
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
 // ReadTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func ReadTransaction(db ethdb.Reader, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
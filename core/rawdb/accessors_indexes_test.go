@@ -19,10 +19,12 @@ package rawdb
 import (
 	"bytes"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/blocktest"
 	"github.com/ethereum/go-ethereum/params"
@@ -112,6 +114,67 @@ func TestLookupStorage(t *testing.T) {
 	}
 }
 
+// Tests that the address index entries can be stored, paged through and deleted.
+func TestAddressTxStorage(t *testing.T) {
+	var (
+		db     = NewMemoryDatabase()
+		key, _ = crypto.GenerateKey()
+		from   = crypto.PubkeyToAddress(key.PublicKey)
+		to     = common.BytesToAddress([]byte{0x11})
+		signer = types.LatestSignerForChainID(big.NewInt(1))
+	)
+	call := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &to, Gas: 21000, GasPrice: big.NewInt(1)})
+	create := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 1, Gas: 100000, GasPrice: big.NewInt(1)})
+	self := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 2, To: &from, Gas: 21000, GasPrice: big.NewInt(1)})
+
+	created := crypto.CreateAddress(from, 1)
+	if addrs := TxAddresses(signer, call); !reflect.DeepEqual(addrs, []common.Address{from, to}) {
+		t.Fatalf("call addresses mismatch: have %v", addrs)
+	}
+	if addrs := TxAddresses(signer, create); !reflect.DeepEqual(addrs, []common.Address{from, created}) {
+		t.Fatalf("creation addresses mismatch: have %v", addrs)
+	}
+	if addrs := TxAddresses(signer, self); !reflect.DeepEqual(addrs, []common.Address{from}) {
+		t.Fatalf("self transfer addresses mismatch: have %v", addrs)
+	}
+	block1 := types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{call, create}, nil, nil, newTestHasher())
+	block2 := types.NewBlock(&types.Header{Number: big.NewInt(2)}, []*types.Transaction{self}, nil, nil, newTestHasher())
+	WriteAddressTxEntriesByBlock(db, signer, block1)
+	WriteAddressTxEntriesByBlock(db, signer, block2)
+
+	expect := []AddressTxEntry{
+		{BlockNumber: 1, Index: 0, Hash: call.Hash()},
+		{BlockNumber: 1, Index: 1, Hash: create.Hash()},
+		{BlockNumber: 2, Index: 0, Hash: self.Hash()},
+	}
+	if entries := ReadAddressTxEntries(db, from, 0, 0, 10, 0); !reflect.DeepEqual(entries, expect) {
+		t.Fatalf("sender entries mismatch: have %v, want %v", entries, expect)
+	}
+	if entries := ReadAddressTxEntries(db, from, 1, 1, 10, 1); !reflect.DeepEqual(entries, expect[1:2]) {
+		t.Fatalf("paged entries mismatch: have %v, want %v", entries, expect[1:2])
+	}
+	if entries := ReadAddressTxEntries(db, from, 0, 0, 1, 0); !reflect.DeepEqual(entries, expect[:2]) {
+		t.Fatalf("ranged entries mismatch: have %v, want %v", entries, expect[:2])
+	}
+	if entries := ReadAddressTxEntries(db, created, 0, 0, 10, 0); !reflect.DeepEqual(entries, expect[1:2]) {
+		t.Fatalf("created contract entries mismatch: have %v, want %v", entries, expect[1:2])
+	}
+	DeleteAddressTxEntry(db, to, 1, 0)
+	if entries := ReadAddressTxEntries(db, to, 0, 0, 10, 0); len(entries) != 0 {
+		t.Fatalf("deleted entries returned: %v", entries)
+	}
+	WriteAddressIndexTail(db, 1)
+	if err := DeleteAddressIndex(db); err != nil {
+		t.Fatalf("failed to delete address index: %v", err)
+	}
+	if entries := ReadAddressTxEntries(db, from, 0, 0, 10, 0); len(entries) != 0 {
+		t.Fatalf("entries left after dropping the index: %v", entries)
+	}
+	if tail := ReadAddressIndexTail(db); tail != nil {
+		t.Fatalf("tail left after dropping the index: %d", *tail)
+	}
+}
+
 func TestDeleteBloomBits(t *testing.T) {
 	// Prepare testing data
 	db := NewMemoryDatabase()
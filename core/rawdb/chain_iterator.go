@@ -88,6 +88,7 @@ func InitDatabaseFromFreezer(db ethdb.Database) {
 type blockTxHashes struct {
 	number uint64
 	hashes []common.Hash
+	addrs  [][]common.Address // Indexed addresses of the transactions, if requested
 }
 
 // iterateTransactions iterates over all transactions in the (canon) block
@@ -95,6 +96,12 @@ type blockTxHashes struct {
 // received from interrupt channel, the iteration will be aborted and result
 // channel will be closed.
 func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool, interrupt chan struct{}) chan *blockTxHashes {
+	return iterateBlockTransactions(db, from, to, reverse, interrupt, nil)
+}
+
+// iterateBlockTransactions is iterateTransactions additionally yielding the
+// indexed addresses of the transactions if a signer is given.
+func iterateBlockTransactions(db ethdb.Database, from uint64, to uint64, reverse bool, interrupt chan struct{}, signer types.Signer) chan *blockTxHashes {
 	// One thread sequentially reads data from db
 	type numberRlp struct {
 		number uint64
@@ -152,12 +159,19 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 				log.Warn("Failed to decode block body", "block", data.number, "error", err)
 				return
 			}
-			var hashes []common.Hash
+			var (
+				hashes []common.Hash
+				addrs  [][]common.Address
+			)
 			for _, tx := range body.Transactions {
 				hashes = append(hashes, tx.Hash())
+				if signer != nil {
+					addrs = append(addrs, TxAddresses(signer, tx))
+				}
 			}
 			result := &blockTxHashes{
 				hashes: hashes,
+				addrs:  addrs,
 				number: data.number,
 			}
 			// Feed the block to the aggregator, or abort on interrupt
@@ -177,15 +191,57 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 	return hashesCh
 }
 
-// indexTransactions creates txlookup indices of the specified block range.
+// blockIndex describes an index maintained per block over a range of the
+// canonical chain, ending with the head and starting with the tail stored
+// along the index.
+type blockIndex struct {
+	noun  string // Name of the indexed items in logs, e.g. "transactions"
+	title string // Capitalized singular of the name, e.g. "Transaction"
+
+	signer    types.Signer // Signer to recover the senders of transactions, if needed
+	write     func(db ethdb.KeyValueWriter, delivery *blockTxHashes)
+	delete    func(db ethdb.KeyValueWriter, delivery *blockTxHashes)
+	writeTail func(db ethdb.KeyValueWriter, number uint64)
+}
+
+// txLookupIndex is the index of transactions by hash.
+var txLookupIndex = &blockIndex{
+	noun:  "transactions",
+	title: "Transaction",
+	write: func(db ethdb.KeyValueWriter, delivery *blockTxHashes) {
+		WriteTxLookupEntries(db, delivery.number, delivery.hashes)
+	},
+	delete: func(db ethdb.KeyValueWriter, delivery *blockTxHashes) {
+		DeleteTxLookupEntries(db, delivery.hashes)
+	},
+	writeTail: WriteTxIndexTail,
+}
+
+// addressIndex returns the index of transactions by sender and recipient.
+func addressIndex(signer types.Signer) *blockIndex {
+	return &blockIndex{
+		noun:   "addresses",
+		title:  "Address",
+		signer: signer,
+		write: func(db ethdb.KeyValueWriter, delivery *blockTxHashes) {
+			WriteAddressTxEntries(db, delivery.number, delivery.hashes, delivery.addrs)
+		},
+		delete: func(db ethdb.KeyValueWriter, delivery *blockTxHashes) {
+			DeleteAddressTxEntries(db, delivery.number, delivery.addrs)
+		},
+		writeTail: WriteAddressIndexTail,
+	}
+}
+
+// indexBlocks creates the indices of the specified block range.
 //
 // This function iterates canonical chain in reverse order, it has one main advantage:
-// We can write tx index tail flag periodically even without the whole indexing
+// We can write index tail flag periodically even without the whole indexing
 // procedure is finished. So that we can resume indexing procedure next time quickly.
 //
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
-func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool, report bool) {
+func indexBlocks(db ethdb.Database, index *blockIndex, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool, report bool) {
 	// short circuit for invalid range
 	if offset := db.BlockStore().AncientOffSet(); offset > from {
 		from = offset
@@ -194,7 +250,7 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 		return
 	}
 	var (
-		hashesCh = iterateTransactions(db, from, to, true, interrupt)
+		hashesCh = iterateBlockTransactions(db, from, to, true, interrupt, index.signer)
 		batch    = db.NewBatch()
 		start    = time.Now()
 		logged   = start.Add(-7 * time.Second)
@@ -223,12 +279,12 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 			// Next block available, pop it off and index it
 			delivery := queue.PopItem()
 			lastNum = delivery.number
-			WriteTxLookupEntries(batch, delivery.number, delivery.hashes)
+			index.write(batch, delivery)
 			blocks++
 			txs += len(delivery.hashes)
 			// If enough data was accumulated in memory or we're at the last block, dump to disk
 			if batch.ValueSize() > ethdb.IdealBatchSize {
-				WriteTxIndexTail(batch, lastNum) // Also write the tail here
+				index.writeTail(batch, lastNum) // Also write the tail here
 				if err := batch.Write(); err != nil {
 					log.Crit("Failed writing batch to db", "error", err)
 					return
@@ -237,7 +293,7 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 			}
 			// If we've spent too much time already, notify the user of what we're doing
 			if time.Since(logged) > 8*time.Second {
-				log.Info("Indexing transactions", "blocks", blocks, "txs", txs, "tail", lastNum, "total", to-from, "elapsed", common.PrettyDuration(time.Since(start)))
+				log.Info("Indexing "+index.noun, "blocks", blocks, "txs", txs, "tail", lastNum, "total", to-from, "elapsed", common.PrettyDuration(time.Since(start)))
 				logged = time.Now()
 			}
 		}
@@ -245,7 +301,7 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 	// Flush the new indexing tail and the last committed data. It can also happen
 	// that the last batch is empty because nothing to index, but the tail has to
 	// be flushed anyway.
-	WriteTxIndexTail(batch, lastNum)
+	index.writeTail(batch, lastNum)
 	if err := batch.Write(); err != nil {
 		log.Crit("Failed writing batch to db", "error", err)
 		return
@@ -256,9 +312,9 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 	}
 	select {
 	case <-interrupt:
-		logger("Transaction indexing interrupted", "blocks", blocks, "txs", txs, "tail", lastNum, "elapsed", common.PrettyDuration(time.Since(start)))
+		logger(index.title+" indexing interrupted", "blocks", blocks, "txs", txs, "tail", lastNum, "elapsed", common.PrettyDuration(time.Since(start)))
 	default:
-		logger("Indexed transactions", "blocks", blocks, "txs", txs, "tail", lastNum, "elapsed", common.PrettyDuration(time.Since(start)))
+		logger("Indexed "+index.noun, "blocks", blocks, "txs", txs, "tail", lastNum, "elapsed", common.PrettyDuration(time.Since(start)))
 	}
 }
 
@@ -272,19 +328,26 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
 func IndexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, report bool) {
-	indexTransactions(db, from, to, interrupt, nil, report)
+	indexBlocks(db, txLookupIndex, from, to, interrupt, nil, report)
 }
 
 // indexTransactionsForTesting is the internal debug version with an additional hook.
 func indexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
-	indexTransactions(db, from, to, interrupt, hook, false)
+	indexBlocks(db, txLookupIndex, from, to, interrupt, hook, false)
 }
 
-// unindexTransactions removes txlookup indices of the specified block range.
+// IndexAddresses creates the address index entries of the transactions in the
+// specified block range. The from is included while to is excluded. Like
+// IndexTransactions, the range is iterated in reverse order.
+func IndexAddresses(db ethdb.Database, signer types.Signer, from uint64, to uint64, interrupt chan struct{}, report bool) {
+	indexBlocks(db, addressIndex(signer), from, to, interrupt, nil, report)
+}
+
+// unindexBlocks removes the indices of the specified block range.
 //
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
-func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool, report bool) {
+func unindexBlocks(db ethdb.Database, index *blockIndex, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool, report bool) {
 	// short circuit for invalid range
 	if offset := db.BlockStore().AncientOffSet(); offset > from {
 		from = offset
@@ -293,7 +356,7 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 		return
 	}
 	var (
-		hashesCh = iterateTransactions(db, from, to, false, interrupt)
+		hashesCh = iterateBlockTransactions(db, from, to, false, interrupt, index.signer)
 		batch    = db.NewBatch()
 		start    = time.Now()
 		logged   = start.Add(-7 * time.Second)
@@ -319,7 +382,7 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 			}
 			delivery := queue.PopItem()
 			nextNum = delivery.number + 1
-			DeleteTxLookupEntries(batch, delivery.hashes)
+			index.delete(batch, delivery)
 			txs += len(delivery.hashes)
 			blocks++
 
@@ -327,7 +390,7 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 			// A batch counts the size of deletion as '1', so we need to flush more
 			// often than that.
 			if blocks%1000 == 0 {
-				WriteTxIndexTail(batch, nextNum)
+				index.writeTail(batch, nextNum)
 				if err := batch.Write(); err != nil {
 					log.Crit("Failed writing batch to db", "error", err)
 					return
@@ -336,7 +399,7 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 			}
 			// If we've spent too much time already, notify the user of what we're doing
 			if time.Since(logged) > 8*time.Second {
-				log.Info("Unindexing transactions", "blocks", blocks, "txs", txs, "total", to-from, "elapsed", common.PrettyDuration(time.Since(start)))
+				log.Info("Unindexing "+index.noun, "blocks", blocks, "txs", txs, "total", to-from, "elapsed", common.PrettyDuration(time.Since(start)))
 				logged = time.Now()
 			}
 		}
@@ -344,7 +407,7 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 	// Flush the new indexing tail and the last committed data. It can also happen
 	// that the last batch is empty because nothing to unindex, but the tail has to
 	// be flushed anyway.
-	WriteTxIndexTail(batch, nextNum)
+	index.writeTail(batch, nextNum)
 	if err := batch.Write(); err != nil {
 		log.Crit("Failed writing batch to db", "error", err)
 		return
@@ -355,9 +418,9 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 	}
 	select {
 	case <-interrupt:
-		logger("Transaction unindexing interrupted", "blocks", blocks, "txs", txs, "tail", to, "elapsed", common.PrettyDuration(time.Since(start)))
+		logger(index.title+" unindexing interrupted", "blocks", blocks, "txs", txs, "tail", to, "elapsed", common.PrettyDuration(time.Since(start)))
 	default:
-		logger("Unindexed transactions", "blocks", blocks, "txs", txs, "tail", to, "elapsed", common.PrettyDuration(time.Since(start)))
+		logger("Unindexed "+index.noun, "blocks", blocks, "txs", txs, "tail", to, "elapsed", common.PrettyDuration(time.Since(start)))
 	}
 }
 
@@ -367,10 +430,16 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
 func UnindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, report bool) {
-	unindexTransactions(db, from, to, interrupt, nil, report)
+	unindexBlocks(db, txLookupIndex, from, to, interrupt, nil, report)
 }
 
 // unindexTransactionsForTesting is the internal debug version with an additional hook.
 func unindexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
-	unindexTransactions(db, from, to, interrupt, hook, false)
+	unindexBlocks(db, txLookupIndex, from, to, interrupt, hook, false)
+}
+
+// UnindexAddresses removes the address index entries of the transactions in
+// the specified block range. The from is included while to is excluded.
+func UnindexAddresses(db ethdb.Database, signer types.Signer, from uint64, to uint64, interrupt chan struct{}, report bool) {
+	unindexBlocks(db, addressIndex(signer), from, to, interrupt, nil, report)
 }
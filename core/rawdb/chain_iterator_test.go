@@ -25,6 +25,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 func TestChainIterator(t *testing.T) {
@@ -206,3 +207,73 @@ func TestIndexTransactions(t *testing.T) {
 	verify(8, 11, true, 8)
 	verify(0, 8, false, 8)
 }
+
+func TestIndexAddresses(t *testing.T) {
+	// Construct test chain db
+	chainDb := NewMemoryDatabase()
+
+	var (
+		key, _ = crypto.GenerateKey()
+		from   = crypto.PubkeyToAddress(key.PublicKey)
+		to     = common.BytesToAddress([]byte{0x11})
+		signer = types.LatestSignerForChainID(big.NewInt(1))
+		txs    []*types.Transaction
+	)
+	// Write empty genesis block
+	block := types.NewBlock(&types.Header{Number: big.NewInt(int64(0))}, nil, nil, nil, newTestHasher())
+	WriteBlock(chainDb, block)
+	WriteCanonicalHash(chainDb, block.Hash(), block.NumberU64())
+
+	for i := uint64(1); i <= 10; i++ {
+		tx := types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    i - 1,
+			GasPrice: big.NewInt(11111),
+			Gas:      1111,
+			To:       &to,
+			Value:    big.NewInt(111),
+		})
+		txs = append(txs, tx)
+		block = types.NewBlock(&types.Header{Number: big.NewInt(int64(i))}, []*types.Transaction{tx}, nil, nil, newTestHasher())
+		WriteBlock(chainDb, block)
+		WriteCanonicalHash(chainDb, block.Hash(), block.NumberU64())
+	}
+	// verify checks whether the address indices in the range [from, to)
+	// are expected.
+	verify := func(start, end int, exist bool, tail uint64) {
+		for i := start; i < end; i++ {
+			if i == 0 {
+				continue
+			}
+			for _, addr := range []common.Address{from, to} {
+				entries := ReadAddressTxEntries(chainDb, addr, uint64(i), 0, uint64(i), 0)
+				if exist && (len(entries) != 1 || entries[0].Hash != txs[i-1].Hash()) {
+					t.Fatalf("Address index of block %d missing for %x: %v", i, addr, entries)
+				}
+				if !exist && len(entries) != 0 {
+					t.Fatalf("Address index of block %d is not deleted for %x", i, addr)
+				}
+			}
+		}
+		number := ReadAddressIndexTail(chainDb)
+		if number == nil || *number != tail {
+			t.Fatalf("Address index tail mismatch")
+		}
+	}
+	IndexAddresses(chainDb, signer, 5, 11, nil, false)
+	verify(5, 11, true, 5)
+	verify(0, 5, false, 5)
+
+	IndexAddresses(chainDb, signer, 0, 5, nil, false)
+	verify(0, 11, true, 0)
+
+	// The transaction index is not affected
+	if ReadTxIndexTail(chainDb) != nil || ReadTxLookupEntry(chainDb, txs[0].Hash()) != nil {
+		t.Fatalf("Transaction index written by address indexing")
+	}
+	UnindexAddresses(chainDb, signer, 0, 5, nil, false)
+	verify(5, 11, true, 5)
+	verify(0, 5, false, 5)
+
+	UnindexAddresses(chainDb, signer, 5, 11, nil, false)
+	verify(0, 11, false, 11)
+}
@@ -0,0 +1,175 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/holiman/uint256"
+)
+
+// HistoricState serves reads of a state whose trie is no longer available,
+// which is the case for all states older than the persisted one of a path-based
+// database. Values modified since are taken from the state histories, all the
+// others from the persisted state. Proofs can not be made as no trie nodes of
+// the historic state are left.
+//
+// Like StateDB, the accessors memorize the first database error, which is
+// returned by Error.
+type HistoricState struct {
+	db       Database
+	reader   *pathdb.HistoricalStateReader
+	trie     Trie // Account trie of the persisted state
+	accounts map[common.Address]*types.StateAccount
+	dbErr    error
+}
+
+// NewHistoricState creates a reader for the state with the given root.
+func NewHistoricState(root common.Hash, db Database) (*HistoricState, error) {
+	reader, err := db.TrieDB().HistoricReader(root)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := db.OpenTrie(reader.Root())
+	if err != nil {
+		return nil, err
+	}
+	return &HistoricState{
+		db:       db,
+		reader:   reader,
+		trie:     tr,
+		accounts: make(map[common.Address]*types.StateAccount),
+	}, nil
+}
+
+func (s *HistoricState) setError(err error) {
+	if s.dbErr == nil {
+		s.dbErr = err
+	}
+}
+
+// Error returns the memorized database failure occurred earlier.
+func (s *HistoricState) Error() error {
+	return s.dbErr
+}
+
+// account returns the account in the historic state, nil if it did not exist.
+func (s *HistoricState) account(addr common.Address) *types.StateAccount {
+	if acct, ok := s.accounts[addr]; ok {
+		return acct
+	}
+	blob, found, err := s.reader.Account(addr)
+	if err != nil {
+		s.setError(err)
+		return nil
+	}
+	var acct *types.StateAccount
+	if !found {
+		acct, err = s.trie.GetAccount(addr)
+		if err != nil {
+			s.setError(fmt.Errorf("historic state: can't get account %x: %w", addr, err))
+			return nil
+		}
+	} else if len(blob) > 0 {
+		acct, err = types.FullAccount(blob)
+		if err != nil {
+			s.setError(fmt.Errorf("historic state: invalid account %x: %w", addr, err))
+			return nil
+		}
+	}
+	s.accounts[addr] = acct
+	return acct
+}
+
+// Exist reports whether the given account existed in the historic state.
+func (s *HistoricState) Exist(addr common.Address) bool {
+	return s.account(addr) != nil
+}
+
+// GetBalance retrieves the balance of the given address, zero if not found.
+func (s *HistoricState) GetBalance(addr common.Address) *uint256.Int {
+	if acct := s.account(addr); acct != nil {
+		return acct.Balance
+	}
+	return common.U2560
+}
+
+// GetNonce retrieves the nonce of the given address, zero if not found.
+func (s *HistoricState) GetNonce(addr common.Address) uint64 {
+	if acct := s.account(addr); acct != nil {
+		return acct.Nonce
+	}
+	return 0
+}
+
+// GetCodeHash retrieves the code hash of the given address, empty if not found.
+func (s *HistoricState) GetCodeHash(addr common.Address) common.Hash {
+	if acct := s.account(addr); acct != nil {
+		return common.BytesToHash(acct.CodeHash)
+	}
+	return common.Hash{}
+}
+
+// GetCode retrieves the code of the given address. Code is never removed from
+// the database, so it is available for historic states as well.
+func (s *HistoricState) GetCode(addr common.Address) []byte {
+	acct := s.account(addr)
+	if acct == nil || common.BytesToHash(acct.CodeHash) == types.EmptyCodeHash {
+		return nil
+	}
+	code, err := s.db.ContractCode(addr, common.BytesToHash(acct.CodeHash))
+	if err != nil {
+		s.setError(fmt.Errorf("historic state: can't load code %x: %w", acct.CodeHash, err))
+	}
+	return code
+}
+
+// GetStorageRoot retrieves the storage root of the given address, empty if
+// not found.
+func (s *HistoricState) GetStorageRoot(addr common.Address) common.Hash {
+	if acct := s.account(addr); acct != nil {
+		return acct.Root
+	}
+	return common.Hash{}
+}
+
+// GetState retrieves the value of a storage slot in the historic state.
+func (s *HistoricState) GetState(addr common.Address, key common.Hash) common.Hash {
+	blob, found, err := s.reader.Storage(addr, key)
+	if err != nil {
+		s.setError(err)
+		return common.Hash{}
+	}
+	if found {
+		if len(blob) == 0 {
+			return common.Hash{}
+		}
+		_, content, _, err := rlp.Split(blob)
+		if err != nil {
+			s.setError(fmt.Errorf("historic state: invalid slot %x of %x: %w", key, addr, err))
+		}
+		return common.BytesToHash(content)
+	}
+	// Not modified since, read it from the storage of the persisted account
+	acct, err := s.trie.GetAccount(addr)
+	if err != nil {
+		s.setError(fmt.Errorf("historic state: can't get account %x: %w", addr, err))
+		return common.Hash{}
+	}
+	if acct == nil || acct.Root == types.EmptyRootHash {
+		return common.Hash{}
+	}
+	tr, err := s.db.OpenStorageTrie(s.reader.Root(), addr, acct.Root, s.trie)
+	if err != nil {
+		s.setError(fmt.Errorf("historic state: can't open storage trie of %x: %w", addr, err))
+		return common.Hash{}
+	}
+	value, err := tr.GetStorage(addr, key.Bytes())
+	if err != nil {
+		s.setError(fmt.Errorf("historic state: can't get slot %x of %x: %w", key, addr, err))
+		return common.Hash{}
+	}
+	return common.BytesToHash(value)
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/holiman/uint256"
+)
+
+func TestHistoricState(t *testing.T) {
+	disk, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false, false, false, false, false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	var (
+		tdb     = triedb.NewDatabase(disk, &triedb.Config{PathDB: pathdb.Defaults})
+		sdb     = NewDatabaseWithNodeDB(disk, tdb)
+		roots   []common.Hash
+		slot    = common.BytesToHash([]byte{0x01})
+		other   = common.BytesToHash([]byte{0x02})
+		addr    = common.BytesToAddress([]byte{0x10})
+		created = common.BytesToAddress([]byte{0x11})
+	)
+	defer disk.Close()
+	defer tdb.Close()
+
+	// Block n sets the balance and slot to n, the second slot is only set
+	// once and a second account is only created in the last block
+	root := types.EmptyRootHash
+	for n := uint64(1); n <= 5; n++ {
+		state, _ := New(root, sdb, nil)
+		state.SetBalance(addr, uint256.NewInt(n))
+		state.SetState(addr, slot, common.Hash(uint256.NewInt(n).Bytes32()))
+		if n == 1 {
+			state.SetState(addr, other, common.BytesToHash([]byte{0xff}))
+			state.SetCode(addr, []byte{0x60, 0x00})
+		}
+		if n == 5 {
+			state.SetNonce(created, 1)
+		}
+		state.Finalise(true)
+		state.AccountsIntermediateRoot()
+		root, _, err = state.Commit(n, nil)
+		if err != nil {
+			t.Fatalf("failed to commit block %d: %v", n, err)
+		}
+		roots = append(roots, root)
+	}
+	if err := tdb.Commit(root, false); err != nil {
+		t.Fatalf("failed to persist state: %v", err)
+	}
+	if _, err := New(roots[1], sdb, nil); err == nil {
+		t.Fatal("historic state still available as trie")
+	}
+	for i, root := range roots[:len(roots)-1] {
+		historic, err := NewHistoricState(root, sdb)
+		if err != nil {
+			t.Fatalf("failed to create historic state %d: %v", i, err)
+		}
+		if balance := historic.GetBalance(addr); balance.Uint64() != uint64(i+1) {
+			t.Errorf("state %d: balance mismatch: have %v, want %d", i, balance, i+1)
+		}
+		if value := historic.GetState(addr, slot); value != common.Hash(uint256.NewInt(uint64(i+1)).Bytes32()) {
+			t.Errorf("state %d: slot mismatch: have %x", i, value)
+		}
+		if value := historic.GetState(addr, other); value != common.BytesToHash([]byte{0xff}) {
+			t.Errorf("state %d: unmodified slot mismatch: have %x", i, value)
+		}
+		if code := historic.GetCode(addr); len(code) != 2 {
+			t.Errorf("state %d: code mismatch: have %x", i, code)
+		}
+		if historic.Exist(created) || historic.GetNonce(created) != 0 {
+			t.Errorf("state %d: account exists before its creation", i)
+		}
+		if err := historic.Error(); err != nil {
+			t.Fatalf("state %d: database error: %v", i, err)
+		}
+	}
+	if _, err := NewHistoricState(common.Hash{0x01}, sdb); !errors.Is(err, pathdb.ErrHistoryPruned) {
+		t.Fatalf("unexpected error for unknown state: %v", err)
+	}
+}
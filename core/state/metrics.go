@@ -34,4 +34,10 @@ var (
 	slotDeletionCount    = metrics.NewRegisteredMeter("state/delete/storage/slot", nil)
 	slotDeletionSize     = metrics.NewRegisteredMeter("state/delete/storage/size", nil)
 	slotDeletionSkip     = metrics.NewRegisteredGauge("state/delete/storage/skip", nil)
+
+	sharedCacheAccountHitMeter  = metrics.NewRegisteredMeter("state/sharedcache/account/hit", nil)
+	sharedCacheAccountMissMeter = metrics.NewRegisteredMeter("state/sharedcache/account/miss", nil)
+	sharedCacheStorageHitMeter  = metrics.NewRegisteredMeter("state/sharedcache/storage/hit", nil)
+	sharedCacheStorageMissMeter = metrics.NewRegisteredMeter("state/sharedcache/storage/miss", nil)
+	sharedCacheResetMeter       = metrics.NewRegisteredMeter("state/sharedcache/reset", nil)
 )
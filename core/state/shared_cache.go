@@ -0,0 +1,128 @@
+package state
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultSharedCacheLimit is the default number of accounts and storage slots
+// a single layer of the shared cache may hold.
+const DefaultSharedCacheLimit = 1_000_000
+
+// SharedCache is a read-through cache of the committed accounts and storage
+// slots of a single state root, shared by all the StateDB instances created on
+// top of that root. Only values read from the snapshot or the trie are ever
+// inserted, so the content of a layer is immutable for its root and readers
+// never observe the writes of another StateDB.
+//
+// Reset swaps in an empty layer for a new root in one step. StateDBs attached
+// to the previous layer keep using it, as it is still valid for their root.
+type SharedCache struct {
+	limit int64
+	layer atomic.Pointer[cacheLayer]
+}
+
+// cacheLayer is the content of the shared cache for a single state root.
+type cacheLayer struct {
+	root     common.Hash
+	limit    int64
+	size     atomic.Int64
+	accounts sync.Map // common.Address -> *types.StateAccount, nil if non-existent
+	storage  sync.Map // common.Address -> *sync.Map of common.Hash -> common.Hash
+}
+
+// NewSharedCache creates a shared cache holding at most limit entries per
+// state root, or DefaultSharedCacheLimit if limit is not positive. The cache
+// is disabled until the first Reset.
+func NewSharedCache(limit int) *SharedCache {
+	if limit <= 0 {
+		limit = DefaultSharedCacheLimit
+	}
+	return &SharedCache{limit: int64(limit)}
+}
+
+// Reset drops all cached data and makes root the state the cache serves.
+func (c *SharedCache) Reset(root common.Hash) {
+	c.layer.Store(&cacheLayer{root: root, limit: c.limit})
+	sharedCacheResetMeter.Mark(1)
+}
+
+// Root returns the state root the cache currently serves.
+func (c *SharedCache) Root() common.Hash {
+	if l := c.layer.Load(); l != nil {
+		return l.root
+	}
+	return common.Hash{}
+}
+
+// layerAt returns the active layer if it belongs to the given root, nil otherwise.
+func (c *SharedCache) layerAt(root common.Hash) *cacheLayer {
+	if l := c.layer.Load(); l != nil && l.root == root {
+		return l
+	}
+	return nil
+}
+
+// account returns a private copy of the cached account. The second value
+// reports whether the address was cached at all, the account itself is nil
+// if it is known not to exist.
+func (l *cacheLayer) account(addr common.Address) (*types.StateAccount, bool) {
+	v, ok := l.accounts.Load(addr)
+	if !ok {
+		sharedCacheAccountMissMeter.Mark(1)
+		return nil, false
+	}
+	sharedCacheAccountHitMeter.Mark(1)
+	if acct := v.(*types.StateAccount); acct != nil {
+		return acct.Copy(), true
+	}
+	return nil, true
+}
+
+// setAccount caches a copy of the committed account, nil if non-existent.
+func (l *cacheLayer) setAccount(addr common.Address, acct *types.StateAccount) {
+	if !l.reserve() {
+		return
+	}
+	if acct != nil {
+		acct = acct.Copy()
+	}
+	if _, loaded := l.accounts.LoadOrStore(addr, acct); loaded {
+		l.size.Add(-1)
+	}
+}
+
+// storageAt returns the cached committed value of a storage slot.
+func (l *cacheLayer) storageAt(addr common.Address, key common.Hash) (common.Hash, bool) {
+	if slots, ok := l.storage.Load(addr); ok {
+		if v, ok := slots.(*sync.Map).Load(key); ok {
+			sharedCacheStorageHitMeter.Mark(1)
+			return v.(common.Hash), true
+		}
+	}
+	sharedCacheStorageMissMeter.Mark(1)
+	return common.Hash{}, false
+}
+
+// setStorage caches the committed value of a storage slot.
+func (l *cacheLayer) setStorage(addr common.Address, key, value common.Hash) {
+	if !l.reserve() {
+		return
+	}
+	slots, _ := l.storage.LoadOrStore(addr, new(sync.Map))
+	if _, loaded := slots.(*sync.Map).LoadOrStore(key, value); loaded {
+		l.size.Add(-1)
+	}
+}
+
+// reserve accounts for a new entry, failing once the layer is full.
+func (l *cacheLayer) reserve() bool {
+	if l.size.Add(1) > l.limit {
+		l.size.Add(-1)
+		return false
+	}
+	return true
+}
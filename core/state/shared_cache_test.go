@@ -0,0 +1,159 @@
+package state
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+var (
+	cachedAddr = common.BytesToAddress([]byte{0x01})
+	cachedSlot = common.BytesToHash([]byte{0x02})
+)
+
+// newCachedState commits an account with a single storage slot and returns the
+// database and root to create states from.
+func newCachedState(t *testing.T) (Database, common.Hash) {
+	db := NewDatabase(rawdb.NewMemoryDatabase())
+	state, _ := New(types.EmptyRootHash, db, nil)
+	state.SetBalance(cachedAddr, uint256.NewInt(100))
+	state.SetState(cachedAddr, cachedSlot, common.BytesToHash([]byte{0x42}))
+	state.Finalise(false)
+	state.AccountsIntermediateRoot()
+	root, _, err := state.Commit(0, nil)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	return db, root
+}
+
+func TestSharedCacheReadThrough(t *testing.T) {
+	db, root := newCachedState(t)
+	cache := NewSharedCache(0)
+	cache.Reset(root)
+
+	first, _ := New(root, db, nil)
+	first.UseSharedCache(cache)
+	first.GetBalance(cachedAddr)
+	first.GetState(cachedAddr, cachedSlot)
+	first.GetBalance(common.BytesToAddress([]byte{0x03}))
+
+	layer := cache.layerAt(root)
+	if acct, ok := layer.account(cachedAddr); !ok || acct.Balance.Uint64() != 100 {
+		t.Fatalf("account not cached: %v %v", acct, ok)
+	}
+	if acct, ok := layer.account(common.BytesToAddress([]byte{0x03})); !ok || acct != nil {
+		t.Fatalf("missing account not cached as non-existent: %v %v", acct, ok)
+	}
+	if value, ok := layer.storageAt(cachedAddr, cachedSlot); !ok || value != common.BytesToHash([]byte{0x42}) {
+		t.Fatalf("storage not cached: %x %v", value, ok)
+	}
+	// A second state is served from the cache even if the database is gone
+	second, _ := New(root, db, nil)
+	second.UseSharedCache(cache)
+	second.trie = nil
+	if balance := second.GetBalance(cachedAddr); balance.Uint64() != 100 {
+		t.Fatalf("balance mismatch: have %v, want 100", balance)
+	}
+}
+
+func TestSharedCacheIsolation(t *testing.T) {
+	db, root := newCachedState(t)
+	cache := NewSharedCache(0)
+	cache.Reset(root)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				state, _ := New(root, db, nil)
+				state.UseSharedCache(cache)
+				if balance := state.GetBalance(cachedAddr); balance.Uint64() != 100 {
+					t.Errorf("state %d: balance mismatch: have %v, want 100", i, balance)
+					return
+				}
+				if value := state.GetState(cachedAddr, cachedSlot); value != common.BytesToHash([]byte{0x42}) {
+					t.Errorf("state %d: slot mismatch: have %x", i, value)
+					return
+				}
+				// Writes are visible to the writer only
+				state.AddBalance(cachedAddr, uint256.NewInt(uint64(i+1)))
+				state.SetState(cachedAddr, cachedSlot, common.BytesToHash([]byte{byte(i)}))
+				state.Finalise(true)
+				if balance := state.GetBalance(cachedAddr); balance.Uint64() != uint64(101+i) {
+					t.Errorf("state %d: own balance mismatch: have %v, want %d", i, balance, 101+i)
+					return
+				}
+				if value := state.GetState(cachedAddr, cachedSlot); value != common.BytesToHash([]byte{byte(i)}) {
+					t.Errorf("state %d: own slot mismatch: have %x", i, value)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// None of the writes above may have leaked into the cache
+	layer := cache.layerAt(root)
+	if acct, _ := layer.account(cachedAddr); acct == nil || acct.Balance.Uint64() != 100 {
+		t.Fatalf("cached account modified: %v", acct)
+	}
+	if value, _ := layer.storageAt(cachedAddr, cachedSlot); value != common.BytesToHash([]byte{0x42}) {
+		t.Fatalf("cached slot modified: %x", value)
+	}
+}
+
+func TestSharedCacheRoot(t *testing.T) {
+	db, root := newCachedState(t)
+	cache := NewSharedCache(0)
+
+	// Not attached before the first reset or after a reset to another root
+	state, _ := New(root, db, nil)
+	state.UseSharedCache(cache)
+	if state.sharedCache != nil {
+		t.Fatal("cache attached before reset")
+	}
+	cache.Reset(root)
+	state.UseSharedCache(cache)
+	state.GetBalance(cachedAddr)
+
+	cache.Reset(common.Hash{0x01})
+	if _, ok := cache.layerAt(common.Hash{0x01}).account(cachedAddr); ok {
+		t.Fatal("account survived reset")
+	}
+	other, _ := New(root, db, nil)
+	other.UseSharedCache(cache)
+	if other.sharedCache != nil {
+		t.Fatal("cache attached to state of another root")
+	}
+	// Hashing the state detaches it, the tries no longer match the root
+	state.IntermediateRoot(true)
+	if state.sharedCache != nil {
+		t.Fatal("cache attached after hashing")
+	}
+}
+
+func TestSharedCacheLimit(t *testing.T) {
+	cache := NewSharedCache(2)
+	cache.Reset(common.Hash{})
+	layer := cache.layerAt(common.Hash{})
+
+	layer.setStorage(cachedAddr, common.Hash{0x01}, common.Hash{0x01})
+	layer.setStorage(cachedAddr, common.Hash{0x01}, common.Hash{0x01})
+	layer.setAccount(cachedAddr, nil)
+	layer.setAccount(common.BytesToAddress([]byte{0x03}), nil)
+
+	if _, ok := layer.account(common.BytesToAddress([]byte{0x03})); ok {
+		t.Fatal("entry cached beyond the limit")
+	}
+	if size := layer.size.Load(); size != 2 {
+		t.Fatalf("size mismatch: have %d, want 2", size)
+	}
+}
@@ -203,10 +203,6 @@ func (s *stateObject) setOriginStorage(key common.Hash, value common.Hash) {
 	s.originStorage[key] = value
 }
 
-// var storCacheMap = make(map[string]common.Hash, 2000000)
-
-// var storAddTmpMap = cmap.New()
-
 // GetCommittedState retrieves a value from the committed account storage trie.
 func (s *stateObject) GetCommittedState(key common.Hash) common.Hash {
 	// If we have a pending write or clean cached, return that
@@ -218,21 +214,6 @@ func (s *stateObject) GetCommittedState(key common.Hash) common.Hash {
 		return value
 	}
 
-	// storageCacheMap := pair.GetStorageCacheMap()
-	// hashedKey := crypto.Keccak256Hash(s.addrHash[:], key[:]).Hex()
-	// if s.db.Flag == 1 {
-	// 	if storageCache, exists := storageCacheMap.Get(hashedKey); exists {
-	// 		storage := storageCache.(common.Hash)
-	// 		s.setOriginStorage(key, storage)
-	// 		return storage
-	// 	}
-	//
-	// 	// if storage, ok := storCacheMap[hashedKey]; ok {
-	// 	// 	s.setOriginStorage(key, storage)
-	// 	// 	return storage
-	// 	// }
-	// }
-
 	// If the object was destructed in *this* block (and potentially resurrected),
 	// the storage has been cleared out, and we should *not* consult the previous
 	// database about any storage values. The only possible alternatives are:
@@ -242,6 +223,13 @@ func (s *stateObject) GetCommittedState(key common.Hash) common.Hash {
 	if _, destructed := s.db.stateObjectsDestruct[s.address]; destructed {
 		return common.Hash{}
 	}
+	// Slots read by another state on the same root can be reused
+	if s.db.sharedCache != nil {
+		if value, ok := s.db.sharedCache.storageAt(s.address, key); ok {
+			s.setOriginStorage(key, value)
+			return value
+		}
+	}
 	// If no live objects are available, attempt to use snapshots
 	var (
 		enc   []byte
@@ -280,13 +268,10 @@ func (s *stateObject) GetCommittedState(key common.Hash) common.Hash {
 		}
 		value.SetBytes(val)
 	}
+	if s.db.sharedCache != nil && s.db.dbErr == nil {
+		s.db.sharedCache.setStorage(s.address, key, value)
+	}
 	s.setOriginStorage(key, value)
-
-	// if s.db.Flag == 1 {
-	// 	storageCacheMap.Set(hashedKey, value)
-	// 	// storAddTmpMap.Set(hashedKey, value)
-	// }
-
 	return value
 }
 
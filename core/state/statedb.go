@@ -159,7 +159,7 @@ type StateDB struct {
 	// Testing hooks
 	onCommit func(states *triestate.Set) // Hook invoked when commit is performed
 
-	Flag int8
+	sharedCache *cacheLayer // Shared read-through cache of the committed state at originalRoot, nil if not attached
 }
 
 // NewWithSharedPool creates a new state with sharedStorge on layer 1.5
@@ -172,6 +172,17 @@ func NewWithSharedPool(root common.Hash, db Database, snaps *snapshot.Tree) (*St
 	return statedb, nil
 }
 
+// UseSharedCache attaches the shared read-through cache to the state, if the
+// cache currently serves the state's root. Otherwise the state keeps reading
+// from the snapshot and the trie only. The cache is detached again as soon as
+// the state is hashed or committed.
+func (s *StateDB) UseSharedCache(cache *SharedCache) {
+	if cache == nil {
+		return
+	}
+	s.sharedCache = cache.layerAt(s.originalRoot)
+}
+
 // New creates a new state from a given trie.
 func New(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error) {
 	sdb := &StateDB{
@@ -709,10 +720,6 @@ func (s *StateDB) getStateObject(addr common.Address) *stateObject {
 	return nil
 }
 
-// var stateObjCacheMap = make(map[string]*stateObject, 100000)
-
-// var stateObjAddTmpMap = cmap.New()
-
 // getDeletedStateObject is similar to getStateObject, but instead of returning
 // nil for a deleted state object, it returns the actual object with the deleted
 // flag set. This is needed by the state journal to revert to the correct s-
@@ -723,26 +730,17 @@ func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
 		return obj
 	}
 
-	// StateDB自己本身无缓存时，在从公共的缓存中获取，如果存在则将其复制成新的实例更新到StateDB中
-	// 复制实例主要是避免线程安全问题，不同线程不同的StateDB操作各自不同的stateObject，可以将stateObjectCacheMap理解成另一个数据库
-	// stateObjectCacheMap := pair.GetStateObjectCacheMap()
-	// if s.Flag == 1 {
-	// 	if objectCache, ok := stateObjectCacheMap.Get(addr.Hex()); ok {
-	// 		objCache := objectCache.(*stateObject)
-	// 		object := newObject(s, addr, objCache.origin)
-	// 		object.code = objCache.code
-	// 		s.setStateObject(object)
-	// 		return object
-	// 	}
-	//
-	// 	// if objCache, ok := stateObjCacheMap[addr.Hex()]; ok {
-	// 	// 	object := newObject(s, addr, objCache.origin)
-	// 	// 	object.code = objCache.code
-	// 	// 	s.setStateObject(object)
-	// 	// 	return object
-	// 	// }
-	// }
-
+	// Committed accounts read by another state on the same root can be reused
+	if s.sharedCache != nil {
+		if data, ok := s.sharedCache.account(addr); ok {
+			if data == nil {
+				return nil
+			}
+			obj := newObject(s, addr, data)
+			s.setStateObject(obj)
+			return obj
+		}
+	}
 	// If no live objects are available, attempt to use snapshots
 	var data *types.StateAccount
 	if s.snap != nil {
@@ -753,6 +751,9 @@ func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
 		}
 		if err == nil {
 			if acc == nil {
+				if s.sharedCache != nil {
+					s.sharedCache.setAccount(addr, nil)
+				}
 				return nil
 			}
 			data = &types.StateAccount{
@@ -791,19 +792,18 @@ func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
 			return nil
 		}
 		if data == nil {
+			if s.sharedCache != nil {
+				s.sharedCache.setAccount(addr, nil)
+			}
 			return nil
 		}
 	}
+	if s.sharedCache != nil {
+		s.sharedCache.setAccount(addr, data)
+	}
 	// Insert into the live set
 	obj := newObject(s, addr, data)
 	s.setStateObject(obj)
-
-	// if s.Flag == 1 {
-	// 	obj.Code()
-	// 	stateObjectCacheMap.Set(addr.Hex(), obj)
-	// 	// stateObjAddTmpMap.Set(addr.Hex(), obj)
-	// }
-
 	return obj
 }
 
@@ -856,12 +856,6 @@ func (s *StateDB) createObject(addr common.Address) (newobj, prev *stateObject)
 		delete(s.storagesOrigin, prev.address)
 	}
 	s.setStateObject(newobj)
-
-	// if s.Flag == 1 {
-	// 	stateObjectCacheMap := pair.GetStateObjectCacheMap()
-	// 	stateObjectCacheMap.Set(addr.Hex(), newobj)
-	// }
-
 	if prev != nil && !prev.deleted {
 		return newobj, prev
 	}
@@ -918,6 +912,7 @@ func (s *StateDB) copyInternal(doPrefetch bool) *StateDB {
 		stateObjectsDirty:    make(map[common.Address]struct{}, len(s.journal.dirties)),
 		stateObjectsDestruct: make(map[common.Address]*types.StateAccount, len(s.stateObjectsDestruct)),
 		storagePool:          s.storagePool,
+		sharedCache:          s.sharedCache,
 		// writeOnSharedStorage: s.writeOnSharedStorage,
 		refund:    s.refund,
 		logs:      make(map[common.Hash][]*types.Log, len(s.logs)),
@@ -1110,6 +1105,9 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 // It is called in between transactions to get the root hash that
 // goes into transaction receipts.
 func (s *StateDB) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
+	// The tries are about to diverge from the cached root
+	s.sharedCache = nil
+
 	// Finalise all the dirty storage states and write them into the tries
 	s.Finalise(deleteEmptyObjects)
 	s.AccountsIntermediateRoot()
@@ -1528,6 +1526,8 @@ func (s *StateDB) Commit(block uint64, failPostCommitFunc func(), postCommitFunc
 		s.StopPrefetcher()
 		return common.Hash{}, nil, fmt.Errorf("commit aborted due to earlier error: %v", s.dbErr)
 	}
+	s.sharedCache = nil
+
 	// Finalize any pending changes and merge everything into the tries
 	var (
 		diffLayer   *types.DiffLayer
@@ -1792,49 +1792,6 @@ func (s *StateDB) Commit(block uint64, failPostCommitFunc func(), postCommitFunc
 		root = types.EmptyRootHash
 	}
 
-	// 增量添加新pair相关stateObj数据缓存，完成后清空临时map
-	// for it := range stateObjAddTmpMap.IterBuffered() {
-	// 	stateObjCacheMap[it.Key] = it.Val.(*stateObject)
-	// }
-	// for it := range storAddTmpMap.IterBuffered() {
-	// 	storCacheMap[it.Key] = it.Val.(common.Hash)
-	// }
-	// stateObjAddTmpMap.Clear()
-	// storAddTmpMap.Clear()
-
-	// 新区块产生后更新stateObj与storage数据缓存
-	// stateObjectCacheMap := pair.GetStateObjectCacheMap()
-	// for addr := range s.stateObjectsDirty {
-	// 	if obj := s.stateObjects[addr]; !obj.deleted {
-	// 		if _, loaded := stateObjectCacheMap.Get(addr.Hex()); loaded {
-	// 			stateObjectCacheMap.Set(addr.Hex(), obj)
-	// 		}
-	//
-	// 		// if _, ok := stateObjCacheMap[addr.Hex()]; ok {
-	// 		// 	stateObjCacheMap[addr.Hex()] = obj
-	// 		// }
-	// 	}
-	// }
-	// storageCacheMap := pair.GetStorageCacheMap()
-	// for addr, storage := range s.storages {
-	// 	for key, val := range storage {
-	// 		hashedKey := crypto.Keccak256Hash(addr[:], key[:]).Hex()
-	// 		if _, exists := storageCacheMap.Get(hashedKey); exists {
-	// 			storageCacheMap.Set(hashedKey, val)
-	// 		}
-	//
-	// 		// if _, ok := storCacheMap[hashedKey]; ok {
-	// 		// 	storCacheMap[hashedKey] = common.Hash(val)
-	// 		// }
-	// 	}
-	// }
-
-	// 统计元素个数
-	// fmt.Printf("stateObjectCacheMap中的元素个数: %d\n", stateObjectCacheMap.Count())
-	// fmt.Printf("storageCacheMap中的元素个数: %d\n", storageCacheMap.Count())
-	// fmt.Printf("stateObjCacheMap中的元素个数: %d\n", len(stateObjCacheMap))
-	// fmt.Printf("storCacheMap中的元素个数: %d\n", len(storCacheMap))
-
 	// Clear all internal flags at the end of commit operation.
 	s.accounts = make(map[common.Hash][]byte)
 	s.storages = make(map[common.Hash]map[common.Hash][]byte)
@@ -0,0 +1,218 @@
+package state
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/holiman/uint256"
+)
+
+// StateDiff is the set of accounts modified since the last Finalise, keyed by
+// address.
+type StateDiff map[common.Address]*AccountDiff
+
+// AccountDiff holds the values of an account before and after the changes
+// recorded in the journal. Fields that did not change are nil.
+type AccountDiff struct {
+	Created    bool                        `json:"created,omitempty"`
+	Destructed bool                        `json:"destructed,omitempty"`
+	Balance    *BalanceDiff                `json:"balance,omitempty"`
+	Nonce      *NonceDiff                  `json:"nonce,omitempty"`
+	Code       *CodeDiff                   `json:"code,omitempty"`
+	Storage    map[common.Hash]StorageDiff `json:"storage,omitempty"`
+}
+
+// BalanceDiff is the balance of an account before and after the changes.
+type BalanceDiff struct {
+	From *hexutil.U256 `json:"from"`
+	To   *hexutil.U256 `json:"to"`
+}
+
+// NonceDiff is the nonce of an account before and after the changes.
+type NonceDiff struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+// CodeDiff is the code of an account before and after the changes.
+type CodeDiff struct {
+	From hexutil.Bytes `json:"from"`
+	To   hexutil.Bytes `json:"to"`
+}
+
+// StorageDiff is the value of a storage slot before and after the changes.
+type StorageDiff struct {
+	From common.Hash `json:"from"`
+	To   common.Hash `json:"to"`
+}
+
+// StorageAfter returns the value a changed storage slot was left with. The
+// second value reports whether the slot was changed at all.
+func (d StateDiff) StorageAfter(addr common.Address, key common.Hash) (common.Hash, bool) {
+	if acct := d[addr]; acct != nil {
+		if slot, ok := acct.Storage[key]; ok {
+			return slot.To, true
+		}
+	}
+	return common.Hash{}, false
+}
+
+// diffOrigin tracks the values an account had before its first change in the
+// journal. Unset fields were not changed.
+type diffOrigin struct {
+	created bool         // account did not exist before the changes
+	prev    *stateObject // account replaced by CreateAccount, storage is read from it
+	balance *uint256.Int
+	nonce   *uint64
+	code    []byte
+	codeSet bool
+	storage map[common.Hash]common.Hash
+}
+
+// setAccount records the values of the account replaced by the first
+// creation of the account, nil if it did not exist.
+func (o *diffOrigin) setAccount(prev *stateObject) {
+	if prev == nil || prev.deleted {
+		o.created = true
+		prev = nil
+	} else {
+		o.prev = prev
+	}
+	if o.balance == nil {
+		o.balance = new(uint256.Int)
+		if prev != nil {
+			o.balance.Set(prev.Balance())
+		}
+	}
+	if o.nonce == nil {
+		var nonce uint64
+		if prev != nil {
+			nonce = prev.Nonce()
+		}
+		o.nonce = &nonce
+	}
+	if !o.codeSet {
+		if prev != nil {
+			o.code = prev.Code()
+		}
+		o.codeSet = true
+	}
+}
+
+// StateDiff returns the accounts modified since the last Finalise together
+// with their values before and after the changes. The original values are
+// taken from the journal, so changes of reverted call frames are not reported.
+func (s *StateDB) StateDiff() StateDiff {
+	origins := make(map[common.Address]*diffOrigin)
+	origin := func(addr common.Address) *diffOrigin {
+		o, ok := origins[addr]
+		if !ok {
+			o = &diffOrigin{storage: make(map[common.Hash]common.Hash)}
+			origins[addr] = o
+		}
+		return o
+	}
+	for _, entry := range s.journal.entries {
+		switch ch := entry.(type) {
+		case createObjectChange:
+			if o := origin(*ch.account); !o.created && o.prev == nil {
+				o.setAccount(nil)
+			}
+		case resetObjectChange:
+			if o := origin(*ch.account); !o.created && o.prev == nil {
+				o.setAccount(ch.prev)
+			}
+		case balanceChange:
+			if o := origin(*ch.account); o.balance == nil {
+				o.balance = new(uint256.Int).Set(ch.prev)
+			}
+		case selfDestructChange:
+			if o := origin(*ch.account); o.balance == nil {
+				o.balance = new(uint256.Int).Set(ch.prevbalance)
+			}
+		case nonceChange:
+			if o := origin(*ch.account); o.nonce == nil {
+				nonce := ch.prev
+				o.nonce = &nonce
+			}
+		case codeChange:
+			if o := origin(*ch.account); !o.codeSet {
+				o.code, o.codeSet = ch.prevcode, true
+			}
+		case storageChange:
+			o := origin(*ch.account)
+			if _, ok := o.storage[ch.key]; ok {
+				continue
+			}
+			// Slots of a replaced account start from its values, not the
+			// empty storage of the new object
+			switch {
+			case o.created:
+				o.storage[ch.key] = common.Hash{}
+			case o.prev != nil:
+				o.storage[ch.key] = o.prev.GetState(ch.key)
+			default:
+				o.storage[ch.key] = ch.prevalue
+			}
+		}
+	}
+	diff := make(StateDiff)
+	for addr, o := range origins {
+		var (
+			obj        = s.getStateObject(addr)
+			destructed = obj != nil && obj.selfDestructed
+			alive      = obj != nil && !destructed
+		)
+		// An empty account touched into existence is removed again by Finalise
+		acct := &AccountDiff{Created: o.created && alive && !obj.empty(), Destructed: destructed}
+		changed := acct.Created || acct.Destructed
+		if o.balance != nil {
+			after := new(uint256.Int)
+			if alive {
+				after.Set(obj.Balance())
+			}
+			if !o.balance.Eq(after) {
+				acct.Balance = &BalanceDiff{From: (*hexutil.U256)(o.balance), To: (*hexutil.U256)(after)}
+				changed = true
+			}
+		}
+		if o.nonce != nil {
+			var after uint64
+			if alive {
+				after = obj.Nonce()
+			}
+			if *o.nonce != after {
+				acct.Nonce = &NonceDiff{From: hexutil.Uint64(*o.nonce), To: hexutil.Uint64(after)}
+				changed = true
+			}
+		}
+		if o.codeSet {
+			var after []byte
+			if alive {
+				after = obj.Code()
+			}
+			if !bytes.Equal(o.code, after) {
+				acct.Code = &CodeDiff{From: o.code, To: after}
+				changed = true
+			}
+		}
+		for key, before := range o.storage {
+			var after common.Hash
+			if alive {
+				after = obj.GetState(key)
+			}
+			if before != after {
+				if acct.Storage == nil {
+					acct.Storage = make(map[common.Hash]StorageDiff)
+				}
+				acct.Storage[key] = StorageDiff{From: before, To: after}
+				changed = true
+			}
+		}
+		if changed {
+			diff[addr] = acct
+		}
+	}
+	return diff
+}
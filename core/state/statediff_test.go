@@ -0,0 +1,66 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+func TestStateDiff(t *testing.T) {
+	db, root := newCachedState(t)
+	state, _ := New(root, db, nil)
+
+	var (
+		created  = common.BytesToAddress([]byte{0x10})
+		reverted = common.BytesToAddress([]byte{0x11})
+		other    = common.BytesToHash([]byte{0x03})
+	)
+	state.SetState(cachedAddr, cachedSlot, common.BytesToHash([]byte{0x43}))
+	state.SetState(cachedAddr, cachedSlot, common.BytesToHash([]byte{0x44}))
+	state.SetState(cachedAddr, other, common.BytesToHash([]byte{0x01}))
+	state.SetState(cachedAddr, other, common.Hash{})
+	state.SetNonce(cachedAddr, 1)
+	state.SetCode(created, []byte{0x60, 0x00})
+	state.AddBalance(created, uint256.NewInt(5))
+
+	// Changes of reverted frames are not reported
+	snap := state.Snapshot()
+	state.AddBalance(reverted, uint256.NewInt(1))
+	state.SubBalance(cachedAddr, uint256.NewInt(1))
+	state.RevertToSnapshot(snap)
+
+	diff := state.StateDiff()
+	if len(diff) != 2 {
+		t.Fatalf("diff size mismatch: have %d, want 2", len(diff))
+	}
+	acct := diff[cachedAddr]
+	if acct == nil || acct.Created || acct.Balance != nil || acct.Code != nil {
+		t.Fatalf("unexpected account diff: %+v", acct)
+	}
+	if acct.Nonce == nil || acct.Nonce.From != 0 || acct.Nonce.To != 1 {
+		t.Fatalf("nonce diff mismatch: %+v", acct.Nonce)
+	}
+	if len(acct.Storage) != 1 {
+		t.Fatalf("storage diff size mismatch: have %d, want 1", len(acct.Storage))
+	}
+	want := StorageDiff{From: common.BytesToHash([]byte{0x42}), To: common.BytesToHash([]byte{0x44})}
+	if slot := acct.Storage[cachedSlot]; slot != want {
+		t.Fatalf("storage diff mismatch: have %+v, want %+v", slot, want)
+	}
+	if value, ok := diff.StorageAfter(cachedAddr, cachedSlot); !ok || value != want.To {
+		t.Fatalf("storage after mismatch: %x %v", value, ok)
+	}
+	acct = diff[created]
+	if acct == nil || !acct.Created || acct.Code == nil || len(acct.Code.From) != 0 || len(acct.Code.To) != 2 {
+		t.Fatalf("unexpected created account diff: %+v", acct)
+	}
+	if acct.Balance == nil || (*uint256.Int)(acct.Balance.From).Uint64() != 0 || (*uint256.Int)(acct.Balance.To).Uint64() != 5 {
+		t.Fatalf("balance diff mismatch: %+v", acct.Balance)
+	}
+	// The diff starts over after finalising
+	state.Finalise(true)
+	if diff := state.StateDiff(); len(diff) != 0 {
+		t.Fatalf("diff not empty after finalise: %v", diff)
+	}
+}
@@ -156,6 +156,15 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 }
 
 func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM, receiptProcessors ...ReceiptProcessor) (*types.Receipt, error) {
+	receipt, _, err := ApplyTransactionWithEVM(msg, config, gp, statedb, blockNumber, blockHash, tx, usedGas, evm, receiptProcessors...)
+	return receipt, err
+}
+
+// ApplyTransactionWithEVM applies a transaction in the given EVM exactly like
+// it is applied during block processing, additionally returning the result of
+// its execution. It lets callers inspect the return data of the transaction
+// and abort its execution through the EVM.
+func ApplyTransactionWithEVM(msg *Message, config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM, receiptProcessors ...ReceiptProcessor) (*types.Receipt, *ExecutionResult, error) {
 	// Create a new context to be used in the EVM environment.
 	txContext := NewEVMTxContext(msg)
 	evm.Reset(txContext, statedb)
@@ -163,7 +172,7 @@ func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, sta
 	// Apply the transaction to the current state (included in the env).
 	result, err := ApplyMessage(evm, msg, gp)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Update the state with pending changes.
@@ -204,7 +213,34 @@ func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, sta
 	for _, receiptProcessor := range receiptProcessors {
 		receiptProcessor.Apply(receipt)
 	}
-	return receipt, err
+	return receipt, result, err
+}
+
+// ApplyBidTransaction applies a transaction of a builder bid in the given EVM,
+// the way the bid simulator of the miner does. The blob gas limit of the block
+// is checked against the number of blobs already included, as it is not
+// enforced during execution, and an unrevertible transaction that fails is an
+// error. The gas used by the transaction is added to the header.
+func ApplyBidTransaction(config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, blobs int, unRevertible bool, evm *vm.EVM) (*types.Receipt, *ExecutionResult, error) {
+	if tx.Type() == types.BlobTxType {
+		if (blobs+len(tx.BlobHashes()))*params.BlobTxBlobGasPerBlob > params.MaxBlobGasPerBlock {
+			return nil, nil, errors.New("max data blobs reached")
+		}
+	}
+	msg, err := TransactionToMessage(tx, types.MakeSigner(config, header.Number, header.Time), header.BaseFee)
+	if err != nil {
+		return nil, nil, err
+	}
+	receipt, result, err := ApplyTransactionWithEVM(msg, config, gp, statedb, header.Number, header.Hash(), tx, &header.GasUsed, evm, NewReceiptBloomGenerator())
+	if err != nil {
+		return nil, nil, err
+	} else if unRevertible && receipt.Status == types.ReceiptStatusFailed {
+		return nil, nil, errors.New("no revertible transaction failed")
+	}
+	if header.BlobGasUsed != nil {
+		*header.BlobGasUsed += receipt.BlobGasUsed
+	}
+	return receipt, result, nil
 }
 
 // ApplyTransaction attempts to apply a transaction to the given state database
@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 )
@@ -46,6 +47,7 @@ type txIndexer struct {
 	//       and all others shouldn't.
 	limit    uint64
 	db       ethdb.Database
+	signer   types.Signer // Sender recovery of the address index, nil if disabled
 	progress chan chan TxIndexProgress
 	term     chan chan struct{}
 	closed   chan struct{}
@@ -56,6 +58,7 @@ func newTxIndexer(limit uint64, chain *BlockChain) *txIndexer {
 	indexer := &txIndexer{
 		limit:    limit,
 		db:       chain.db,
+		signer:   chain.addressSigner,
 		progress: make(chan chan TxIndexProgress),
 		term:     make(chan chan struct{}),
 		closed:   make(chan struct{}),
@@ -68,7 +71,14 @@ func newTxIndexer(limit uint64, chain *BlockChain) *txIndexer {
 	} else {
 		msg = fmt.Sprintf("last %d blocks", limit)
 	}
-	log.Info("Initialized transaction indexer", "range", msg)
+	log.Info("Initialized transaction indexer", "range", msg, "addresses", indexer.signer != nil)
+
+	// A disabled address index misses the blocks imported meanwhile, drop its
+	// tail to have it rebuilt when enabled again.
+	if tail := rawdb.ReadAddressIndexTail(chain.db); tail != nil && indexer.signer == nil {
+		log.Warn("Address index is disabled, remove it with 'geth db drop-address-index'", "tail", *tail)
+		rawdb.DeleteAddressIndexTail(chain.db)
+	}
 
 	return indexer
 }
@@ -83,6 +93,30 @@ func (indexer *txIndexer) run(tail *uint64, head uint64, stop chan struct{}, don
 	if head == 0 {
 		return
 	}
+	indexer.update(tail, head, func(from, to uint64, report bool) {
+		rawdb.IndexTransactions(indexer.db, from, to, stop, report)
+	}, func(from, to uint64, report bool) {
+		rawdb.UnindexTransactions(indexer.db, from, to, stop, report)
+	})
+	// The address index follows the transaction index with the same range
+	if indexer.signer == nil {
+		return
+	}
+	select {
+	case <-stop:
+		return
+	default:
+	}
+	indexer.update(rawdb.ReadAddressIndexTail(indexer.db), head, func(from, to uint64, report bool) {
+		rawdb.IndexAddresses(indexer.db, indexer.signer, from, to, stop, report)
+	}, func(from, to uint64, report bool) {
+		rawdb.UnindexAddresses(indexer.db, indexer.signer, from, to, stop, report)
+	})
+}
+
+// update moves the range of an index with the given tail to the configured
+// limit below the head, indexing or unindexing blocks as needed.
+func (indexer *txIndexer) update(tail *uint64, head uint64, index, unindex func(from, to uint64, report bool)) {
 	// The tail flag is not existent, it means the node is just initialized
 	// and all blocks in the chain (part of them may from ancient store) are
 	// not indexed yet, index the chain according to the configured limit.
@@ -91,7 +125,7 @@ func (indexer *txIndexer) run(tail *uint64, head uint64, stop chan struct{}, don
 		if indexer.limit != 0 && head >= indexer.limit {
 			from = head - indexer.limit + 1
 		}
-		rawdb.IndexTransactions(indexer.db, from, head+1, stop, true)
+		index(from, head+1, true)
 		return
 	}
 	// The tail flag is existent (which means indexes in [tail, head] should be
@@ -105,7 +139,7 @@ func (indexer *txIndexer) run(tail *uint64, head uint64, stop chan struct{}, don
 			if end > head+1 {
 				end = head + 1
 			}
-			rawdb.IndexTransactions(indexer.db, 0, end, stop, true)
+			index(0, end, true)
 		}
 		return
 	}
@@ -113,10 +147,10 @@ func (indexer *txIndexer) run(tail *uint64, head uint64, stop chan struct{}, don
 	// limit and the latest chain head.
 	if head-indexer.limit+1 < *tail {
 		// Reindex a part of missing indices and rewind index tail to HEAD-limit
-		rawdb.IndexTransactions(indexer.db, head-indexer.limit+1, *tail, stop, true)
+		index(head-indexer.limit+1, *tail, true)
 	} else {
 		// Unindex a part of stale indices and forward index tail to HEAD-limit
-		rawdb.UnindexTransactions(indexer.db, *tail, head-indexer.limit+1, stop, false)
+		unindex(*tail, head-indexer.limit+1, false)
 	}
 }
 
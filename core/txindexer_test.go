@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
@@ -68,6 +69,13 @@ func TestTxIndexer(t *testing.T) {
 				t.Fatalf("unexpected %d %x", number, tx.Hash().Hex())
 			}
 		}
+		entries := rawdb.ReadAddressTxEntries(db, testBankAddress, number, 0, number, 0)
+		if exist && len(entries) != len(block.Transactions()) {
+			t.Fatalf("missing address index of block %d", number)
+		}
+		if !exist && len(entries) != 0 {
+			t.Fatalf("unexpected address index of block %d", number)
+		}
 	}
 	verify := func(db ethdb.Database, expTail uint64, indexer *txIndexer) {
 		tail := rawdb.ReadTxIndexTail(db)
@@ -77,6 +85,9 @@ func TestTxIndexer(t *testing.T) {
 		if *tail != expTail {
 			t.Fatalf("Unexpected tx index tail, want %v, got %d", expTail, *tail)
 		}
+		if tail := rawdb.ReadAddressIndexTail(db); tail == nil || *tail != expTail {
+			t.Fatalf("Unexpected address index tail, want %v, got %v", expTail, tail)
+		}
 		if *tail != 0 {
 			for number := uint64(0); number < *tail; number += 1 {
 				verifyIndexes(db, number, false)
@@ -219,6 +230,7 @@ func TestTxIndexer(t *testing.T) {
 		indexer := &txIndexer{
 			limit:    c.limitA,
 			db:       db,
+			signer:   types.LatestSigner(gspec.Config),
 			progress: make(chan chan TxIndexProgress),
 		}
 		indexer.run(nil, 128, make(chan struct{}), make(chan struct{}))
@@ -241,3 +253,74 @@ func TestTxIndexer(t *testing.T) {
 		os.RemoveAll(frdir)
 	}
 }
+
+// TestAddressIndexReorg tests that the address index follows the canonical
+// chain across reorgs.
+func TestAddressIndexReorg(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		addrA   = common.HexToAddress("0xaaaa")
+		addrB   = common.HexToAddress("0xbbbb")
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   types.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000000)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		engine = ethash.NewFaker()
+		signer = types.LatestSigner(gspec.Config)
+	)
+	generate := func(n int, to common.Address) []*types.Block {
+		_, blocks, _ := GenerateChainWithGenesis(gspec, engine, n, func(i int, gen *BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(address), to, big.NewInt(1000), params.TxGas, gen.header.BaseFee, nil), signer, key)
+			gen.AddTx(tx)
+		})
+		return blocks
+	}
+	blocksA, blocksB := generate(3, addrA), generate(4, addrB)
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil, EnableAddressIndex)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// check verifies the indexed transactions of an address against the
+	// transactions of the given blocks.
+	check := func(addr common.Address, blocks []*types.Block) {
+		t.Helper()
+		entries, err := chain.GetAddressTransactions(addr, 0, 0, 100, 100)
+		if err != nil {
+			t.Fatalf("failed to read address index: %v", err)
+		}
+		if len(entries) != len(blocks) {
+			t.Fatalf("%x: entry count mismatch: have %d, want %d", addr, len(entries), len(blocks))
+		}
+		for i, block := range blocks {
+			if entries[i].BlockNumber != block.NumberU64() || entries[i].Hash != block.Transactions()[0].Hash() {
+				t.Fatalf("%x: entry %d mismatch: have %v", addr, i, entries[i])
+			}
+		}
+		if raw := rawdb.ReadAddressTxEntries(chain.db, addr, 0, 0, 100, 0); len(raw) != len(blocks) {
+			t.Fatalf("%x: stale entries left: have %d, want %d", addr, len(raw), len(blocks))
+		}
+	}
+	if _, err := chain.InsertChain(blocksA); err != nil {
+		t.Fatalf("failed to insert chain A: %v", err)
+	}
+	check(address, blocksA)
+	check(addrA, blocksA)
+
+	if _, err := chain.InsertChain(blocksB); err != nil {
+		t.Fatalf("failed to insert chain B: %v", err)
+	}
+	check(address, blocksB)
+	check(addrA, nil)
+	check(addrB, blocksB)
+
+	// Paging continues after the last returned entry
+	entries, _ := chain.GetAddressTransactions(address, 2, 0, 100, 1)
+	if len(entries) != 1 || entries[0].BlockNumber != 2 {
+		t.Fatalf("paged entries mismatch: %v", entries)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/core/monitor"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var errMaliciousVoteMonitorDisabled = errors.New("malicious vote monitor is not enabled")
+
+// MonitorAPI exposes the evidence found by the misbehaviour monitors.
+type MonitorAPI struct {
+	eth *Ethereum
+}
+
+// NewMonitorAPI creates a new instance of MonitorAPI.
+func NewMonitorAPI(eth *Ethereum) *MonitorAPI {
+	return &MonitorAPI{eth: eth}
+}
+
+// GetDoubleSignEvidence returns the double sign evidence found by the double
+// sign monitor, oldest first.
+func (api *MonitorAPI) GetDoubleSignEvidence() ([]*monitor.DoubleSignEvidence, error) {
+	m := api.eth.blockchain.DoubleSignMonitor()
+	if m == nil {
+		return nil, errors.New("double sign monitor is not enabled")
+	}
+	return m.Evidence(), nil
+}
+
+// GetMaliciousVoteEvidence returns the conflicting votes found by the malicious
+// vote monitor, oldest first.
+func (api *MonitorAPI) GetMaliciousVoteEvidence() ([]*monitor.MaliciousVoteEvidence, error) {
+	m := api.eth.handler.maliciousVoteMonitor
+	if m == nil {
+		return nil, errMaliciousVoteMonitorDisabled
+	}
+	return m.Evidence(), nil
+}
+
+// MaliciousVoteEvidence sends a notification each time the malicious vote
+// monitor finds conflicting votes.
+func (api *MonitorAPI) MaliciousVoteEvidence(ctx context.Context) (*rpc.Subscription, error) {
+	m := api.eth.handler.maliciousVoteMonitor
+	if m == nil {
+		return &rpc.Subscription{}, errMaliciousVoteMonitorDisabled
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		evidenceCh := make(chan *monitor.MaliciousVoteEvidence, 16)
+		evidenceSub := m.SubscribeEvidence(evidenceCh)
+		defer evidenceSub.Unsubscribe()
+
+		for {
+			select {
+			case e := <-evidenceCh:
+				notifier.Notify(rpcSub.ID, e)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
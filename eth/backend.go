@@ -118,6 +118,10 @@ type Ethereum struct {
 	shutdownTracker *shutdowncheck.ShutdownTracker // Tracks if and when the node has shutdown ungracefully
 
 	votePool *vote.VotePool
+
+	doubleSignReporter    *evidenceReporter[*monitor.DoubleSignEvidence]    // Submits the double sign evidence, nil if disabled
+	maliciousVoteReporter *evidenceReporter[*monitor.MaliciousVoteEvidence] // Submits the malicious vote evidence, nil if disabled
+	validatorTracker      event.Subscription                                // Feeds the imported blocks to the Parlia validator tracker
 }
 
 // New creates a new Ethereum object (including the
@@ -300,14 +304,21 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if stack.Config().EnableDoubleSignMonitor {
 		bcOps = append(bcOps, core.EnableDoubleSignChecker)
 	}
+	if config.AddressIndex {
+		bcOps = append(bcOps, core.EnableAddressIndex)
+	}
 
 	peers := newPeerSet()
 	bcOps = append(bcOps, core.EnableBlockValidator(chainConfig, eth.engine, config.TriesVerifyMode, peers))
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, config.Genesis, &overrides, eth.engine, vmConfig, eth.shouldPreserve, &config.TransactionHistory, bcOps...)
-	eth.blockchain.SetEthApi(ethAPI)
 	if err != nil {
 		return nil, err
 	}
+	if m := eth.blockchain.DoubleSignMonitor(); m != nil {
+		if err := m.OpenJournal(stack.ResolvePath("doublesign.journal")); err != nil {
+			return nil, fmt.Errorf("failed to open double sign journal: %v", err)
+		}
+	}
 	eth.bloomIndexer.Start(eth.blockchain)
 
 	if config.BlobPool.Datadir != "" {
@@ -363,6 +374,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		eth.handler.votepool = votePool
 		if stack.Config().EnableMaliciousVoteMonitor {
 			eth.handler.maliciousVoteMonitor = monitor.NewMaliciousVoteMonitor()
+			if err := eth.handler.maliciousVoteMonitor.OpenJournal(stack.ResolvePath("maliciousvote.journal")); err != nil {
+				return nil, fmt.Errorf("failed to open malicious vote journal: %v", err)
+			}
 			log.Info("Create MaliciousVoteMonitor successfully")
 		}
 
@@ -379,6 +393,38 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		}
 	}
 
+	// Create the reporters of the evidence found by the monitors
+	if config.DoubleSignReporter != (common.Address{}) || config.MaliciousVoteReporter != (common.Address{}) {
+		var (
+			txAPI     = ethapi.NewTransactionAPI(eth.APIBackend, new(ethapi.AddrLocker))
+			engine, _ = eth.engine.(*parlia.Parlia)
+		)
+		if reporter := config.DoubleSignReporter; reporter != (common.Address{}) {
+			m := eth.blockchain.DoubleSignMonitor()
+			switch {
+			case m == nil:
+				log.Warn("Double sign reporter requires the double sign monitor, not reporting")
+			case engine == nil:
+				log.Warn("Double sign reporter requires the Parlia engine, not reporting")
+			default:
+				eth.doubleSignReporter = newDoubleSignReporter(m, engine, eth.APIBackend, txAPI, reporter, config.DoubleSignReportDryRun)
+				log.Info("Create double sign reporter successfully", "reporter", reporter, "dryrun", config.DoubleSignReportDryRun)
+			}
+		}
+		if reporter := config.MaliciousVoteReporter; reporter != (common.Address{}) {
+			m := eth.handler.maliciousVoteMonitor
+			switch {
+			case m == nil:
+				log.Warn("Malicious vote reporter requires the malicious vote monitor, not reporting")
+			case engine == nil:
+				log.Warn("Malicious vote reporter requires the Parlia engine, not reporting")
+			default:
+				eth.maliciousVoteReporter = newMaliciousVoteReporter(m, engine, eth.APIBackend, txAPI, reporter, config.MaliciousVoteReportDryRun)
+				log.Info("Create malicious vote reporter successfully", "reporter", reporter, "dryrun", config.MaliciousVoteReportDryRun)
+			}
+		}
+	}
+
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.Miner.GasPrice
@@ -443,8 +489,8 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
-	// Append all the local APIs and return
-	return append(apis, []rpc.API{
+	// Append all the local APIs
+	apis = append(apis, []rpc.API{
 		{
 			Namespace: "eth",
 			Service:   NewEthereumAPI(s),
@@ -468,6 +514,13 @@ func (s *Ethereum) APIs() []rpc.API {
 			Service:   s.netRPCService,
 		},
 	}...)
+	if s.blockchain.DoubleSignMonitor() != nil || s.handler.maliciousVoteMonitor != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "monitor",
+			Service:   NewMonitorAPI(s),
+		})
+	}
+	return apis
 }
 
 func (s *Ethereum) ResetWithGenesisBlock(gb *types.Block) {
@@ -680,6 +733,16 @@ func (s *Ethereum) Start() error {
 	}
 	// Start the networking layer and the light server if requested
 	s.handler.Start(maxPeers, s.p2pServer.MaxPeersPerIP)
+
+	if s.doubleSignReporter != nil {
+		s.doubleSignReporter.start()
+	}
+	if s.maliciousVoteReporter != nil {
+		s.maliciousVoteReporter.start()
+	}
+	if parlia, ok := s.engine.(*parlia.Parlia); ok {
+		s.validatorTracker = parlia.TrackValidators(s.blockchain)
+	}
 	return nil
 }
 
@@ -692,6 +755,18 @@ func (s *Ethereum) Stop() error {
 	s.trustDialCandidates.Close()
 	s.bscDialCandidates.Close()
 	s.handler.Stop()
+	if s.doubleSignReporter != nil {
+		s.doubleSignReporter.stop()
+	}
+	if s.maliciousVoteReporter != nil {
+		s.maliciousVoteReporter.stop()
+	}
+	if s.validatorTracker != nil {
+		s.validatorTracker.Unsubscribe()
+	}
+	if s.handler.maliciousVoteMonitor != nil {
+		s.handler.maliciousVoteMonitor.Close()
+	}
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
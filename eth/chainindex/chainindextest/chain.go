@@ -0,0 +1,78 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package chainindextest provides the test chain shared by the tests of the
+// indexes built on chainindex.
+package chainindextest
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Chain serves the blocks of a chain to an indexer. Blocks may be replaced
+// between syncs to simulate reorgs.
+type Chain struct {
+	Blocks []*types.Block
+	feed   event.Feed
+}
+
+// NewChain creates a chain of n blocks, see NewBlocks.
+func NewChain(n int, salt byte) *Chain {
+	return &Chain{Blocks: NewBlocks(n, salt)}
+}
+
+// NewBlocks creates n empty blocks starting at genesis. The salt is stored in
+// the extra data of the headers, telling apart the blocks of different chains.
+func NewBlocks(n int, salt byte) []*types.Block {
+	blocks := make([]*types.Block, n)
+	for i := range blocks {
+		blocks[i] = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(int64(i)), Extra: []byte{salt}})
+	}
+	return blocks
+}
+
+// HeaderByNumber returns the header of a block, or nil if it is not in the chain.
+func (c *Chain) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if int(number) >= len(c.Blocks) {
+		return nil, nil
+	}
+	return c.Blocks[number].Header(), nil
+}
+
+// BlockByNumber returns a block, or nil if it is not in the chain.
+func (c *Chain) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if int(number) >= len(c.Blocks) {
+		return nil, nil
+	}
+	return c.Blocks[number], nil
+}
+
+// CurrentHeader returns the header of the last block.
+func (c *Chain) CurrentHeader() *types.Header {
+	return c.Blocks[len(c.Blocks)-1].Header()
+}
+
+// SubscribeChainHeadEvent subscribes to head events. None are sent unless the
+// test does so itself.
+func (c *Chain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package chainindex implements the head following loop shared by the indexes
+// maintained alongside the canonical chain.
+package chainindex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Backend is the chain access required by the runner.
+type Backend interface {
+	HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error)
+	CurrentHeader() *types.Header
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Store is the persisted range of indexed blocks of an index.
+type Store interface {
+	// Range returns the range of indexed blocks. The last return value is
+	// false if nothing is indexed.
+	Range() (uint64, uint64, bool)
+
+	// BlockHash returns the hash of an indexed block.
+	BlockHash(number uint64) (common.Hash, error)
+
+	// Truncate removes the indexed blocks above the given block number.
+	Truncate(number uint64) error
+}
+
+// Hooks are the index specific parts of a runner.
+type Hooks struct {
+	// First returns the first block to index up to the given head, once the
+	// blocks that are no longer canonical have been removed.
+	First func(head uint64) (uint64, error)
+
+	// Index indexes a single canonical block.
+	Index func(ctx context.Context, number uint64) error
+}
+
+// Runner keeps an index in sync with the canonical chain. It follows the head
+// as new blocks are imported, indexing the blocks up to it and removing the
+// indexed blocks of reorged chains.
+type Runner struct {
+	name    string // Name of the index in the logs
+	backend Backend
+	store   Store
+	hooks   Hooks
+
+	lock   sync.Mutex // Serializes the updates of the index
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRunner creates a runner maintaining the given store.
+func NewRunner(name string, backend Backend, store Store, hooks Hooks) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		name:    name,
+		backend: backend,
+		store:   store,
+		hooks:   hooks,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start implements node.Lifecycle, starting the indexing of new blocks.
+func (r *Runner) Start() error {
+	r.wg.Add(1)
+	go r.loop()
+	return nil
+}
+
+// Stop implements node.Lifecycle, interrupting the indexing.
+func (r *Runner) Stop() error {
+	r.cancel()
+	r.wg.Wait()
+	return nil
+}
+
+// Exclusive runs the given function while no other update of the index is in
+// progress.
+func (r *Runner) Exclusive(fn func() error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return fn()
+}
+
+func (r *Runner) loop() {
+	defer r.wg.Done()
+
+	headCh := make(chan core.ChainHeadEvent, 16)
+	sub := r.backend.SubscribeChainHeadEvent(headCh)
+	defer sub.Unsubscribe()
+
+	if head := r.backend.CurrentHeader(); head != nil {
+		r.sync(head)
+	}
+	for {
+		select {
+		case ev := <-headCh:
+			// Only the most recent head matters, skip the ones queued meanwhile
+			for len(headCh) > 0 {
+				ev = <-headCh
+			}
+			r.sync(ev.Block.Header())
+		case <-sub.Err():
+			return
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// sync updates the index to the given head, logging failures.
+func (r *Runner) sync(head *types.Header) {
+	if err := r.Sync(r.ctx, head); err != nil && r.ctx.Err() == nil {
+		log.Warn("Failed to update index", "index", r.name, "head", head.Number, "err", err)
+	}
+}
+
+// Sync updates the index to the given head. Indexed blocks that are no longer
+// canonical are removed and the blocks up to the head indexed.
+func (r *Runner) Sync(ctx context.Context, head *types.Header) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	number := head.Number.Uint64()
+	if err := r.rewind(ctx, number); err != nil {
+		return err
+	}
+	from, err := r.hooks.First(number)
+	if err != nil {
+		return err
+	}
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for n := from; n <= number; n++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.hooks.Index(ctx, n); err != nil {
+			return err
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing blocks", "index", r.name, "number", n, "head", number, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return nil
+}
+
+// rewind removes the indexed blocks that are above the head or no longer
+// canonical.
+func (r *Runner) rewind(ctx context.Context, head uint64) error {
+	if err := r.store.Truncate(head); err != nil {
+		return err
+	}
+	tail, indexed, ok := r.store.Range()
+	if !ok {
+		return nil
+	}
+	for n := indexed; ; n-- {
+		hash, err := r.store.BlockHash(n)
+		if err != nil {
+			return err
+		}
+		header, err := r.backend.HeaderByNumber(ctx, rpc.BlockNumber(n))
+		if err != nil {
+			return err
+		}
+		if header != nil && header.Hash() == hash {
+			if n < indexed {
+				log.Info("Rewound index after reorg", "index", r.name, "number", n)
+			}
+			return r.store.Truncate(n)
+		}
+		if n == tail {
+			// Nothing indexed is canonical anymore, start over
+			if tail == 0 {
+				return errors.New("index of a different genesis")
+			}
+			log.Warn("Index diverged from the canonical chain, reindexing", "index", r.name, "tail", tail)
+			return r.store.Truncate(tail - 1)
+		}
+	}
+}
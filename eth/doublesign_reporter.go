@@ -0,0 +1,193 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
+	"github.com/ethereum/go-ethereum/core/monitor"
+	"github.com/ethereum/go-ethereum/core/systemcontracts"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// evidenceReportTimeout caps the time spent estimating and sending a single
+// evidence transaction.
+const evidenceReportTimeout = 30 * time.Second
+
+// evidenceMonitor is a monitor journaling the evidence of a single kind.
+type evidenceMonitor[E any] interface {
+	Evidence() []E
+	SubscribeEvidence(ch chan<- E) event.Subscription
+}
+
+// evidenceKind describes how the evidence of a monitor is submitted.
+type evidenceKind[E any] struct {
+	name      string                     // Name of the evidence in the logs
+	context   func(E) []interface{}      // Log context identifying the evidence
+	submitted func(E) bool               // Whether the evidence was already submitted
+	pack      func(E) ([]byte, error)    // Packs the slash contract call of the evidence
+	mark      func(E, common.Hash) error // Journals the submission of the evidence
+}
+
+// evidenceReporter submits the evidence found by a monitor to the slash
+// contract from the configured reporter account. The account has to be
+// unlocked, and should not be used to send other transactions as the nonces
+// are only serialized among the reports.
+type evidenceReporter[E any] struct {
+	monitor  evidenceMonitor[E]
+	kind     evidenceKind[E]
+	backend  ethapi.Backend
+	txAPI    *ethapi.TransactionAPI
+	reporter common.Address
+	dryRun   bool
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newDoubleSignReporter creates a reporter of the evidence found by the double
+// sign monitor.
+func newDoubleSignReporter(m *monitor.DoubleSignMonitor, engine *parlia.Parlia, backend ethapi.Backend, txAPI *ethapi.TransactionAPI, reporter common.Address, dryRun bool) *evidenceReporter[*monitor.DoubleSignEvidence] {
+	return &evidenceReporter[*monitor.DoubleSignEvidence]{
+		monitor: m,
+		kind: evidenceKind[*monitor.DoubleSignEvidence]{
+			name: "double sign",
+			context: func(e *monitor.DoubleSignEvidence) []interface{} {
+				return []interface{}{"number", uint64(e.Number), "validator", e.Coinbase}
+			},
+			submitted: func(e *monitor.DoubleSignEvidence) bool { return e.Submission != nil },
+			pack: func(e *monitor.DoubleSignEvidence) ([]byte, error) {
+				return engine.DoubleSignEvidenceData(e.Header1, e.Header2)
+			},
+			mark: func(e *monitor.DoubleSignEvidence, tx common.Hash) error {
+				return m.MarkSubmitted(uint64(e.Number), e.Coinbase, tx)
+			},
+		},
+		backend:  backend,
+		txAPI:    txAPI,
+		reporter: reporter,
+		dryRun:   dryRun,
+		quit:     make(chan struct{}),
+	}
+}
+
+// newMaliciousVoteReporter creates a reporter of the evidence found by the
+// malicious vote monitor.
+func newMaliciousVoteReporter(m *monitor.MaliciousVoteMonitor, engine *parlia.Parlia, backend ethapi.Backend, txAPI *ethapi.TransactionAPI, reporter common.Address, dryRun bool) *evidenceReporter[*monitor.MaliciousVoteEvidence] {
+	return &evidenceReporter[*monitor.MaliciousVoteEvidence]{
+		monitor: m,
+		kind: evidenceKind[*monitor.MaliciousVoteEvidence]{
+			name: "malicious vote",
+			context: func(e *monitor.MaliciousVoteEvidence) []interface{} {
+				return []interface{}{"id", e.ID, "target", e.VoteB.Data.TargetNumber}
+			},
+			submitted: func(e *monitor.MaliciousVoteEvidence) bool { return e.Submission != nil },
+			pack: func(e *monitor.MaliciousVoteEvidence) ([]byte, error) {
+				return engine.FinalityViolationEvidenceData(e.VoteA, e.VoteB)
+			},
+			mark: func(e *monitor.MaliciousVoteEvidence, tx common.Hash) error {
+				return m.MarkSubmitted(e.ID, tx)
+			},
+		},
+		backend:  backend,
+		txAPI:    txAPI,
+		reporter: reporter,
+		dryRun:   dryRun,
+		quit:     make(chan struct{}),
+	}
+}
+
+func (r *evidenceReporter[E]) start() {
+	r.wg.Add(1)
+	go r.loop()
+}
+
+func (r *evidenceReporter[E]) stop() {
+	close(r.quit)
+	r.wg.Wait()
+}
+
+func (r *evidenceReporter[E]) loop() {
+	defer r.wg.Done()
+
+	evidenceCh := make(chan E, 16)
+	sub := r.monitor.SubscribeEvidence(evidenceCh)
+	defer sub.Unsubscribe()
+
+	// Retry the evidence journaled by a previous run but never submitted
+	for _, e := range r.monitor.Evidence() {
+		if !r.kind.submitted(e) {
+			r.report(e)
+		}
+	}
+	for {
+		select {
+		case e := <-evidenceCh:
+			r.report(e)
+		case <-sub.Err():
+			return
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// report submits a single evidence, or only estimates its transaction in dry
+// run mode. Failures are logged, the slash contract rejects evidence that is
+// too old or was already submitted by someone else.
+func (r *evidenceReporter[E]) report(e E) {
+	ctx, cancel := context.WithTimeout(context.Background(), evidenceReportTimeout)
+	defer cancel()
+
+	logger := log.New(append([]interface{}{"kind", r.kind.name, "reporter", r.reporter}, r.kind.context(e)...)...)
+	data, err := r.kind.pack(e)
+	if err != nil {
+		logger.Error("Failed to pack evidence", "err", err)
+		return
+	}
+	var (
+		to    = common.HexToAddress(systemcontracts.SlashContract)
+		input = hexutil.Bytes(data)
+		args  = ethapi.TransactionArgs{From: &r.reporter, To: &to, Input: &input}
+	)
+	if r.dryRun {
+		gas, err := ethapi.DoEstimateGas(ctx, r.backend, args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil, r.backend.RPCGasCap())
+		if err != nil {
+			logger.Warn("Evidence would be rejected", "err", err)
+			return
+		}
+		logger.Info("Evidence not submitted in dry run mode", "gas", uint64(gas))
+		return
+	}
+	hash, err := r.txAPI.SendTransaction(ctx, args)
+	if err != nil {
+		logger.Warn("Failed to submit evidence", "err", err)
+		return
+	}
+	logger.Info("Submitted evidence", "tx", hash)
+	if err := r.kind.mark(e, hash); err != nil {
+		logger.Error("Failed to journal evidence submission", "err", err)
+	}
+}
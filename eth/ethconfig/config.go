@@ -32,6 +32,8 @@ import (
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/tokenindex"
+	"github.com/ethereum/go-ethereum/eth/tracers/traceindex"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/miner"
@@ -67,6 +69,8 @@ var Defaults = Config{
 	Miner:              miner.DefaultConfig,
 	TxPool:             legacypool.DefaultConfig,
 	BlobPool:           blobpool.DefaultConfig,
+	TraceIndex:         traceindex.DefaultConfig,
+	TokenIndex:         tokenindex.DefaultConfig,
 	RPCGasCap:          50000000,
 	RPCEVMTimeout:      5 * time.Second,
 	GPO:                FullNodeGPO,
@@ -175,6 +179,17 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Index options
+	AddressIndex bool              `toml:",omitempty"` // Whether to index the transactions by sender and recipient along with the tx indices.
+	TraceIndex   traceindex.Config // Index of the call traces and internal value transfers of imported blocks
+	TokenIndex   tokenindex.Config // Index of the token transfers and balances of imported blocks
+
+	// Evidence reporting options
+	DoubleSignReporter        common.Address `toml:",omitempty"` // Account submitting the evidence found by the double sign monitor, none if zero
+	DoubleSignReportDryRun    bool           `toml:",omitempty"` // Whether to only estimate the double sign evidence transactions instead of sending them
+	MaliciousVoteReporter     common.Address `toml:",omitempty"` // Account submitting the evidence found by the malicious vote monitor, none if zero
+	MaliciousVoteReportDryRun bool           `toml:",omitempty"` // Whether to only estimate the malicious vote evidence transactions instead of sending them
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
@@ -11,69 +11,78 @@ import (
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/tokenindex"
+	"github.com/ethereum/go-ethereum/eth/tracers/traceindex"
 	"github.com/ethereum/go-ethereum/miner"
 )
 
 // MarshalTOML marshals as TOML.
 func (c Config) MarshalTOML() (interface{}, error) {
 	type Config struct {
-		Genesis                 *core.Genesis `toml:",omitempty"`
-		NetworkId               uint64
-		SyncMode                downloader.SyncMode
-		DisablePeerTxBroadcast  bool
-		EthDiscoveryURLs        []string
-		SnapDiscoveryURLs       []string
-		TrustDiscoveryURLs      []string
-		BscDiscoveryURLs        []string
-		NoPruning               bool
-		NoPrefetch              bool
-		DirectBroadcast         bool
-		DisableSnapProtocol     bool
-		EnableTrustProtocol     bool
-		PipeCommit              bool
-		RangeLimit              bool
-		TxLookupLimit           uint64 `toml:",omitempty"`
-		TransactionHistory      uint64 `toml:",omitempty"`
-		StateHistory            uint64 `toml:",omitempty"`
-		StateScheme             string `toml:",omitempty"`
-		PathSyncFlush           bool   `toml:",omitempty"`
-		JournalFileEnabled      bool
-		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
-		LightServ               int                    `toml:",omitempty"`
-		LightIngress            int                    `toml:",omitempty"`
-		LightEgress             int                    `toml:",omitempty"`
-		LightPeers              int                    `toml:",omitempty"`
-		LightNoPrune            bool                   `toml:",omitempty"`
-		LightNoSyncServe        bool                   `toml:",omitempty"`
-		SkipBcVersionCheck      bool                   `toml:"-"`
-		DatabaseHandles         int                    `toml:"-"`
-		DatabaseCache           int
-		DatabaseFreezer         string
-		DatabaseDiff            string
-		PersistDiff             bool
-		DiffBlock               uint64
-		PruneAncientData        bool
-		TrieCleanCache          int
-		TrieDirtyCache          int
-		TrieTimeout             time.Duration
-		SnapshotCache           int
-		TriesInMemory           uint64
-		TriesVerifyMode         core.VerifyMode
-		Preimages               bool
-		FilterLogCacheSize      int
-		Miner                   miner.Config
-		TxPool                  legacypool.Config
-		BlobPool                blobpool.Config
-		GPO                     gasprice.Config
-		EnablePreimageRecording bool
-		DocRoot                 string `toml:"-"`
-		RPCGasCap               uint64
-		RPCEVMTimeout           time.Duration
-		RPCTxFeeCap             float64
-		OverridePassedForkTime      *uint64 `toml:",omitempty"`
-		OverrideBohr            *uint64 `toml:",omitempty"`
-		OverrideVerkle          *uint64 `toml:",omitempty"`
-		BlobExtraReserve        uint64
+		Genesis                   *core.Genesis `toml:",omitempty"`
+		NetworkId                 uint64
+		SyncMode                  downloader.SyncMode
+		DisablePeerTxBroadcast    bool
+		EthDiscoveryURLs          []string
+		SnapDiscoveryURLs         []string
+		TrustDiscoveryURLs        []string
+		BscDiscoveryURLs          []string
+		NoPruning                 bool
+		NoPrefetch                bool
+		DirectBroadcast           bool
+		DisableSnapProtocol       bool
+		EnableTrustProtocol       bool
+		PipeCommit                bool
+		RangeLimit                bool
+		TxLookupLimit             uint64 `toml:",omitempty"`
+		TransactionHistory        uint64 `toml:",omitempty"`
+		StateHistory              uint64 `toml:",omitempty"`
+		StateScheme               string `toml:",omitempty"`
+		PathSyncFlush             bool   `toml:",omitempty"`
+		JournalFileEnabled        bool
+		RequiredBlocks            map[uint64]common.Hash `toml:"-"`
+		LightServ                 int                    `toml:",omitempty"`
+		LightIngress              int                    `toml:",omitempty"`
+		LightEgress               int                    `toml:",omitempty"`
+		LightPeers                int                    `toml:",omitempty"`
+		LightNoPrune              bool                   `toml:",omitempty"`
+		LightNoSyncServe          bool                   `toml:",omitempty"`
+		SkipBcVersionCheck        bool                   `toml:"-"`
+		DatabaseHandles           int                    `toml:"-"`
+		DatabaseCache             int
+		DatabaseFreezer           string
+		DatabaseDiff              string
+		PersistDiff               bool
+		DiffBlock                 uint64
+		PruneAncientData          bool
+		TrieCleanCache            int
+		TrieDirtyCache            int
+		TrieTimeout               time.Duration
+		SnapshotCache             int
+		TriesInMemory             uint64
+		TriesVerifyMode           core.VerifyMode
+		Preimages                 bool
+		FilterLogCacheSize        int
+		Miner                     miner.Config
+		TxPool                    legacypool.Config
+		BlobPool                  blobpool.Config
+		GPO                       gasprice.Config
+		EnablePreimageRecording   bool
+		AddressIndex              bool `toml:",omitempty"`
+		TraceIndex                traceindex.Config
+		TokenIndex                tokenindex.Config
+		DoubleSignReporter        common.Address `toml:",omitempty"`
+		DoubleSignReportDryRun    bool           `toml:",omitempty"`
+		MaliciousVoteReporter     common.Address `toml:",omitempty"`
+		MaliciousVoteReportDryRun bool           `toml:",omitempty"`
+		DocRoot                   string         `toml:"-"`
+		RPCGasCap                 uint64
+		RPCEVMTimeout             time.Duration
+		RPCTxFeeCap               float64
+		OverridePassedForkTime    *uint64 `toml:",omitempty"`
+		OverrideBohr              *uint64 `toml:",omitempty"`
+		OverrideVerkle            *uint64 `toml:",omitempty"`
+		BlobExtraReserve          uint64
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -125,6 +134,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.BlobPool = c.BlobPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.AddressIndex = c.AddressIndex
+	enc.TraceIndex = c.TraceIndex
+	enc.TokenIndex = c.TokenIndex
+	enc.DoubleSignReporter = c.DoubleSignReporter
+	enc.DoubleSignReportDryRun = c.DoubleSignReportDryRun
+	enc.MaliciousVoteReporter = c.MaliciousVoteReporter
+	enc.MaliciousVoteReportDryRun = c.MaliciousVoteReportDryRun
 	enc.DocRoot = c.DocRoot
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCEVMTimeout = c.RPCEVMTimeout
@@ -139,63 +155,70 @@ func (c Config) MarshalTOML() (interface{}, error) {
 // UnmarshalTOML unmarshals from TOML.
 func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	type Config struct {
-		Genesis                 *core.Genesis `toml:",omitempty"`
-		NetworkId               *uint64
-		SyncMode                *downloader.SyncMode
-		DisablePeerTxBroadcast  *bool
-		EthDiscoveryURLs        []string
-		SnapDiscoveryURLs       []string
-		TrustDiscoveryURLs      []string
-		BscDiscoveryURLs        []string
-		NoPruning               *bool
-		NoPrefetch              *bool
-		DirectBroadcast         *bool
-		DisableSnapProtocol     *bool
-		EnableTrustProtocol     *bool
-		PipeCommit              *bool
-		RangeLimit              *bool
-		TxLookupLimit           *uint64 `toml:",omitempty"`
-		TransactionHistory      *uint64 `toml:",omitempty"`
-		StateHistory            *uint64 `toml:",omitempty"`
-		StateScheme             *string `toml:",omitempty"`
-		PathSyncFlush           *bool   `toml:",omitempty"`
-		JournalFileEnabled      *bool
-		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
-		LightServ               *int                   `toml:",omitempty"`
-		LightIngress            *int                   `toml:",omitempty"`
-		LightEgress             *int                   `toml:",omitempty"`
-		LightPeers              *int                   `toml:",omitempty"`
-		LightNoPrune            *bool                  `toml:",omitempty"`
-		LightNoSyncServe        *bool                  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool                  `toml:"-"`
-		DatabaseHandles         *int                   `toml:"-"`
-		DatabaseCache           *int
-		DatabaseFreezer         *string
-		DatabaseDiff            *string
-		PersistDiff             *bool
-		DiffBlock               *uint64
-		PruneAncientData        *bool
-		TrieCleanCache          *int
-		TrieDirtyCache          *int
-		TrieTimeout             *time.Duration
-		SnapshotCache           *int
-		TriesInMemory           *uint64
-		TriesVerifyMode         *core.VerifyMode
-		Preimages               *bool
-		FilterLogCacheSize      *int
-		Miner                   *miner.Config
-		TxPool                  *legacypool.Config
-		BlobPool                *blobpool.Config
-		GPO                     *gasprice.Config
-		EnablePreimageRecording *bool
-		DocRoot                 *string `toml:"-"`
-		RPCGasCap               *uint64
-		RPCEVMTimeout           *time.Duration
-		RPCTxFeeCap             *float64
-		OverridePassedForkTime      *uint64 `toml:",omitempty"`
-		OverrideBohr            *uint64 `toml:",omitempty"`
-		OverrideVerkle          *uint64 `toml:",omitempty"`
-		BlobExtraReserve        *uint64
+		Genesis                   *core.Genesis `toml:",omitempty"`
+		NetworkId                 *uint64
+		SyncMode                  *downloader.SyncMode
+		DisablePeerTxBroadcast    *bool
+		EthDiscoveryURLs          []string
+		SnapDiscoveryURLs         []string
+		TrustDiscoveryURLs        []string
+		BscDiscoveryURLs          []string
+		NoPruning                 *bool
+		NoPrefetch                *bool
+		DirectBroadcast           *bool
+		DisableSnapProtocol       *bool
+		EnableTrustProtocol       *bool
+		PipeCommit                *bool
+		RangeLimit                *bool
+		TxLookupLimit             *uint64 `toml:",omitempty"`
+		TransactionHistory        *uint64 `toml:",omitempty"`
+		StateHistory              *uint64 `toml:",omitempty"`
+		StateScheme               *string `toml:",omitempty"`
+		PathSyncFlush             *bool   `toml:",omitempty"`
+		JournalFileEnabled        *bool
+		RequiredBlocks            map[uint64]common.Hash `toml:"-"`
+		LightServ                 *int                   `toml:",omitempty"`
+		LightIngress              *int                   `toml:",omitempty"`
+		LightEgress               *int                   `toml:",omitempty"`
+		LightPeers                *int                   `toml:",omitempty"`
+		LightNoPrune              *bool                  `toml:",omitempty"`
+		LightNoSyncServe          *bool                  `toml:",omitempty"`
+		SkipBcVersionCheck        *bool                  `toml:"-"`
+		DatabaseHandles           *int                   `toml:"-"`
+		DatabaseCache             *int
+		DatabaseFreezer           *string
+		DatabaseDiff              *string
+		PersistDiff               *bool
+		DiffBlock                 *uint64
+		PruneAncientData          *bool
+		TrieCleanCache            *int
+		TrieDirtyCache            *int
+		TrieTimeout               *time.Duration
+		SnapshotCache             *int
+		TriesInMemory             *uint64
+		TriesVerifyMode           *core.VerifyMode
+		Preimages                 *bool
+		FilterLogCacheSize        *int
+		Miner                     *miner.Config
+		TxPool                    *legacypool.Config
+		BlobPool                  *blobpool.Config
+		GPO                       *gasprice.Config
+		EnablePreimageRecording   *bool
+		AddressIndex              *bool `toml:",omitempty"`
+		TraceIndex                *traceindex.Config
+		TokenIndex                *tokenindex.Config
+		DoubleSignReporter        *common.Address `toml:",omitempty"`
+		DoubleSignReportDryRun    *bool           `toml:",omitempty"`
+		MaliciousVoteReporter     *common.Address `toml:",omitempty"`
+		MaliciousVoteReportDryRun *bool           `toml:",omitempty"`
+		DocRoot                   *string         `toml:"-"`
+		RPCGasCap                 *uint64
+		RPCEVMTimeout             *time.Duration
+		RPCTxFeeCap               *float64
+		OverridePassedForkTime    *uint64 `toml:",omitempty"`
+		OverrideBohr              *uint64 `toml:",omitempty"`
+		OverrideVerkle            *uint64 `toml:",omitempty"`
+		BlobExtraReserve          *uint64
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -348,6 +371,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.AddressIndex != nil {
+		c.AddressIndex = *dec.AddressIndex
+	}
+	if dec.TraceIndex != nil {
+		c.TraceIndex = *dec.TraceIndex
+	}
+	if dec.TokenIndex != nil {
+		c.TokenIndex = *dec.TokenIndex
+	}
+	if dec.DoubleSignReporter != nil {
+		c.DoubleSignReporter = *dec.DoubleSignReporter
+	}
+	if dec.DoubleSignReportDryRun != nil {
+		c.DoubleSignReportDryRun = *dec.DoubleSignReportDryRun
+	}
+	if dec.MaliciousVoteReporter != nil {
+		c.MaliciousVoteReporter = *dec.MaliciousVoteReporter
+	}
+	if dec.MaliciousVoteReportDryRun != nil {
+		c.MaliciousVoteReportDryRun = *dec.MaliciousVoteReportDryRun
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -0,0 +1,171 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tokenindex
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// defaultTransferLimit is the number of transfers bsc_getTokenTransfers
+	// returns if no count is given.
+	defaultTransferLimit = 1000
+
+	// maxTransferLimit caps the number of transfers of a single query.
+	maxTransferLimit = 10000
+)
+
+var errNotIndexed = errors.New("token index is empty")
+
+// API exposes the token index in the bsc namespace.
+type API struct {
+	store *Store
+}
+
+// NewAPI creates the query API of a token index.
+func NewAPI(store *Store) *API {
+	return &API{store: store}
+}
+
+// APIs returns the RPC services of the token index.
+func APIs(store *Store) []rpc.API {
+	return []rpc.API{{
+		Namespace: "bsc",
+		Service:   NewAPI(store),
+	}}
+}
+
+// TransferPage is a page of token transfers. Next is the cursor of the
+// following page, nil if there are no more transfers.
+type TransferPage struct {
+	Transfers []*Transfer `json:"transfers"`
+	Next      *Location   `json:"next"`
+}
+
+// TokenBalance is the balance of a token held by an address.
+type TokenBalance struct {
+	Token   common.Address `json:"token"`
+	Balance *hexutil.Big   `json:"balance"`
+}
+
+// TokenBalances are the token balances of an address at a block. Tail is the
+// first indexed block, the balances only sum up the transfers from there on.
+type TokenBalances struct {
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	Tail        hexutil.Uint64  `json:"tail"`
+	Balances    []*TokenBalance `json:"balances"`
+}
+
+// GetTokenTransfers returns the token transfers from or to an address within
+// the block range, oldest first, optionally only those of a single token. The
+// range defaults to the indexed blocks, pages are continued by passing the
+// returned cursor.
+func (api *API) GetTokenTransfers(address common.Address, token *common.Address, fromBlock, toBlock *rpc.BlockNumber, cursor *Location, count *hexutil.Uint64) (*TransferPage, error) {
+	from, to, err := api.resolveRange(fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	limit := defaultTransferLimit
+	if count != nil && *count > 0 {
+		limit = int(min(uint64(*count), maxTransferLimit))
+	}
+	// Fetch one more transfer to know whether there is a next page
+	transfers, err := api.store.Transfers(address, token, from, to, cursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &TransferPage{Transfers: transfers}
+	if len(transfers) > limit {
+		page.Transfers = transfers[:limit]
+		last := page.Transfers[limit-1]
+		page.Next = &Location{Block: last.BlockNumber, Log: last.LogIndex}
+	}
+	if page.Transfers == nil {
+		page.Transfers = []*Transfer{}
+	}
+	return page, nil
+}
+
+// GetTokenBalances returns the balances of the tokens an address has indexed
+// transfers of, after the given block. The block defaults to the indexed head.
+func (api *API) GetTokenBalances(address common.Address, blockNr *rpc.BlockNumber) (*TokenBalances, error) {
+	_, number, err := api.resolveRange(blockNr, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := api.store.Tokens(address)
+	if err != nil {
+		return nil, err
+	}
+	tail, _, _ := api.store.Range()
+	res := &TokenBalances{
+		BlockNumber: hexutil.Uint64(number),
+		Tail:        hexutil.Uint64(tail),
+		Balances:    []*TokenBalance{},
+	}
+	for _, token := range tokens {
+		balance, err := api.store.Balance(address, token, number)
+		if err != nil {
+			return nil, err
+		}
+		if balance == nil {
+			continue
+		}
+		res.Balances = append(res.Balances, &TokenBalance{Token: token, Balance: (*hexutil.Big)(balance)})
+	}
+	return res, nil
+}
+
+// resolveRange resolves a block range of a query, which must be covered by the
+// index.
+func (api *API) resolveRange(fromBlock, toBlock *rpc.BlockNumber) (uint64, uint64, error) {
+	tail, head, ok := api.store.Range()
+	if !ok {
+		return 0, 0, errNotIndexed
+	}
+	resolve := func(number *rpc.BlockNumber, def uint64) (uint64, error) {
+		switch {
+		case number == nil:
+			return def, nil
+		case *number == rpc.LatestBlockNumber:
+			return head, nil
+		case *number < 0:
+			return 0, fmt.Errorf("unsupported block number %v", *number)
+		}
+		return uint64(*number), nil
+	}
+	from, err := resolve(fromBlock, tail)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := resolve(toBlock, head)
+	if err != nil {
+		return 0, 0, err
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if from < tail || to > head {
+		return 0, 0, fmt.Errorf("block range %d-%d not covered by the token index %d-%d", from, to, tail, head)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tokenindex
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(rawdb.NewMemoryDatabase())
+	api := NewAPI(store)
+	if _, err := api.GetTokenBalances(alice, nil); err != errNotIndexed {
+		t.Fatalf("expected empty index error, got %v", err)
+	}
+	for n := uint64(1); n <= 3; n++ {
+		if err := store.Write(n, common.Hash{byte(n)}, Transfers(n, common.Hash{byte(n)}, testReceipts())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Balances default to the head
+	balances, err := api.GetTokenBalances(alice, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances.BlockNumber != 3 || balances.Tail != 1 || len(balances.Balances) != 2 {
+		t.Fatalf("wrong balances: %+v", balances)
+	}
+	for _, b := range balances.Balances {
+		if want := map[common.Address]int64{usdt: 210, busd: 21}[b.Token]; b.Balance.ToInt().Int64() != want {
+			t.Fatalf("wrong balance of %x: have %v, want %d", b.Token, b.Balance, want)
+		}
+	}
+	number := rpc.BlockNumber(1)
+	if balances, _ = api.GetTokenBalances(bob, &number); len(balances.Balances) != 2 {
+		t.Fatalf("wrong balances at block 1: %+v", balances)
+	}
+	number = 4
+	if _, err := api.GetTokenBalances(alice, &number); err == nil {
+		t.Fatal("expected error for blocks outside of the index")
+	}
+	// Transfers are paged by cursor
+	size := hexutil.Uint64(5)
+	page, err := api.GetTokenTransfers(alice, nil, nil, nil, nil, &size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Transfers) != 5 || page.Next == nil {
+		t.Fatalf("wrong first page: %+v", page)
+	}
+	if page, err = api.GetTokenTransfers(alice, nil, nil, nil, page.Next, &size); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Transfers) != 4 || page.Next != nil || page.Transfers[0].BlockNumber != 2 {
+		t.Fatalf("wrong last page: %+v", page)
+	}
+	if page, _ = api.GetTokenTransfers(bob, &busd, nil, nil, nil, nil); len(page.Transfers) != 3 {
+		t.Fatalf("wrong busd transfers: %+v", page)
+	}
+}
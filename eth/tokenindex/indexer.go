@@ -0,0 +1,112 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tokenindex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/chainindex"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Config are the settings of the token index.
+type Config struct {
+	Enabled bool   // Whether to index the token transfers of imported blocks
+	From    uint64 // Block to start indexing at (0 = head), balances only sum up the transfers from there on
+}
+
+// DefaultConfig contains the default settings of the token index, starting at
+// the head at the time it is first enabled.
+var DefaultConfig = Config{}
+
+// Backend is the chain access required by the indexer.
+type Backend interface {
+	HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error)
+	GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error)
+	CurrentHeader() *types.Header
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Indexer keeps the token index in sync with the canonical chain. It indexes
+// the blocks from the configured start block up to the head, following the
+// head as new blocks are imported and rolling back the blocks of reorged
+// chains.
+type Indexer struct {
+	*chainindex.Runner
+
+	backend Backend
+	store   *Store
+	from    uint64
+}
+
+// NewIndexer creates an indexer maintaining the given store. The index starts
+// at the given block, or at the head if zero.
+func NewIndexer(backend Backend, store *Store, from uint64) *Indexer {
+	ix := &Indexer{
+		backend: backend,
+		store:   store,
+		from:    from,
+	}
+	ix.Runner = chainindex.NewRunner("tokens", backend, store, chainindex.Hooks{
+		First: ix.first,
+		Index: ix.index,
+	})
+	return ix
+}
+
+// Store returns the store maintained by the indexer.
+func (ix *Indexer) Store() *Store {
+	return ix.store
+}
+
+// first returns the first block to index up to the given head.
+func (ix *Indexer) first(head uint64) (uint64, error) {
+	if _, indexed, ok := ix.store.Range(); ok {
+		return indexed + 1, nil
+	}
+	// Nodes without the receipts of old blocks can't index from the genesis,
+	// so the start block has to be asked for explicitly
+	if ix.from == 0 {
+		return head, nil
+	}
+	return ix.from, nil
+}
+
+// index indexes a single canonical block.
+func (ix *Indexer) index(ctx context.Context, number uint64) error {
+	header, err := ix.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		return fmt.Errorf("block #%d not found", number)
+	}
+	hash := header.Hash()
+	receipts, err := ix.backend.GetReceipts(ctx, hash)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve receipts of block #%d: %w", number, err)
+	}
+	if receipts == nil && header.ReceiptHash != types.EmptyReceiptsHash {
+		return fmt.Errorf("receipts of block #%d not found", number)
+	}
+	return ix.store.Write(number, hash, Transfers(number, hash, receipts))
+}
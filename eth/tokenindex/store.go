@@ -0,0 +1,458 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tokenindex maintains a persistent index of the ERC20/BEP20 token
+// transfers of the canonical chain, keeping the transfer history and running
+// balances of every holder.
+package tokenindex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Database layout:
+//
+//	blockPrefix + num (uint64 big endian) -> block JSON (hash and transfers)
+//	transferPrefix + holder + num (uint64 big endian) + log (uint32 big endian) -> Transfer JSON
+//	balancePrefix + holder + token + ^num (uint64 big endian) -> balance after the block
+//	tokenPrefix + holder + token -> nil
+//
+// The block numbers of balances are inverted, so that the first entry found
+// from a block on is the latest balance at that block. Both the sender and
+// the recipient of a transfer have an entry, except the zero address of
+// mints and burns.
+var (
+	blockPrefix    = []byte("b")
+	transferPrefix = []byte("t")
+	balancePrefix  = []byte("h")
+	tokenPrefix    = []byte("k")
+)
+
+// transferTopic is the topic of the ERC20 Transfer(address,address,uint256)
+// event.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// Location identifies a transfer in the index.
+type Location struct {
+	Block uint64 `json:"blockNumber"`
+	Log   uint32 `json:"logIndex"`
+}
+
+// Transfer is a token transfer decoded from a Transfer log.
+type Transfer struct {
+	BlockNumber uint64         `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     uint32         `json:"transactionIndex"`
+	LogIndex    uint32         `json:"logIndex"`
+	Token       common.Address `json:"token"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Value       *hexutil.Big   `json:"value"`
+}
+
+// indexedBlock is the stored form of an indexed block.
+type indexedBlock struct {
+	Hash      common.Hash `json:"hash"`
+	Transfers []*Transfer `json:"transfers"`
+}
+
+// holding is a token balance of a holder.
+type holding struct {
+	holder common.Address
+	token  common.Address
+}
+
+// Transfers decodes the token transfers of the logs of a block. Logs which
+// don't have the layout of an ERC20 Transfer, like the ERC721 event of the
+// same signature, are skipped.
+func Transfers(number uint64, hash common.Hash, receipts types.Receipts) []*Transfer {
+	transfers := []*Transfer{}
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if len(log.Topics) != 3 || log.Topics[0] != transferTopic || len(log.Data) != 32 {
+				continue
+			}
+			transfers = append(transfers, &Transfer{
+				BlockNumber: number,
+				BlockHash:   hash,
+				TxHash:      log.TxHash,
+				TxIndex:     uint32(log.TxIndex),
+				LogIndex:    uint32(log.Index),
+				Token:       log.Address,
+				From:        common.BytesToAddress(log.Topics[1].Bytes()),
+				To:          common.BytesToAddress(log.Topics[2].Bytes()),
+				Value:       (*hexutil.Big)(new(big.Int).SetBytes(log.Data)),
+			})
+		}
+	}
+	return transfers
+}
+
+// holders returns the holders whose history contains a transfer.
+func (t *Transfer) holders() []common.Address {
+	var holders []common.Address
+	if t.From != (common.Address{}) {
+		holders = append(holders, t.From)
+	}
+	if t.To != (common.Address{}) && t.To != t.From {
+		holders = append(holders, t.To)
+	}
+	return holders
+}
+
+// changes returns the balance changes made by the transfers of a block.
+func changes(transfers []*Transfer) map[holding]*big.Int {
+	deltas := make(map[holding]*big.Int)
+	add := func(h holding, v *big.Int) {
+		if h.holder == (common.Address{}) {
+			return
+		}
+		if deltas[h] == nil {
+			deltas[h] = new(big.Int)
+		}
+		deltas[h].Add(deltas[h], v)
+	}
+	for _, t := range transfers {
+		value := t.Value.ToInt()
+		add(holding{t.From, t.Token}, new(big.Int).Neg(value))
+		add(holding{t.To, t.Token}, value)
+	}
+	return deltas
+}
+
+func blockKey(number uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, blockPrefix...), number)
+}
+
+func transferKey(holder common.Address, loc Location) []byte {
+	key := make([]byte, 0, len(transferPrefix)+common.AddressLength+12)
+	key = append(key, transferPrefix...)
+	key = append(key, holder.Bytes()...)
+	key = binary.BigEndian.AppendUint64(key, loc.Block)
+	return binary.BigEndian.AppendUint32(key, loc.Log)
+}
+
+func balanceKey(h holding, number uint64) []byte {
+	key := make([]byte, 0, len(balancePrefix)+2*common.AddressLength+8)
+	key = append(key, balancePrefix...)
+	key = append(key, h.holder.Bytes()...)
+	key = append(key, h.token.Bytes()...)
+	return binary.BigEndian.AppendUint64(key, ^number)
+}
+
+func tokenKey(h holding) []byte {
+	key := make([]byte, 0, len(tokenPrefix)+2*common.AddressLength)
+	key = append(key, tokenPrefix...)
+	key = append(key, h.holder.Bytes()...)
+	return append(key, h.token.Bytes()...)
+}
+
+// Store is the key-value store of the token index. The indexed blocks form a
+// contiguous range of the canonical chain, the balances are the sums of the
+// transfers of this range.
+type Store struct {
+	db ethdb.KeyValueStore
+
+	lock       sync.RWMutex
+	tail, head uint64 // Range of indexed blocks, valid if indexed is set
+	indexed    bool
+}
+
+// NewStore creates a token index store on top of db.
+func NewStore(db ethdb.KeyValueStore) *Store {
+	s := &Store{db: db}
+
+	it := db.NewIterator(blockPrefix, nil)
+	for it.Next() {
+		if len(it.Key()) != len(blockPrefix)+8 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(it.Key()[len(blockPrefix):])
+		if !s.indexed {
+			s.tail, s.indexed = number, true
+		}
+		s.head = number
+	}
+	it.Release()
+	return s
+}
+
+// Range returns the range of indexed blocks. The last return value is false
+// if nothing is indexed.
+func (s *Store) Range() (uint64, uint64, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tail, s.head, s.indexed
+}
+
+// BlockHash returns the hash of an indexed block, or the zero hash if the
+// block is not indexed.
+func (s *Store) BlockHash(number uint64) (common.Hash, error) {
+	block, err := s.block(number)
+	if err != nil || block == nil {
+		return common.Hash{}, err
+	}
+	return block.Hash, nil
+}
+
+// block returns an indexed block, or nil if the block is not indexed.
+func (s *Store) block(number uint64) (*indexedBlock, error) {
+	if ok, err := s.db.Has(blockKey(number)); !ok {
+		return nil, err
+	}
+	blob, err := s.db.Get(blockKey(number))
+	if err != nil {
+		return nil, err
+	}
+	block := new(indexedBlock)
+	if err := json.Unmarshal(blob, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// Write indexes the transfers of a block. As balances accumulate, the block
+// must be the successor of the indexed head, or start a new range if nothing
+// is indexed.
+func (s *Store) Write(number uint64, hash common.Hash, transfers []*Transfer) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.indexed && number != s.head+1 {
+		return fmt.Errorf("block %d is not the successor of the indexed head %d", number, s.head)
+	}
+	if transfers == nil {
+		transfers = []*Transfer{}
+	}
+	blob, err := json.Marshal(&indexedBlock{Hash: hash, Transfers: transfers})
+	if err != nil {
+		return err
+	}
+	batch := s.db.NewBatch()
+	if err := batch.Put(blockKey(number), blob); err != nil {
+		return err
+	}
+	for _, transfer := range transfers {
+		blob, err := json.Marshal(transfer)
+		if err != nil {
+			return err
+		}
+		for _, holder := range transfer.holders() {
+			if err := batch.Put(transferKey(holder, Location{number, transfer.LogIndex}), blob); err != nil {
+				return err
+			}
+		}
+	}
+	for h, delta := range changes(transfers) {
+		balance, _, err := s.balance(h, number)
+		if err != nil {
+			return err
+		}
+		if balance == nil {
+			if err := batch.Put(tokenKey(h), nil); err != nil {
+				return err
+			}
+			balance = new(big.Int)
+		}
+		blob, err := balance.Add(balance, delta).MarshalText()
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(balanceKey(h, number), blob); err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if !s.indexed {
+		s.tail, s.indexed = number, true
+	}
+	s.head = number
+	return nil
+}
+
+// Truncate removes the indexed blocks above the given block number, rolling
+// back the balances. It's used to drop the blocks of a reorged chain.
+func (s *Store) Truncate(number uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.indexed || number >= s.head {
+		return nil
+	}
+	from := number + 1
+	if from < s.tail {
+		from = s.tail
+	}
+	for n := s.head; n >= from; n-- {
+		if err := s.delete(n); err != nil {
+			return err
+		}
+		s.head = n - 1
+		if n == s.tail {
+			s.indexed = false
+			break
+		}
+	}
+	return nil
+}
+
+// delete removes the entries of the indexed head block.
+func (s *Store) delete(number uint64) error {
+	block, err := s.block(number)
+	if err != nil {
+		return err
+	}
+	if block == nil {
+		return nil
+	}
+	batch := s.db.NewBatch()
+	for _, transfer := range block.Transfers {
+		for _, holder := range transfer.holders() {
+			if err := batch.Delete(transferKey(holder, Location{number, transfer.LogIndex})); err != nil {
+				return err
+			}
+		}
+	}
+	for h := range changes(block.Transfers) {
+		if err := batch.Delete(balanceKey(h, number)); err != nil {
+			return err
+		}
+		// Forget the token if the block made the first transfer of the holder
+		if number > 0 {
+			if balance, _, err := s.balance(h, number-1); err != nil {
+				return err
+			} else if balance != nil {
+				continue
+			}
+		}
+		if err := batch.Delete(tokenKey(h)); err != nil {
+			return err
+		}
+	}
+	if err := batch.Delete(blockKey(number)); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// balance returns the balance of a holding after the given block and the
+// block it was last changed by, or nil if the holder has no transfers of the
+// token up to the block.
+func (s *Store) balance(h holding, number uint64) (*big.Int, uint64, error) {
+	key := balanceKey(h, number)
+	prefix := key[:len(key)-8]
+
+	it := s.db.NewIterator(prefix, key[len(prefix):])
+	defer it.Release()
+
+	for it.Next() {
+		if len(it.Key()) != len(key) {
+			continue
+		}
+		balance := new(big.Int)
+		if err := balance.UnmarshalText(it.Value()); err != nil {
+			return nil, 0, err
+		}
+		return balance, ^binary.BigEndian.Uint64(it.Key()[len(prefix):]), nil
+	}
+	return nil, 0, it.Error()
+}
+
+// Balance returns the balance of a token held by an address after the given
+// block, or nil if the holder has no indexed transfers of the token up to the
+// block.
+func (s *Store) Balance(holder, token common.Address, number uint64) (*big.Int, error) {
+	balance, _, err := s.balance(holding{holder, token}, number)
+	return balance, err
+}
+
+// Tokens returns the tokens an address has indexed transfers of.
+func (s *Store) Tokens(holder common.Address) ([]common.Address, error) {
+	prefix := append(append([]byte{}, tokenPrefix...), holder.Bytes()...)
+
+	it := s.db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var tokens []common.Address
+	for it.Next() {
+		if len(it.Key()) != len(prefix)+common.AddressLength {
+			continue
+		}
+		tokens = append(tokens, common.BytesToAddress(it.Key()[len(prefix):]))
+	}
+	return tokens, it.Error()
+}
+
+// Transfers returns the transfers from or to an address within the inclusive
+// block range, in chain order. If token is not nil, only the transfers of that
+// token are returned. The iteration starts after the given location if not
+// nil, and returns at most limit transfers.
+func (s *Store) Transfers(holder common.Address, token *common.Address, from, to uint64, after *Location, limit int) ([]*Transfer, error) {
+	if from > to {
+		return nil, errors.New("invalid block range")
+	}
+	var (
+		prefix = append(append([]byte{}, transferPrefix...), holder.Bytes()...)
+		start  = binary.BigEndian.AppendUint64(nil, from)
+	)
+	if after != nil {
+		start = transferKey(holder, *after)[len(prefix):]
+	}
+	it := s.db.NewIterator(prefix, start)
+	defer it.Release()
+
+	var transfers []*Transfer
+	for it.Next() && (limit <= 0 || len(transfers) < limit) {
+		if len(it.Key()) != len(prefix)+12 {
+			continue
+		}
+		if after != nil && bytes.Equal(it.Key()[len(prefix):], start) {
+			continue
+		}
+		number := binary.BigEndian.Uint64(it.Key()[len(prefix):])
+		if number < from {
+			continue
+		}
+		if number > to {
+			break
+		}
+		transfer := new(Transfer)
+		if err := json.Unmarshal(it.Value(), transfer); err != nil {
+			return nil, err
+		}
+		if token != nil && transfer.Token != *token {
+			continue
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, it.Error()
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tokenindex
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/chainindex/chainindextest"
+)
+
+var (
+	alice = common.HexToAddress("0xa1")
+	bob   = common.HexToAddress("0xb0")
+	usdt  = common.HexToAddress("0x55")
+	busd  = common.HexToAddress("0xe9")
+)
+
+// transferLog returns a Transfer log of a token.
+func transferLog(token, from, to common.Address, value int64, index uint) *types.Log {
+	return &types.Log{
+		Address: token,
+		Topics:  []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:    common.BigToHash(big.NewInt(value)).Bytes(),
+		Index:   index,
+	}
+}
+
+// testReceipts returns the receipts of a block: a mint of usdt to alice, a
+// usdt transfer from alice to bob, a busd transfer to alice and an ERC721
+// transfer which is not indexed.
+func testReceipts() types.Receipts {
+	nft := transferLog(busd, bob, alice, 1, 3)
+	nft.Topics = append(nft.Topics, common.Hash{1})
+	nft.Data = nil
+	return types.Receipts{
+		{Logs: []*types.Log{transferLog(usdt, common.Address{}, alice, 100, 0), transferLog(usdt, alice, bob, 30, 1)}},
+		{Logs: []*types.Log{transferLog(busd, bob, alice, 7, 2), nft}},
+	}
+}
+
+func TestTransfers(t *testing.T) {
+	t.Parallel()
+
+	transfers := Transfers(1, common.Hash{1}, testReceipts())
+	if len(transfers) != 3 {
+		t.Fatalf("wrong number of transfers: %d", len(transfers))
+	}
+	if tr := transfers[1]; tr.Token != usdt || tr.From != alice || tr.To != bob || tr.Value.ToInt().Int64() != 30 || tr.LogIndex != 1 {
+		t.Fatalf("wrong transfer: %+v", tr)
+	}
+}
+
+func TestStoreBalances(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(rawdb.NewMemoryDatabase())
+	for n := uint64(1); n <= 3; n++ {
+		if err := store.Write(n, common.Hash{byte(n)}, Transfers(n, common.Hash{byte(n)}, testReceipts())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Write(5, common.Hash{5}, nil); err == nil {
+		t.Fatal("expected error writing non-successive block")
+	}
+	checkBalance := func(holder, token common.Address, number uint64, want int64) {
+		t.Helper()
+		balance, err := store.Balance(holder, token, number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if balance == nil || balance.Int64() != want {
+			t.Fatalf("wrong balance of %x at %d: have %v, want %d", holder, number, balance, want)
+		}
+	}
+	checkBalance(alice, usdt, 1, 70)
+	checkBalance(alice, usdt, 2, 140)
+	checkBalance(alice, usdt, 100, 210)
+	checkBalance(bob, busd, 3, -21)
+	if balance, _ := store.Balance(alice, usdt, 0); balance != nil {
+		t.Fatalf("unexpected balance before the first transfer: %v", balance)
+	}
+	// The zero address of mints is not indexed
+	if tokens, _ := store.Tokens(common.Address{}); len(tokens) != 0 {
+		t.Fatalf("unexpected tokens of the zero address: %v", tokens)
+	}
+	if tokens, _ := store.Tokens(alice); len(tokens) != 2 {
+		t.Fatalf("wrong tokens of alice: %v", tokens)
+	}
+	// Transfers are filtered by token and paged by location
+	transfers, err := store.Transfers(alice, &usdt, 2, 3, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 4 || transfers[0].BlockNumber != 2 {
+		t.Fatalf("wrong usdt transfers: %+v", transfers)
+	}
+	if transfers, _ = store.Transfers(alice, nil, 0, 100, &Location{Block: 3, Log: 1}, 0); len(transfers) != 1 || transfers[0].LogIndex != 2 {
+		t.Fatalf("wrong transfers after cursor: %+v", transfers)
+	}
+	// Rolled back blocks take their balances and transfers along
+	if err := store.Truncate(1); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(alice, usdt, 100, 70)
+	if transfers, _ = store.Transfers(bob, nil, 0, 100, nil, 0); len(transfers) != 2 {
+		t.Fatalf("wrong transfers after rollback: %+v", transfers)
+	}
+	if err := store.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := store.Range(); ok {
+		t.Fatal("expected empty index")
+	}
+	if tokens, _ := store.Tokens(alice); len(tokens) != 0 {
+		t.Fatalf("tokens left after rollback: %v", tokens)
+	}
+}
+
+// testBackend serves the blocks of a test chain to the indexer. The receipts
+// of its blocks carry a transfer from alice to bob of the token at the salt of
+// the chain.
+type testBackend struct {
+	*chainindextest.Chain
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	for _, block := range b.Blocks {
+		if block.Hash() == hash {
+			token := common.BytesToAddress(block.Extra())
+			return types.Receipts{{Logs: []*types.Log{transferLog(token, alice, bob, 1, 0)}}}, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestIndexer(t *testing.T) {
+	t.Parallel()
+
+	backend := &testBackend{Chain: chainindextest.NewChain(8, 0)}
+	store := NewStore(rawdb.NewMemoryDatabase())
+	ix := NewIndexer(backend, store, 2)
+
+	checkRange := func(tail, head uint64) {
+		t.Helper()
+		if have, haveHead, ok := store.Range(); !ok || have != tail || haveHead != head {
+			t.Fatalf("wrong index range: have %d-%d (%v), want %d-%d", have, haveHead, ok, tail, head)
+		}
+		for n := tail; n <= head; n++ {
+			if hash, _ := store.BlockHash(n); hash != backend.Blocks[n].Hash() {
+				t.Fatalf("block %d: wrong hash %x", n, hash)
+			}
+		}
+	}
+	checkBalance := func(token common.Address, want int64) {
+		t.Helper()
+		if balance, _ := store.Balance(bob, token, 100); (balance == nil && want != 0) || (balance != nil && balance.Int64() != want) {
+			t.Fatalf("wrong balance of token %x: have %v, want %d", token, balance, want)
+		}
+	}
+	// The index starts at the configured block
+	if err := ix.Sync(context.Background(), backend.CurrentHeader()); err != nil {
+		t.Fatal(err)
+	}
+	checkRange(2, 7)
+	checkBalance(common.Address{}, 6)
+
+	// Reorged blocks are rolled back and reindexed
+	backend.Blocks = append(backend.Blocks[:6], chainindextest.NewBlocks(10, 1)[6:]...)
+	if err := ix.Sync(context.Background(), backend.CurrentHeader()); err != nil {
+		t.Fatal(err)
+	}
+	checkRange(2, 9)
+	checkBalance(common.Address{}, 4)
+	checkBalance(common.BytesToAddress([]byte{1}), 4)
+
+	// Rewinds drop the blocks above the head
+	backend.Blocks = backend.Blocks[:7]
+	if err := ix.Sync(context.Background(), backend.CurrentHeader()); err != nil {
+		t.Fatal(err)
+	}
+	checkRange(2, 6)
+	checkBalance(common.BytesToAddress([]byte{1}), 1)
+}
+
+func TestIndexerStartsAtHead(t *testing.T) {
+	t.Parallel()
+
+	backend := &testBackend{Chain: chainindextest.NewChain(8, 0)}
+	store := NewStore(rawdb.NewMemoryDatabase())
+	ix := NewIndexer(backend, store, DefaultConfig.From)
+
+	// Without a start block the index doesn't reach back to the genesis
+	if err := ix.Sync(context.Background(), backend.CurrentHeader()); err != nil {
+		t.Fatal(err)
+	}
+	if tail, head, ok := store.Range(); !ok || tail != 7 || head != 7 {
+		t.Fatalf("wrong index range: have %d-%d (%v), want 7-7", tail, head, ok)
+	}
+}
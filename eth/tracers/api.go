@@ -168,6 +168,13 @@ type TraceCallConfig struct {
 	StateOverrides *ethapi.StateOverride
 	BlockOverrides *ethapi.BlockOverrides
 	TxIndex        *hexutil.Uint
+	StateDiff      bool // Return the state changes of the call along with the trace
+}
+
+// stateDiffTraceResult is the result of a traceCall with StateDiff enabled.
+type stateDiffTraceResult struct {
+	Result    interface{}     `json:"result"`
+	StateDiff state.StateDiff `json:"stateDiff"`
 }
 
 // StdTraceConfig holds extra parameters to standard-json trace functions.
@@ -622,29 +629,16 @@ func (api *API) StandardTraceBadBlockToFile(ctx context.Context, hash common.Has
 // executes all the transactions contained within. The return value will be one item
 // per transaction, dependent on the requested tracer.
 func (api *API) traceBlock(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
-	if block.NumberU64() == 0 {
-		return nil, errors.New("genesis is not traceable")
-	}
-	// Prepare base state
-	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
-	if err != nil {
-		return nil, err
-	}
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
 	}
-	statedb, release, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true, false)
+	parent, statedb, release, err := api.blockPrestate(ctx, block, reexec)
 	if err != nil {
 		return nil, err
 	}
 	defer release()
 
-	// upgrade build-in system contract before normal txs if Feynman is not enabled
-	if !api.backend.ChainConfig().IsFeynman(block.Number(), block.Time()) {
-		systemcontracts.UpgradeBuildInSystemContract(api.backend.ChainConfig(), block.Number(), parent.Time(), block.Time(), statedb)
-	}
-
 	// JS tracers have high overhead. In this case run a parallel
 	// process that generates states in one thread and traces txes
 	// in separate worker threads.
@@ -655,16 +649,56 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	}
 
 	// Native tracers have low overhead
+	results := make([]*txTraceResult, len(block.Transactions()))
+	err = api.executeBlock(ctx, block, parent, statedb, func(i int, tx *types.Transaction, msg *core.Message, txctx *Context, blockCtx vm.BlockContext, isSystemTx bool) error {
+		res, err := api.traceTx(ctx, msg, txctx, blockCtx, statedb, config, isSystemTx)
+		if err != nil {
+			return err
+		}
+		results[i] = &txTraceResult{TxHash: tx.Hash(), Result: res}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// blockPrestate returns the parent of the block together with the state the
+// transactions of the block are executed on.
+func (api *API) blockPrestate(ctx context.Context, block *types.Block, reexec uint64) (*types.Block, *state.StateDB, StateReleaseFunc, error) {
+	if block.NumberU64() == 0 {
+		return nil, nil, nil, errors.New("genesis is not traceable")
+	}
+	// Prepare base state
+	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	statedb, release, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// upgrade build-in system contract before normal txs if Feynman is not enabled
+	if !api.backend.ChainConfig().IsFeynman(block.Number(), block.Time()) {
+		systemcontracts.UpgradeBuildInSystemContract(api.backend.ChainConfig(), block.Number(), parent.Time(), block.Time(), statedb)
+	}
+	return parent, statedb, release, nil
+}
+
+// executeBlock runs fn for every transaction of the block on top of the given
+// parent state, which fn is expected to apply the transaction to. The state is
+// finalised after every transaction, fn sees the changes of its transaction
+// only.
+func (api *API) executeBlock(ctx context.Context, block *types.Block, parent *types.Block, statedb *state.StateDB, fn func(i int, tx *types.Transaction, msg *core.Message, txctx *Context, blockCtx vm.BlockContext, isSystemTx bool) error) error {
 	var (
-		txs            = block.Transactions()
 		blockHash      = block.Hash()
 		is158          = api.backend.ChainConfig().IsEIP158(block.Number())
 		blockCtx       = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
 		signer         = types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time())
-		results        = make([]*txTraceResult, len(txs))
 		beforeSystemTx = true
 	)
-	for i, tx := range txs {
+	for i, tx := range block.Transactions() {
 		// upgrade build-in system contract before system txs if Feynman is enabled
 		if beforeSystemTx {
 			if posa, ok := api.backend.Engine().(consensus.PoSA); ok {
@@ -682,8 +716,9 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 				}
 			}
 		}
+		// Keep the system contract upgrades out of the changes fn sees
+		statedb.Finalise(is158)
 
-		// Generate the next state snapshot fast without tracing
 		msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
 		txctx := &Context{
 			BlockHash:   blockHash,
@@ -691,16 +726,14 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 			TxIndex:     i,
 			TxHash:      tx.Hash(),
 		}
-		res, err := api.traceTx(ctx, msg, txctx, blockCtx, statedb, config, !beforeSystemTx)
-		if err != nil {
-			return nil, err
+		if err := fn(i, tx, msg, txctx, blockCtx, !beforeSystemTx); err != nil {
+			return err
 		}
-		results[i] = &txTraceResult{TxHash: tx.Hash(), Result: res}
 		// Finalize the state so any modifications are written to the trie
 		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
 		statedb.Finalise(is158)
 	}
-	return results, nil
+	return nil
 }
 
 // traceBlockParallel is for tracers that have a high overhead (read JS tracers). One thread
@@ -1077,7 +1110,16 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 	if config != nil {
 		traceConfig = &config.TraceConfig
 	}
-	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig, false)
+	if config == nil || !config.StateDiff {
+		return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig, false)
+	}
+	// Leave the system contract upgrade and the overrides out of the diff
+	statedb.Finalise(api.backend.ChainConfig().IsEIP158(block.Number()))
+	result, err := api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig, false)
+	if err != nil {
+		return nil, err
+	}
+	return &stateDiffTraceResult{Result: result, StateDiff: statedb.StateDiff()}, nil
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
@@ -1137,11 +1179,17 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 
 // APIs return the collection of RPC services the tracer package offers.
 func APIs(backend Backend) []rpc.API {
+	api := NewAPI(backend)
+
 	// Append all the local APIs and return
 	return []rpc.API{
 		{
 			Namespace: "debug",
-			Service:   NewAPI(backend),
+			Service:   api,
+		},
+		{
+			Namespace: "trace",
+			Service:   NewTraceAPI(api),
 		},
 	}
 }
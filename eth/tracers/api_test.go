@@ -43,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
 	"golang.org/x/exp/slices"
 )
 
@@ -402,6 +403,27 @@ func TestTraceCall(t *testing.T) {
 			}
 		}
 	}
+	// Trace a transfer with the state diff
+	latest := rpc.LatestBlockNumber
+	result, err := api.TraceCall(context.Background(), ethapi.TransactionArgs{
+		From:  &accounts[0].addr,
+		To:    &accounts[1].addr,
+		Value: (*hexutil.Big)(big.NewInt(1000)),
+	}, rpc.BlockNumberOrHash{BlockNumber: &latest}, &TraceCallConfig{StateDiff: true})
+	if err != nil {
+		t.Fatalf("failed to trace call with state diff: %v", err)
+	}
+	diff := result.(*stateDiffTraceResult).StateDiff
+	if sender := diff[accounts[0].addr]; sender == nil || sender.Nonce == nil || sender.Nonce.To != sender.Nonce.From+1 {
+		t.Fatalf("sender nonce not in diff: %+v", sender)
+	}
+	recipient := diff[accounts[1].addr]
+	if recipient == nil || recipient.Balance == nil || recipient.Nonce != nil {
+		t.Fatalf("unexpected recipient diff: %+v", recipient)
+	}
+	if have := new(uint256.Int).Sub((*uint256.Int)(recipient.Balance.To), (*uint256.Int)(recipient.Balance.From)); have.Uint64() != 1000 {
+		t.Fatalf("recipient balance change mismatch: have %v, want 1000", have)
+	}
 }
 
 func TestTraceTransaction(t *testing.T) {
@@ -0,0 +1,417 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxTraceFilterBlocks is the maximum number of blocks trace_filter traces
+	// in a single request.
+	maxTraceFilterBlocks = 100
+
+	// Trace types of trace_replayBlockTransactions.
+	traceTypeTrace     = "trace"
+	traceTypeStateDiff = "stateDiff"
+	traceTypeVMTrace   = "vmTrace"
+)
+
+// flatTraceConfig runs the flat call tracer with parity error messages.
+var flatTraceConfig = newTraceConfig("flatCallTracer", `{"convertParityErrors":true}`)
+
+func newTraceConfig(tracer string, config string) *TraceConfig {
+	return &TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(config)}
+}
+
+// TraceAPI implements the OpenEthereum style trace namespace on top of the
+// flatCallTracer. The traces of a transaction are the flattened list of its
+// call frames.
+type TraceAPI struct {
+	api *API
+}
+
+// NewTraceAPI creates the trace namespace on top of the tracing API.
+func NewTraceAPI(api *API) *TraceAPI {
+	return &TraceAPI{api: api}
+}
+
+// TraceFilterArgs selects the traces returned by trace_filter. Traces match if
+// their sender is in FromAddress and their recipient is in ToAddress, empty
+// lists match all addresses. After and Count page through the matching traces.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// ReplayResult is the result of a transaction replayed by
+// trace_replayBlockTransactions. Trace types that were not requested are empty.
+type ReplayResult struct {
+	Output          hexutil.Bytes                         `json:"output"`
+	StateDiff       map[common.Address]*ParityAccountDiff `json:"stateDiff"`
+	Trace           []json.RawMessage                     `json:"trace"`
+	VMTrace         json.RawMessage                       `json:"vmTrace"`
+	TransactionHash common.Hash                           `json:"transactionHash"`
+}
+
+// ParityAccountDiff is the change of an account in the parity stateDiff format.
+// Every field is either "=" if unchanged, or an object with a single key: "+"
+// with the value of a created account, "-" with the value of a destroyed one
+// or "*" with the values before and after the change.
+type ParityAccountDiff struct {
+	Balance interface{}                 `json:"balance"`
+	Nonce   interface{}                 `json:"nonce"`
+	Code    interface{}                 `json:"code"`
+	Storage map[common.Hash]interface{} `json:"storage"`
+}
+
+// flatTraceAddresses holds the addresses of a flat trace matched by trace_filter.
+type flatTraceAddresses struct {
+	Action struct {
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`       // Destroyed account of selfdestructs
+		RefundAddress *common.Address `json:"refundAddress"` // Beneficiary of selfdestructs
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"` // Created account of creations
+		Code    hexutil.Bytes   `json:"code"`
+		Output  hexutil.Bytes   `json:"output"`
+	} `json:"result"`
+}
+
+// Block returns the traces of all the transactions of a block.
+func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]json.RawMessage, error) {
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return api.blockTraces(ctx, block)
+}
+
+// Transaction returns the traces of a transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]json.RawMessage, error) {
+	result, err := api.api.TraceTransaction(ctx, hash, flatTraceConfig)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFlatTraces(result)
+}
+
+// Filter returns the traces of a range of blocks matching the given addresses.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]json.RawMessage, error) {
+	from, err := api.resolveNumber(ctx, args.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.resolveNumber(ctx, args.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if to-from >= maxTraceFilterBlocks {
+		return nil, fmt.Errorf("block range %d-%d exceeds the limit of %d blocks", from, to, maxTraceFilterBlocks)
+	}
+	var (
+		skip    uint64
+		matches = []json.RawMessage{}
+	)
+	if args.After != nil {
+		skip = *args.After
+	}
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block, err := api.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if len(block.Transactions()) == 0 {
+			continue
+		}
+		traces, err := api.blockTraces(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			ok, err := args.Matches(trace)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			matches = append(matches, trace)
+			if args.Count != nil && uint64(len(matches)) >= *args.Count {
+				return matches, nil
+			}
+		}
+	}
+	return matches, nil
+}
+
+// ReplayBlockTransactions replays the transactions of a block and returns the
+// requested trace types for each of them: "trace" for the call traces,
+// "stateDiff" for the state changes and "vmTrace" for the executed instructions.
+//
+// The stateDiff of a destroyed account lists the storage slots modified by the
+// transaction only, not all the slots the account held.
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string) ([]*ReplayResult, error) {
+	var withTrace, withStateDiff, withVMTrace bool
+	for _, typ := range traceTypes {
+		switch typ {
+		case traceTypeTrace:
+			withTrace = true
+		case traceTypeStateDiff:
+			withStateDiff = true
+		case traceTypeVMTrace:
+			withVMTrace = true
+		default:
+			return nil, fmt.Errorf("unknown trace type %q", typ)
+		}
+	}
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	parent, statedb, release, err := api.api.blockPrestate(ctx, block, defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	tracerConfig := `{"flatCallTracer":{"convertParityErrors":true}}`
+	if withVMTrace {
+		tracerConfig = `{"flatCallTracer":{"convertParityErrors":true},"vmTracer":{}}`
+	}
+	var (
+		config  = newTraceConfig("muxTracer", tracerConfig)
+		results = make([]*ReplayResult, len(block.Transactions()))
+	)
+	err = api.api.executeBlock(ctx, block, parent, statedb, func(i int, tx *types.Transaction, msg *core.Message, txctx *Context, blockCtx vm.BlockContext, isSystemTx bool) error {
+		res, err := api.api.traceTx(ctx, msg, txctx, blockCtx, statedb, config, isSystemTx)
+		if err != nil {
+			return err
+		}
+		var traces map[string]json.RawMessage
+		if err := json.Unmarshal(res.(json.RawMessage), &traces); err != nil {
+			return err
+		}
+		frames, err := decodeFlatTraces(traces["flatCallTracer"])
+		if err != nil {
+			return err
+		}
+		result := &ReplayResult{TransactionHash: tx.Hash(), Trace: []json.RawMessage{}}
+		if len(frames) > 0 {
+			var top flatTraceAddresses
+			if err := json.Unmarshal(frames[0], &top); err != nil {
+				return err
+			}
+			if top.Result != nil {
+				result.Output = top.Result.Output
+				if result.Output == nil {
+					result.Output = top.Result.Code
+				}
+			}
+		}
+		if result.Output == nil {
+			result.Output = hexutil.Bytes{}
+		}
+		if withTrace {
+			result.Trace = frames
+		}
+		if withVMTrace {
+			result.VMTrace = traces["vmTracer"]
+		}
+		if withStateDiff {
+			result.StateDiff = parityStateDiff(statedb.StateDiff(), statedb)
+		}
+		results[i] = result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// BlockTraces returns the flat traces of all the transactions of a block in
+// the format of trace_block.
+func BlockTraces(ctx context.Context, backend Backend, block *types.Block) ([]json.RawMessage, error) {
+	return NewTraceAPI(NewAPI(backend)).blockTraces(ctx, block)
+}
+
+// blockTraces returns the flattened traces of all the transactions of a block.
+func (api *TraceAPI) blockTraces(ctx context.Context, block *types.Block) ([]json.RawMessage, error) {
+	results, err := api.api.traceBlock(ctx, block, flatTraceConfig)
+	if err != nil {
+		return nil, err
+	}
+	traces := []json.RawMessage{}
+	for _, result := range results {
+		frames, err := decodeFlatTraces(result.Result)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, frames...)
+	}
+	return traces, nil
+}
+
+// resolveNumber resolves a block number of trace_filter, defaulting to the
+// latest block.
+func (api *TraceAPI) resolveNumber(ctx context.Context, number *rpc.BlockNumber) (uint64, error) {
+	n := rpc.LatestBlockNumber
+	if number != nil {
+		n = *number
+	}
+	if n == rpc.PendingBlockNumber {
+		return 0, errors.New("tracing the pending block is not supported")
+	}
+	header, err := api.api.backend.HeaderByNumber(ctx, n)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block #%d not found", n)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// Matches reports whether a flat trace matches the addresses of the filter.
+func (args *TraceFilterArgs) Matches(trace json.RawMessage) (bool, error) {
+	if len(args.FromAddress) == 0 && len(args.ToAddress) == 0 {
+		return true, nil
+	}
+	var addrs flatTraceAddresses
+	if err := json.Unmarshal(trace, &addrs); err != nil {
+		return false, err
+	}
+	from, to := addrs.Action.From, addrs.Action.To
+	if from == nil {
+		from = addrs.Action.Address
+	}
+	if to == nil {
+		if addrs.Result != nil && addrs.Result.Address != nil {
+			to = addrs.Result.Address
+		} else {
+			to = addrs.Action.RefundAddress
+		}
+	}
+	return containsAddress(args.FromAddress, from) && containsAddress(args.ToAddress, to), nil
+}
+
+// containsAddress reports whether addr is in the list, an empty list contains
+// every address.
+func containsAddress(list []common.Address, addr *common.Address) bool {
+	if len(list) == 0 {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	for _, a := range list {
+		if a == *addr {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeFlatTraces splits the result of the flatCallTracer into its traces.
+func decodeFlatTraces(result interface{}) ([]json.RawMessage, error) {
+	blob, ok := result.(json.RawMessage)
+	if !ok {
+		return nil, fmt.Errorf("unexpected trace result type %T", result)
+	}
+	var frames []json.RawMessage
+	if err := json.Unmarshal(blob, &frames); err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+// parityStateDiff converts the state changes of a transaction to the parity
+// format. The state must not have been finalised since the transaction, the
+// values of unchanged fields are read from it.
+func parityStateDiff(diff state.StateDiff, statedb *state.StateDB) map[common.Address]*ParityAccountDiff {
+	result := make(map[common.Address]*ParityAccountDiff, len(diff))
+	for addr, acct := range diff {
+		var (
+			balance = (*hexutil.U256)(statedb.GetBalance(addr).Clone())
+			nonce   = hexutil.Uint64(statedb.GetNonce(addr))
+			code    = hexutil.Bytes(statedb.GetCode(addr))
+			out     = &ParityAccountDiff{Storage: make(map[common.Hash]interface{})}
+		)
+		if acct.Balance != nil {
+			out.Balance = parityDelta(acct, true, acct.Balance.From, acct.Balance.To)
+		} else {
+			out.Balance = parityDelta(acct, false, balance, balance)
+		}
+		if acct.Nonce != nil {
+			out.Nonce = parityDelta(acct, true, acct.Nonce.From, acct.Nonce.To)
+		} else {
+			out.Nonce = parityDelta(acct, false, nonce, nonce)
+		}
+		if acct.Code != nil {
+			out.Code = parityDelta(acct, true, acct.Code.From, acct.Code.To)
+		} else {
+			out.Code = parityDelta(acct, false, code, code)
+		}
+		for key, slot := range acct.Storage {
+			out.Storage[key] = parityDelta(acct, true, slot.From, slot.To)
+		}
+		result[addr] = out
+	}
+	return result
+}
+
+// parityDelta returns a field of a parity account diff.
+func parityDelta(acct *state.AccountDiff, changed bool, from, to interface{}) interface{} {
+	switch {
+	case acct.Created:
+		return map[string]interface{}{"+": to}
+	case acct.Destructed:
+		return map[string]interface{}{"-": from}
+	case changed:
+		return map[string]interface{}{"*": map[string]interface{}{"from": from, "to": to}}
+	default:
+		return "="
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
+)
+
+func TestTraceFilterMatches(t *testing.T) {
+	t.Parallel()
+
+	var (
+		a = common.HexToAddress("0xa")
+		b = common.HexToAddress("0xb")
+		c = common.HexToAddress("0xc")
+	)
+	call := json.RawMessage(`{"action":{"from":"0x000000000000000000000000000000000000000a","to":"0x000000000000000000000000000000000000000b"},"type":"call"}`)
+	create := json.RawMessage(`{"action":{"from":"0x000000000000000000000000000000000000000a"},"result":{"address":"0x000000000000000000000000000000000000000c"},"type":"create"}`)
+	suicide := json.RawMessage(`{"action":{"address":"0x000000000000000000000000000000000000000c","refundAddress":"0x000000000000000000000000000000000000000b"},"type":"suicide"}`)
+
+	var tests = []struct {
+		args  TraceFilterArgs
+		trace json.RawMessage
+		want  bool
+	}{
+		{TraceFilterArgs{}, call, true},
+		{TraceFilterArgs{FromAddress: []common.Address{a}}, call, true},
+		{TraceFilterArgs{FromAddress: []common.Address{b}}, call, false},
+		{TraceFilterArgs{FromAddress: []common.Address{a}, ToAddress: []common.Address{b}}, call, true},
+		{TraceFilterArgs{FromAddress: []common.Address{a}, ToAddress: []common.Address{c}}, call, false},
+		{TraceFilterArgs{ToAddress: []common.Address{c}}, create, true},
+		{TraceFilterArgs{FromAddress: []common.Address{c}, ToAddress: []common.Address{b}}, suicide, true},
+		{TraceFilterArgs{FromAddress: []common.Address{a}}, suicide, false},
+	}
+	for i, tc := range tests {
+		have, err := tc.args.Matches(tc.trace)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if have != tc.want {
+			t.Errorf("test %d: have %v, want %v", i, have, tc.want)
+		}
+	}
+}
+
+func TestParityStateDiff(t *testing.T) {
+	t.Parallel()
+
+	var (
+		a          = common.HexToAddress("0xa")
+		b          = common.HexToAddress("0xb")
+		slot       = common.HexToHash("0x1")
+		statedb, _ = state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	)
+	statedb.SetBalance(a, uint256.NewInt(100))
+	statedb.SetNonce(a, 1)
+	statedb.Finalise(true)
+
+	// Transfer to a new contract account and bump the sender nonce
+	statedb.SubBalance(a, uint256.NewInt(10))
+	statedb.SetNonce(a, 2)
+	statedb.CreateAccount(b)
+	statedb.AddBalance(b, uint256.NewInt(10))
+	statedb.SetCode(b, []byte{0x1})
+	statedb.SetState(b, slot, common.HexToHash("0x2a"))
+
+	blob, err := json.Marshal(parityStateDiff(statedb.StateDiff(), statedb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{` +
+		`"0x000000000000000000000000000000000000000a":{"balance":{"*":{"from":"0x64","to":"0x5a"}},"nonce":{"*":{"from":"0x1","to":"0x2"}},"code":"=","storage":{}},` +
+		`"0x000000000000000000000000000000000000000b":{"balance":{"+":"0xa"},"nonce":{"+":"0x0"},"code":{"+":"0x01"},"storage":{"0x0000000000000000000000000000000000000000000000000000000000000001":{"+":"0x000000000000000000000000000000000000000000000000000000000000002a"}}}` +
+		`}`
+	if string(blob) != want {
+		t.Fatalf("wrong state diff\nhave: %s\nwant: %s", blob, want)
+	}
+}
+
+func TestTraceAPIArgs(t *testing.T) {
+	t.Parallel()
+
+	genesis := &core.Genesis{Config: params.TestChainConfig}
+	backend := newTestBackend(t, maxTraceFilterBlocks+1, genesis, func(i int, b *core.BlockGen) {})
+	defer backend.chain.Stop()
+	api := NewTraceAPI(NewAPI(backend))
+
+	from, to := rpc.BlockNumber(0), rpc.BlockNumber(maxTraceFilterBlocks)
+	if _, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &from, ToBlock: &to}); err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Fatalf("expected block range error, got %v", err)
+	}
+	if _, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &to, ToBlock: &from}); err == nil {
+		t.Fatal("expected invalid block range error")
+	}
+	// Ranges without transactions are not traced at all
+	from = 1
+	traces, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &from, ToBlock: &to})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 0 {
+		t.Fatalf("expected no traces, got %d", len(traces))
+	}
+	if _, err := api.ReplayBlockTransactions(context.Background(), 1, []string{"trace", "stateDiffs"}); err == nil || !strings.Contains(err.Error(), "unknown trace type") {
+		t.Fatalf("expected trace type error, got %v", err)
+	}
+}
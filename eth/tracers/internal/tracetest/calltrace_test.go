@@ -360,6 +360,22 @@ func TestInternals(t *testing.T) {
 			tracer: mkTracer("prestateTracer", nil),
 			want:   `{"0x0000000000000000000000000000000000000000":{"balance":"0x0"},"0x000000000000000000000000000000000000feed":{"balance":"0x1c6bf52647880"},"0x00000000000000000000000000000000deadbeef":{"balance":"0x0","code":"0x6001600052600160ff60016000f560ff6000a0"},"0x91ff9a805d36f54e3e272e230f3e3f5c1b330804":{"balance":"0x0"}}`,
 		},
+		{
+			name: "VM trace of storage, memory and calls",
+			code: []byte{
+				byte(vm.PUSH1), 0x2a,
+				byte(vm.PUSH1), 0x0,
+				byte(vm.SSTORE),
+				byte(vm.PUSH1), 0x1,
+				byte(vm.PUSH1), 0x0,
+				byte(vm.MSTORE),
+				byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+				byte(vm.DUP1), byte(vm.PUSH1), 0xff, byte(vm.GAS),
+				byte(vm.CALL),
+			},
+			tracer: mkTracer("vmTracer", nil),
+			want:   `{"code":"0x602a600055600160005260008080808060ff5af1","ops":[{"cost":3,"ex":{"mem":null,"push":["0x2a"],"store":null,"used":58997},"pc":0,"sub":null},{"cost":3,"ex":{"mem":null,"push":["0x0"],"store":null,"used":58994},"pc":2,"sub":null},{"cost":20000,"ex":{"mem":null,"push":[],"store":{"key":"0x0","val":"0x2a"},"used":38994},"pc":4,"sub":null},{"cost":3,"ex":{"mem":null,"push":["0x1"],"store":null,"used":38991},"pc":5,"sub":null},{"cost":3,"ex":{"mem":null,"push":["0x0"],"store":null,"used":38988},"pc":7,"sub":null},{"cost":6,"ex":{"mem":{"off":0,"data":"0x0000000000000000000000000000000000000000000000000000000000000001"},"push":[],"store":null,"used":38982},"pc":9,"sub":null},{"cost":3,"ex":{"mem":null,"push":["0x0"],"store":null,"used":38979},"pc":10,"sub":null},{"cost":3,"ex":{"mem":null,"push":["0x0"],"store":null,"used":38976},"pc":12,"sub":null},{"cost":3,"ex":{"mem":null,"push":["0x0"],"store":null,"used":38973},"pc":13,"sub":null},{"cost":3,"ex":{"mem":null,"push":["0x0"],"store":null,"used":38970},"pc":14,"sub":null},{"cost":3,"ex":{"mem":null,"push":["0x0"],"store":null,"used":38967},"pc":15,"sub":null},{"cost":3,"ex":{"mem":null,"push":["0xff"],"store":null,"used":38964},"pc":16,"sub":null},{"cost":2,"ex":{"mem":null,"push":["0x9832"],"store":null,"used":38962},"pc":18,"sub":null},{"cost":38365,"ex":{"mem":null,"push":["0x1"],"store":null,"used":38262},"pc":19,"sub":{"code":"0x","ops":[]}},{"cost":0,"ex":{"mem":null,"push":[],"store":null,"used":38262},"pc":20,"sub":null}]}`,
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			state := tests.MakePreState(rawdb.NewMemoryDatabase(),
@@ -0,0 +1,250 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("vmTracer", newVMTracer, false)
+}
+
+// vmTrace is the parity style trace of the instructions executed by a call
+// frame. Calls and creations carry the trace of the called frame in Sub.
+type vmTrace struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []*vmTraceOp  `json:"ops"`
+}
+
+type vmTraceOp struct {
+	Cost uint64     `json:"cost"`
+	Ex   *vmTraceEx `json:"ex"` // Effects of the instruction, nil if it failed
+	Pc   uint64     `json:"pc"`
+	Sub  *vmTrace   `json:"sub"`
+}
+
+type vmTraceEx struct {
+	Mem   *vmTraceMem   `json:"mem"`
+	Push  []string      `json:"push"`
+	Store *vmTraceStore `json:"store"`
+	Used  uint64        `json:"used"` // Gas left after the instruction
+}
+
+type vmTraceMem struct {
+	Off  uint64        `json:"off"`
+	Data hexutil.Bytes `json:"data"`
+}
+
+type vmTraceStore struct {
+	Key string `json:"key"`
+	Val string `json:"val"`
+}
+
+// vmTraceFrame tracks the trace of an active call frame. The effects of an
+// instruction are only known at the next step of its frame, so the last
+// instruction is kept around until then.
+type vmTraceFrame struct {
+	trace *vmTrace
+
+	last    *vmTraceOp
+	op      vm.OpCode
+	gas     uint64
+	memOff  uint64 // Memory region written by the last instruction
+	memSize uint64
+	store   *vmTraceStore
+}
+
+// vmTracer reports the instructions executed by a transaction in the format of
+// the vmTrace of parity's trace_replayTransaction.
+type vmTracer struct {
+	env       *vm.EVM
+	root      *vmTrace
+	frames    []*vmTraceFrame
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newVMTracer returns a new vmTracer.
+func newVMTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &vmTracer{}, nil
+}
+
+// code returns the code run by a frame entered with the given parameters.
+func (t *vmTracer) code(create bool, to common.Address, input []byte) []byte {
+	if create {
+		return common.CopyBytes(input)
+	}
+	return common.CopyBytes(t.env.StateDB.GetCode(to))
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *vmTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.root = &vmTrace{Code: t.code(create, to, input), Ops: []*vmTraceOp{}}
+	t.frames = []*vmTraceFrame{{trace: t.root}}
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *vmTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if len(t.frames) > 0 {
+		t.frames[0].finish(nil, 0)
+	}
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *vmTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	frame.finish(scope, gas)
+
+	next := &vmTraceOp{Cost: cost, Pc: pc}
+	frame.trace.Ops = append(frame.trace.Ops, next)
+	frame.last, frame.op, frame.gas = next, op, gas-cost
+	frame.memOff, frame.memSize, frame.store = 0, 0, nil
+
+	// Note the memory and storage written by the instruction
+	stack := scope.Stack
+	switch op {
+	case vm.MSTORE:
+		frame.memOff, frame.memSize = stack.Back(0).Uint64(), 32
+	case vm.MSTORE8:
+		frame.memOff, frame.memSize = stack.Back(0).Uint64(), 1
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY, vm.MCOPY:
+		frame.memOff, frame.memSize = stack.Back(0).Uint64(), stack.Back(2).Uint64()
+	case vm.EXTCODECOPY:
+		frame.memOff, frame.memSize = stack.Back(1).Uint64(), stack.Back(3).Uint64()
+	case vm.CALL, vm.CALLCODE:
+		frame.memOff, frame.memSize = stack.Back(5).Uint64(), stack.Back(6).Uint64()
+	case vm.DELEGATECALL, vm.STATICCALL:
+		frame.memOff, frame.memSize = stack.Back(4).Uint64(), stack.Back(5).Uint64()
+	case vm.SSTORE:
+		frame.store = &vmTraceStore{Key: stack.Back(0).Hex(), Val: stack.Back(1).Hex()}
+	}
+}
+
+// finish fills in the effects of the last instruction of the frame. The scope
+// and gas are those of the next step, nil if the frame ended with the
+// instruction.
+func (f *vmTraceFrame) finish(scope *vm.ScopeContext, gas uint64) {
+	if f.last == nil {
+		return
+	}
+	ex := &vmTraceEx{Used: f.gas, Push: []string{}, Store: f.store}
+	if scope != nil {
+		ex.Used = gas
+		data := scope.Stack.Data()
+		if n := pushedItems(f.op); n <= len(data) {
+			for _, item := range data[len(data)-n:] {
+				ex.Push = append(ex.Push, item.Hex())
+			}
+		}
+		if mem := scope.Memory.Data(); f.memSize > 0 && f.memOff < uint64(len(mem)) {
+			end := f.memOff + f.memSize
+			if end > uint64(len(mem)) || end < f.memOff {
+				end = uint64(len(mem))
+			}
+			ex.Mem = &vmTraceMem{Off: f.memOff, Data: common.CopyBytes(mem[f.memOff:end])}
+		}
+	}
+	f.last.Ex = ex
+	f.last = nil
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *vmTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+	// Failed instructions have no effects
+	if len(t.frames) > 0 {
+		t.frames[len(t.frames)-1].last = nil
+	}
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *vmTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	sub := &vmTrace{Code: t.code(typ == vm.CREATE || typ == vm.CREATE2, to, input), Ops: []*vmTraceOp{}}
+	if typ != vm.SELFDESTRUCT {
+		if parent := t.frames[len(t.frames)-1]; parent.last != nil {
+			parent.last.Sub = sub
+		}
+	}
+	t.frames = append(t.frames, &vmTraceFrame{trace: sub})
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *vmTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if len(t.frames) <= 1 {
+		return
+	}
+	t.frames[len(t.frames)-1].finish(nil, 0)
+	t.frames = t.frames[:len(t.frames)-1]
+}
+
+func (*vmTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (*vmTracer) CaptureTxEnd(restGas uint64) {}
+
+func (*vmTracer) CaptureSystemTxEnd(intrinsicGas uint64) {}
+
+// GetResult returns the vmTrace of the transaction.
+func (t *vmTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.root)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *vmTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// pushedItems returns the number of stack items reported as pushed by an
+// instruction. Swaps report all the items they reorder.
+func pushedItems(op vm.OpCode) int {
+	switch {
+	case op.IsPush(), vm.DUP1 <= op && op <= vm.DUP16:
+		return 1
+	case vm.SWAP1 <= op && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	}
+	switch op {
+	case vm.ADD, vm.MUL, vm.SUB, vm.DIV, vm.SDIV, vm.MOD, vm.SMOD, vm.ADDMOD, vm.MULMOD, vm.EXP, vm.SIGNEXTEND,
+		vm.LT, vm.GT, vm.SLT, vm.SGT, vm.EQ, vm.ISZERO, vm.AND, vm.OR, vm.XOR, vm.NOT, vm.BYTE, vm.SHL, vm.SHR, vm.SAR,
+		vm.KECCAK256, vm.ADDRESS, vm.BALANCE, vm.ORIGIN, vm.CALLER, vm.CALLVALUE, vm.CALLDATALOAD, vm.CALLDATASIZE,
+		vm.CODESIZE, vm.GASPRICE, vm.EXTCODESIZE, vm.RETURNDATASIZE, vm.EXTCODEHASH, vm.BLOCKHASH, vm.COINBASE,
+		vm.TIMESTAMP, vm.NUMBER, vm.DIFFICULTY, vm.GASLIMIT, vm.CHAINID, vm.SELFBALANCE, vm.BASEFEE, vm.BLOBHASH,
+		vm.BLOBBASEFEE, vm.MLOAD, vm.SLOAD, vm.TLOAD, vm.PC, vm.MSIZE, vm.GAS,
+		vm.CREATE, vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.CREATE2, vm.STATICCALL:
+		return 1
+	}
+	return 0
+}
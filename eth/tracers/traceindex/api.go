@@ -0,0 +1,289 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package traceindex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// defaultTransferLimit is the number of transfers trace_internalTransfers
+	// returns if no count is given.
+	defaultTransferLimit = 1000
+
+	// maxResultLimit caps the number of results of a single query.
+	maxResultLimit = 10000
+
+	// maxUnfilteredBlocks is the maximum number of blocks trace_indexedFilter
+	// scans in a single request if no address is given.
+	maxUnfilteredBlocks = 10000
+
+	// filterWindow is the number of blocks whose locations trace_indexedFilter
+	// merges at once.
+	filterWindow = 1000
+)
+
+var errNotIndexed = errors.New("trace index is empty")
+
+// API exposes the trace index in the trace namespace.
+type API struct {
+	store *Store
+}
+
+// NewAPI creates the query API of a trace index.
+func NewAPI(store *Store) *API {
+	return &API{store: store}
+}
+
+// APIs returns the RPC services of the trace index.
+func APIs(store *Store) []rpc.API {
+	return []rpc.API{{
+		Namespace: "trace",
+		Service:   NewAPI(store),
+	}}
+}
+
+// IndexRange is the range of blocks covered by the trace index.
+type IndexRange struct {
+	Tail hexutil.Uint64 `json:"tail"`
+	Head hexutil.Uint64 `json:"head"`
+}
+
+// TransferPage is a page of internal transfers. Next is the cursor of the
+// following page, nil if there are no more transfers.
+type TransferPage struct {
+	Transfers []*Transfer `json:"transfers"`
+	Next      *Location   `json:"next"`
+}
+
+// IndexRange returns the range of indexed blocks.
+func (api *API) IndexRange() (*IndexRange, error) {
+	tail, head, ok := api.store.Range()
+	if !ok {
+		return nil, errNotIndexed
+	}
+	return &IndexRange{Tail: hexutil.Uint64(tail), Head: hexutil.Uint64(head)}, nil
+}
+
+// IndexedFilter is trace_filter served from the trace index. The block range
+// defaults to the indexed blocks and must be covered by the index.
+func (api *API) IndexedFilter(ctx context.Context, args tracers.TraceFilterArgs) ([]json.RawMessage, error) {
+	from, to, err := api.resolveRange(args.FromBlock, args.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		skip  uint64
+		limit = uint64(maxResultLimit)
+	)
+	if args.After != nil {
+		skip = *args.After
+	}
+	if args.Count != nil && *args.Count < limit {
+		limit = *args.Count
+	}
+	matches := []json.RawMessage{}
+	collect := func(trace json.RawMessage) (bool, error) {
+		ok, err := args.Matches(trace)
+		if err != nil || !ok {
+			return true, err
+		}
+		if skip > 0 {
+			skip--
+			return true, nil
+		}
+		matches = append(matches, trace)
+		return uint64(len(matches)) < limit, nil
+	}
+	// Without addresses every trace of the range matches
+	addrs := args.FromAddress
+	if len(addrs) == 0 {
+		addrs = args.ToAddress
+	}
+	if len(addrs) == 0 {
+		if to-from >= maxUnfilteredBlocks {
+			return nil, fmt.Errorf("block range %d-%d exceeds the limit of %d blocks without address filter", from, to, maxUnfilteredBlocks)
+		}
+		var ierr error
+		err := api.store.Traces(from, to, func(_ uint64, traces []json.RawMessage) bool {
+			for _, trace := range traces {
+				if more, err := collect(trace); err != nil || !more {
+					ierr = err
+					return false
+				}
+			}
+			return ctx.Err() == nil
+		})
+		if err == nil {
+			err = ierr
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+		return matches, err
+	}
+	// Otherwise merge the locations of the addresses in chain order, a window
+	// of blocks at a time. No address contributes more locations than results
+	// are still needed, if that cut any short the window is continued after
+	// the last location all addresses were merged up to.
+	var (
+		cached *indexedBlock // Traces of the last block decoded
+		number uint64
+	)
+	for start := from; start <= to; start += filterWindow {
+		end := min(start+filterWindow-1, to)
+
+		var after *Location
+		for {
+			var (
+				batch  = int(min(skip, maxResultLimit) + limit - uint64(len(matches)))
+				locs   []Location
+				cutoff *Location
+			)
+			for _, addr := range addrs {
+				found, err := api.store.Locations(addr, start, end, after, batch)
+				if err != nil {
+					return nil, err
+				}
+				if len(found) == batch {
+					if last := found[len(found)-1]; cutoff == nil || locationLess(last, *cutoff) {
+						cutoff = &last
+					}
+				}
+				locs = append(locs, found...)
+			}
+			sort.Slice(locs, func(i, j int) bool { return locationLess(locs[i], locs[j]) })
+
+			for i, loc := range locs {
+				if cutoff != nil && locationLess(*cutoff, loc) {
+					break
+				}
+				if i > 0 && loc == locs[i-1] {
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				if cached == nil || number != loc.Block {
+					block, err := api.store.block(loc.Block)
+					if err != nil {
+						return nil, err
+					}
+					if block == nil {
+						continue
+					}
+					cached, number = block, loc.Block
+				}
+				if int(loc.Position) >= len(cached.Traces) {
+					continue
+				}
+				if more, err := collect(cached.Traces[loc.Position]); err != nil || !more {
+					return matches, err
+				}
+			}
+			if cutoff == nil {
+				break
+			}
+			after = cutoff
+		}
+		if end == to {
+			break
+		}
+	}
+	return matches, nil
+}
+
+// InternalTransfers returns the value transfers made from or to an address by
+// internal calls within the block range, oldest first. The range defaults to
+// the indexed blocks, pages are continued by passing the returned cursor.
+func (api *API) InternalTransfers(address common.Address, fromBlock, toBlock *rpc.BlockNumber, cursor *Location, count *hexutil.Uint64) (*TransferPage, error) {
+	from, to, err := api.resolveRange(fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	limit := defaultTransferLimit
+	if count != nil && *count > 0 {
+		limit = int(min(uint64(*count), maxResultLimit))
+	}
+	// Fetch one more transfer to know whether there is a next page
+	transfers, err := api.store.Transfers(address, from, to, cursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &TransferPage{Transfers: transfers}
+	if len(transfers) > limit {
+		page.Transfers = transfers[:limit]
+		last := page.Transfers[limit-1]
+		page.Next = &Location{Block: last.BlockNumber, Tx: last.TxPosition, Position: last.TracePos}
+	}
+	if page.Transfers == nil {
+		page.Transfers = []*Transfer{}
+	}
+	return page, nil
+}
+
+// locationLess reports whether location a precedes location b in chain order.
+func locationLess(a, b Location) bool {
+	if a.Block != b.Block {
+		return a.Block < b.Block
+	}
+	return a.Position < b.Position
+}
+
+// resolveRange resolves a block range of a query, which must be covered by the
+// index.
+func (api *API) resolveRange(fromBlock, toBlock *rpc.BlockNumber) (uint64, uint64, error) {
+	tail, head, ok := api.store.Range()
+	if !ok {
+		return 0, 0, errNotIndexed
+	}
+	resolve := func(number *rpc.BlockNumber, def uint64) (uint64, error) {
+		switch {
+		case number == nil:
+			return def, nil
+		case *number == rpc.LatestBlockNumber:
+			return head, nil
+		case *number < 0:
+			return 0, fmt.Errorf("unsupported block number %v", *number)
+		}
+		return uint64(*number), nil
+	}
+	from, err := resolve(fromBlock, tail)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := resolve(toBlock, head)
+	if err != nil {
+		return 0, 0, err
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if from < tail || to > head {
+		return 0, 0, fmt.Errorf("block range %d-%d not covered by the trace index %d-%d", from, to, tail, head)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package traceindex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(rawdb.NewMemoryDatabase())
+	api := NewAPI(store)
+	if _, err := api.IndexRange(); err != errNotIndexed {
+		t.Fatalf("expected empty index error, got %v", err)
+	}
+	for n := uint64(1); n <= 3; n++ {
+		if err := store.Write(n, common.Hash{byte(n)}, testTraces()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Filters by address and without
+	traces, err := api.IndexedFilter(context.Background(), tracers.TraceFilterArgs{ToAddress: []common.Address{carol}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 6 {
+		t.Fatalf("wrong number of traces to carol: %d", len(traces))
+	}
+	after, count := uint64(2), uint64(4)
+	traces, err = api.IndexedFilter(context.Background(), tracers.TraceFilterArgs{FromAddress: []common.Address{alice}, After: &after, Count: &count})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 4 || string(traces[0]) != string(testTraces()[4]) {
+		t.Fatalf("wrong page of traces from alice: %s", traces)
+	}
+	// Pages of a filter rejecting some of the locations match the full result
+	filter := tracers.TraceFilterArgs{FromAddress: []common.Address{alice}, ToAddress: []common.Address{carol}}
+	all, err := api.IndexedFilter(context.Background(), filter)
+	if err != nil || len(all) == 0 {
+		t.Fatalf("no traces from alice to carol: %v", err)
+	}
+	for i := range all {
+		after, count := uint64(i), uint64(1)
+		filter.After, filter.Count = &after, &count
+		page, err := api.IndexedFilter(context.Background(), filter)
+		if err != nil || len(page) != 1 || string(page[0]) != string(all[i]) {
+			t.Fatalf("wrong page %d of traces from alice to carol: %s (%v)", i, page, err)
+		}
+	}
+	from := rpc.BlockNumber(2)
+	if traces, _ = api.IndexedFilter(context.Background(), tracers.TraceFilterArgs{FromBlock: &from}); len(traces) != 10 {
+		t.Fatalf("wrong number of unfiltered traces: %d", len(traces))
+	}
+	to := rpc.BlockNumber(4)
+	if _, err := api.IndexedFilter(context.Background(), tracers.TraceFilterArgs{ToBlock: &to}); err == nil {
+		t.Fatal("expected error for blocks outside of the index")
+	}
+	// Transfers are paged by cursor
+	size := hexutil.Uint64(2)
+	page, err := api.InternalTransfers(bob, nil, nil, nil, &size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Transfers) != 2 || page.Next == nil {
+		t.Fatalf("wrong first page: %+v", page)
+	}
+	if page, err = api.InternalTransfers(bob, nil, nil, page.Next, &size); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Transfers) != 1 || page.Next != nil || page.Transfers[0].BlockNumber != 3 {
+		t.Fatalf("wrong last page: %+v", page)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package traceindex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/chainindex"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Config are the settings of the trace index.
+type Config struct {
+	Enabled bool   // Whether to index the traces of imported blocks
+	History uint64 // Number of recent blocks to keep indexed, 0 keeps everything
+}
+
+// DefaultConfig contains the default settings of the trace index.
+var DefaultConfig = Config{
+	History: 90000,
+}
+
+// Backend is the chain access required by the indexer.
+type Backend interface {
+	tracers.Backend
+	CurrentHeader() *types.Header
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// traceFunc returns the flat traces of a block.
+type traceFunc func(ctx context.Context, block *types.Block) ([]json.RawMessage, error)
+
+// Indexer keeps the trace index in sync with the canonical chain. Starting
+// from the head at the time it is first enabled, it indexes every new block,
+// rewinds the index on reorgs and prunes the blocks that fall out of the
+// configured history. Older blocks are indexed by Backfill.
+type Indexer struct {
+	*chainindex.Runner
+
+	backend Backend
+	store   *Store
+	history uint64
+	trace   traceFunc
+}
+
+// NewIndexer creates an indexer maintaining the given store.
+func NewIndexer(backend Backend, store *Store, history uint64) *Indexer {
+	ix := &Indexer{
+		backend: backend,
+		store:   store,
+		history: history,
+		trace: func(ctx context.Context, block *types.Block) ([]json.RawMessage, error) {
+			return tracers.BlockTraces(ctx, backend, block)
+		},
+	}
+	ix.Runner = chainindex.NewRunner("traces", backend, store, chainindex.Hooks{
+		First: ix.first,
+		Index: ix.index,
+	})
+	return ix
+}
+
+// Store returns the store maintained by the indexer.
+func (ix *Indexer) Store() *Store {
+	return ix.store
+}
+
+// first returns the first block to index up to the given head. An empty index
+// starts at the head, blocks falling out of the history are pruned.
+func (ix *Indexer) first(head uint64) (uint64, error) {
+	from := head
+	if _, indexed, ok := ix.store.Range(); ok {
+		from = indexed + 1
+	}
+	if ix.history == 0 || head < ix.history {
+		return from, nil
+	}
+	// Far behind indexes catch up on the retained history only
+	limit := head - ix.history + 1
+	if from < limit {
+		from = limit
+	}
+	return from, ix.store.Prune(limit)
+}
+
+// index indexes a single canonical block.
+func (ix *Indexer) index(ctx context.Context, number uint64) error {
+	block, err := ix.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return err
+	}
+	if block == nil {
+		return fmt.Errorf("block #%d not found", number)
+	}
+	// The genesis block has no transactions and can't be traced
+	var traces []json.RawMessage
+	if number > 0 {
+		if traces, err = ix.trace(ctx, block); err != nil {
+			return fmt.Errorf("failed to trace block #%d: %w", number, err)
+		}
+	}
+	return ix.store.Write(number, block.Hash(), traces)
+}
+
+// Backfill indexes the canonical blocks from the given block number down to
+// the current tail of the index. Nothing is indexed below the retained
+// history.
+func (ix *Indexer) Backfill(ctx context.Context, from uint64) error {
+	head := ix.backend.CurrentHeader()
+	if err := ix.Sync(ctx, head); err != nil {
+		return err
+	}
+	return ix.Exclusive(func() error {
+		number := head.Number.Uint64()
+		if ix.history > 0 && number >= ix.history && from < number-ix.history+1 {
+			from = number - ix.history + 1
+		}
+		tail, _, _ := ix.store.Range()
+		var (
+			start  = time.Now()
+			logged = time.Now()
+		)
+		for n := tail; n > from; n-- {
+			if err := ix.index(ctx, n-1); err != nil {
+				return err
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Backfilling trace index", "number", n-1, "target", from, "elapsed", common.PrettyDuration(time.Since(start)))
+				logged = time.Now()
+			}
+		}
+		log.Info("Backfilled trace index", "from", from, "head", number, "elapsed", common.PrettyDuration(time.Since(start)))
+		return nil
+	})
+}
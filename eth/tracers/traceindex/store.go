@@ -0,0 +1,547 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package traceindex maintains a persistent index of the flat call traces of
+// the canonical chain, so that the traces and internal value transfers of an
+// address can be queried without re-executing the blocks.
+package traceindex
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Database layout:
+//
+//	blockPrefix + num (uint64 big endian) -> block JSON (hash and flat traces)
+//	addressPrefix + address + num (uint64 big endian) + tx (uint32 big endian) + pos (uint32 big endian) -> nil
+//	transferPrefix + address + num (uint64 big endian) + tx (uint32 big endian) + pos (uint32 big endian) -> Transfer JSON
+//
+// The position of a trace is its index in the traces of the block. Both the
+// sender and the recipient of a trace or transfer have an entry.
+var (
+	blockPrefix    = []byte("b")
+	addressPrefix  = []byte("a")
+	transferPrefix = []byte("v")
+)
+
+// Location identifies a trace in the index.
+type Location struct {
+	Block    uint64 `json:"blockNumber"`
+	Tx       uint32 `json:"transactionPosition"`
+	Position uint32 `json:"tracePosition"` // Index of the trace in the traces of the block
+}
+
+// Transfer is a value transfer made by an internal call, creation or
+// selfdestruct. Transfers of reverted frames are not indexed.
+type Transfer struct {
+	BlockNumber  uint64         `json:"blockNumber"`
+	BlockHash    common.Hash    `json:"blockHash"`
+	TxHash       common.Hash    `json:"transactionHash"`
+	TxPosition   uint32         `json:"transactionPosition"`
+	TracePos     uint32         `json:"tracePosition"`
+	TraceAddress []int          `json:"traceAddress"`
+	Type         string         `json:"type"`
+	From         common.Address `json:"from"`
+	To           common.Address `json:"to"`
+	Value        *hexutil.Big   `json:"value"`
+}
+
+// indexedBlock is the stored form of an indexed block.
+type indexedBlock struct {
+	Hash   common.Hash       `json:"hash"`
+	Traces []json.RawMessage `json:"traces"`
+}
+
+// flatTrace holds the fields of a flat trace the index is built from.
+type flatTrace struct {
+	Action struct {
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`       // Destroyed account of selfdestructs
+		RefundAddress *common.Address `json:"refundAddress"` // Beneficiary of selfdestructs
+		Value         *hexutil.Big    `json:"value"`
+		Balance       *hexutil.Big    `json:"balance"` // Transferred balance of selfdestructs
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"` // Created account of creations
+	} `json:"result"`
+	Error               string       `json:"error"`
+	TraceAddress        []int        `json:"traceAddress"`
+	TransactionHash     *common.Hash `json:"transactionHash"`
+	TransactionPosition uint32       `json:"transactionPosition"`
+	Type                string       `json:"type"`
+}
+
+// parties returns the sender and recipient of a trace, either may be nil.
+func (t *flatTrace) parties() (from, to *common.Address) {
+	from, to = t.Action.From, t.Action.To
+	if from == nil {
+		from = t.Action.Address
+	}
+	if to == nil {
+		if t.Result != nil && t.Result.Address != nil {
+			to = t.Result.Address
+		} else {
+			to = t.Action.RefundAddress
+		}
+	}
+	return from, to
+}
+
+// value returns the value moved by a trace.
+func (t *flatTrace) value() *big.Int {
+	switch {
+	case t.Action.Value != nil:
+		return t.Action.Value.ToInt()
+	case t.Action.Balance != nil:
+		return t.Action.Balance.ToInt()
+	}
+	return new(big.Int)
+}
+
+// blockEntries are the index entries derived from the traces of a block.
+type blockEntries struct {
+	addresses []addressEntry
+	transfers []*Transfer
+}
+
+type addressEntry struct {
+	addr common.Address
+	loc  Location
+}
+
+// entries decodes the traces of a block into its index entries.
+func entries(number uint64, hash common.Hash, traces []json.RawMessage) (*blockEntries, error) {
+	var (
+		res    = new(blockEntries)
+		failed = make(map[string]bool) // Trace addresses of the failed frames of the current tx
+		lastTx = -1
+	)
+	for i, blob := range traces {
+		var trace flatTrace
+		if err := json.Unmarshal(blob, &trace); err != nil {
+			return nil, fmt.Errorf("trace %d of block %d: %w", i, number, err)
+		}
+		loc := Location{Block: number, Tx: trace.TransactionPosition, Position: uint32(i)}
+
+		from, to := trace.parties()
+		if from != nil {
+			res.addresses = append(res.addresses, addressEntry{*from, loc})
+		}
+		if to != nil && (from == nil || *to != *from) {
+			res.addresses = append(res.addresses, addressEntry{*to, loc})
+		}
+		// Track the failed frames of the transaction, nothing moved in their
+		// subtraces either
+		if int(trace.TransactionPosition) != lastTx {
+			lastTx, failed = int(trace.TransactionPosition), make(map[string]bool)
+		}
+		reverted := trace.Error != ""
+		for depth := 0; depth < len(trace.TraceAddress) && !reverted; depth++ {
+			reverted = failed[traceAddressKey(trace.TraceAddress[:depth])]
+		}
+		if reverted {
+			failed[traceAddressKey(trace.TraceAddress)] = true
+			continue
+		}
+		// The top level call is the transaction itself, only internal value
+		// transfers are indexed
+		if len(trace.TraceAddress) == 0 || from == nil || to == nil {
+			continue
+		}
+		value := trace.value()
+		if value.Sign() == 0 {
+			continue
+		}
+		transfer := &Transfer{
+			BlockNumber:  number,
+			BlockHash:    hash,
+			TxPosition:   trace.TransactionPosition,
+			TracePos:     uint32(i),
+			TraceAddress: trace.TraceAddress,
+			Type:         trace.Type,
+			From:         *from,
+			To:           *to,
+			Value:        (*hexutil.Big)(value),
+		}
+		if trace.TransactionHash != nil {
+			transfer.TxHash = *trace.TransactionHash
+		}
+		res.transfers = append(res.transfers, transfer)
+	}
+	return res, nil
+}
+
+func traceAddressKey(addr []int) string {
+	parts := make([]string, len(addr))
+	for i, n := range addr {
+		parts[i] = fmt.Sprint(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+func blockKey(number uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, blockPrefix...), number)
+}
+
+// locationKey returns the key of an address or transfer entry.
+func locationKey(prefix []byte, addr common.Address, loc Location) []byte {
+	key := make([]byte, 0, len(prefix)+common.AddressLength+16)
+	key = append(key, prefix...)
+	key = append(key, addr.Bytes()...)
+	key = binary.BigEndian.AppendUint64(key, loc.Block)
+	key = binary.BigEndian.AppendUint32(key, loc.Tx)
+	return binary.BigEndian.AppendUint32(key, loc.Position)
+}
+
+// decodeLocation decodes the location of an address or transfer entry key.
+func decodeLocation(prefix []byte, key []byte) (Location, bool) {
+	if len(key) != len(prefix)+common.AddressLength+16 {
+		return Location{}, false
+	}
+	key = key[len(prefix)+common.AddressLength:]
+	return Location{
+		Block:    binary.BigEndian.Uint64(key),
+		Tx:       binary.BigEndian.Uint32(key[8:]),
+		Position: binary.BigEndian.Uint32(key[12:]),
+	}, true
+}
+
+// Store is the key-value store of the trace index. The indexed blocks form a
+// contiguous range of the canonical chain.
+type Store struct {
+	db ethdb.KeyValueStore
+
+	lock       sync.RWMutex
+	tail, head uint64 // Range of indexed blocks, valid if indexed is set
+	indexed    bool
+}
+
+// NewStore creates a trace index store on top of db.
+func NewStore(db ethdb.KeyValueStore) *Store {
+	s := &Store{db: db}
+
+	it := db.NewIterator(blockPrefix, nil)
+	for it.Next() {
+		if len(it.Key()) != len(blockPrefix)+8 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(it.Key()[len(blockPrefix):])
+		if !s.indexed {
+			s.tail, s.indexed = number, true
+		}
+		s.head = number
+	}
+	it.Release()
+	return s
+}
+
+// Range returns the range of indexed blocks. The last return value is false
+// if nothing is indexed.
+func (s *Store) Range() (uint64, uint64, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tail, s.head, s.indexed
+}
+
+// BlockHash returns the hash of an indexed block, or the zero hash if the
+// block is not indexed.
+func (s *Store) BlockHash(number uint64) (common.Hash, error) {
+	block, err := s.block(number)
+	if err != nil || block == nil {
+		return common.Hash{}, err
+	}
+	return block.Hash, nil
+}
+
+// block returns an indexed block, or nil if the block is not indexed.
+func (s *Store) block(number uint64) (*indexedBlock, error) {
+	if ok, err := s.db.Has(blockKey(number)); !ok {
+		return nil, err
+	}
+	blob, err := s.db.Get(blockKey(number))
+	if err != nil {
+		return nil, err
+	}
+	block := new(indexedBlock)
+	if err := json.Unmarshal(blob, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// Write indexes the traces of a block. The block must extend the indexed
+// range at either end, or start a new range if nothing is indexed.
+func (s *Store) Write(number uint64, hash common.Hash, traces []json.RawMessage) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.indexed && number+1 != s.tail && number != s.head+1 {
+		return fmt.Errorf("block %d is not adjacent to the indexed range %d-%d", number, s.tail, s.head)
+	}
+	if traces == nil {
+		traces = []json.RawMessage{}
+	}
+	entries, err := entries(number, hash, traces)
+	if err != nil {
+		return err
+	}
+	blob, err := json.Marshal(&indexedBlock{Hash: hash, Traces: traces})
+	if err != nil {
+		return err
+	}
+	batch := s.db.NewBatch()
+	if err := batch.Put(blockKey(number), blob); err != nil {
+		return err
+	}
+	for _, entry := range entries.addresses {
+		if err := batch.Put(locationKey(addressPrefix, entry.addr, entry.loc), nil); err != nil {
+			return err
+		}
+	}
+	for _, transfer := range entries.transfers {
+		blob, err := json.Marshal(transfer)
+		if err != nil {
+			return err
+		}
+		loc := Location{Block: number, Tx: transfer.TxPosition, Position: transfer.TracePos}
+		if err := batch.Put(locationKey(transferPrefix, transfer.From, loc), blob); err != nil {
+			return err
+		}
+		if transfer.To != transfer.From {
+			if err := batch.Put(locationKey(transferPrefix, transfer.To, loc), blob); err != nil {
+				return err
+			}
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	switch {
+	case !s.indexed:
+		s.tail, s.head, s.indexed = number, number, true
+	case number < s.tail:
+		s.tail = number
+	default:
+		s.head = number
+	}
+	return nil
+}
+
+// Truncate removes the indexed blocks above the given block number, used to
+// drop the blocks of a reorged chain.
+func (s *Store) Truncate(number uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.indexed || number >= s.head {
+		return nil
+	}
+	from := number + 1
+	if from < s.tail {
+		from = s.tail
+	}
+	for n := s.head; n >= from; n-- {
+		if err := s.delete(n); err != nil {
+			return err
+		}
+		s.head = n - 1
+		if n == s.tail {
+			s.indexed = false
+			break
+		}
+	}
+	return nil
+}
+
+// Prune removes the indexed blocks below the given block number.
+func (s *Store) Prune(limit uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.indexed || limit <= s.tail {
+		return nil
+	}
+	var pruned int
+	for n := s.tail; n < limit; n++ {
+		if err := s.delete(n); err != nil {
+			return err
+		}
+		pruned++
+		s.tail = n + 1
+		if n == s.head {
+			s.indexed = false
+			break
+		}
+	}
+	log.Debug("Pruned trace index", "blocks", pruned, "below", limit)
+	return nil
+}
+
+// delete removes the entries of an indexed block.
+func (s *Store) delete(number uint64) error {
+	block, err := s.block(number)
+	if err != nil {
+		return err
+	}
+	if block == nil {
+		return nil
+	}
+	batch := s.db.NewBatch()
+	entries, err := entries(number, block.Hash, block.Traces)
+	if err != nil {
+		log.Warn("Dropping corrupted trace index entry", "number", number, "err", err)
+	} else {
+		for _, entry := range entries.addresses {
+			if err := batch.Delete(locationKey(addressPrefix, entry.addr, entry.loc)); err != nil {
+				return err
+			}
+		}
+		for _, transfer := range entries.transfers {
+			loc := Location{Block: number, Tx: transfer.TxPosition, Position: transfer.TracePos}
+			if err := batch.Delete(locationKey(transferPrefix, transfer.From, loc)); err != nil {
+				return err
+			}
+			if err := batch.Delete(locationKey(transferPrefix, transfer.To, loc)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := batch.Delete(blockKey(number)); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// Traces returns the traces of the indexed blocks in the inclusive range.
+// The traces of each block are passed to fn, which stops the iteration by
+// returning false.
+func (s *Store) Traces(from, to uint64, fn func(number uint64, traces []json.RawMessage) bool) error {
+	if from > to {
+		return errors.New("invalid block range")
+	}
+	it := s.db.NewIterator(blockPrefix, blockKey(from)[len(blockPrefix):])
+	defer it.Release()
+
+	for it.Next() {
+		if len(it.Key()) != len(blockPrefix)+8 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(it.Key()[len(blockPrefix):])
+		if number > to {
+			break
+		}
+		var block indexedBlock
+		if err := json.Unmarshal(it.Value(), &block); err != nil {
+			return err
+		}
+		if !fn(number, block.Traces) {
+			break
+		}
+	}
+	return it.Error()
+}
+
+// Trace returns the trace at the given location, or nil if it's not indexed.
+func (s *Store) Trace(loc Location) (json.RawMessage, error) {
+	block, err := s.block(loc.Block)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	if int(loc.Position) >= len(block.Traces) {
+		return nil, nil
+	}
+	return block.Traces[loc.Position], nil
+}
+
+// Locations returns the locations of the traces of an address within the
+// inclusive block range, in chain order. The iteration starts after the given
+// location if not nil, and returns at most limit locations.
+func (s *Store) Locations(addr common.Address, from, to uint64, after *Location, limit int) ([]Location, error) {
+	var locs []Location
+	err := s.iterate(addressPrefix, addr, from, to, after, limit, func(loc Location, _ []byte) error {
+		locs = append(locs, loc)
+		return nil
+	})
+	return locs, err
+}
+
+// Transfers returns the internal value transfers from or to an address within
+// the inclusive block range, in chain order. The iteration starts after the
+// given location if not nil, and returns at most limit transfers.
+func (s *Store) Transfers(addr common.Address, from, to uint64, after *Location, limit int) ([]*Transfer, error) {
+	var transfers []*Transfer
+	err := s.iterate(transferPrefix, addr, from, to, after, limit, func(_ Location, value []byte) error {
+		transfer := new(Transfer)
+		if err := json.Unmarshal(value, transfer); err != nil {
+			return err
+		}
+		transfers = append(transfers, transfer)
+		return nil
+	})
+	return transfers, err
+}
+
+// iterate calls fn with the entries of an address within the inclusive block
+// range, starting after the given location and stopping after limit entries.
+func (s *Store) iterate(prefix []byte, addr common.Address, from, to uint64, after *Location, limit int, fn func(Location, []byte) error) error {
+	if from > to {
+		return errors.New("invalid block range")
+	}
+	var (
+		addrPrefix = append(append([]byte{}, prefix...), addr.Bytes()...)
+		start      = binary.BigEndian.AppendUint64(nil, from)
+		count      int
+	)
+	if after != nil {
+		start = locationKey(nil, common.Address{}, *after)[common.AddressLength:]
+	}
+	it := s.db.NewIterator(addrPrefix, start)
+	defer it.Release()
+
+	for it.Next() && (limit <= 0 || count < limit) {
+		loc, ok := decodeLocation(prefix, it.Key())
+		if !ok {
+			continue
+		}
+		if after != nil && loc == *after {
+			continue
+		}
+		if loc.Block < from {
+			continue
+		}
+		if loc.Block > to {
+			break
+		}
+		if err := fn(loc, it.Value()); err != nil {
+			return err
+		}
+		count++
+	}
+	return it.Error()
+}
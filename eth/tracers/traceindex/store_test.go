@@ -0,0 +1,170 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package traceindex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/chainindex/chainindextest"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+var (
+	alice = common.HexToAddress("0xa1")
+	bob   = common.HexToAddress("0xb0")
+	carol = common.HexToAddress("0xc0")
+)
+
+// testTrace returns a flat call trace of the given transaction.
+func testTrace(tx int, traceAddress string, from, to common.Address, value int64, errMsg string) json.RawMessage {
+	trace := fmt.Sprintf(`{"action":{"callType":"call","from":"%s","to":"%s","value":"%#x"},"traceAddress":%s,"transactionHash":"%s","transactionPosition":%d,"type":"call"`,
+		from.Hex(), to.Hex(), value, traceAddress, common.BigToHash(big.NewInt(int64(tx+1))).Hex(), tx)
+	if errMsg != "" {
+		trace += fmt.Sprintf(`,"error":"%s"`, errMsg)
+	}
+	return json.RawMessage(trace + "}")
+}
+
+// testTraces returns the traces of a block: alice calls bob which sends value
+// to carol, then a second transaction whose internal transfer is reverted by
+// its parent frame.
+func testTraces() []json.RawMessage {
+	return []json.RawMessage{
+		testTrace(0, `[]`, alice, bob, 0, ""),
+		testTrace(0, `[0]`, bob, carol, 5, ""),
+		testTrace(1, `[]`, alice, bob, 0, ""),
+		testTrace(1, `[0]`, bob, alice, 0, "execution reverted"),
+		testTrace(1, `[0,0]`, alice, carol, 7, ""),
+	}
+}
+
+func TestStoreEntries(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(rawdb.NewMemoryDatabase())
+	hash := common.HexToHash("0x01")
+	if err := store.Write(10, hash, testTraces()); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Write(12, hash, nil); err == nil {
+		t.Fatal("expected error writing non-adjacent block")
+	}
+	locs, err := store.Locations(carol, 0, 100, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Location{{Block: 10, Tx: 0, Position: 1}, {Block: 10, Tx: 1, Position: 4}}
+	if fmt.Sprint(locs) != fmt.Sprint(want) {
+		t.Fatalf("wrong locations: have %v, want %v", locs, want)
+	}
+	// Pages start after the cursor
+	if locs, _ = store.Locations(carol, 0, 100, &want[0], 0); len(locs) != 1 || locs[0] != want[1] {
+		t.Fatalf("wrong locations after cursor: %v", locs)
+	}
+	// Only the transfer of the successful frame is indexed
+	transfers, err := store.Transfers(carol, 0, 100, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 1 || transfers[0].From != bob || transfers[0].Value.ToInt().Int64() != 5 || transfers[0].TracePos != 1 {
+		t.Fatalf("wrong transfers: %+v", transfers)
+	}
+	if transfers, _ = store.Transfers(bob, 0, 100, nil, 0); len(transfers) != 1 {
+		t.Fatalf("wrong sender transfers: %+v", transfers)
+	}
+	// Entries of removed blocks are gone
+	if err := store.Truncate(9); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := store.Range(); ok {
+		t.Fatal("expected empty index")
+	}
+	if locs, _ = store.Locations(carol, 0, 100, nil, 0); len(locs) != 0 {
+		t.Fatalf("expected no locations, got %v", locs)
+	}
+	if transfers, _ = store.Transfers(carol, 0, 100, nil, 0); len(transfers) != 0 {
+		t.Fatalf("expected no transfers, got %v", transfers)
+	}
+}
+
+// testBackend serves the blocks of a test chain to the indexer. The tracing
+// backend is embedded one level deeper, so the methods of the chain win.
+type testBackend struct {
+	*chainindextest.Chain
+	unimplementedBackend
+}
+
+type unimplementedBackend struct {
+	tracers.Backend
+}
+
+func TestIndexer(t *testing.T) {
+	t.Parallel()
+
+	backend := &testBackend{Chain: chainindextest.NewChain(8, 0)}
+	store := NewStore(rawdb.NewMemoryDatabase())
+	ix := NewIndexer(backend, store, 5)
+	ix.trace = func(ctx context.Context, block *types.Block) ([]json.RawMessage, error) {
+		return []json.RawMessage{testTrace(0, `[]`, alice, common.BytesToAddress(block.Extra()), 0, "")}, nil
+	}
+	checkRange := func(tail, head uint64) {
+		t.Helper()
+		if have, haveHead, ok := store.Range(); !ok || have != tail || haveHead != head {
+			t.Fatalf("wrong index range: have %d-%d (%v), want %d-%d", have, haveHead, ok, tail, head)
+		}
+		for n := tail; n <= head; n++ {
+			if hash, _ := store.BlockHash(n); hash != backend.Blocks[n].Hash() {
+				t.Fatalf("block %d: wrong hash %x", n, hash)
+			}
+		}
+	}
+	// An empty index starts at the head, backfills stop at the history
+	if err := ix.Sync(context.Background(), backend.CurrentHeader()); err != nil {
+		t.Fatal(err)
+	}
+	checkRange(7, 7)
+	if err := ix.Backfill(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	checkRange(3, 7)
+
+	// Reorged blocks are reindexed and the tail pruned
+	backend.Blocks = append(backend.Blocks[:6], chainindextest.NewBlocks(10, 1)[6:]...)
+	if err := ix.Sync(context.Background(), backend.CurrentHeader()); err != nil {
+		t.Fatal(err)
+	}
+	checkRange(5, 9)
+	if locs, _ := store.Locations(common.BytesToAddress([]byte{1}), 0, 100, nil, 0); len(locs) != 4 {
+		t.Fatalf("wrong number of reorged locations: %v", locs)
+	}
+	if locs, _ := store.Locations(common.BytesToAddress([]byte{0}), 0, 100, nil, 0); len(locs) != 1 {
+		t.Fatalf("wrong number of remaining locations: %v", locs)
+	}
+	// Rewinds drop the blocks above the head
+	backend.Blocks = backend.Blocks[:7]
+	if err := ix.Sync(context.Background(), backend.CurrentHeader()); err != nil {
+		t.Fatal(err)
+	}
+	checkRange(5, 6)
+}
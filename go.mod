@@ -61,7 +61,6 @@ require (
 	github.com/miguelmota/go-solidity-sha3 v0.1.1
 	github.com/naoina/toml v0.1.2-0.20170918210437-9fafd6967416
 	github.com/olekukonko/tablewriter v0.0.5
-	github.com/orcaman/concurrent-map v1.0.0
 	github.com/panjf2000/ants/v2 v2.4.5
 	github.com/peterh/liner v1.2.0
 	github.com/pkg/errors v0.9.1
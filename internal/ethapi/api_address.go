@@ -0,0 +1,137 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// addressTxPageSize is the number of transactions eth_getTransactionsByAddress
+// returns per page.
+const addressTxPageSize = 1000
+
+// AddressTxCursor is the position of the first transaction of a page of
+// eth_getTransactionsByAddress.
+type AddressTxCursor struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	Index       hexutil.Uint   `json:"transactionIndex"`
+}
+
+// AddressTransactions is a page of the transactions of an address. Next is the
+// cursor of the following page, nil if there are no more transactions.
+type AddressTransactions struct {
+	Transactions []*RPCTransaction `json:"transactions"`
+	Next         *AddressTxCursor  `json:"next"`
+}
+
+// GetTransactionsByAddress returns the transactions sent or received by an
+// address within the block range, oldest first. The range defaults to the
+// blocks covered by the address index up to the head, pages are continued by
+// passing the returned cursor. Contract creations are reported for the address
+// of the created contract.
+func (s *TransactionAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, fromBlock, toBlock *rpc.BlockNumber, cursor *AddressTxCursor) (*AddressTransactions, error) {
+	chain := s.b.Chain()
+	if chain == nil {
+		return nil, errors.New("address index is not available")
+	}
+	tail, ok, err := chain.AddressIndexTail()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, NewTxIndexingError()
+	}
+	from, err := s.resolveBlockNumber(ctx, fromBlock, tail)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.resolveBlockNumber(ctx, toBlock, s.b.CurrentHeader().Number.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if from < tail {
+		return nil, fmt.Errorf("block %d is below the address index tail %d", from, tail)
+	}
+	number, index := from, uint32(0)
+	if cursor != nil && uint64(cursor.BlockNumber) >= from {
+		number, index = uint64(cursor.BlockNumber), uint32(cursor.Index)
+	}
+	// Fetch one more transaction to know whether there is a next page
+	entries, err := chain.GetAddressTransactions(address, number, index, to, addressTxPageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &AddressTransactions{Transactions: []*RPCTransaction{}}
+	if len(entries) > addressTxPageSize {
+		next := entries[addressTxPageSize]
+		page.Next = &AddressTxCursor{BlockNumber: hexutil.Uint64(next.BlockNumber), Index: hexutil.Uint(next.Index)}
+		entries = entries[:addressTxPageSize]
+	}
+	var (
+		header *types.Header
+		body   *types.Body
+	)
+	for _, entry := range entries {
+		if header == nil || header.Number.Uint64() != entry.BlockNumber {
+			if header, err = s.b.HeaderByNumber(ctx, rpc.BlockNumber(entry.BlockNumber)); err != nil {
+				return nil, err
+			}
+			if header == nil {
+				return nil, fmt.Errorf("block #%d not found", entry.BlockNumber)
+			}
+			if body, err = s.b.GetBody(ctx, header.Hash(), rpc.BlockNumber(entry.BlockNumber)); err != nil {
+				return nil, err
+			}
+		}
+		// The chain may have been reorged since the lookup
+		if int(entry.Index) >= len(body.Transactions) || body.Transactions[entry.Index].Hash() != entry.Hash {
+			continue
+		}
+		tx := body.Transactions[entry.Index]
+		page.Transactions = append(page.Transactions, newRPCTransaction(tx, header.Hash(), entry.BlockNumber, header.Time, uint64(entry.Index), header.BaseFee, s.b.ChainConfig()))
+	}
+	return page, nil
+}
+
+// resolveBlockNumber resolves an optional block number of a range query, named
+// blocks resolving to their current number.
+func (s *TransactionAPI) resolveBlockNumber(ctx context.Context, number *rpc.BlockNumber, def uint64) (uint64, error) {
+	if number == nil {
+		return def, nil
+	}
+	if *number >= 0 {
+		return uint64(*number), nil
+	}
+	header, err := s.b.HeaderByNumber(ctx, *number)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block %v not found", *number)
+	}
+	return header.Number.Uint64(), nil
+}
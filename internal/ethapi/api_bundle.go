@@ -0,0 +1,337 @@
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/gopool"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// CallBundleArgs are the arguments of eth_callBundle.
+type CallBundleArgs struct {
+	Txs              []hexutil.Bytes        `json:"txs"`
+	StateBlockNumber *rpc.BlockNumberOrHash `json:"stateBlockNumber"` // Block the bundle is applied on top of, latest if nil
+	BlockNumber      *hexutil.Big           `json:"blockNumber"`      // Number of the simulated block, the state block's successor if nil
+	Coinbase         *common.Address        `json:"coinbase"`
+	Timestamp        *hexutil.Uint64        `json:"timestamp"`
+	GasLimit         *hexutil.Uint64        `json:"gasLimit"`
+	BaseFee          *hexutil.Big           `json:"baseFee"`
+	Timeout          *hexutil.Uint64        `json:"timeout"`      // Milliseconds, capped by the RPC EVM timeout, which applies if nil or 0
+	UnRevertible     []common.Hash          `json:"unRevertible"` // Transactions that fail the bundle if they revert
+}
+
+// BundleTxResult is the outcome of a single transaction of a simulated bundle.
+type BundleTxResult struct {
+	TxHash            common.Hash     `json:"txHash"`
+	From              common.Address  `json:"fromAddress"`
+	To                *common.Address `json:"toAddress"`
+	GasUsed           hexutil.Uint64  `json:"gasUsed"`
+	GasPrice          *hexutil.Big    `json:"gasPrice"`          // Effective gas price
+	GasFees           *hexutil.Big    `json:"gasFees"`           // Tips paid to the block producer
+	CoinbaseDiff      *hexutil.Big    `json:"coinbaseDiff"`      // Balance change of the block producer
+	EthSentToCoinbase *hexutil.Big    `json:"ethSentToCoinbase"` // Direct payments to the block producer
+	Value             hexutil.Bytes   `json:"value,omitempty"`
+	Error             string          `json:"error,omitempty"`
+	Revert            string          `json:"revert,omitempty"`
+}
+
+// BundleResult is the outcome of a simulated bundle.
+type BundleResult struct {
+	BundleHash        common.Hash      `json:"bundleHash"`
+	Results           []BundleTxResult `json:"results"`
+	CoinbaseDiff      *hexutil.Big     `json:"coinbaseDiff"`
+	GasFees           *hexutil.Big     `json:"gasFees"`
+	EthSentToCoinbase *hexutil.Big     `json:"ethSentToCoinbase"`
+	BundleGasPrice    *hexutil.Big     `json:"bundleGasPrice"` // Producer revenue per unit of gas
+	TotalGasUsed      hexutil.Uint64   `json:"totalGasUsed"`
+	StateBlockNumber  hexutil.Uint64   `json:"stateBlockNumber"`
+}
+
+// BundleNotification is a single message of the eth_subscribe("callBundleStream")
+// subscription. The transaction results are sent as they are executed, followed
+// by either the complete bundle result or the error that aborted the bundle.
+type BundleNotification struct {
+	Tx     *BundleTxResult `json:"tx,omitempty"`
+	Bundle *BundleResult   `json:"bundle,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// BundleAPI offers the dry-run of transaction bundles before they are sent to
+// a builder or validator.
+type BundleAPI struct {
+	b Backend
+}
+
+// NewBundleAPI creates a new BundleAPI.
+func NewBundleAPI(b Backend) *BundleAPI {
+	return &BundleAPI{b}
+}
+
+// CallBundle applies a bundle of signed transactions on top of a block and
+// returns the outcome of every transaction. The transactions are applied the
+// way the bid simulator of the miner applies the transactions of a bid, so a
+// bundle that passes here also passes there on the same state.
+//
+// Note, this function doesn't make any changes in the state/blockchain.
+func (s *BundleAPI) CallBundle(ctx context.Context, args CallBundleArgs) (*BundleResult, error) {
+	return s.callBundle(ctx, args, nil)
+}
+
+// CallBundleStream is the subscription flavour of CallBundle, streaming the
+// result of every transaction as soon as it has been executed.
+func (s *BundleAPI) CallBundleStream(ctx context.Context, args CallBundleArgs) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	gopool.Submit(func() {
+		// The simulation outlives the subscribe request, bind it to the subscription
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-rpcSub.Err():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		result, err := s.callBundle(ctx, args, func(res *BundleTxResult) {
+			notifier.Notify(rpcSub.ID, &BundleNotification{Tx: res})
+		})
+		if err != nil {
+			notifier.Notify(rpcSub.ID, &BundleNotification{Error: err.Error()})
+			return
+		}
+		notifier.Notify(rpcSub.ID, &BundleNotification{Bundle: result})
+	})
+	return rpcSub, nil
+}
+
+// callBundle executes the bundle, reporting each transaction result to onResult
+// if not nil.
+func (s *BundleAPI) callBundle(ctx context.Context, args CallBundleArgs, onResult func(*BundleTxResult)) (*BundleResult, error) {
+	if len(args.Txs) == 0 {
+		return nil, errors.New("bundle missing txs")
+	}
+	txs := make([]*types.Transaction, len(args.Txs))
+	for i, raw := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			return nil, fmt.Errorf("invalid tx %d: %v", i, err)
+		}
+		txs[i] = tx
+	}
+	unRevertible := make(map[common.Hash]bool, len(args.UnRevertible))
+	for _, hash := range args.UnRevertible {
+		unRevertible[hash] = true
+	}
+	// The client may shorten the execution, but not extend it past the server limit
+	timeout := s.b.RPCEVMTimeout()
+	if args.Timeout != nil && *args.Timeout > 0 {
+		if requested := time.Duration(*args.Timeout) * time.Millisecond; timeout == 0 || requested < timeout {
+			timeout = requested
+		}
+	}
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	stateBlock := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if args.StateBlockNumber != nil {
+		stateBlock = *args.StateBlockNumber
+	}
+	statedb, parent, err := s.b.StateAndHeaderByNumberOrHash(ctx, stateBlock)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	header, err := s.bundleHeader(args, parent)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		config   = s.b.ChainConfig()
+		gasLimit = header.GasLimit
+	)
+	// Leave room for the system transactions and the bid payment, like the bid simulator
+	if config.Parlia != nil {
+		if gasLimit < params.SystemTxsGas+params.PayBidTxGasLimit {
+			return nil, fmt.Errorf("gas limit %d too low", gasLimit)
+		}
+		gasLimit -= params.SystemTxsGas + params.PayBidTxGasLimit
+	}
+	if gasCap := s.b.RPCGasCap(); gasCap != 0 && gasLimit > gasCap {
+		log.Warn("Caller gas above allowance, capping", "requested", gasLimit, "cap", gasCap)
+		gasLimit = gasCap
+	}
+	var (
+		signer = types.MakeSigner(config, header.Number, header.Time)
+		gp     = new(core.GasPool).AddGas(gasLimit)
+		evm    = vm.NewEVM(core.NewEVMBlockContext(header, NewChainContext(ctx, s.b), &header.Coinbase), vm.TxContext{}, statedb, config, vm.Config{})
+		blobs  int
+
+		hashes = make([]byte, 0, len(txs)*common.HashLength)
+		result = &BundleResult{
+			Results:          make([]BundleTxResult, 0, len(txs)),
+			StateBlockNumber: hexutil.Uint64(parent.Number.Uint64()),
+		}
+		coinbaseDiff = new(big.Int)
+		gasFees      = new(big.Int)
+	)
+	// Abort the execution of the bundle once the timeout expires or the call is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	gopool.Submit(func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+		case <-done:
+		}
+	})
+	for i, tx := range txs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		msg, err := core.TransactionToMessage(tx, signer, header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("tx %d (%x): %w", i, tx.Hash(), err)
+		}
+		statedb.SetTxContext(tx.Hash(), i)
+		before := producerBalance(config, statedb, header.Coinbase)
+
+		receipt, execution, err := core.ApplyBidTransaction(config, gp, statedb, header, tx, blobs, unRevertible[tx.Hash()], evm)
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tx %d (%x): %w", i, tx.Hash(), err)
+		}
+		blobs += len(tx.BlobHashes())
+		hashes = append(hashes, tx.Hash().Bytes()...)
+
+		res := BundleTxResult{
+			TxHash:  tx.Hash(),
+			From:    msg.From,
+			To:      tx.To(),
+			GasUsed: hexutil.Uint64(receipt.GasUsed),
+		}
+		var (
+			price = msg.GasPrice
+			tip   = msg.GasPrice
+		)
+		if header.BaseFee != nil {
+			tip = new(big.Int).Sub(msg.GasPrice, header.BaseFee)
+		}
+		fees := new(big.Int).Mul(tip, new(big.Int).SetUint64(receipt.GasUsed))
+		diff := new(big.Int).Sub(producerBalance(config, statedb, header.Coinbase), before)
+		res.GasPrice = (*hexutil.Big)(price)
+		res.GasFees = (*hexutil.Big)(fees)
+		res.CoinbaseDiff = (*hexutil.Big)(diff)
+		res.EthSentToCoinbase = (*hexutil.Big)(new(big.Int).Sub(diff, fees))
+
+		if execution.Err != nil {
+			res.Error = execution.Err.Error()
+			if revert := execution.Revert(); len(revert) > 0 {
+				res.Revert = newRevertError(revert).Error()
+			}
+		} else {
+			res.Value = execution.Return()
+		}
+		coinbaseDiff.Add(coinbaseDiff, diff)
+		gasFees.Add(gasFees, fees)
+		result.TotalGasUsed += res.GasUsed
+		result.Results = append(result.Results, res)
+		if onResult != nil {
+			onResult(&res)
+		}
+	}
+	result.BundleHash = crypto.Keccak256Hash(hashes)
+	result.CoinbaseDiff = (*hexutil.Big)(coinbaseDiff)
+	result.GasFees = (*hexutil.Big)(gasFees)
+	result.EthSentToCoinbase = (*hexutil.Big)(new(big.Int).Sub(coinbaseDiff, gasFees))
+	result.BundleGasPrice = (*hexutil.Big)(new(big.Int))
+	if result.TotalGasUsed > 0 {
+		result.BundleGasPrice = (*hexutil.Big)(new(big.Int).Div(coinbaseDiff, new(big.Int).SetUint64(uint64(result.TotalGasUsed))))
+	}
+	log.Debug("Executed bundle", "hash", result.BundleHash, "txs", len(txs), "gas", result.TotalGasUsed, "coinbaseDiff", coinbaseDiff)
+	return result, nil
+}
+
+// bundleHeader creates the header of the block the bundle is simulated in, on
+// top of parent.
+func (s *BundleAPI) bundleHeader(args CallBundleArgs, parent *types.Header) (*types.Header, error) {
+	config := s.b.ChainConfig()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		Coinbase:   parent.Coinbase,
+		Difficulty: parent.Difficulty,
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + timestampIncrement,
+	}
+	if args.BlockNumber != nil {
+		header.Number = args.BlockNumber.ToInt()
+	}
+	if args.Coinbase != nil {
+		header.Coinbase = *args.Coinbase
+	}
+	if args.Timestamp != nil {
+		header.Time = uint64(*args.Timestamp)
+	}
+	if args.GasLimit != nil {
+		header.GasLimit = uint64(*args.GasLimit)
+	}
+	if header.Number.Cmp(parent.Number) <= 0 {
+		return nil, fmt.Errorf("block number %v not above state block %v", header.Number, parent.Number)
+	}
+	if config.IsLondon(header.Number) {
+		if args.BaseFee != nil {
+			header.BaseFee = args.BaseFee.ToInt()
+		} else {
+			header.BaseFee = eip1559.CalcBaseFee(config, parent)
+		}
+	}
+	if config.IsCancun(header.Number, header.Time) {
+		var excess uint64
+		if parent.ExcessBlobGas != nil && parent.BlobGasUsed != nil {
+			excess = eip4844.CalcExcessBlobGas(*parent.ExcessBlobGas, *parent.BlobGasUsed)
+		} else {
+			excess = eip4844.CalcExcessBlobGas(0, 0)
+		}
+		header.ExcessBlobGas = &excess
+		header.BlobGasUsed = new(uint64)
+	}
+	return header, nil
+}
+
+// producerBalance returns the balance of the block producer. Under Parlia the
+// transaction fees are collected by the system address and distributed at the
+// end of the block, so they are counted in.
+func producerBalance(config *params.ChainConfig, statedb *state.StateDB, coinbase common.Address) *big.Int {
+	balance := statedb.GetBalance(coinbase).ToBig()
+	if config.Parlia != nil && coinbase != consensus.SystemAddress {
+		balance.Add(balance, statedb.GetBalance(consensus.SystemAddress).ToBig())
+	}
+	return balance
+}
@@ -0,0 +1,47 @@
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// historicState returns the state of a block whose trie is no longer available
+// out of the state histories of a path-based database. err is the error the
+// regular state lookup failed with, it is returned as is if the block is not
+// known or the database keeps no state histories.
+func (s *BlockChainAPI) historicState(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, err error) (*state.HistoricState, error) {
+	header, herr := s.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || herr != nil {
+		return nil, err
+	}
+	chain := s.b.Chain()
+	if chain == nil || chain.TrieDB().Scheme() != rawdb.PathScheme {
+		return nil, err
+	}
+	historic, herr := state.NewHistoricState(header.Root, chain.StateCache())
+	if herr != nil {
+		return nil, fmt.Errorf("state of block %d is not available: %w", header.Number, herr)
+	}
+	return historic, nil
+}
+
+// errHistoricProof is returned by GetProof for a historic state. Its values
+// can be rebuilt out of the state histories, but not the trie nodes proving
+// them.
+var errHistoricProof = errors.New("proofs unavailable for historic state")
+
+// historicProof answers GetProof for a block whose trie is no longer available.
+// It fails with errHistoricProof if the state is historic rather than serving
+// the values without proofs, and with the error of the regular state lookup if
+// it is not.
+func (s *BlockChainAPI) historicProof(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, err error) (*AccountResult, error) {
+	if _, err := s.historicState(ctx, blockNrOrHash, err); err != nil {
+		return nil, err
+	}
+	return nil, errHistoricProof
+}
@@ -5,10 +5,12 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/gopool"
 	"github.com/ethereum/go-ethereum/paircache"
+	"github.com/ethereum/go-ethereum/paircache/amm"
 	"github.com/ethereum/go-ethereum/paircache/pairtypes"
 	solsha3 "github.com/miguelmota/go-solidity-sha3"
 	"math/big"
@@ -21,19 +23,32 @@ import (
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/gasestimator"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
 var LatestBlockNumber = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
 
+// minProfit 是triangle被认为有利润的最小收益
+var minProfit = big.NewInt(5000000)
+
+var (
+	ammQuotedMeter   = metrics.NewRegisteredMeter("pair/amm/quoted", nil)   // Triangles priced natively
+	ammFallbackMeter = metrics.NewRegisteredMeter("pair/amm/fallback", nil) // Triangles with unknown pools sent to the contract
+	ammSkippedMeter  = metrics.NewRegisteredMeter("pair/amm/skipped", nil)  // Triangles dropped without any eth_call
+)
+
 type Wei struct {
 	BitSize int
 	Data    string
 }
 
 type ROI struct {
-	Triangle pairtypes.Triangle
+	Route    pairtypes.Route
 	CallData string
 	Profit   big.Int
 }
@@ -114,27 +129,6 @@ func getRoisTest(s *BlockChainAPI, triangular *pairtypes.ITriangularArbitrageTri
 	}
 }
 
-func getRois(s *BlockChainAPI, triangular *pairtypes.ITriangularArbitrageTriangular, param *ArbitrageQueryParam, ctx context.Context) ([]*big.Int, error) {
-	data, _ := paircache.Encoder("arbitrageQuery", triangular, param.Start, param.End, param.Pieces)
-	bytes := hexutil.Bytes(data)
-	args := TransactionArgs{From: &paircache.From, To: &paircache.To, Data: &bytes}
-	call, err := s.FlagCall(ctx, args, &LatestBlockNumber, nil, nil)
-	if err != nil {
-		return nil, err
-	} else {
-		roiStr := hex.EncodeToString(call)
-		lenth := len(roiStr) / 64
-		rois := make([]*big.Int, lenth-2)
-		for j := 0; j < lenth; j++ {
-			if j > 1 {
-				roi, _ := new(big.Int).SetString(roiStr[64*j:64*(j+1)], 16)
-				rois[j-2] = roi
-			}
-		}
-		return rois, err
-	}
-}
-
 func getArbitrageQueryParam(start *big.Int, index, step int) *ArbitrageQueryParam {
 	if index >= 10 {
 		index = 9
@@ -197,11 +191,10 @@ func SubmitTestCall(wg *sync.WaitGroup, s *BlockChainAPI, results chan interface
 	})
 }
 
-func SubmitCall(wg *sync.WaitGroup, s *BlockChainAPI, results chan interface{}, triangle *pairtypes.Triangle) {
-	t := *triangle
+func SubmitCall(ctx context.Context, wg *sync.WaitGroup, s *BlockChainAPI, results chan interface{}, route pairtypes.Route, stateAt pairState) {
 	gopool.Submit(func() {
 		defer wg.Done()
-		pairWorker(s, results, t)
+		pairWorker(ctx, s, results, route, stateAt)
 	})
 }
 
@@ -212,11 +205,83 @@ func FlagDoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrH
 	if state == nil || err != nil {
 		return nil, err
 	}
-	state.Flag = 1
+	useSharedCache(b, state)
 
 	return doCall(ctx, b, args, state, header, overrides, blockOverrides, timeout, globalGasCap)
 }
 
+// pairState returns the state arbitrage routes are evaluated against. Every call
+// returns a state of its own which the caller is free to modify.
+type pairState func(ctx context.Context) (*state.StateDB, *types.Header, error)
+
+// blockPairState returns the states of an imported block, read through the
+// shared state cache.
+func (s *BlockChainAPI) blockPairState(blockNrOrHash rpc.BlockNumberOrHash) pairState {
+	return func(ctx context.Context) (*state.StateDB, *types.Header, error) {
+		statedb, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		if statedb == nil {
+			return nil, nil, fmt.Errorf("state of block %v not available", blockNrOrHash)
+		}
+		useSharedCache(s.b, statedb)
+		return statedb, header, nil
+	}
+}
+
+// useSharedCache attaches the chain's shared state cache to statedb. It only
+// takes effect if statedb is on top of the current head.
+func useSharedCache(b Backend, statedb *state.StateDB) {
+	if chain := b.Chain(); chain != nil {
+		statedb.UseSharedCache(chain.SharedStateCache())
+	}
+}
+
+// pairCall executes a call against a state returned by stateAt.
+func (s *BlockChainAPI) pairCall(ctx context.Context, args TransactionArgs, stateAt pairState) (hexutil.Bytes, error) {
+	statedb, header, err := stateAt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := doCall(ctx, s.b, args, statedb, header, nil, nil, s.b.RPCEVMTimeout(), s.b.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
+	// If the result contains a revert reason, try to unpack and return it.
+	if len(result.Revert()) > 0 {
+		return nil, newRevertError(result.Revert())
+	}
+	return result.Return(), result.Err
+}
+
+// pairEstimateGas estimates the gas of a call against a state returned by stateAt.
+func (s *BlockChainAPI) pairEstimateGas(ctx context.Context, args TransactionArgs, stateAt pairState) (hexutil.Uint64, error) {
+	statedb, header, err := stateAt(ctx)
+	if err != nil {
+		return 0, err
+	}
+	opts := &gasestimator.Options{
+		Config:     s.b.ChainConfig(),
+		Chain:      NewChainContext(ctx, s.b),
+		Header:     header,
+		State:      statedb,
+		ErrorRatio: estimateGasErrorRatio,
+	}
+	call, err := args.ToMessage(s.b.RPCGasCap(), header.BaseFee)
+	if err != nil {
+		return 0, err
+	}
+	estimate, revert, err := gasestimator.Estimate(ctx, call, opts, s.b.RPCGasCap())
+	if err != nil {
+		if len(revert) > 0 {
+			return 0, newRevertError(revert)
+		}
+		return 0, err
+	}
+	return hexutil.Uint64(estimate), nil
+}
+
 func (s *BlockChainAPI) FlagCall(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (hexutil.Bytes, error) {
 	if blockNrOrHash == nil {
 		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
@@ -337,7 +402,7 @@ func workerDirect(s *BlockChainAPI, results chan<- interface{}, triangle pairtyp
 	}
 
 	ROI := &ROI{
-		Triangle: triangle,
+		Route:    triangle.Route(),
 		CallData: calldata,
 		Profit:   *roi13,
 	}
@@ -447,7 +512,7 @@ func workerTest(s *BlockChainAPI, results chan<- interface{}, triangle pairtypes
 	}
 
 	ROI := &ROI{
-		Triangle: triangle,
+		Route:    triangle.Route(),
 		CallData: calldata,
 		Profit:   *rois[13],
 	}
@@ -456,53 +521,53 @@ func workerTest(s *BlockChainAPI, results chan<- interface{}, triangle pairtypes
 	return
 }
 
-func pairWorker(s *BlockChainAPI, results chan<- interface{}, triangle pairtypes.Triangle) {
-	// 设置上下文，用于控制每个任务方法执行超时时间
-	ctx := context.Background()
-	triangular := &pairtypes.ITriangularArbitrageTriangular{
-		Token0:  common.HexToAddress(triangle.Token0),
-		Router0: common.HexToAddress(triangle.Router0),
-		Pair0:   common.HexToAddress(triangle.Pair0),
-		Token1:  common.HexToAddress(triangle.Token1),
-		Router1: common.HexToAddress(triangle.Router1),
-		Pair1:   common.HexToAddress(triangle.Pair1),
-		Token2:  common.HexToAddress(triangle.Token2),
-		Router2: common.HexToAddress(triangle.Router2),
-		Pair2:   common.HexToAddress(triangle.Pair2),
+// queryRoute runs the arbitrage grid query of a route over the given ratio range.
+func queryRoute(ctx context.Context, s *BlockChainAPI, route pairtypes.Route, param *ArbitrageQueryParam, stateAt pairState) (*paircache.QueryResult, error) {
+	data, err := paircache.EncodeQuery(route, param.Start, param.End, param.Pieces)
+	if err != nil {
+		return nil, err
 	}
-
-	param := getArbitrageQueryParam(big.NewInt(0), 0, 10000)
-	rois, err := getRois(s, triangular, param, ctx)
+	bytes := hexutil.Bytes(data)
+	args := TransactionArgs{From: &paircache.From, To: &paircache.To, Data: &bytes}
+	call, err := s.pairCall(ctx, args, stateAt)
 	if err != nil {
-		results <- err
-		return
+		return nil, err
 	}
-
-	index := resolveROI(rois)
-	param = getArbitrageQueryParam(param.Start, index, 1000)
-	rois, err = getRois(s, triangular, param, ctx)
+	result, err := paircache.DecodeQuery(route, call)
 	if err != nil {
-		results <- err
-		return
+		return nil, err
 	}
-	index = resolveROI(rois)
+	// Remember the executors for the routes priced natively later on
+	paircache.RecordExecutors(paircache.GetAMMRegistry(), route, result)
+	return result, nil
+}
 
-	param = getArbitrageQueryParam(param.Start, index, 100)
-	rois, err = getRois(s, triangular, param, ctx)
-	if err != nil {
-		results <- err
+func pairWorker(ctx context.Context, s *BlockChainAPI, results chan<- interface{}, route pairtypes.Route, stateAt pairState) {
+	fail := func(err error) {
+		results <- &pairtypes.RouteError{Route: route.ID, Err: err}
+	}
+	// 评估被取消时不再发起新的eth_call
+	if err := ctx.Err(); err != nil {
+		fail(err)
 		return
 	}
-	index = resolveROI(rois)
 
-	param = getArbitrageQueryParam(param.Start, index, 10)
-	rois, err = getRois(s, triangular, param, ctx)
+	// 逐级缩小比例区间：每次将区间切分为10份，取第一个无利润采样点所在的区间继续查询
+	param := getArbitrageQueryParam(big.NewInt(0), 0, 10000)
+	for _, step := range []int{1000, 100, 10} {
+		result, err := queryRoute(ctx, s, route, param, stateAt)
+		if err != nil {
+			fail(err)
+			return
+		}
+		param = getArbitrageQueryParam(param.Start, result.FirstEmpty(), step)
+	}
+	result, err := queryRoute(ctx, s, route, param, stateAt)
 	if err != nil {
-		results <- err
+		fail(err)
 		return
 	}
-	index = resolveROI(rois)
-	point := new(big.Int).Add(param.Start, big.NewInt(int64(index)))
+	point := new(big.Int).Add(param.Start, big.NewInt(int64(result.FirstEmpty())))
 	if point.Cmp(big.NewInt(0)) == 0 {
 		results <- nil
 		return
@@ -511,56 +576,119 @@ func pairWorker(s *BlockChainAPI, results chan<- interface{}, triangle pairtypes
 	param.End = point
 	param.Pieces = big.NewInt(1)
 
-	rois, err = getRois(s, triangular, param, ctx)
+	result, err = queryRoute(ctx, s, route, param, stateAt)
 	if err != nil {
-		results <- err
+		fail(err)
 		return
 	}
-
-	if rois == nil || rois[13] == nil || rois[13].Cmp(big.NewInt(5000000)) < 0 {
+	if len(result.Points) == 0 || result.Points[0].Profit.Cmp(minProfit) < 0 {
 		results <- nil
 		return
 	}
 
-	snapshotsHash := solsha3.SoliditySHA3(solsha3.Int256(rois[3]), solsha3.Int256(rois[4]), solsha3.Int256(rois[5]))
-	subHex := hex.EncodeToString(snapshotsHash)[0:2]
-
-	parameters := []interface{}{
-		hex.EncodeToString(solsha3.Uint32(big.NewInt(0))),
-		subHex,
-		common.BigToAddress(rois[0]),
-		getWei(rois[6], 96),
-		common.BigToAddress(rois[1]),
-		getWei(rois[7], 96),
-		common.BigToAddress(rois[2]),
-		getWei(rois[10], 96),
-		triangular.Token0,
-		getWei(rois[11], 96),
-		triangular.Pair0,
-		getWei(rois[12], 96),
-		triangular.Token1,
-		getWei(rois[13], 96),
-		triangular.Pair1,
-		triangular.Token2,
-		triangular.Pair2,
-	}
-
-	calldata, err := EncodePackedBsc(parameters)
+	calldata, err := paircache.EncodeExecution(route, result, 0)
 	if err != nil {
-		results <- err
+		fail(err)
 		return
 	}
 
 	ROI := &ROI{
-		Triangle: triangle,
-		CallData: calldata,
-		Profit:   *rois[13],
+		Route:    route,
+		CallData: hex.EncodeToString(calldata),
+		Profit:   *result.Points[0].Profit,
 	}
 
 	results <- ROI
 	return
 }
 
+// quoteRoutes prices the routes natively against the evaluated state. Routes
+// whose pools are all modelled are settled without any eth_call: the ones that
+// reach minProfit are sent to results along with their execution calldata and
+// returned as quoted, the others are dropped. Only the three hop routes with
+// pools unknown to the AMM registry, or with routers whose executor no contract
+// query reported yet, are returned for the contract grid search, the deployed
+// contract can't query routes of other lengths.
+func (s *BlockChainAPI) quoteRoutes(ctx context.Context, stateAt pairState, routes []pairtypes.Route, results chan<- interface{}) (quoted, fallback []pairtypes.Route) {
+	statedb, _, err := stateAt(ctx)
+	if err != nil {
+		log.Warn("Failed to load state for native route pricing", "err", err)
+		return nil, routes
+	}
+	quoter := amm.NewQuoter(paircache.GetAMMRegistry(), statedb)
+
+	for _, route := range routes {
+		quote, err := quoter.QuoteRoute(route)
+		switch {
+		case errors.Is(err, amm.ErrUnknownPool) && len(route.Hops) != 3:
+			ammSkippedMeter.Mark(1)
+			log.Debug("Failed to price route natively", "id", route.ID, "err", err)
+		case errors.Is(err, amm.ErrUnknownPool):
+			ammFallbackMeter.Mark(1)
+			fallback = append(fallback, route)
+		case err != nil:
+			ammSkippedMeter.Mark(1)
+			log.Debug("Failed to price route natively", "id", route.ID, "err", err)
+		case quote.Profit.Cmp(minProfit) < 0:
+			ammQuotedMeter.Mark(1)
+			ammSkippedMeter.Mark(1)
+		default:
+			ammQuotedMeter.Mark(1)
+			result, err := paircache.QuoteResult(paircache.GetAMMRegistry(), route, quote)
+			if errors.Is(err, paircache.ErrUnknownExecutor) && len(route.Hops) == 3 {
+				ammFallbackMeter.Mark(1)
+				fallback = append(fallback, route)
+				continue
+			}
+			log.Debug("Route profitable in native pricing", "id", route.ID, "amountIn", quote.AmountIn, "profit", quote.Profit)
+			quoted = append(quoted, route)
+			results <- quoteROI(route, quote, result, err)
+		}
+	}
+	return quoted, fallback
+}
+
+// quoteROI encodes the execution of a natively priced route at its optimal
+// input, out of the query result converted from the quote.
+func quoteROI(route pairtypes.Route, quote *amm.Quote, result *paircache.QueryResult, err error) interface{} {
+	if err != nil {
+		return &pairtypes.RouteError{Route: route.ID, Err: err}
+	}
+	calldata, err := paircache.EncodeExecution(route, result, 0)
+	if err != nil {
+		return &pairtypes.RouteError{Route: route.ID, Err: err}
+	}
+	return &ROI{
+		Route:    route,
+		CallData: hex.EncodeToString(calldata),
+		Profit:   *quote.Profit,
+	}
+}
+
+// selectROIs keeps the most profitable ROIs such that no pool is used by more
+// than one of them. The input must be sorted by descending profit.
+func selectROIs(rois []ROI) []ROI {
+	uniquePairs := make(map[common.Address]bool)
+	var filteredROIs []ROI
+	for _, roi := range rois {
+		pairs := roi.Route.Pairs()
+		used := false
+		for _, pair := range pairs {
+			used = used || uniquePairs[pair]
+		}
+		if used {
+			// 如果任何一个 pair 已经出现过，跳过该结构体（删除）
+			continue
+		}
+		// 如果不存在，则将该结构体加入结果集，并标记 pairs 为已出现
+		filteredROIs = append(filteredROIs, roi)
+		for _, pair := range pairs {
+			uniquePairs[pair] = true
+		}
+	}
+	return filteredROIs
+}
+
 func (s *BlockChainAPI) CallBatch() (string, error) {
 	// 读取任务测试数据
 	log.Info("开始执行CallBatch")
@@ -622,21 +750,7 @@ func (s *BlockChainAPI) CallBatch() (string, error) {
 		log.Info("降序排序rois成功", "rois", rois)
 
 		// 将排序后的rois去重过滤，保证每个pair只能出现一次，重复时将Profit较小的ROI都删除，只保留Profit最大的ROI
-		// 去重，保证 Pair0, Pair1, Pair2 中的值只出现一次
-		uniquePairs := make(map[string]bool)
-		var filteredROIs []ROI
-		for _, roi := range rois {
-			if uniquePairs[roi.Triangle.Pair0] || uniquePairs[roi.Triangle.Pair1] || uniquePairs[roi.Triangle.Pair2] {
-				// 如果任何一个 pair 已经出现过，跳过该结构体（删除）
-				continue
-			}
-
-			// 如果不存在，则将该结构体加入结果集，并标记 pairs 为已出现
-			filteredROIs = append(filteredROIs, roi)
-			uniquePairs[roi.Triangle.Pair0] = true
-			uniquePairs[roi.Triangle.Pair1] = true
-			uniquePairs[roi.Triangle.Pair2] = true
-		}
+		filteredROIs := selectROIs(rois)
 		log.Info("排序去重获rois成功", "filteredROIs", filteredROIs)
 
 		// 计算预估总gas
@@ -674,40 +788,64 @@ func (s *BlockChainAPI) CallBatch() (string, error) {
 	return "ok", nil
 }
 
-// PairCallBatch executes Call
-func (s *BlockChainAPI) PairCallBatch(triangles []pairtypes.Triangle) error {
+// PairCallBatch evaluates the given routes on top of the state of the head
+// block. The returned opportunities are the profitable routes, most profitable
+// first and with no two sharing a pair. All in-flight calls are aborted once ctx
+// is cancelled.
+func (s *BlockChainAPI) PairCallBatch(ctx context.Context, head common.Hash, routes []pairtypes.Route) (*pairtypes.BatchResult, error) {
+	return s.pairCallBatch(ctx, head, s.blockPairState(rpc.BlockNumberOrHashWithHash(head, false)), routes)
+}
+
+// pairCallBatch evaluates the given routes against the states returned by
+// stateAt, head is the block the states are derived from.
+func (s *BlockChainAPI) pairCallBatch(ctx context.Context, head common.Hash, stateAt pairState, routes []pairtypes.Route) (*pairtypes.BatchResult, error) {
 	// 初始化构造当前区块公共数据
 	start := time.Now()
-	log.Info("开始执行PairCallBatch")
-	results := make(chan interface{}, len(triangles))
+	log.Info("开始执行PairCallBatch", "head", head)
+	results := make(chan interface{}, len(routes))
+	quoted, fallback := s.quoteRoutes(ctx, stateAt, routes, results)
+	candidates := append(quoted, fallback...)
+	batch := &pairtypes.BatchResult{
+		Screened: len(routes) - len(candidates),
+		Quoted:   len(quoted),
+	}
 
-	// 提交任务到协程池，所有协程完成后关闭结果读取通道
+	// 只有包含未知 pool 的 route 提交到协程池经合约评估，所有协程完成后关闭结果读取通道
 	var wg sync.WaitGroup
-	for _, triangle := range triangles {
+	for _, route := range fallback {
 		wg.Add(1)
-		SubmitCall(&wg, s, results, &triangle)
+		SubmitCall(ctx, &wg, s, results, route, stateAt)
 	}
 	wg.Wait()
 	close(results)
 	selectSince := time.Since(start)
-	log.Info("所有eth_call查询任务执行完成花费时长", "runtime", selectSince, "所在的区块号", s.BlockNumber())
+	batch.QueryTime = selectSince
+	log.Info("所有eth_call查询任务执行完成花费时长", "runtime", selectSince, "head", head)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	// 读取任务结果通道数据进行处理
+	// 读取任务结果通道数据进行处理，没有利润的 route 不会返回自身信息，
+	// 先按 route 记录为无利润，再用有利润和失败的结果覆盖
 	rois := make([]ROI, 0, 5000)
-	resultMap := make(map[string]interface{}, len(triangles))
-	i := 1
+	index := make(map[int64]int, len(candidates))
+	for _, route := range candidates {
+		if _, ok := index[route.ID]; !ok {
+			index[route.ID] = len(batch.Results)
+			batch.Results = append(batch.Results, pairtypes.RouteResult{Route: route.ID})
+		}
+	}
 	// 处理结果
 	for result := range results {
-		itoa := strconv.Itoa(i)
 		switch v := result.(type) {
 		case *ROI:
 			rois = append(rois, *v)
-		case error:
-			resultMap[itoa] = v.Error()
-		default:
-			resultMap[itoa] = v
+			batch.Results[index[v.Route.ID]].Profit = new(big.Int).Set(&v.Profit)
+		case *pairtypes.RouteError:
+			// Routes the contract can't query or execute are expected to fail
+			log.Debug("Failed to evaluate route", "id", v.Route, "err", v.Err)
+			batch.Results[index[v.Route]].Error = v.Err.Error()
 		}
-		i += 1
 	}
 
 	if len(rois) > 0 {
@@ -719,42 +857,45 @@ func (s *BlockChainAPI) PairCallBatch(triangles []pairtypes.Triangle) error {
 		log.Info("降序排序rois成功", "rois", rois)
 
 		// 将排序后的rois去重过滤，保证每个pair只能出现一次，重复时将Profit较小的ROI都删除，只保留Profit最大的ROI
-		// 去重，保证 Pair0, Pair1, Pair2 中的值只出现一次
-		uniquePairs := make(map[string]bool)
-		var filteredROIs []ROI
-		for _, roi := range rois {
-			if uniquePairs[roi.Triangle.Pair0] || uniquePairs[roi.Triangle.Pair1] || uniquePairs[roi.Triangle.Pair2] {
-				// 如果任何一个 pair 已经出现过，跳过该结构体（删除）
-				continue
-			}
-
-			// 如果不存在，则将该结构体加入结果集，并标记 pairs 为已出现
-			filteredROIs = append(filteredROIs, roi)
-			uniquePairs[roi.Triangle.Pair0] = true
-			uniquePairs[roi.Triangle.Pair1] = true
-			uniquePairs[roi.Triangle.Pair2] = true
-		}
+		filteredROIs := selectROIs(rois)
 		log.Info("排序去重获rois成功", "filteredROIs", filteredROIs)
 
 		// 计算预估总gas
+		estimateStart := time.Now()
 		var gasTotal hexutil.Uint64
 		for _, filteredROI := range filteredROIs {
 			decodeString, _ := hex.DecodeString(filteredROI.CallData)
 			bytes := hexutil.Bytes(decodeString)
 			args := TransactionArgs{From: &paircache.From, To: &paircache.To, Data: &bytes}
-			gas, err := s.EstimateGas(context.Background(), args, &LatestBlockNumber, nil)
+			gas, err := s.pairEstimateGas(ctx, args, stateAt)
 			if err != nil {
-				log.Error("存在roi的预估gas计算异常", "err", err)
+				// The quoted state may have moved on, the execution is expected to revert then
+				log.Debug("Failed to estimate arbitrage gas", "id", filteredROI.Route.ID, "err", err)
 			}
 			gasTotal = gasTotal + gas
+
+			result := &batch.Results[index[filteredROI.Route.ID]]
+			result.Chosen = true
+			result.Gas = uint64(gas)
+			if err != nil {
+				result.Error = err.Error()
+			}
+			batch.Opportunities = append(batch.Opportunities, pairtypes.Opportunity{
+				Route:    filteredROI.Route,
+				To:       paircache.To,
+				CallData: decodeString,
+				Profit:   new(big.Int).Set(&filteredROI.Profit),
+				Gas:      uint64(gas),
+			})
 		}
+		batch.EstimateTime = time.Since(estimateStart)
 		log.Info("计算预估总gas成功", "gasTotal", gasTotal)
 	}
 
 	totalSince := time.Since(start)
 	log.Info("处理结果完成", "共耗时", totalSince)
 
-	return nil
+	return batch, nil
 }
 
 func GetEthCallData() ([]CallBatchArgs, error) {
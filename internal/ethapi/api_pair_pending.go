@@ -0,0 +1,108 @@
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// pendingState is the state after a pending transaction was executed on top of
+// the head block. It implements pairtypes.PendingState.
+type pendingState struct {
+	api     *BlockChainAPI
+	head    common.Hash
+	header  *types.Header // Header of the block the transaction is simulated in
+	logs    []*types.Log
+	gasUsed uint64
+
+	lock  sync.Mutex // Protects the copying of the post state by concurrent evaluations
+	state *state.StateDB
+}
+
+// SimulatePending executes a pending transaction on top of the state of the
+// head block, as the first transaction of the next block. A transaction that
+// reverts is not an error, it simply leaves no logs behind.
+func (s *BlockChainAPI) SimulatePending(ctx context.Context, head common.Hash, tx *types.Transaction) (pairtypes.PendingState, error) {
+	statedb, parent, err := s.b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(head, false))
+	if err != nil {
+		return nil, err
+	}
+	if statedb == nil {
+		return nil, fmt.Errorf("state of block %x not available", head)
+	}
+	useSharedCache(s.b, statedb)
+	config := s.b.ChainConfig()
+	header := &types.Header{
+		ParentHash: head,
+		Coinbase:   parent.Coinbase,
+		Difficulty: parent.Difficulty,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       uint64(time.Now().Unix()),
+	}
+	if header.Time <= parent.Time {
+		header.Time = parent.Time + 1
+	}
+	if config.IsLondon(header.Number) {
+		header.BaseFee = eip1559.CalcBaseFee(config, parent)
+	}
+	msg, err := core.TransactionToMessage(tx, types.MakeSigner(config, header.Number, header.Time), header.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	statedb.SetTxContext(tx.Hash(), 0)
+	blockCtx := core.NewEVMBlockContext(header, NewChainContext(ctx, s.b), nil)
+	evm := s.b.GetEVM(ctx, msg, statedb, header, &vm.Config{}, &blockCtx)
+
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(header.GasLimit))
+	if err != nil {
+		return nil, err
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
+	statedb.Finalise(config.IsEIP158(header.Number))
+
+	return &pendingState{
+		api:     s,
+		head:    head,
+		header:  header,
+		logs:    statedb.GetLogs(tx.Hash(), header.Number.Uint64(), common.Hash{}),
+		gasUsed: result.UsedGas,
+		state:   statedb,
+	}, nil
+}
+
+// Logs returns the logs emitted by the pending transaction.
+func (p *pendingState) Logs() []*types.Log {
+	return p.logs
+}
+
+// GasUsed returns the gas used by the pending transaction.
+func (p *pendingState) GasUsed() uint64 {
+	return p.gasUsed
+}
+
+// PairCallBatch evaluates the given routes against the state after the pending
+// transaction, the opportunities are meant to be executed right behind it.
+func (p *pendingState) PairCallBatch(ctx context.Context, routes []pairtypes.Route) (*pairtypes.BatchResult, error) {
+	return p.api.pairCallBatch(ctx, p.head, p.stateAt, routes)
+}
+
+func (p *pendingState) stateAt(ctx context.Context) (*state.StateDB, *types.Header, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.state.Copy(), p.header, nil
+}
@@ -998,7 +998,7 @@ func TestCall(t *testing.T) {
 		},
 	}
 	for i, tc := range testSuite {
-		result, err := api.Call(context.Background(), tc.call, &rpc.BlockNumberOrHash{BlockNumber: &tc.blockNumber}, &tc.overrides, &tc.blockOverrides)
+		result, err := api.Call(context.Background(), tc.call, &rpc.BlockNumberOrHash{BlockNumber: &tc.blockNumber}, &tc.overrides, &tc.blockOverrides, nil)
 		if tc.expectErr != nil {
 			if err == nil {
 				t.Errorf("test %d: want error %v, have nothing", i, tc.expectErr)
@@ -1016,12 +1016,223 @@ func TestCall(t *testing.T) {
 			t.Errorf("test %d: want no error, have %v", i, err)
 			continue
 		}
-		if !reflect.DeepEqual(result.String(), tc.want) {
-			t.Errorf("test %d, result mismatch, have\n%v\n, want\n%v\n", i, result.String(), tc.want)
+		if output := result.(hexutil.Bytes); !reflect.DeepEqual(output.String(), tc.want) {
+			t.Errorf("test %d, result mismatch, have\n%v\n, want\n%v\n", i, output.String(), tc.want)
 		}
 	}
 }
 
+func TestCallWithStateDiff(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(2)
+		genesis  = &core.Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: types.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		contract = common.HexToAddress("0xc0ffee")
+	)
+	api := NewBlockChainAPI(newTestBackend(t, 1, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		b.SetPoS()
+	}))
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	overrides := StateOverride{
+		// sstore(0, 0x2a), the override itself is not part of the diff
+		contract: {Code: hex2Bytes("602a600055")},
+	}
+	res, err := api.Call(context.Background(), TransactionArgs{
+		From:  &accounts[0].addr,
+		To:    &contract,
+		Value: (*hexutil.Big)(big.NewInt(1000)),
+	}, &latest, &overrides, nil, &CallConfig{StateDiff: true})
+	if err != nil {
+		t.Fatalf("failed to call: %v", err)
+	}
+	result := res.(*CallStateDiffResult)
+	if len(result.StateDiff) != 2 {
+		t.Fatalf("diff size mismatch: have %d, want 2", len(result.StateDiff))
+	}
+	sender := result.StateDiff[accounts[0].addr]
+	if sender == nil || sender.Nonce == nil || sender.Nonce.From != 0 || sender.Nonce.To != 1 {
+		t.Fatalf("unexpected sender diff: %+v", sender)
+	}
+	callee := result.StateDiff[contract]
+	if callee == nil || callee.Code != nil || callee.Balance == nil || (*uint256.Int)(callee.Balance.To).Uint64() != 1000 {
+		t.Fatalf("unexpected contract diff: %+v", callee)
+	}
+	want := state.StorageDiff{To: common.BigToHash(big.NewInt(0x2a))}
+	if slot := callee.Storage[common.Hash{}]; slot != want {
+		t.Fatalf("storage diff mismatch: have %+v, want %+v", slot, want)
+	}
+}
+
+func TestSimulateV1(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(2)
+		genesis  = &core.Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: types.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		genBlocks = 2
+		emitter   = common.HexToAddress("0xc000000000000000000000000000000000000000")
+		reverter  = common.HexToAddress("0xc100000000000000000000000000000000000000")
+		numberer  = common.HexToAddress("0xc200000000000000000000000000000000000000")
+		value     = (*hexutil.Big)(big.NewInt(params.Ether * 6 / 10))
+	)
+	api := NewBlockChainAPI(newTestBackend(t, genBlocks, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		b.SetPoS()
+	}))
+	overrides := &StateOverride{
+		emitter:  {Code: hex2Bytes("60016000a000")},       // LOG0 of a single zero byte
+		reverter: {Code: hex2Bytes("60006000fd")},         // REVERT
+		numberer: {Code: hex2Bytes("4360005260206000f3")}, // Return the block number
+	}
+	opts := simOpts{
+		TraceTransfers: true,
+		BlockStateCalls: []simBlock{
+			{
+				StateOverrides: overrides,
+				Calls: []TransactionArgs{
+					{From: &accounts[0].addr, To: &accounts[1].addr, Value: value},
+					{From: &accounts[0].addr, To: &emitter},
+					{From: &accounts[0].addr, To: &reverter},
+				},
+			},
+			{
+				// Skips a block, which is filled in empty
+				BlockOverrides: &BlockOverrides{Number: (*hexutil.Big)(big.NewInt(int64(genBlocks + 3)))},
+				Calls: []TransactionArgs{
+					{From: &accounts[1].addr, To: &numberer},
+				},
+			},
+		},
+	}
+	results, err := api.SimulateV1(context.Background(), opts, nil)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("block count mismatch: have %d, want 3", len(results))
+	}
+	for i, block := range results {
+		if number := block["number"].(*hexutil.Big).ToInt().Int64(); number != int64(genBlocks+1+i) {
+			t.Errorf("block %d: number mismatch: have %d, want %d", i, number, genBlocks+1+i)
+		}
+		if i > 0 && block["parentHash"] != results[i-1]["hash"] {
+			t.Errorf("block %d: not chained to the previous block", i)
+		}
+	}
+	calls := results[0]["calls"].([]simCallResult)
+	if len(calls[0].Logs) != 1 || calls[0].Logs[0].Address != transferAddress || calls[0].Logs[0].Topics[2] != common.BytesToHash(accounts[1].addr.Bytes()) {
+		t.Errorf("transfer log mismatch: %v", calls[0].Logs)
+	}
+	if len(calls[1].Logs) != 1 || calls[1].Logs[0].Address != emitter || calls[1].Logs[0].Index != 1 || calls[1].Logs[0].TxIndex != 1 {
+		t.Errorf("emitted log mismatch: %v", calls[1].Logs)
+	}
+	if calls[1].Logs[0].BlockHash != results[0]["hash"] {
+		t.Errorf("log block hash not repaired")
+	}
+	if calls[2].Status != hexutil.Uint64(types.ReceiptStatusFailed) || calls[2].Error == nil || calls[2].Error.Code != errCodeReverted {
+		t.Errorf("revert mismatch: %+v", calls[2])
+	}
+	if calls := results[1]["calls"].([]simCallResult); len(calls) != 0 {
+		t.Errorf("gap block not empty: %d calls", len(calls))
+	}
+	calls = results[2]["calls"].([]simCallResult)
+	if have := new(big.Int).SetBytes(calls[0].ReturnValue); have.Int64() != int64(genBlocks+3) {
+		t.Errorf("overridden block number mismatch: have %d, want %d", have, genBlocks+3)
+	}
+
+	// State changes carry over, the second transfer cannot be funded anymore
+	transfer := TransactionArgs{From: &accounts[0].addr, To: &accounts[1].addr, Value: value, MaxFeePerGas: (*hexutil.Big)(big.NewInt(params.GWei))}
+	opts = simOpts{
+		Validation: true,
+		BlockStateCalls: []simBlock{
+			{Calls: []TransactionArgs{transfer}},
+			{Calls: []TransactionArgs{transfer}},
+		},
+	}
+	_, err = api.SimulateV1(context.Background(), opts, nil)
+	var txErr *invalidTxError
+	if !errors.As(err, &txErr) || txErr.Code != errCodeInsufficientFunds {
+		t.Fatalf("chained transfer error mismatch: %v", err)
+	}
+	// Block numbers must increase
+	opts = simOpts{BlockStateCalls: []simBlock{{BlockOverrides: &BlockOverrides{Number: (*hexutil.Big)(big.NewInt(1))}}}}
+	if _, err := api.SimulateV1(context.Background(), opts, nil); err == nil {
+		t.Fatal("simulated block below the base")
+	}
+}
+
+func TestCallBundle(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(1)
+		reverter = common.HexToAddress("0xc100000000000000000000000000000000000000")
+		coinbase = common.HexToAddress("0xc0ffee0000000000000000000000000000000000")
+		genesis  = &core.Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: types.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				reverter:         {Code: common.FromHex("60006000fd")},
+			},
+		}
+		signer = types.LatestSigner(params.MergedTestChainConfig)
+	)
+	api := NewBundleAPI(newTestBackend(t, 1, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		b.SetPoS()
+	}))
+	sign := func(nonce uint64, to common.Address, value int64, gas uint64) hexutil.Bytes {
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: nonce, To: &to, Value: big.NewInt(value), Gas: gas, GasPrice: big.NewInt(params.GWei)}), signer, accounts[0].key)
+		raw, _ := tx.MarshalBinary()
+		return raw
+	}
+	payment, revert := sign(0, coinbase, 1000, params.TxGas), sign(1, reverter, 0, 100000)
+
+	result, err := api.CallBundle(context.Background(), CallBundleArgs{Txs: []hexutil.Bytes{payment, revert}, Coinbase: &coinbase})
+	if err != nil {
+		t.Fatalf("bundle failed: %v", err)
+	}
+	if len(result.Results) != 2 || result.StateBlockNumber != 1 {
+		t.Fatalf("bundle result mismatch: %+v", result)
+	}
+	paid := result.Results[0]
+	if paid.EthSentToCoinbase.ToInt().Int64() != 1000 || paid.GasUsed != hexutil.Uint64(params.TxGas) || paid.Error != "" {
+		t.Errorf("payment result mismatch: %+v", paid)
+	}
+	if new(big.Int).Add(paid.GasFees.ToInt(), big.NewInt(1000)).Cmp(paid.CoinbaseDiff.ToInt()) != 0 {
+		t.Errorf("coinbase diff mismatch: have %v, want fees %v + 1000", paid.CoinbaseDiff, paid.GasFees)
+	}
+	if reverted := result.Results[1]; reverted.Error == "" || reverted.EthSentToCoinbase.ToInt().Sign() != 0 {
+		t.Errorf("revert result mismatch: %+v", reverted)
+	}
+	if uint64(result.TotalGasUsed) != uint64(paid.GasUsed+result.Results[1].GasUsed) || result.EthSentToCoinbase.ToInt().Int64() != 1000 {
+		t.Errorf("bundle totals mismatch: %+v", result)
+	}
+
+	// A reverting transaction marked unrevertible fails the bundle, so does a bad nonce
+	var tx types.Transaction
+	tx.UnmarshalBinary(revert)
+	if _, err := api.CallBundle(context.Background(), CallBundleArgs{Txs: []hexutil.Bytes{payment, revert}, UnRevertible: []common.Hash{tx.Hash()}}); err == nil {
+		t.Error("unrevertible transaction reverted without error")
+	}
+	if _, err := api.CallBundle(context.Background(), CallBundleArgs{Txs: []hexutil.Bytes{revert}}); !errors.Is(err, core.ErrNonceTooHigh) {
+		t.Errorf("nonce error mismatch: %v", err)
+	}
+
+	// The block gas limit requested by the caller is capped by the RPC gas cap
+	gasLimit := hexutil.Uint64(100_000_000)
+	if _, err := api.CallBundle(context.Background(), CallBundleArgs{Txs: []hexutil.Bytes{sign(0, coinbase, 0, 20_000_000)}, GasLimit: &gasLimit}); !errors.Is(err, core.ErrGasLimitReached) {
+		t.Errorf("gas cap error mismatch: %v", err)
+	}
+}
+
 func TestSignTransaction(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
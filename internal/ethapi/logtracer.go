@@ -0,0 +1,142 @@
+package ethapi
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+var (
+	// keccak256("Transfer(address,address,uint256)")
+	transferTopic = common.HexToHash("ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	// ERC-7528
+	transferAddress = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+)
+
+// logTracer collects the logs emitted by a simulated call as the EVM executes
+// it, dropping those of reverted frames. It can additionally emit an ERC20-like
+// Transfer log for every native value transfer, interleaved with the regular
+// logs in execution order.
+type logTracer struct {
+	// logs keeps logs for all open call frames. This lets us clear logs for
+	// failed calls.
+	logs           [][]*types.Log
+	count          int
+	traceTransfers bool
+	blockNumber    uint64
+	blockHash      common.Hash
+	txHash         common.Hash
+	txIdx          uint
+}
+
+func newLogTracer(traceTransfers bool, blockNumber uint64, blockHash, txHash common.Hash, txIndex uint) *logTracer {
+	return &logTracer{
+		traceTransfers: traceTransfers,
+		blockNumber:    blockNumber,
+		blockHash:      blockHash,
+		txHash:         txHash,
+		txIdx:          txIndex,
+	}
+}
+
+func (t *logTracer) CaptureTxStart(gasLimit uint64)         {}
+func (t *logTracer) CaptureTxEnd(restGas uint64)            {}
+func (t *logTracer) CaptureSystemTxEnd(intrinsicGas uint64) {}
+
+func (t *logTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.logs = append(t.logs, make([]*types.Log, 0))
+	if t.traceTransfers && value != nil && value.Sign() > 0 {
+		t.captureTransfer(from, to, value)
+	}
+}
+
+func (t *logTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if err != nil && len(t.logs) > 0 {
+		t.logs[0] = nil
+	}
+}
+
+func (t *logTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.logs = append(t.logs, make([]*types.Log, 0))
+	if t.traceTransfers && typ != vm.DELEGATECALL && value != nil && value.Sign() > 0 {
+		t.captureTransfer(from, to, value)
+	}
+}
+
+func (t *logTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	size := len(t.logs)
+	if size <= 1 {
+		return
+	}
+	call := t.logs[size-1]
+	t.logs = t.logs[:size-1]
+	size--
+
+	// Clear logs if the call failed
+	if err == nil {
+		t.logs[size-1] = append(t.logs[size-1], call...)
+	}
+}
+
+func (t *logTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || op < vm.LOG0 || op > vm.LOG4 {
+		return
+	}
+	stack := scope.Stack.Data()
+	mStart, mSize := stack[len(stack)-1], stack[len(stack)-2]
+	topics := make([]common.Hash, int(op-vm.LOG0))
+	for i := range topics {
+		topics[i] = common.Hash(stack[len(stack)-3-i].Bytes32())
+	}
+	// The memory of the log is paid for, but not yet expanded
+	data := make([]byte, mSize.Uint64())
+	if offset := int64(mStart.Uint64()); int64(scope.Memory.Len()) > offset {
+		copy(data, scope.Memory.GetPtr(offset, min(int64(len(data)), int64(scope.Memory.Len())-offset)))
+	}
+	t.captureLog(scope.Contract.Address(), topics, data)
+}
+
+func (t *logTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *logTracer) captureLog(address common.Address, topics []common.Hash, data []byte) {
+	t.logs[len(t.logs)-1] = append(t.logs[len(t.logs)-1], &types.Log{
+		Address:     address,
+		Topics:      topics,
+		Data:        data,
+		BlockNumber: t.blockNumber,
+		BlockHash:   t.blockHash,
+		TxHash:      t.txHash,
+		TxIndex:     t.txIdx,
+		Index:       uint(t.count),
+	})
+	t.count++
+}
+
+func (t *logTracer) captureTransfer(from, to common.Address, value *big.Int) {
+	topics := []common.Hash{
+		transferTopic,
+		common.BytesToHash(from.Bytes()),
+		common.BytesToHash(to.Bytes()),
+	}
+	t.captureLog(transferAddress, topics, common.BigToHash(value).Bytes())
+}
+
+// reset prepares the tracer for the next transaction.
+func (t *logTracer) reset(txHash common.Hash, txIdx uint) {
+	t.logs = nil
+	t.txHash = txHash
+	t.txIdx = txIdx
+}
+
+// Logs returns the logs of the last traced transaction that were not reverted.
+func (t *logTracer) Logs() []*types.Log {
+	if len(t.logs) == 0 {
+		return nil
+	}
+	return t.logs[0]
+}
+
+var _ vm.EVMLogger = (*logTracer)(nil)
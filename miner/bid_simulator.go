@@ -20,6 +20,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -836,21 +837,12 @@ func (r *BidRuntime) commitTransaction(chain *core.BlockChain, chainConfig *para
 		if sc == nil {
 			return errors.New("blob transaction without blobs in miner")
 		}
-		// Checking against blob gas limit: It's kind of ugly to perform this check here, but there
-		// isn't really a better place right now. The blob gas limit is checked at block validation time
-		// and not during execution. This means core.ApplyTransaction will not return an error if the
-		// tx has too many blobs. So we have to explicitly check it here.
-		if (env.blobs+len(sc.Blobs))*params.BlobTxBlobGasPerBlob > params.MaxBlobGasPerBlock {
-			return errors.New("max data blobs reached")
-		}
 	}
 
-	receipt, err := core.ApplyTransaction(chainConfig, chain, &env.coinbase, env.gasPool, env.state, env.header, tx,
-		&env.header.GasUsed, *chain.GetVMConfig(), core.NewReceiptBloomGenerator())
+	evm := vm.NewEVM(core.NewEVMBlockContext(env.header, chain, &env.coinbase), vm.TxContext{}, env.state, chainConfig, *chain.GetVMConfig())
+	receipt, _, err := core.ApplyBidTransaction(chainConfig, env.gasPool, env.state, env.header, tx, env.blobs, unRevertible, evm)
 	if err != nil {
 		return err
-	} else if unRevertible && receipt.Status == types.ReceiptStatusFailed {
-		return errors.New("no revertible transaction failed")
 	}
 
 	if tx.Type() == types.BlobTxType {
@@ -859,7 +851,6 @@ func (r *BidRuntime) commitTransaction(chain *core.BlockChain, chainConfig *para
 		env.receipts = append(env.receipts, receipt)
 		env.sidecars = append(env.sidecars, sc)
 		env.blobs += len(sc.Blobs)
-		*env.header.BlobGasUsed += receipt.BlobGasUsed
 	} else {
 		env.txs = append(env.txs, tx)
 		env.receipts = append(env.receipts, receipt)
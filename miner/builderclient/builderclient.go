@@ -3,6 +3,8 @@ package builderclient
 import (
 	"context"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -31,3 +33,26 @@ func newClient(c *rpc.Client) *Client {
 func (ec *Client) ReportIssue(ctx context.Context, args *types.BidIssue) error {
 	return ec.c.CallContext(ctx, nil, "mev_reportIssue", args)
 }
+
+// SendBid sends a bid to the validator behind the endpoint
+func (ec *Client) SendBid(ctx context.Context, args *types.BidArgs) (common.Hash, error) {
+	var hash common.Hash
+	err := ec.c.CallContext(ctx, &hash, "mev_sendBid", args)
+	return hash, err
+}
+
+// SendRawTransaction sends a signed transaction to the endpoint
+func (ec *Client) SendRawTransaction(ctx context.Context, tx *types.Transaction) (common.Hash, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var hash common.Hash
+	err = ec.c.CallContext(ctx, &hash, "eth_sendRawTransaction", hexutil.Encode(data))
+	return hash, err
+}
+
+// Close closes the underlying RPC connection
+func (ec *Client) Close() {
+	ec.c.Close()
+}
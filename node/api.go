@@ -180,6 +180,8 @@ func (api *adminAPI) StartHTTP(host *string, port *int, cors *string, apis *stri
 		rpcEndpointConfig: rpcEndpointConfig{
 			batchItemLimit:         api.node.config.BatchRequestLimit,
 			batchResponseSizeLimit: api.node.config.BatchResponseMaxSize,
+			limits:                 api.node.config.RPCLimits,
+			recorder:               api.node.recorder,
 		},
 	}
 	if cors != nil {
@@ -258,6 +260,8 @@ func (api *adminAPI) StartWS(host *string, port *int, allowedOrigins *string, ap
 		rpcEndpointConfig: rpcEndpointConfig{
 			batchItemLimit:         api.node.config.BatchRequestLimit,
 			batchResponseSizeLimit: api.node.config.BatchResponseMaxSize,
+			limits:                 api.node.config.RPCLimits,
+			recorder:               api.node.recorder,
 		},
 	}
 	if apis != nil {
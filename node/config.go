@@ -232,6 +232,26 @@ type Config struct {
 	// BatchResponseMaxSize is the maximum number of bytes returned from a batched rpc call.
 	BatchResponseMaxSize int `toml:",omitempty"`
 
+	// RPCLimits configures the API keys and request limits of the HTTP and
+	// WebSocket RPC servers. It does not apply to IPC and the authenticated
+	// engine API.
+	RPCLimits *rpc.LimitConfig `toml:",omitempty"`
+
+	// RPCRecordFile is the file the calls served over HTTP and WebSocket are
+	// recorded to, recording is disabled if empty.
+	RPCRecordFile string `toml:",omitempty"`
+
+	// RPCRecordMaxSize is the size in megabytes the recording is rotated at.
+	RPCRecordMaxSize int `toml:",omitempty"`
+
+	// RPCRecordMaxBackups is the maximum number of rotated recordings to keep.
+	RPCRecordMaxBackups int `toml:",omitempty"`
+
+	// RPCRecordMethods are the methods, or whole namespaces, that are recorded.
+	// All methods are recorded if empty, except the personal namespace and the
+	// signing methods which never are.
+	RPCRecordMethods []string `toml:",omitempty"`
+
 	// JWTSecret is the path to the hex-encoded jwt secret.
 	JWTSecret string `toml:",omitempty"`
 
@@ -58,14 +58,15 @@ type Node struct {
 	state         int           // Tracks state of node lifecycle
 
 	lock          sync.Mutex
-	lifecycles    []Lifecycle // All registered backends, services, and auxiliary services that have a lifecycle
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	http          *httpServer //
-	ws            *httpServer //
-	httpAuth      *httpServer //
-	wsAuth        *httpServer //
-	ipc           *ipcServer  // Stores information about the ipc http server
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	lifecycles    []Lifecycle   // All registered backends, services, and auxiliary services that have a lifecycle
+	rpcAPIs       []rpc.API     // List of APIs currently provided by the node
+	http          *httpServer   //
+	ws            *httpServer   //
+	httpAuth      *httpServer   //
+	wsAuth        *httpServer   //
+	ipc           *ipcServer    // Stores information about the ipc http server
+	inprocHandler *rpc.Server   // In-process RPC request handler to process the API requests
+	recorder      *rpc.Recorder // Recorder of the calls served over HTTP and WebSocket, if enabled
 
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
@@ -195,6 +196,14 @@ func New(conf *Config) (*Node, error) {
 	}
 
 	// Configure RPC servers.
+	if conf.RPCRecordFile != "" {
+		recorder, err := rpc.NewFileRecorder(conf.RPCRecordFile, conf.RPCRecordMaxSize, conf.RPCRecordMaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		recorder.SetMethods(conf.RPCRecordMethods)
+		node.recorder = recorder
+	}
 	node.http = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.httpAuth = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.ws = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
@@ -286,6 +295,11 @@ func (n *Node) doClose(errs []error) error {
 	if err := n.accman.Close(); err != nil {
 		errs = append(errs, err)
 	}
+	if n.recorder != nil {
+		if err := n.recorder.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	if n.keyDirTemp {
 		if err := os.RemoveAll(n.keyDir); err != nil {
 			errs = append(errs, err)
@@ -454,6 +468,8 @@ func (n *Node) startRPC() error {
 	rpcConfig := rpcEndpointConfig{
 		batchItemLimit:         n.config.BatchRequestLimit,
 		batchResponseSizeLimit: n.config.BatchResponseMaxSize,
+		limits:                 n.config.RPCLimits,
+		recorder:               n.recorder,
 	}
 
 	initHttp := func(server *httpServer, port int) error {
@@ -688,6 +704,12 @@ func (n *Node) Config() *Config {
 	return n.config
 }
 
+// RPCRecorder returns the recorder of the calls served over HTTP and WebSocket,
+// nil if recording is disabled.
+func (n *Node) RPCRecorder() *rpc.Recorder {
+	return n.recorder
+}
+
 // Server retrieves the currently running P2P network layer. This method is meant
 // only to inspect fields of the currently running server. Callers should not
 // start or stop the returned server.
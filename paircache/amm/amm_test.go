@@ -0,0 +1,194 @@
+package amm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+type testState map[common.Address]map[common.Hash]common.Hash
+
+func (s testState) GetState(addr common.Address, hash common.Hash) common.Hash {
+	return s[addr][hash]
+}
+
+func (s testState) setPair(pair, token0, token1 common.Address, reserve0, reserve1 *big.Int) {
+	packed := new(big.Int).Lsh(reserve1, 112)
+	packed.Or(packed, reserve0)
+	packed.Or(packed, new(big.Int).Lsh(big.NewInt(1700000000), 224))
+	s[pair] = map[common.Hash]common.Hash{
+		token0Slot:   common.BytesToHash(token0.Bytes()),
+		token1Slot:   common.BytesToHash(token1.Bytes()),
+		reservesSlot: common.BigToHash(packed),
+	}
+}
+
+var (
+	tokenA = common.HexToAddress("0xa")
+	tokenB = common.HexToAddress("0xb")
+	tokenC = common.HexToAddress("0xc")
+	pairAB = common.HexToAddress("0xab")
+	pairBC = common.HexToAddress("0xbc")
+	pairCA = common.HexToAddress("0xca")
+
+	uniFee = Fee{997, 1000}
+)
+
+func ether(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e18))
+}
+
+func TestReadPool(t *testing.T) {
+	state := make(testState)
+	state.setPair(pairAB, tokenA, tokenB, ether(100), ether(200))
+
+	pool, err := ReadPool(state, pairAB, uniFee)
+	if err != nil {
+		t.Fatalf("failed to read pool: %v", err)
+	}
+	if pool.Token0 != tokenA || pool.Token1 != tokenB {
+		t.Fatalf("token mismatch: have %v/%v", pool.Token0, pool.Token1)
+	}
+	if pool.Reserve0.Cmp(ether(100)) != 0 || pool.Reserve1.Cmp(ether(200)) != 0 {
+		t.Fatalf("reserve mismatch: have %v/%v", pool.Reserve0, pool.Reserve1)
+	}
+	if _, err := ReadPool(state, pairBC, uniFee); !errors.Is(err, ErrEmptyPool) {
+		t.Fatalf("expected empty pool error, have %v", err)
+	}
+}
+
+func TestAmountOut(t *testing.T) {
+	pool := &Pool{Token0: tokenA, Token1: tokenB, Reserve0: big.NewInt(10000), Reserve1: big.NewInt(20000), Fee: uniFee}
+
+	// 1000*997*20000 / (10000*1000 + 1000*997) = 1813.22...
+	out, err := pool.AmountOut(tokenA, big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Cmp(big.NewInt(1813)) != 0 {
+		t.Fatalf("amount out mismatch: have %v, want 1813", out)
+	}
+	if _, err := pool.AmountOut(tokenC, big.NewInt(1000)); !errors.Is(err, ErrTokenMismatch) {
+		t.Fatalf("expected token mismatch, have %v", err)
+	}
+}
+
+func testCycle(reserveCA *big.Int) []Leg {
+	return []Leg{
+		{Pool: &Pool{Address: pairAB, Token0: tokenA, Token1: tokenB, Reserve0: ether(1000), Reserve1: ether(2000), Fee: uniFee}, TokenIn: tokenA},
+		{Pool: &Pool{Address: pairBC, Token0: tokenB, Token1: tokenC, Reserve0: ether(2000), Reserve1: ether(4000), Fee: uniFee}, TokenIn: tokenB},
+		{Pool: &Pool{Address: pairCA, Token0: tokenA, Token1: tokenC, Reserve0: reserveCA, Reserve1: ether(4000), Fee: uniFee}, TokenIn: tokenC},
+	}
+}
+
+func TestOptimalCycleBalanced(t *testing.T) {
+	quote, err := OptimalCycle(testCycle(ether(1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Profitable() {
+		t.Fatalf("balanced cycle reported profitable: %+v", quote)
+	}
+}
+
+func TestOptimalCycleProfitable(t *testing.T) {
+	legs := testCycle(ether(1100))
+	quote, err := OptimalCycle(legs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !quote.Profitable() {
+		t.Fatal("mispriced cycle reported unprofitable")
+	}
+	if len(quote.Amounts) != 3 || quote.Amounts[2].Cmp(quote.AmountOut) != 0 {
+		t.Fatalf("leg amounts mismatch: %v", quote.Amounts)
+	}
+	// Nudging the input either way must not beat the closed form optimum
+	for _, delta := range []*big.Int{ether(-1), big.NewInt(-1e15), big.NewInt(1e15), ether(1)} {
+		amountIn := new(big.Int).Add(quote.AmountIn, delta)
+		amounts, err := SimulateCycle(legs, amountIn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		profit := new(big.Int).Sub(amounts[2], amountIn)
+		if profit.Cmp(quote.Profit) > 0 {
+			t.Fatalf("input %v beats optimum: profit %v > %v", amountIn, profit, quote.Profit)
+		}
+	}
+}
+
+func TestOptimalCycleNotClosed(t *testing.T) {
+	legs := testCycle(ether(1000))[:2]
+	if _, err := OptimalCycle(legs); err == nil {
+		t.Fatal("expected error for open route")
+	}
+}
+
+func TestQuoteTriangle(t *testing.T) {
+	state := make(testState)
+	state.setPair(pairAB, tokenA, tokenB, ether(1000), ether(2000))
+	state.setPair(pairBC, tokenB, tokenC, ether(2000), ether(4000))
+	state.setPair(pairCA, tokenA, tokenC, ether(1100), ether(4000))
+
+	router := common.HexToAddress("0x1")
+	registry := NewRegistry()
+	if err := registry.Register(router, uniFee); err != nil {
+		t.Fatal(err)
+	}
+	triangle := pairtypes.Triangle{
+		Token0: tokenA.Hex(), Router0: router.Hex(), Pair0: pairAB.Hex(),
+		Token1: tokenB.Hex(), Router1: router.Hex(), Pair1: pairBC.Hex(),
+		Token2: tokenC.Hex(), Router2: router.Hex(), Pair2: pairCA.Hex(),
+	}
+	quoter := NewQuoter(registry, state)
+	quote, err := quoter.QuoteTriangle(triangle)
+	if err != nil {
+		t.Fatalf("failed to quote triangle: %v", err)
+	}
+	want, _ := OptimalCycle(testCycle(ether(1100)))
+	if quote.Profit.Cmp(want.Profit) != 0 || quote.AmountIn.Cmp(want.AmountIn) != 0 {
+		t.Fatalf("quote mismatch: have %v/%v, want %v/%v", quote.AmountIn, quote.Profit, want.AmountIn, want.Profit)
+	}
+	for i, pair := range []common.Address{pairAB, pairBC, pairCA} {
+		if quote.Snapshots[i] != state[pair][reservesSlot] {
+			t.Fatalf("leg %d snapshot mismatch: have %x, want %x", i, quote.Snapshots[i], state[pair][reservesSlot])
+		}
+	}
+
+	triangle.Router2 = common.HexToAddress("0x2").Hex()
+	if _, err := quoter.QuoteTriangle(triangle); !errors.Is(err, ErrUnknownPool) {
+		t.Fatalf("expected unknown pool error, have %v", err)
+	}
+}
+
+func TestQuoteRouteTwoHops(t *testing.T) {
+	// The same token pair priced differently on two exchanges
+	pairAB2 := common.HexToAddress("0xab2")
+	state := make(testState)
+	state.setPair(pairAB, tokenA, tokenB, ether(1000), ether(2000))
+	state.setPair(pairAB2, tokenA, tokenB, ether(1100), ether(2000))
+
+	router := common.HexToAddress("0x1")
+	registry := NewRegistry()
+	registry.Register(router, uniFee)
+
+	route := pairtypes.Route{Hops: []pairtypes.Hop{
+		{Token: tokenA, Router: router, Pair: pairAB},
+		{Token: tokenB, Router: router, Pair: pairAB2},
+	}}
+	quote, err := NewQuoter(registry, state).QuoteRoute(route)
+	if err != nil {
+		t.Fatalf("failed to quote route: %v", err)
+	}
+	if !quote.Profitable() || len(quote.Amounts) != 2 {
+		t.Fatalf("cross exchange route not profitable: %+v", quote)
+	}
+	// The reverse direction buys where it is expensive and sells where it is cheap
+	route.Hops[0], route.Hops[1] = pairtypes.Hop{Token: tokenA, Router: router, Pair: pairAB2}, pairtypes.Hop{Token: tokenB, Router: router, Pair: pairAB}
+	if quote, err := NewQuoter(registry, state).QuoteRoute(route); err != nil || quote.Profitable() {
+		t.Fatalf("reverse route reported profitable: %+v, %v", quote, err)
+	}
+}
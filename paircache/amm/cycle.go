@@ -0,0 +1,116 @@
+package amm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Leg is a single swap of a cycle, selling TokenIn into Pool.
+type Leg struct {
+	Pool    *Pool
+	TokenIn common.Address
+}
+
+// Quote is the result of the optimal input search of a cycle.
+type Quote struct {
+	AmountIn  *big.Int      // Optimal amount of the start token to sell
+	AmountOut *big.Int      // Amount of the start token received back
+	Profit    *big.Int      // AmountOut - AmountIn, never negative
+	Amounts   []*big.Int    // Output amount of every leg, in order
+	Snapshots []common.Hash // Reserves slot of the pool of every leg, in order
+}
+
+// Profitable reports whether the cycle yields a strictly positive profit.
+func (q *Quote) Profitable() bool {
+	return q.Profit.Sign() > 0
+}
+
+// OptimalCycle computes the input amount maximising the profit of a cyclic
+// route of constant-product pools.
+//
+// A single swap with fee n/d maps x to n*Rout*x / (d*Rin + n*x), which is of the
+// form A*x / (B + C*x). Such functions are closed under composition, so the
+// whole cycle collapses into one A, B, C triple and the profit A*x/(B+C*x) - x
+// is maximised at x* = (sqrt(A*B) - B) / C. The cycle is only profitable if its
+// marginal rate at zero, A/B, exceeds one.
+func OptimalCycle(legs []Leg) (*Quote, error) {
+	if len(legs) < 2 {
+		return nil, errors.New("cycle needs at least two legs")
+	}
+	var (
+		A = big.NewInt(1)
+		B = big.NewInt(1)
+		C = new(big.Int)
+
+		token     = legs[0].TokenIn
+		snapshots = make([]common.Hash, 0, len(legs))
+	)
+	for i, leg := range legs {
+		if leg.TokenIn != token {
+			return nil, fmt.Errorf("leg %d sells %v, expected %v", i, leg.TokenIn, token)
+		}
+		reserveIn, reserveOut, tokenOut, err := leg.Pool.Reserves(leg.TokenIn)
+		if err != nil {
+			return nil, err
+		}
+		var (
+			n = new(big.Int).SetUint64(leg.Pool.Fee.Numerator)
+			d = new(big.Int).SetUint64(leg.Pool.Fee.Denominator)
+			a = new(big.Int).Mul(n, reserveOut)
+			b = new(big.Int).Mul(d, reserveIn)
+		)
+		// A' = a*A, B' = b*B, C' = b*C + n*A
+		C.Add(C.Mul(C, b), new(big.Int).Mul(n, A))
+		A.Mul(A, a)
+		B.Mul(B, b)
+
+		token = tokenOut
+		snapshots = append(snapshots, leg.Pool.Snapshot)
+	}
+	if token != legs[0].TokenIn {
+		return nil, fmt.Errorf("route ends with %v, not a cycle of %v", token, legs[0].TokenIn)
+	}
+	quote := &Quote{AmountIn: new(big.Int), AmountOut: new(big.Int), Profit: new(big.Int), Snapshots: snapshots}
+	if A.Cmp(B) <= 0 {
+		return quote, nil
+	}
+	amountIn := new(big.Int).Sqrt(new(big.Int).Mul(A, B))
+	amountIn.Sub(amountIn, B)
+	amountIn.Div(amountIn, C)
+	if amountIn.Sign() <= 0 {
+		return quote, nil
+	}
+	// Replay the swaps with the exact on-chain rounding
+	amounts, err := SimulateCycle(legs, amountIn)
+	if err != nil {
+		return nil, err
+	}
+	amountOut := amounts[len(amounts)-1]
+	if amountOut.Cmp(amountIn) <= 0 {
+		return quote, nil
+	}
+	quote.AmountIn = amountIn
+	quote.AmountOut = amountOut
+	quote.Profit = new(big.Int).Sub(amountOut, amountIn)
+	quote.Amounts = amounts
+	return quote, nil
+}
+
+// SimulateCycle executes the legs in order starting with amountIn and returns
+// the output amount of every leg.
+func SimulateCycle(legs []Leg, amountIn *big.Int) ([]*big.Int, error) {
+	amounts := make([]*big.Int, 0, len(legs))
+	amount := amountIn
+	for _, leg := range legs {
+		out, err := leg.Pool.AmountOut(leg.TokenIn, amount)
+		if err != nil {
+			return nil, err
+		}
+		amounts = append(amounts, out)
+		amount = out
+	}
+	return amounts, nil
+}
@@ -0,0 +1,123 @@
+// Package amm simulates constant-product (UniswapV2-style) pools natively,
+// reading pool reserves straight from the state instead of issuing eth_calls.
+package amm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Storage layout of a UniswapV2Pair contract. All the V2 forks deployed on BSC
+// keep the same layout, only the swap fee differs.
+var (
+	token0Slot   = common.BigToHash(big.NewInt(6))
+	token1Slot   = common.BigToHash(big.NewInt(7))
+	reservesSlot = common.BigToHash(big.NewInt(8))
+)
+
+var (
+	// ErrUnknownPool is returned if the pool type of a leg is not known by the
+	// registry, the caller is expected to fall back to the on-chain contract.
+	ErrUnknownPool = errors.New("unknown pool type")
+
+	// ErrEmptyPool is returned if a pool has no liquidity.
+	ErrEmptyPool = errors.New("empty pool")
+
+	// ErrTokenMismatch is returned if the input token of a leg is not one of
+	// the tokens of its pool.
+	ErrTokenMismatch = errors.New("token not in pool")
+)
+
+// StateReader is the subset of state.StateDB needed to load pools.
+type StateReader interface {
+	GetState(addr common.Address, hash common.Hash) common.Hash
+}
+
+// Fee is the swap fee of a pool expressed as the fraction of the input amount
+// that is actually swapped, e.g. 9975/10000 for a 0.25% fee.
+type Fee struct {
+	Numerator   uint64
+	Denominator uint64
+}
+
+// Validate checks that the fee is a proper fraction.
+func (f Fee) Validate() error {
+	if f.Denominator == 0 || f.Numerator == 0 || f.Numerator > f.Denominator {
+		return fmt.Errorf("invalid fee %d/%d", f.Numerator, f.Denominator)
+	}
+	return nil
+}
+
+// Pool is a snapshot of a constant-product pool.
+type Pool struct {
+	Address  common.Address
+	Token0   common.Address
+	Token1   common.Address
+	Reserve0 *big.Int
+	Reserve1 *big.Int
+	Fee      Fee
+	Snapshot common.Hash // Raw reserves slot the reserves were unpacked from
+}
+
+// ReadPool loads the tokens and reserves of a UniswapV2-style pair from the state.
+func ReadPool(state StateReader, addr common.Address, fee Fee) (*Pool, error) {
+	if err := fee.Validate(); err != nil {
+		return nil, err
+	}
+	pool := &Pool{
+		Address: addr,
+		Token0:  common.BytesToAddress(state.GetState(addr, token0Slot).Bytes()),
+		Token1:  common.BytesToAddress(state.GetState(addr, token1Slot).Bytes()),
+		Fee:     fee,
+	}
+	pool.Snapshot = state.GetState(addr, reservesSlot)
+	pool.Reserve0, pool.Reserve1 = unpackReserves(pool.Snapshot)
+	if pool.Reserve0.Sign() == 0 || pool.Reserve1.Sign() == 0 {
+		return nil, ErrEmptyPool
+	}
+	return pool, nil
+}
+
+// unpackReserves splits the reserves slot of a pair, laid out as reserve0
+// (uint112) | reserve1 (uint112) | blockTimestampLast (uint32), low to high.
+func unpackReserves(slot common.Hash) (*big.Int, *big.Int) {
+	packed := slot.Big()
+	mask := new(big.Int).Sub(new(big.Int).Lsh(common.Big1, 112), common.Big1)
+	return new(big.Int).And(packed, mask), new(big.Int).And(new(big.Int).Rsh(packed, 112), mask)
+}
+
+// Reserves returns the reserves of the pool ordered by swap direction.
+func (p *Pool) Reserves(tokenIn common.Address) (reserveIn, reserveOut *big.Int, tokenOut common.Address, err error) {
+	switch tokenIn {
+	case p.Token0:
+		return p.Reserve0, p.Reserve1, p.Token1, nil
+	case p.Token1:
+		return p.Reserve1, p.Reserve0, p.Token0, nil
+	default:
+		return nil, nil, common.Address{}, fmt.Errorf("%w: %v not in pool %v", ErrTokenMismatch, tokenIn, p.Address)
+	}
+}
+
+// AmountOut mirrors UniswapV2Library.getAmountOut, rounding down exactly as the
+// contract does.
+func (p *Pool) AmountOut(tokenIn common.Address, amountIn *big.Int) (*big.Int, error) {
+	reserveIn, reserveOut, _, err := p.Reserves(tokenIn)
+	if err != nil {
+		return nil, err
+	}
+	return getAmountOut(amountIn, reserveIn, reserveOut, p.Fee), nil
+}
+
+func getAmountOut(amountIn, reserveIn, reserveOut *big.Int, fee Fee) *big.Int {
+	if amountIn.Sign() <= 0 {
+		return new(big.Int)
+	}
+	amountInWithFee := new(big.Int).Mul(amountIn, new(big.Int).SetUint64(fee.Numerator))
+	numerator := new(big.Int).Mul(amountInWithFee, reserveOut)
+	denominator := new(big.Int).Mul(reserveIn, new(big.Int).SetUint64(fee.Denominator))
+	denominator.Add(denominator, amountInWithFee)
+	return numerator.Div(numerator, denominator)
+}
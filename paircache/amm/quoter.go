@@ -0,0 +1,127 @@
+package amm
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+// Registry maps router addresses to the fee of the constant-product pools they
+// trade against. Pools behind routers that are not registered are unknown.
+// It also records the executor the arbitrage contract trades the pools of a
+// router through, as reported by the contract queries.
+type Registry struct {
+	fees      map[common.Address]Fee
+	executors map[common.Address]common.Address
+	lock      sync.RWMutex
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		fees:      make(map[common.Address]Fee),
+		executors: make(map[common.Address]common.Address),
+	}
+}
+
+// DefaultRegistry returns a registry preloaded with the well known fixed fee
+// UniswapV2 forks on BSC.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(common.HexToAddress("0x10ED43C718714eb63d5aA57B78B54704E256024E"), Fee{9975, 10000}) // PancakeSwap V2
+	return r
+}
+
+// Register sets the pool fee of the given router.
+func (r *Registry) Register(router common.Address, fee Fee) error {
+	if err := fee.Validate(); err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.fees[router] = fee
+	return nil
+}
+
+// Fee returns the pool fee of the given router.
+func (r *Registry) Fee(router common.Address) (Fee, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	fee, ok := r.fees[router]
+	return fee, ok
+}
+
+// SetExecutor sets the executor of the pools of the given router.
+func (r *Registry) SetExecutor(router, executor common.Address) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.executors[router] = executor
+}
+
+// Executor returns the executor of the pools of the given router.
+func (r *Registry) Executor(router common.Address) (common.Address, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	executor, ok := r.executors[router]
+	return executor, ok
+}
+
+// Quoter prices triangles against a single state. Pools are loaded lazily and
+// cached, so a quoter must only be used for one block and, like the StateDB it
+// reads from, is not safe for concurrent use.
+type Quoter struct {
+	registry *Registry
+	state    StateReader
+	pools    map[common.Address]*Pool
+}
+
+// NewQuoter creates a quoter reading pools from the given state.
+func NewQuoter(registry *Registry, state StateReader) *Quoter {
+	return &Quoter{
+		registry: registry,
+		state:    state,
+		pools:    make(map[common.Address]*Pool),
+	}
+}
+
+// QuoteTriangle computes the optimal input of a triangle that sells Token0 into
+// Pair0, Token1 into Pair1 and Token2 into Pair2.
+func (q *Quoter) QuoteTriangle(triangle pairtypes.Triangle) (*Quote, error) {
+	return q.QuoteRoute(triangle.Route())
+}
+
+// QuoteRoute computes the optimal input of a route of any length. ErrUnknownPool
+// is returned if the router of any hop is not in the registry.
+func (q *Quoter) QuoteRoute(route pairtypes.Route) (*Quote, error) {
+	legs := make([]Leg, 0, len(route.Hops))
+	for i, hop := range route.Hops {
+		fee, ok := q.registry.Fee(hop.Router)
+		if !ok {
+			return nil, fmt.Errorf("%w: router %s of leg %d", ErrUnknownPool, hop.Router, i)
+		}
+		pool, err := q.pool(hop.Pair, fee)
+		if err != nil {
+			return nil, err
+		}
+		legs = append(legs, Leg{Pool: pool, TokenIn: hop.Token})
+	}
+	return OptimalCycle(legs)
+}
+
+func (q *Quoter) pool(addr common.Address, fee Fee) (*Pool, error) {
+	if pool, ok := q.pools[addr]; ok && pool.Fee == fee {
+		return pool, nil
+	}
+	pool, err := ReadPool(q.state, addr, fee)
+	if err != nil {
+		return nil, err
+	}
+	q.pools[addr] = pool
+	return pool, nil
+}
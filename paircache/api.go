@@ -0,0 +1,46 @@
+package paircache
+
+// API 提供 pair 缓存的管理接口，注册在 pair 命名空间下，仅通过 IPC 与认证端点访问
+type API struct {
+	service *Service
+}
+
+// ReloadResult 是 pair_reload 的返回结果，只包含差异的数量以避免返回过大的列表
+type ReloadResult struct {
+	Generation       uint64 `json:"generation"`
+	Triangles        int    `json:"triangles"`
+	Routes           int    `json:"routes"`
+	Discovered       int    `json:"discovered"`
+	Pairs            int    `json:"pairs"`
+	Topics           int    `json:"topics"`
+	AddedTriangles   int    `json:"addedTriangles"`
+	RemovedTriangles int    `json:"removedTriangles"`
+	ChangedTriangles int    `json:"changedTriangles"`
+	AddedPairs       int    `json:"addedPairs"`
+	RemovedPairs     int    `json:"removedPairs"`
+	AddedTopics      int    `json:"addedTopics"`
+	RemovedTopics    int    `json:"removedTopics"`
+}
+
+// Reload 立即从数据源重新加载 triangle 与 topic 并原子替换当前快照
+func (api *API) Reload() (*ReloadResult, error) {
+	snap, diff, err := api.service.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return &ReloadResult{
+		Generation:       snap.Generation(),
+		Triangles:        snap.TriangleCount(),
+		Routes:           snap.RouteCount(),
+		Discovered:       snap.DiscoveredCount(),
+		Pairs:            snap.PairCount(),
+		Topics:           snap.TopicCount(),
+		AddedTriangles:   len(diff.AddedTriangles),
+		RemovedTriangles: len(diff.RemovedTriangles),
+		ChangedTriangles: len(diff.ChangedTriangles),
+		AddedPairs:       len(diff.AddedPairs),
+		RemovedPairs:     len(diff.RemovedPairs),
+		AddedTopics:      len(diff.AddedTopics),
+		RemovedTopics:    len(diff.RemovedTopics),
+	}, nil
+}
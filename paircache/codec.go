@@ -0,0 +1,215 @@
+package paircache
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/paircache/amm"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+// 套利合约查询结果的布局，N 为 route 的跳数，查询结果按 ABI 解码为 int256[] 后依次为：
+//
+//	[0, N)     每一跳的执行合约地址
+//	[N, 2N)    每一跳 pair 的状态快照，用于执行时校验状态未变
+//	[2N, ...)  网格采样点，每个采样点 2N+2 个元素：AmountIn、N 个 Quote、N 个 Amount、Profit
+//
+// 已部署的合约只提供三跳的 arbitrageQuery，其余跳数的 route 只能由原生 AMM 定价评估
+
+// executeMethod 是套利合约的执行方法，它不声明参数，直接从 calldata 读取紧凑编码的执行参数
+const executeMethod = "arb_wcnwzblucpyf"
+
+// executionAmountBits 是执行 calldata 中每个金额的位宽
+const executionAmountBits = 96
+
+var (
+	// ErrMalformedQuery 表示查询结果的长度与 route 的跳数不匹配
+	ErrMalformedQuery = errors.New("malformed arbitrage query result")
+
+	// ErrUnsupportedQuery 表示 route 的跳数无法通过合约查询
+	ErrUnsupportedQuery = errors.New("arbitrage contract only queries three hop routes")
+
+	// ErrUnsupportedExecution 表示 route 的跳数无法由合约执行
+	ErrUnsupportedExecution = errors.New("arbitrage contract only executes three hop routes")
+
+	// ErrUnknownExecutor 表示合约查询尚未报告 router 对应的执行合约地址
+	ErrUnknownExecutor = errors.New("executor of router not known")
+)
+
+// QueryPoint 是查询网格中的一个采样点，AmountIn 为 0 表示该比例下已无利润
+type QueryPoint struct {
+	AmountIn *big.Int
+	Quotes   []*big.Int
+	Amounts  []*big.Int
+	Profit   *big.Int
+}
+
+// QueryResult 是按 route 跳数解析后的查询结果
+type QueryResult struct {
+	Executors []common.Address
+	Snapshots []*big.Int
+	Points    []QueryPoint
+}
+
+// FirstEmpty 返回第一个无利润采样点的索引，所有采样点都有利润时返回采样点数量
+func (r *QueryResult) FirstEmpty() int {
+	for i, point := range r.Points {
+		if point.AmountIn.Sign() == 0 {
+			return i
+		}
+	}
+	return len(r.Points)
+}
+
+// queryMethod 返回 route 对应的查询方法及参数
+func queryMethod(route pairtypes.Route) (string, interface{}, error) {
+	if n := len(route.Hops); n != 3 {
+		return "", nil, fmt.Errorf("%w: route %d has %d hops", ErrUnsupportedQuery, route.ID, n)
+	}
+	hops := route.Hops
+	return "arbitrageQuery", &pairtypes.ITriangularArbitrageTriangular{
+		Token0: hops[0].Token, Router0: hops[0].Router, Pair0: hops[0].Pair,
+		Token1: hops[1].Token, Router1: hops[1].Router, Pair1: hops[1].Pair,
+		Token2: hops[2].Token, Router2: hops[2].Router, Pair2: hops[2].Pair,
+	}, nil
+}
+
+// EncodeQuery 编码 route 在 [start, end] 比例区间内切分为 pieces 份的网格查询
+func EncodeQuery(route pairtypes.Route, start, end, pieces *big.Int) ([]byte, error) {
+	method, arg, err := queryMethod(route)
+	if err != nil {
+		return nil, err
+	}
+	return Encoder(method, arg, start, end, pieces)
+}
+
+// DecodeQuery 按 ABI 解码查询返回值并按 route 跳数拆分为 QueryResult
+func DecodeQuery(route pairtypes.Route, output []byte) (*QueryResult, error) {
+	method, _, err := queryMethod(route)
+	if err != nil {
+		return nil, err
+	}
+	values, err := ABI.Unpack(method, output)
+	if err != nil {
+		return nil, err
+	}
+	rois, ok := values[0].([]*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected type %T", ErrMalformedQuery, values[0])
+	}
+	n := len(route.Hops)
+	if len(rois) < 2*n || (len(rois)-2*n)%(2*n+2) != 0 {
+		return nil, fmt.Errorf("%w: %d values for %d hops", ErrMalformedQuery, len(rois), n)
+	}
+	result := &QueryResult{
+		Executors: make([]common.Address, n),
+		Snapshots: rois[n : 2*n],
+	}
+	for i := 0; i < n; i++ {
+		result.Executors[i] = common.BigToAddress(rois[i])
+	}
+	for rest := rois[2*n:]; len(rest) > 0; rest = rest[2*n+2:] {
+		result.Points = append(result.Points, QueryPoint{
+			AmountIn: rest[0],
+			Quotes:   rest[1 : n+1],
+			Amounts:  rest[n+1 : 2*n+1],
+			Profit:   rest[2*n+1],
+		})
+	}
+	return result, nil
+}
+
+// RecordExecutors 将合约查询报告的每一跳执行合约地址按 router 记录到 registry，供 QuoteResult 使用
+func RecordExecutors(registry *amm.Registry, route pairtypes.Route, result *QueryResult) {
+	for i, hop := range route.Hops {
+		if i < len(result.Executors) {
+			registry.SetExecutor(hop.Router, result.Executors[i])
+		}
+	}
+}
+
+// QuoteResult 将原生 AMM 报价转换为只含一个采样点的查询结果，以便不经合约查询直接编码执行 calldata。
+// 每一跳的执行合约地址取自合约查询此前为其 router 报告的地址，尚未报告时返回 ErrUnknownExecutor，
+// 调用方应改用合约查询。状态快照为 pair 的 reserves 存储槽，原生报价按链上取整精确计算，
+// 因此每一跳的 Quote 与 Amount 相同
+func QuoteResult(registry *amm.Registry, route pairtypes.Route, quote *amm.Quote) (*QueryResult, error) {
+	n := len(route.Hops)
+	if len(quote.Amounts) != n || len(quote.Snapshots) != n {
+		return nil, fmt.Errorf("%w: quote for %d hops, route has %d", ErrMalformedQuery, len(quote.Amounts), n)
+	}
+	result := &QueryResult{
+		Executors: make([]common.Address, n),
+		Snapshots: make([]*big.Int, n),
+		Points: []QueryPoint{{
+			AmountIn: quote.AmountIn,
+			Quotes:   quote.Amounts,
+			Amounts:  quote.Amounts,
+			Profit:   quote.Profit,
+		}},
+	}
+	for i, hop := range route.Hops {
+		executor, ok := registry.Executor(hop.Router)
+		if !ok {
+			return nil, fmt.Errorf("%w: router %s of hop %d", ErrUnknownExecutor, hop.Router, i)
+		}
+		result.Executors[i] = executor
+		result.Snapshots[i] = quote.Snapshots[i].Big()
+	}
+	return result, nil
+}
+
+// EncodeExecution 根据查询结果的某个采样点编码套利合约的执行 calldata，紧凑编码格式为：
+//
+//	执行方法选择器 | 快照哈希首字节 | 地址与金额交替排列
+//
+// 选择器取自 ABI 中的 executeMethod，其余部分由合约直接读取。地址依次为各跳的执行合约地址
+// 及各跳的 token、pair，金额依次为 AmountIn、第一个 Quote、各跳的 Amount 和 Profit，
+// 每个金额以 uint96 紧跟在同序号的地址之后，多出的地址不带金额。合约只能执行三跳的 route
+func EncodeExecution(route pairtypes.Route, result *QueryResult, index int) ([]byte, error) {
+	n := len(route.Hops)
+	if n != 3 {
+		return nil, fmt.Errorf("%w: route %d has %d hops", ErrUnsupportedExecution, route.ID, n)
+	}
+	if len(result.Executors) != n || len(result.Snapshots) != n {
+		return nil, fmt.Errorf("%w: query result for %d hops, route has %d", ErrMalformedQuery, len(result.Executors), n)
+	}
+	if index < 0 || index >= len(result.Points) {
+		return nil, fmt.Errorf("sample point %d out of range", index)
+	}
+	point := result.Points[index]
+
+	snapshots := make([][]byte, n)
+	for i, snapshot := range result.Snapshots {
+		snapshots[i] = math.U256Bytes(new(big.Int).Set(snapshot))
+	}
+	addresses := append([]common.Address(nil), result.Executors...)
+	for _, hop := range route.Hops {
+		addresses = append(addresses, hop.Token, hop.Pair)
+	}
+	amounts := append([]*big.Int{point.AmountIn, point.Quotes[0]}, point.Amounts...)
+	amounts = append(amounts, point.Profit)
+
+	for i, amount := range amounts {
+		if amount.Sign() < 0 || amount.BitLen() > executionAmountBits {
+			return nil, fmt.Errorf("amount %d out of uint%d range: %v", i, executionAmountBits, amount)
+		}
+	}
+	selector, err := Encoder(executeMethod)
+	if err != nil {
+		return nil, err
+	}
+	calldata := make([]byte, 0, len(selector)+1+len(addresses)*common.AddressLength+len(amounts)*executionAmountBits/8)
+	calldata = append(calldata, selector...)
+	calldata = append(calldata, crypto.Keccak256(snapshots...)[0])
+	for i, addr := range addresses {
+		calldata = append(calldata, addr.Bytes()...)
+		if i < len(amounts) {
+			calldata = append(calldata, math.U256Bytes(new(big.Int).Set(amounts[i]))[32-executionAmountBits/8:]...)
+		}
+	}
+	return calldata, nil
+}
@@ -0,0 +1,195 @@
+package paircache
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/paircache/amm"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+func testRoute(hops int) pairtypes.Route {
+	route := pairtypes.Route{ID: 1}
+	for i := 0; i < hops; i++ {
+		route.Hops = append(route.Hops, pairtypes.Hop{
+			Token:  common.BigToAddress(big.NewInt(int64(0x10 + i))),
+			Router: common.BigToAddress(big.NewInt(int64(0x20 + i))),
+			Pair:   common.BigToAddress(big.NewInt(int64(0x30 + i))),
+		})
+	}
+	return route
+}
+
+// testQueryOutput builds the raw contract output of a route query with the
+// given number of sample points, the first empty point at index empty.
+func testQueryOutput(t *testing.T, method string, hops, points, empty int) []byte {
+	var rois []*big.Int
+	for i := 0; i < hops; i++ {
+		rois = append(rois, big.NewInt(int64(0x40+i)))
+	}
+	for i := 0; i < hops; i++ {
+		rois = append(rois, big.NewInt(int64(1000+i)))
+	}
+	for p := 0; p < points; p++ {
+		for i := 0; i < 2*hops+2; i++ {
+			value := int64(0)
+			if p < empty {
+				value = int64(100*(p+1) + i)
+			}
+			rois = append(rois, big.NewInt(value))
+		}
+	}
+	output, err := ABI.Methods[method].Outputs.Pack(rois)
+	if err != nil {
+		t.Fatalf("failed to pack query output: %v", err)
+	}
+	return output
+}
+
+// TestQueryABI checks that only the methods of the deployed arbitrage contract
+// are encoded.
+func TestQueryABI(t *testing.T) {
+	var methods []string
+	for name := range ABI.Methods {
+		methods = append(methods, name)
+	}
+	sort.Strings(methods)
+	if have, want := strings.Join(methods, ","), "arb_wcnwzblucpyf,arbitrageQuery,isTriangularValid"; have != want {
+		t.Fatalf("contract methods mismatch: have %s, want %s", have, want)
+	}
+}
+
+func TestEncodeQueryMethod(t *testing.T) {
+	data, err := EncodeQuery(testRoute(3), big.NewInt(0), big.NewInt(10000), big.NewInt(1000))
+	if err != nil {
+		t.Fatalf("failed to encode query: %v", err)
+	}
+	if !bytes.Equal(data[:4], ABI.Methods["arbitrageQuery"].ID) {
+		t.Error("selector mismatch, want arbitrageQuery")
+	}
+	for _, hops := range []int{1, 2, 4} {
+		if _, err := EncodeQuery(testRoute(hops), big.NewInt(0), big.NewInt(1), big.NewInt(1)); !errors.Is(err, ErrUnsupportedQuery) {
+			t.Errorf("%d hops: expected unsupported query error, have %v", hops, err)
+		}
+	}
+}
+
+func TestDecodeQuery(t *testing.T) {
+	route := testRoute(3)
+	result, err := DecodeQuery(route, testQueryOutput(t, "arbitrageQuery", 3, 3, 2))
+	if err != nil {
+		t.Fatalf("failed to decode query: %v", err)
+	}
+	if len(result.Executors) != 3 || result.Executors[2] != common.BigToAddress(big.NewInt(0x42)) {
+		t.Fatalf("executors mismatch: %v", result.Executors)
+	}
+	if len(result.Points) != 3 || result.FirstEmpty() != 2 {
+		t.Fatalf("points mismatch: have %d, first empty %d", len(result.Points), result.FirstEmpty())
+	}
+	point := result.Points[1]
+	if point.AmountIn.Int64() != 200 || len(point.Quotes) != 3 || point.Amounts[0].Int64() != 204 || point.Profit.Int64() != 207 {
+		t.Fatalf("point mismatch: %+v", point)
+	}
+	// A two hop result must not be accepted for a three hop route
+	if _, err := DecodeQuery(route, testQueryOutput(t, "arbitrageQuery", 2, 1, 1)); !errors.Is(err, ErrMalformedQuery) {
+		t.Fatalf("expected malformed query error, have %v", err)
+	}
+}
+
+// TestEncodeExecutionTriangle checks that three hop routes keep the calldata
+// layout the arbitrage contract was deployed with.
+func TestEncodeExecutionTriangle(t *testing.T) {
+	route := testRoute(3)
+	result, err := DecodeQuery(route, testQueryOutput(t, "arbitrageQuery", 3, 1, 1))
+	if err != nil {
+		t.Fatalf("failed to decode query: %v", err)
+	}
+	calldata, err := EncodeExecution(route, result, 0)
+	if err != nil {
+		t.Fatalf("failed to encode execution: %v", err)
+	}
+	var (
+		hash   = crypto.Keccak256(common.BigToHash(big.NewInt(1000)).Bytes(), common.BigToHash(big.NewInt(1001)).Bytes(), common.BigToHash(big.NewInt(1002)).Bytes())
+		amount = func(v int64) string { return hex.EncodeToString(common.BigToHash(big.NewInt(v)).Bytes()[20:]) }
+		addr   = func(a common.Address) string { return hex.EncodeToString(a.Bytes()) }
+		hops   = route.Hops
+	)
+	want := strings.Join([]string{
+		"00000000", hex.EncodeToString(hash[:1]),
+		addr(result.Executors[0]), amount(100), // AmountIn
+		addr(result.Executors[1]), amount(101), // First quote
+		addr(result.Executors[2]), amount(104), // First amount
+		addr(hops[0].Token), amount(105),
+		addr(hops[0].Pair), amount(106),
+		addr(hops[1].Token), amount(107), // Profit
+		addr(hops[1].Pair), addr(hops[2].Token), addr(hops[2].Pair),
+	}, "")
+	if have := hex.EncodeToString(calldata); have != want {
+		t.Fatalf("calldata mismatch:\nhave %s\nwant %s", have, want)
+	}
+	if _, err := EncodeExecution(route, result, 1); err == nil {
+		t.Fatal("expected error for out of range sample point")
+	}
+	// Amounts must fit the uint96 fields
+	result.Points[0].Profit = new(big.Int).Lsh(common.Big1, 96)
+	if _, err := EncodeExecution(route, result, 0); err == nil {
+		t.Fatal("expected error for amount overflowing uint96")
+	}
+}
+
+func TestEncodeExecutionHops(t *testing.T) {
+	route := testRoute(4)
+	result := &QueryResult{
+		Executors: make([]common.Address, 4),
+		Snapshots: []*big.Int{common.Big1, common.Big1, common.Big1, common.Big1},
+		Points:    []QueryPoint{{AmountIn: common.Big1, Quotes: []*big.Int{common.Big1}, Amounts: []*big.Int{common.Big1}, Profit: common.Big1}},
+	}
+	if _, err := EncodeExecution(route, result, 0); !errors.Is(err, ErrUnsupportedExecution) {
+		t.Fatalf("expected unsupported execution error, have %v", err)
+	}
+}
+
+func TestQuoteResult(t *testing.T) {
+	route := testRoute(3)
+	quote := &amm.Quote{
+		AmountIn:  big.NewInt(100),
+		AmountOut: big.NewInt(130),
+		Profit:    big.NewInt(30),
+		Amounts:   []*big.Int{big.NewInt(110), big.NewInt(120), big.NewInt(130)},
+		Snapshots: []common.Hash{{1}, {2}, {3}},
+	}
+	// The executors are only known once a contract query reported them
+	registry := amm.NewRegistry()
+	if _, err := QuoteResult(registry, route, quote); !errors.Is(err, ErrUnknownExecutor) {
+		t.Fatalf("expected unknown executor error, have %v", err)
+	}
+	queried, err := DecodeQuery(route, testQueryOutput(t, "arbitrageQuery", 3, 1, 1))
+	if err != nil {
+		t.Fatalf("failed to decode query: %v", err)
+	}
+	RecordExecutors(registry, route, queried)
+
+	result, err := QuoteResult(registry, route, quote)
+	if err != nil {
+		t.Fatalf("failed to convert quote: %v", err)
+	}
+	if result.Executors[2] != queried.Executors[2] || result.Snapshots[1].Cmp(common.Hash{2}.Big()) != 0 {
+		t.Fatalf("executors or snapshots mismatch: %v %v", result.Executors, result.Snapshots)
+	}
+	if len(result.Points) != 1 || result.Points[0].Profit.Int64() != 30 || result.Points[0].Quotes[0].Int64() != 110 {
+		t.Fatalf("point mismatch: %+v", result.Points)
+	}
+	if _, err := EncodeExecution(route, result, 0); err != nil {
+		t.Fatalf("failed to encode execution: %v", err)
+	}
+	if _, err := QuoteResult(registry, testRoute(2), quote); !errors.Is(err, ErrMalformedQuery) {
+		t.Fatalf("expected malformed query error, have %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package paircache
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/paircache/pipeline"
+	"github.com/ethereum/go-ethereum/paircache/scheduler"
+	"github.com/ethereum/go-ethereum/paircache/submit"
+)
+
+// Config 是 pair 缓存服务的配置，Source 为空时不启用该服务
+type Config struct {
+	Source          string           `toml:",omitempty"` // triangle 数据源类型：mysql、file 或 memory
+	MySQLDSN        string           `toml:",omitempty"` // mysql 数据源的 DSN
+	TriangleFile    string           `toml:",omitempty"` // file 数据源的 JSON/CSV 文件路径
+	TopicFile       string           `toml:",omitempty"` // JSON 格式的 topic 文件路径
+	TriangleRefresh time.Duration    // triangle 刷新周期
+	TopicRefresh    time.Duration    // topic 刷新周期
+	QueueSize       int              // 等待套利评估的区块队列长度
+	MaxTriangles    int              // 每个区块最多评估的 route 数量，0 表示只受时间预算限制
+	Budget          time.Duration    // 每个区块套利评估的时间预算
+	Mempool         bool             // 是否模拟 pending 交易并评估其后的 backrun 套利
+	MempoolWorkers  int              // 同时模拟评估的 pending 交易数量
+	MempoolBudget   time.Duration    // 单个 pending 交易后 backrun 评估的时间预算
+	RunHistory      uint64           // 评估结果持久化保留的区块数，0 表示全部保留
+	DiscoverHops    int              // 从 pair 图中发现 route 的最大跳数，0 表示不发现。非三跳的 route 只在所有 pool 均为已知类型时评估
+	DiscoverBases   []common.Address // 发现的 route 的起止 token
+	DiscoverLimit   int              // 最多发现的 route 数量
+	Submit          submit.Config    // 套利 bundle 的签名与提交配置
+}
+
+// DefaultConfig 是 pair 缓存服务的默认配置
+var DefaultConfig = Config{
+	TriangleRefresh: time.Hour,
+	TopicRefresh:    time.Minute,
+	QueueSize:       4,
+	Budget:          scheduler.DefaultBudget,
+	MempoolWorkers:  pipeline.DefaultMempoolWorkers,
+	MempoolBudget:   pipeline.DefaultMempoolBudget,
+	RunHistory:      100000,
+	DiscoverBases:   []common.Address{common.HexToAddress("0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c")}, // WBNB
+	DiscoverLimit:   10000,
+	Submit:          submit.DefaultConfig,
+}
+
+// Enabled 返回是否配置了 triangle 数据源
+func (c *Config) Enabled() bool {
+	return c.Source != ""
+}
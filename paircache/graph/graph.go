@@ -0,0 +1,131 @@
+// Package graph discovers arbitrage routes from the graph of known pools, in
+// which tokens are vertices and every pool is an edge between its two tokens.
+package graph
+
+import (
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+const (
+	// MinHops is the shortest route, a round trip through two pools of the
+	// same token pair on different exchanges.
+	MinHops = 2
+
+	// MaxHops is the longest route the evaluator supports.
+	MaxHops = 4
+)
+
+// edge is a pool as seen from one of its tokens.
+type edge struct {
+	pair     common.Address
+	router   common.Address
+	tokenOut common.Address
+}
+
+// Graph is an undirected multigraph of tokens connected by pools.
+type Graph struct {
+	edges map[common.Address][]edge
+	pools map[common.Address]bool
+}
+
+// New creates an empty pool graph.
+func New() *Graph {
+	return &Graph{
+		edges: make(map[common.Address][]edge),
+		pools: make(map[common.Address]bool),
+	}
+}
+
+// FromTriangles builds the pool graph spanned by the legs of the given
+// triangles. Every leg sells its token into its pair for the next leg's token,
+// which is enough to recover both tokens of the pair.
+func FromTriangles(triangles []pairtypes.Triangle) *Graph {
+	g := New()
+	for _, triangle := range triangles {
+		hops := triangle.Route().Hops
+		for i, hop := range hops {
+			g.AddPool(hop.Pair, hop.Router, hop.Token, hops[(i+1)%len(hops)].Token)
+		}
+	}
+	return g
+}
+
+// AddPool inserts a pool trading token0 against token1 through router. Pools
+// already in the graph are ignored.
+func (g *Graph) AddPool(pair, router, token0, token1 common.Address) {
+	if g.pools[pair] || token0 == token1 {
+		return
+	}
+	g.pools[pair] = true
+	g.edges[token0] = append(g.edges[token0], edge{pair: pair, router: router, tokenOut: token1})
+	g.edges[token1] = append(g.edges[token1], edge{pair: pair, router: router, tokenOut: token0})
+}
+
+// Pools returns the number of pools in the graph.
+func (g *Graph) Pools() int {
+	return len(g.pools)
+}
+
+// Cycles enumerates the simple cycles starting and ending at one of the base
+// tokens with between minHops and maxHops pools, no pool or intermediate token
+// visited twice. Both directions of a cycle are returned since they are
+// different trades. At most limit routes are returned, in a deterministic order.
+func (g *Graph) Cycles(bases []common.Address, minHops, maxHops, limit int) []pairtypes.Route {
+	minHops = max(minHops, MinHops)
+	maxHops = min(maxHops, MaxHops)
+
+	// Sort the adjacency lists so that truncated results are reproducible
+	for _, edges := range g.edges {
+		sort.Slice(edges, func(i, j int) bool {
+			return edges[i].pair.Cmp(edges[j].pair) < 0
+		})
+	}
+	var (
+		routes []pairtypes.Route
+		seen   = make(map[int64]bool)
+		hops   = make([]pairtypes.Hop, 0, maxHops)
+		pairs  = make(map[common.Address]bool)
+		tokens = make(map[common.Address]bool)
+	)
+	var walk func(base, token common.Address) bool
+	walk = func(base, token common.Address) bool {
+		for _, e := range g.edges[token] {
+			if pairs[e.pair] {
+				continue
+			}
+			hops = append(hops, pairtypes.Hop{Token: token, Router: e.router, Pair: e.pair})
+			switch {
+			case e.tokenOut == base:
+				if len(hops) >= minHops {
+					route := pairtypes.Route{ID: pairtypes.NewRouteID(hops), Hops: append([]pairtypes.Hop(nil), hops...)}
+					if !seen[route.ID] {
+						seen[route.ID] = true
+						routes = append(routes, route)
+						if len(routes) >= limit {
+							return false
+						}
+					}
+				}
+			case len(hops) < maxHops && !tokens[e.tokenOut]:
+				pairs[e.pair], tokens[e.tokenOut] = true, true
+				more := walk(base, e.tokenOut)
+				delete(pairs, e.pair)
+				delete(tokens, e.tokenOut)
+				if !more {
+					return false
+				}
+			}
+			hops = hops[:len(hops)-1]
+		}
+		return true
+	}
+	for _, base := range bases {
+		if limit <= 0 || !walk(base, base) {
+			break
+		}
+	}
+	return routes
+}
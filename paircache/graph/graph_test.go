@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+var (
+	wbnb = common.HexToAddress("0xa")
+	busd = common.HexToAddress("0xb")
+	cake = common.HexToAddress("0xc")
+	usdt = common.HexToAddress("0xd")
+
+	router0 = common.HexToAddress("0x100")
+	router1 = common.HexToAddress("0x101")
+)
+
+func testGraph() *Graph {
+	g := New()
+	g.AddPool(common.HexToAddress("0x1"), router0, wbnb, busd)
+	g.AddPool(common.HexToAddress("0x2"), router1, wbnb, busd) // Same pair on another exchange
+	g.AddPool(common.HexToAddress("0x3"), router0, busd, cake)
+	g.AddPool(common.HexToAddress("0x4"), router0, cake, wbnb)
+	g.AddPool(common.HexToAddress("0x5"), router0, cake, usdt)
+	g.AddPool(common.HexToAddress("0x6"), router0, usdt, wbnb)
+	return g
+}
+
+func countByLength(routes []pairtypes.Route) map[int]int {
+	counts := make(map[int]int)
+	for _, route := range routes {
+		counts[len(route.Hops)]++
+	}
+	return counts
+}
+
+func TestCycles(t *testing.T) {
+	routes := testGraph().Cycles([]common.Address{wbnb}, 2, 4, 1000)
+
+	// 2 hops: 0x1->0x2 and 0x2->0x1
+	// 3 hops: {0x1,0x2} x busd-cake-wbnb, and cake-usdt-wbnb, all in both directions
+	// 4 hops: {0x1,0x2} x busd-cake-usdt-wbnb in both directions
+	want := map[int]int{2: 2, 3: 6, 4: 4}
+	if have := countByLength(routes); len(have) != len(want) || have[2] != want[2] || have[3] != want[3] || have[4] != want[4] {
+		t.Fatalf("route lengths mismatch: have %v, want %v", have, want)
+	}
+	for _, route := range routes {
+		if route.ID >= 0 {
+			t.Fatalf("discovered route with non-negative id %d", route.ID)
+		}
+		if route.Hops[0].Token != wbnb {
+			t.Fatalf("route does not start at the base token: %+v", route)
+		}
+		pairs := make(map[common.Address]bool)
+		for i, hop := range route.Hops {
+			if pairs[hop.Pair] {
+				t.Fatalf("route %d visits pair %v twice", route.ID, hop.Pair)
+			}
+			pairs[hop.Pair] = true
+			if i > 0 && hop.Token == wbnb {
+				t.Fatalf("route %d passes through the base token", route.ID)
+			}
+		}
+	}
+}
+
+func TestCyclesLimits(t *testing.T) {
+	g := testGraph()
+	if routes := g.Cycles([]common.Address{wbnb}, 3, 3, 1000); len(routes) != 6 {
+		t.Fatalf("3 hop route count mismatch: have %d, want 6", len(routes))
+	}
+	first := g.Cycles([]common.Address{wbnb}, 2, 4, 5)
+	if len(first) != 5 {
+		t.Fatalf("limited route count mismatch: have %d, want 5", len(first))
+	}
+	// Truncation must be deterministic across runs
+	again := g.Cycles([]common.Address{wbnb}, 2, 4, 5)
+	for i := range first {
+		if !first[i].Equal(again[i]) {
+			t.Fatalf("route %d differs between runs", i)
+		}
+	}
+	if routes := g.Cycles([]common.Address{usdt, common.HexToAddress("0xe")}, 2, 2, 1000); len(routes) != 0 {
+		t.Fatalf("unexpected 2 hop routes: %+v", routes)
+	}
+}
+
+func TestFromTriangles(t *testing.T) {
+	triangle := pairtypes.Triangle{
+		ID:     1,
+		Token0: wbnb.Hex(), Router0: router0.Hex(), Pair0: "0x1",
+		Token1: busd.Hex(), Router1: router0.Hex(), Pair1: "0x3",
+		Token2: cake.Hex(), Router2: router0.Hex(), Pair2: "0x4",
+	}
+	g := FromTriangles([]pairtypes.Triangle{triangle, triangle})
+	if g.Pools() != 3 {
+		t.Fatalf("pool count mismatch: have %d, want 3", g.Pools())
+	}
+	routes := g.Cycles([]common.Address{wbnb}, 2, 4, 1000)
+	if len(routes) != 2 {
+		t.Fatalf("route count mismatch: have %d, want 2", len(routes))
+	}
+	// The triangle itself must be rediscovered with the same legs
+	found := false
+	for _, route := range routes {
+		want := triangle.Route()
+		want.ID = route.ID
+		found = found || route.Equal(want)
+	}
+	if !found {
+		t.Fatalf("triangle not rediscovered: %+v", routes)
+	}
+}
@@ -1,58 +1,41 @@
 package mysqldb
 
 import (
+	"errors"
 	"fmt"
-	"log"
-	"sync"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
-// DB 是全局数据库连接池
-var (
-	mysqldb  *sqlx.DB
-	once     sync.Once
-	user     = "root"
-	password = "FG0mKQ35JRvaXxacGgBtXT1uwerwoVwi"
-	// hostname = "135.181.218.173:3306"
-	hostname        = "192.168.100.102:3306"
-	dbname          = "arbitrage-bsc"
+// 连接池默认配置
+const (
 	maxOpenConns    = 10
 	maxIdleConns    = 5
 	connMaxLifetime = time.Hour
 )
 
-// InitDB 初始化数据库连接
-func init() {
-	once.Do(func() {
-		// 构建 DSN (Data Source Name)
-		dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true", user, password, hostname, dbname)
-
-		// 打开数据库连接
-		var err error
-		mysqldb, err = sqlx.Open("mysql", dsn)
-		if err != nil {
-			log.Fatalf("Error opening database: %v", err)
-		}
-
-		// 配置连接池
-		mysqldb.SetMaxOpenConns(maxOpenConns)
-		mysqldb.SetMaxIdleConns(maxIdleConns)
-		mysqldb.SetConnMaxLifetime(connMaxLifetime)
+// Open 根据 DSN 打开数据库连接池并验证连接，DSN 格式为 user:password@tcp(host:port)/dbname
+func Open(dsn string) (*sqlx.DB, error) {
+	if dsn == "" {
+		return nil, errors.New("empty mysql dsn")
+	}
+	// 打开数据库连接
+	db, err := sqlx.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
 
-		// 验证连接
-		if err = mysqldb.Ping(); err != nil {
-			log.Fatalf("Error pinging database: %v", err)
-		}
-	})
-}
+	// 配置连接池
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(connMaxLifetime)
 
-// GetDB 返回数据库连接池对象
-func GetMysqlDB() *sqlx.DB {
-	if mysqldb == nil {
-		log.Fatal("Database not initialized. Call InitDB first.")
+	// 验证连接
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error pinging database: %w", err)
 	}
-	return mysqldb
+	return db, nil
 }
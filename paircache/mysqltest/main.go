@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
@@ -15,6 +16,8 @@ var abiStr = "[{\"inputs\":[],\"name\":\"arb_wcnwzblucpyf\",\"outputs\":[],\"sta
 
 var ABI *abi.ABI
 
+var dsn = flag.String("dsn", "", "mysql DSN, e.g. user:password@tcp(host:3306)/arbitrage-bsc")
+
 func init() {
 	// 加载三角合约abi
 	if parsed, err := abi.JSON(strings.NewReader(abiStr)); err != nil {
@@ -32,7 +35,13 @@ func Encoder(name string, args ...interface{}) ([]byte, error) {
 
 func main() {
 	// 初始化数据库连接
-	mysqlDB := mysqldb.GetMysqlDB()
+	flag.Parse()
+	mysqlDB, err := mysqldb.Open(*dsn)
+	if err != nil {
+		fmt.Printf("连接数据库失败，err=%v\n", err)
+		return
+	}
+	defer mysqlDB.Close()
 
 	// 使用流式查询，逐行处理数据
 	rows, err := mysqlDB.Queryx("SELECT id, token0, router0, pair0, token1, router1, pair1, token2, router2, pair2 FROM arbitrage_triangle limit 0, 10")
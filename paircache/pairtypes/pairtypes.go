@@ -1,17 +1,79 @@
 package pairtypes
 
 import (
+	"context"
 	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/orcaman/concurrent-map"
-	"strings"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
+// PairAPI 执行 triangle 套利评估，head 为评估所基于的区块，ctx 取消时中止评估
 type PairAPI interface {
-	PairCallBatch(triangulars []Triangle) error
+	PairCallBatch(ctx context.Context, head common.Hash, routes []Route) (*BatchResult, error)
 	CallBatch() (string, error)
 }
 
+// PendingAPI 在 head 区块之上模拟 pending 交易，用于评估紧跟该交易的 backrun 套利
+type PendingAPI interface {
+	SimulatePending(ctx context.Context, head common.Hash, tx *types.Transaction) (PendingState, error)
+}
+
+// PendingState 是 pending 交易在 head 区块之上执行后的状态
+type PendingState interface {
+	Logs() []*types.Log // 交易产生的日志，交易回滚时为空
+	GasUsed() uint64
+	// PairCallBatch 在交易执行后的状态上评估 route，语义与 PairAPI.PairCallBatch 一致
+	PairCallBatch(ctx context.Context, routes []Route) (*BatchResult, error)
+}
+
+// BatchResult 是一次 PairCallBatch 的完整评估结果
+type BatchResult struct {
+	Screened      int           // 原生定价筛除、未发起 eth_call 的 route 数量
+	Quoted        int           // 原生定价直接得出结果、未发起 eth_call 的 route 数量
+	Results       []RouteResult // 每个未被筛除的 route 一条结果，来自原生定价或合约评估
+	Opportunities []Opportunity // 按利润降序排列且 pair 互不重叠的套利机会
+	QueryTime     time.Duration // 原生定价与合约评估耗时
+	EstimateTime  time.Duration // gas 预估耗时
+}
+
+// RouteResult 是单个 route 的合约评估结果
+type RouteResult struct {
+	Route  int64    `json:"route"`
+	Profit *big.Int `json:"profit,omitempty"` // 为空表示未达到最小利润
+	Gas    uint64   `json:"gas,omitempty"`    // 仅被选中的 route 有 gas 预估
+	Chosen bool     `json:"chosen"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// RouteError 是单个 route 评估失败的错误
+type RouteError struct {
+	Route int64
+	Err   error
+}
+
+func (e *RouteError) Error() string {
+	return fmt.Sprintf("route %d: %v", e.Route, e.Err)
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// Opportunity 是一条评估通过的套利机会，CallData 发送到 To 即可执行该套利
+type Opportunity struct {
+	Route    Route
+	To       common.Address
+	CallData []byte
+	Profit   *big.Int
+	Gas      uint64 // 基于 head 状态预估的 gas，预估失败时为 0
+}
+
 type Triangle struct {
 	ID      int64  `db:"id"`
 	Token0  string `db:"token0"`
@@ -37,102 +99,287 @@ type ITriangularArbitrageTriangular struct {
 	Pair2   common.Address
 }
 
+// PairCache 持有当前生效的 PairSnapshot，读取方总是拿到一个完整的快照，
+// 刷新时在旁路构建新的快照后原子替换
 type PairCache struct {
-	TriangleMap     cmap.ConcurrentMap
-	PairTriangleMap cmap.ConcurrentMap
-	TopicMap        map[string]string
+	current atomic.Pointer[PairSnapshot]
 }
 
-// NewPairCache 创建一个新的 PairCache
+// NewPairCache 创建一个新的 PairCache，初始快照为空的第 0 代
 func NewPairCache() *PairCache {
-	return &PairCache{
-		TriangleMap:     cmap.New(),
-		PairTriangleMap: cmap.New(),
-	}
+	pc := new(PairCache)
+	pc.current.Store(emptySnapshot)
+	return pc
 }
 
-// AddTriangle 向 TriangleMap 添加一个 Triangle
-func (pc *PairCache) AddTriangle(id string, triangle Triangle) {
-	pc.TriangleMap.Set(id, triangle)
+// Snapshot 返回当前生效的快照，调用方在一次处理中应始终使用同一个快照
+func (pc *PairCache) Snapshot() *PairSnapshot {
+	return pc.current.Load()
 }
 
-// AddPairTriangle 向 PairTriangleMap 添加一个元素
-func (pc *PairCache) AddPairTriangle(pair string, id string) {
-	// 如果 key 不存在，则创建一个新的 Set
-	if set, exists := pc.PairTriangleMap.Get(pair); exists {
-		set.(*Set).Add(id)
-	} else {
-		newSet := NewSet()
-		newSet.Add(id)
-		pc.PairTriangleMap.Set(pair, newSet)
-	}
+// Swap 原子地将当前快照替换为 next，并返回被替换的快照
+func (pc *PairCache) Swap(next *PairSnapshot) *PairSnapshot {
+	return pc.current.Swap(next)
 }
 
-// GetTriangle 安全地从 TriangleMap 中获取 Triangle
+// GetTriangle 从当前快照中获取 Triangle
 func (pc *PairCache) GetTriangle(id string) (Triangle, bool) {
-	if triangle, exists := pc.TriangleMap.Get(id); exists {
-		return triangle.(Triangle), true
-	} else {
-		return Triangle{}, false
-	}
+	return pc.Snapshot().Triangle(id)
 }
 
-// GetPairSet 安全地从 PairTriangleMap 中获取 Set
-func (pc *PairCache) GetPairSet(pair string) *Set {
-	if set, exists := pc.PairTriangleMap.Get(pair); exists {
-		return set.(*Set)
-	}
-	return NewSet()
+// GetPairTriangles 从当前快照中获取 pair 关联的 triangleId
+func (pc *PairCache) GetPairTriangles(pair string) []string {
+	return pc.Snapshot().PairTriangles(pair)
 }
 
-// TriangleMapSize 返回 TriangleMap 中的元素数量
+// GetTopic 从当前快照中获取 topic0 对应的 pair 操作类型
+func (pc *PairCache) GetTopic(topic string) string {
+	return pc.Snapshot().Topic(topic)
+}
+
+// TriangleMapSize 返回当前快照中 triangle 的数量
 func (pc *PairCache) TriangleMapSize() int {
-	return pc.TriangleMap.Count()
+	return pc.Snapshot().TriangleCount()
 }
 
-// PairTriangleMapSize 返回 PairTriangleMap 中的元素数量
+// PairTriangleMapSize 返回当前快照中 pair 的数量
 func (pc *PairCache) PairTriangleMapSize() int {
-	return pc.PairTriangleMap.Count()
+	return pc.Snapshot().PairCount()
+}
+
+// emptySnapshot 是不包含任何数据的第 0 代快照
+var emptySnapshot = &PairSnapshot{
+	triangles:     make(map[string]Triangle),
+	pairTriangles: make(map[string][]string),
+	routes:        make(map[string]Route),
+	pairRoutes:    make(map[string][]string),
+	topics:        make(map[string]string),
+}
+
+// PairSnapshot 是某一代 triangle、route、pair 与 topic 索引的不可变快照，创建后不允许修改，
+// 因此可以在不加锁的情况下被并发读取。routes 包含由 triangle 转换的 route 和从 pair 图中
+// 发现的 route
+type PairSnapshot struct {
+	generation    uint64
+	triangles     map[string]Triangle // triangleId -> Triangle
+	pairTriangles map[string][]string // pair 地址 -> 有序的 triangleId 列表
+	routes        map[string]Route    // routeId -> Route
+	pairRoutes    map[string][]string // pair 地址 -> 有序的 routeId 列表
+	discovered    int                 // 从 pair 图中发现的 route 数量
+	topics        map[string]string   // topic0 -> pair 操作类型
+}
+
+// NewPairSnapshot 根据 triangle 和 topic 构建一个新的快照，pair 地址统一转换为 checksum 格式
+func NewPairSnapshot(generation uint64, triangles []Triangle, topics map[string]string) *PairSnapshot {
+	return NewRouteSnapshot(generation, triangles, nil, topics)
+}
+
+// NewRouteSnapshot 在 NewPairSnapshot 的基础上额外索引从 pair 图中发现的 route，
+// 与 triangle 重复的 route 会被忽略
+func NewRouteSnapshot(generation uint64, triangles []Triangle, discovered []Route, topics map[string]string) *PairSnapshot {
+	snap := &PairSnapshot{
+		generation:    generation,
+		triangles:     make(map[string]Triangle, len(triangles)),
+		pairTriangles: make(map[string][]string),
+		routes:        make(map[string]Route, len(triangles)+len(discovered)),
+		pairRoutes:    make(map[string][]string),
+	}
+	for _, triangle := range triangles {
+		triangle.Pair0 = common.HexToAddress(triangle.Pair0).Hex()
+		triangle.Pair1 = common.HexToAddress(triangle.Pair1).Hex()
+		triangle.Pair2 = common.HexToAddress(triangle.Pair2).Hex()
+		snap.triangles[strconv.FormatInt(triangle.ID, 10)] = triangle
+	}
+	for id, triangle := range snap.triangles {
+		for _, pair := range []string{triangle.Pair0, triangle.Pair1, triangle.Pair2} {
+			snap.pairTriangles[pair] = append(snap.pairTriangles[pair], id)
+		}
+		snap.routes[id] = triangle.Route()
+	}
+	// triangle 与发现的 route 重复时保留 triangle
+	for _, route := range discovered {
+		if _, ok := snap.routes[route.Key()]; !ok {
+			snap.routes[route.Key()] = route
+			snap.discovered++
+		}
+	}
+	for id, route := range snap.routes {
+		for _, pair := range route.Pairs() {
+			snap.pairRoutes[pair.Hex()] = append(snap.pairRoutes[pair.Hex()], id)
+		}
+	}
+	// 同一个 triangle 的多条腿可能是同一个 pair，排序后去重
+	for pair, ids := range snap.pairTriangles {
+		sort.Strings(ids)
+		snap.pairTriangles[pair] = dedupSorted(ids)
+	}
+	for pair, ids := range snap.pairRoutes {
+		sort.Strings(ids)
+		snap.pairRoutes[pair] = dedupSorted(ids)
+	}
+	snap.topics = copyTopics(topics)
+	return snap
 }
 
-// Set 实现一个set
-type Set struct {
-	data cmap.ConcurrentMap
+// WithTriangles 返回 triangle 与发现的 route 被替换、topic 保持不变的下一代快照
+func (s *PairSnapshot) WithTriangles(triangles []Triangle, discovered []Route) *PairSnapshot {
+	next := NewRouteSnapshot(s.generation+1, triangles, discovered, nil)
+	next.topics = s.topics
+	return next
 }
 
-// NewSet 创建一个新的线程安全的 Set
-func NewSet() *Set {
-	return &Set{
-		data: cmap.New(),
+// WithTopics 返回 topic 被替换、triangle 与 route 索引保持不变的下一代快照
+func (s *PairSnapshot) WithTopics(topics map[string]string) *PairSnapshot {
+	return &PairSnapshot{
+		generation:    s.generation + 1,
+		triangles:     s.triangles,
+		pairTriangles: s.pairTriangles,
+		routes:        s.routes,
+		pairRoutes:    s.pairRoutes,
+		discovered:    s.discovered,
+		topics:        copyTopics(topics),
 	}
 }
 
-// Add 向 Set 中添加一个元素
-func (s *Set) Add(value string) {
-	s.data.Set(value, struct{}{})
+// Generation 返回快照的代数，每次替换快照代数加一
+func (s *PairSnapshot) Generation() uint64 {
+	return s.generation
+}
+
+// Triangle 获取 triangleId 对应的 Triangle
+func (s *PairSnapshot) Triangle(id string) (Triangle, bool) {
+	triangle, ok := s.triangles[id]
+	return triangle, ok
+}
+
+// PairTriangles 获取 pair 关联的 triangleId 列表，返回的切片不允许修改
+func (s *PairSnapshot) PairTriangles(pair string) []string {
+	return s.pairTriangles[pair]
+}
+
+// Route 获取 routeId 对应的 Route，返回值的 Hops 不允许修改
+func (s *PairSnapshot) Route(id string) (Route, bool) {
+	route, ok := s.routes[id]
+	return route, ok
+}
+
+// PairRoutes 获取 pair 关联的 routeId 列表，返回的切片不允许修改
+func (s *PairSnapshot) PairRoutes(pair string) []string {
+	return s.pairRoutes[pair]
+}
+
+// Topic 获取 topic0 对应的 pair 操作类型，不存在时返回空字符串
+func (s *PairSnapshot) Topic(topic string) string {
+	return s.topics[topic]
+}
+
+// TriangleCount 返回快照中 triangle 的数量
+func (s *PairSnapshot) TriangleCount() int {
+	return len(s.triangles)
+}
+
+// RouteCount 返回快照中 route 的数量，包含由 triangle 转换的 route
+func (s *PairSnapshot) RouteCount() int {
+	return len(s.routes)
+}
+
+// DiscoveredCount 返回快照中从 pair 图中发现的 route 数量
+func (s *PairSnapshot) DiscoveredCount() int {
+	return s.discovered
+}
+
+// PairCount 返回快照中 pair 的数量
+func (s *PairSnapshot) PairCount() int {
+	return len(s.pairRoutes)
 }
 
-// Size 返回 Set 中元素的数量
-func (s *Set) Size() int {
-	return s.data.Count()
+// TopicCount 返回快照中 topic 的数量
+func (s *PairSnapshot) TopicCount() int {
+	return len(s.topics)
 }
 
-// Remove 从 Set 中删除一个元素
-func (s *Set) Remove(value string) {
-	s.data.Remove(value)
+// PairDiff 描述两代快照之间的差异，内容发生变化的 triangle 记为 Changed
+type PairDiff struct {
+	AddedTriangles   []string
+	RemovedTriangles []string
+	ChangedTriangles []string
+	AddedPairs       []string
+	RemovedPairs     []string
+	AddedTopics      []string
+	RemovedTopics    []string
 }
 
-// Contains 检查 Set 中是否包含一个元素
-func (s *Set) Contains(value string) bool {
-	_, exists := s.data.Get(value)
-	return exists
+// Empty 返回两代快照之间是否没有任何差异
+func (d *PairDiff) Empty() bool {
+	return len(d.AddedTriangles) == 0 && len(d.RemovedTriangles) == 0 && len(d.ChangedTriangles) == 0 &&
+		len(d.AddedPairs) == 0 && len(d.RemovedPairs) == 0 && len(d.AddedTopics) == 0 && len(d.RemovedTopics) == 0
 }
 
-func (s *Set) GetData() cmap.ConcurrentMap {
-	return s.data
+// Diff 计算从当前快照到 next 的差异，结果中的各列表均已排序
+func (s *PairSnapshot) Diff(next *PairSnapshot) *PairDiff {
+	diff := new(PairDiff)
+	for id, triangle := range next.triangles {
+		if old, ok := s.triangles[id]; !ok {
+			diff.AddedTriangles = append(diff.AddedTriangles, id)
+		} else if old != triangle {
+			diff.ChangedTriangles = append(diff.ChangedTriangles, id)
+		}
+	}
+	for id := range s.triangles {
+		if _, ok := next.triangles[id]; !ok {
+			diff.RemovedTriangles = append(diff.RemovedTriangles, id)
+		}
+	}
+	diff.AddedPairs, diff.RemovedPairs = diffKeys(s.pairRoutes, next.pairRoutes)
+	for topic, oper := range next.topics {
+		if old, ok := s.topics[topic]; !ok || old != oper {
+			diff.AddedTopics = append(diff.AddedTopics, topic)
+		}
+	}
+	for topic := range s.topics {
+		if _, ok := next.topics[topic]; !ok {
+			diff.RemovedTopics = append(diff.RemovedTopics, topic)
+		}
+	}
+	sort.Strings(diff.AddedTriangles)
+	sort.Strings(diff.RemovedTriangles)
+	sort.Strings(diff.ChangedTriangles)
+	sort.Strings(diff.AddedTopics)
+	sort.Strings(diff.RemovedTopics)
+	return diff
 }
 
-// String 方法
-func (s Set) String() string {
-	return fmt.Sprintf("[%s] (length: %d)", strings.Join(s.data.Keys(), ", "), len(s.data.Keys()))
+// diffKeys 返回 next 中新增的 key 和 prev 中被删除的 key，均已排序
+func diffKeys(prev, next map[string][]string) (added []string, removed []string) {
+	for key := range next {
+		if _, ok := prev[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	for key := range prev {
+		if _, ok := next[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+func dedupSorted(ids []string) []string {
+	out := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func copyTopics(topics map[string]string) map[string]string {
+	cpy := make(map[string]string, len(topics))
+	for topic, oper := range topics {
+		cpy[topic] = oper
+	}
+	return cpy
 }
@@ -0,0 +1,116 @@
+package pairtypes
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func testTriangle(id int64, pairs ...string) Triangle {
+	return Triangle{ID: id, Pair0: pairs[0], Pair1: pairs[1], Pair2: pairs[2]}
+}
+
+func TestPairSnapshotIndex(t *testing.T) {
+	snap := NewPairSnapshot(1, []Triangle{
+		testTriangle(1, "0x01", "0x02", "0x03"),
+		testTriangle(2, "0x01", "0x04", "0x04"),
+	}, map[string]string{"0xaa": "Swap"})
+
+	if snap.TriangleCount() != 2 || snap.PairCount() != 4 || snap.TopicCount() != 1 {
+		t.Fatalf("unexpected snapshot size: triangles %d, pairs %d, topics %d", snap.TriangleCount(), snap.PairCount(), snap.TopicCount())
+	}
+	pair1 := "0x0000000000000000000000000000000000000001"
+	if ids := snap.PairTriangles(pair1); !reflect.DeepEqual(ids, []string{"1", "2"}) {
+		t.Fatalf("pair triangles mismatch: have %v", ids)
+	}
+	pair4 := "0x0000000000000000000000000000000000000004"
+	if ids := snap.PairTriangles(pair4); !reflect.DeepEqual(ids, []string{"2"}) {
+		t.Fatalf("duplicated legs not deduplicated: have %v", ids)
+	}
+	if triangle, ok := snap.Triangle("1"); !ok || triangle.Pair0 != pair1 {
+		t.Fatalf("triangle not normalised: %v", triangle)
+	}
+}
+
+func TestPairSnapshotDiff(t *testing.T) {
+	prev := NewPairSnapshot(1, []Triangle{
+		testTriangle(1, "0x01", "0x02", "0x03"),
+		testTriangle(2, "0x01", "0x04", "0x05"),
+	}, map[string]string{"0xaa": "Swap", "0xbb": "Sync"})
+
+	next := prev.WithTriangles([]Triangle{
+		testTriangle(1, "0x01", "0x02", "0x06"),
+		testTriangle(3, "0x01", "0x02", "0x03"),
+	}, nil)
+	if next.Generation() != 2 {
+		t.Fatalf("generation mismatch: have %d, want 2", next.Generation())
+	}
+	if next.TopicCount() != 2 {
+		t.Fatalf("topics not carried over: have %d", next.TopicCount())
+	}
+	diff := prev.Diff(next)
+	want := &PairDiff{
+		AddedTriangles:   []string{"3"},
+		RemovedTriangles: []string{"2"},
+		ChangedTriangles: []string{"1"},
+		AddedPairs:       []string{"0x0000000000000000000000000000000000000006"},
+		RemovedPairs:     []string{"0x0000000000000000000000000000000000000004", "0x0000000000000000000000000000000000000005"},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("diff mismatch:\nhave %+v\nwant %+v", diff, want)
+	}
+
+	topics := next.WithTopics(map[string]string{"0xaa": "Swap", "0xcc": "Balancer"})
+	diff = next.Diff(topics)
+	if !reflect.DeepEqual(diff.AddedTopics, []string{"0xcc"}) || !reflect.DeepEqual(diff.RemovedTopics, []string{"0xbb"}) {
+		t.Fatalf("topic diff mismatch: %+v", diff)
+	}
+	if len(diff.AddedTriangles) != 0 || len(diff.RemovedPairs) != 0 {
+		t.Fatalf("unexpected triangle diff: %+v", diff)
+	}
+}
+
+func TestPairCacheSwap(t *testing.T) {
+	cache := NewPairCache()
+	if cache.Snapshot().Generation() != 0 || cache.TriangleMapSize() != 0 {
+		t.Fatal("new cache is not empty")
+	}
+	prev := cache.Swap(NewPairSnapshot(1, []Triangle{testTriangle(1, "0x01", "0x02", "0x03")}, nil))
+	if prev.Generation() != 0 {
+		t.Fatalf("swapped out generation mismatch: have %d", prev.Generation())
+	}
+	if _, ok := cache.GetTriangle("1"); !ok {
+		t.Fatal("triangle missing after swap")
+	}
+	if _, ok := prev.Triangle("1"); ok {
+		t.Fatal("previous snapshot was modified")
+	}
+}
+
+func TestRouteSnapshotIndex(t *testing.T) {
+	triangle := testTriangle(1, "0x01", "0x02", "0x03")
+	hop := func(pair int64) Hop { return Hop{Pair: common.BigToAddress(big.NewInt(pair))} }
+	discovered := []Route{
+		triangle.Route(), // Duplicate of the triangle, must be dropped
+		{ID: NewRouteID([]Hop{hop(1), hop(5)}), Hops: []Hop{hop(1), hop(5)}},
+	}
+	snap := NewRouteSnapshot(1, []Triangle{triangle}, discovered, nil)
+	if snap.RouteCount() != 2 || snap.DiscoveredCount() != 1 || snap.PairCount() != 4 {
+		t.Fatalf("unexpected snapshot size: routes %d, discovered %d, pairs %d", snap.RouteCount(), snap.DiscoveredCount(), snap.PairCount())
+	}
+	pair1 := "0x0000000000000000000000000000000000000001"
+	if ids := snap.PairRoutes(pair1); len(ids) != 2 {
+		t.Fatalf("pair routes mismatch: have %v", ids)
+	}
+	if ids := snap.PairTriangles(pair1); !reflect.DeepEqual(ids, []string{"1"}) {
+		t.Fatalf("discovered routes leaked into triangle index: %v", ids)
+	}
+	if route, ok := snap.Route(discovered[1].Key()); !ok || !route.Equal(discovered[1]) {
+		t.Fatalf("discovered route missing: %v", route)
+	}
+	if next := snap.WithTopics(map[string]string{"0xaa": "Swap"}); next.DiscoveredCount() != 1 {
+		t.Fatal("discovered routes not carried over")
+	}
+}
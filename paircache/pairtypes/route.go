@@ -0,0 +1,94 @@
+package pairtypes
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Hop 是 route 中的一跳：把 Token 通过 Router 卖入 Pair
+type Hop struct {
+	Token  common.Address `json:"token"`
+	Router common.Address `json:"router"`
+	Pair   common.Address `json:"pair"`
+}
+
+// Route 是一条首尾相接的多跳套利路径，Hops[i].Token 卖入 Hops[i].Pair 换出
+// Hops[i+1].Token，最后一跳换回 Hops[0].Token。由 Triangle 转换而来的 route 沿用
+// triangle 的 ID，从 pair 图中发现的 route 使用 NewRouteID 生成的负数 ID
+type Route struct {
+	ID   int64 `json:"id"`
+	Hops []Hop `json:"hops"`
+}
+
+// NewRouteID 根据各跳内容生成稳定的负数 ID，保证重新发现的同一条 route 的 ID 不变，
+// 且不会与 triangle 的正数 ID 冲突
+func NewRouteID(hops []Hop) int64 {
+	blob := make([]byte, 0, len(hops)*3*common.AddressLength)
+	for _, hop := range hops {
+		blob = append(blob, hop.Token.Bytes()...)
+		blob = append(blob, hop.Router.Bytes()...)
+		blob = append(blob, hop.Pair.Bytes()...)
+	}
+	return -int64(binary.BigEndian.Uint64(crypto.Keccak256(blob)[:8])>>1) - 1
+}
+
+// Key 返回 route 在快照索引中使用的 key
+func (r Route) Key() string {
+	return strconv.FormatInt(r.ID, 10)
+}
+
+// Pairs 返回 route 依次经过的 pair 地址
+func (r Route) Pairs() []common.Address {
+	pairs := make([]common.Address, len(r.Hops))
+	for i, hop := range r.Hops {
+		pairs[i] = hop.Pair
+	}
+	return pairs
+}
+
+// Triangle 将三跳的 route 转换回 Triangle，跳数不为三时返回 false
+func (r Route) Triangle() (Triangle, bool) {
+	if len(r.Hops) != 3 {
+		return Triangle{}, false
+	}
+	return Triangle{
+		ID:      r.ID,
+		Token0:  r.Hops[0].Token.Hex(),
+		Router0: r.Hops[0].Router.Hex(),
+		Pair0:   r.Hops[0].Pair.Hex(),
+		Token1:  r.Hops[1].Token.Hex(),
+		Router1: r.Hops[1].Router.Hex(),
+		Pair1:   r.Hops[1].Pair.Hex(),
+		Token2:  r.Hops[2].Token.Hex(),
+		Router2: r.Hops[2].Router.Hex(),
+		Pair2:   r.Hops[2].Pair.Hex(),
+	}, true
+}
+
+// Equal 返回两条 route 是否完全相同
+func (r Route) Equal(other Route) bool {
+	if r.ID != other.ID || len(r.Hops) != len(other.Hops) {
+		return false
+	}
+	for i := range r.Hops {
+		if r.Hops[i] != other.Hops[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Route 将 Triangle 转换为三跳的 Route
+func (t Triangle) Route() Route {
+	return Route{
+		ID: t.ID,
+		Hops: []Hop{
+			{Token: common.HexToAddress(t.Token0), Router: common.HexToAddress(t.Router0), Pair: common.HexToAddress(t.Pair0)},
+			{Token: common.HexToAddress(t.Token1), Router: common.HexToAddress(t.Router1), Pair: common.HexToAddress(t.Pair1)},
+			{Token: common.HexToAddress(t.Token2), Router: common.HexToAddress(t.Router2), Pair: common.HexToAddress(t.Pair2)},
+		},
+	}
+}
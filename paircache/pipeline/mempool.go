@@ -0,0 +1,298 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+	"github.com/ethereum/go-ethereum/paircache/scheduler"
+)
+
+const (
+	// txChanSize is the size of channel listening to NewTxsEvent.
+	txChanSize = 4096
+
+	// DefaultMempoolQueueSize is the default number of pending transactions
+	// waiting for simulation.
+	DefaultMempoolQueueSize = 1024
+
+	// DefaultMempoolWorkers is the default number of pending transactions
+	// simulated and evaluated concurrently.
+	DefaultMempoolWorkers = 4
+
+	// DefaultMempoolBudget is the default time the evaluation of the routes
+	// touched by a single pending transaction may take.
+	DefaultMempoolBudget = 200 * time.Millisecond
+)
+
+var (
+	pendingMeter          = metrics.NewRegisteredMeter("pair/mempool/pending", nil)
+	pendingDroppedMeter   = metrics.NewRegisteredMeter("pair/mempool/dropped", nil)   // Transactions dropped because the queue was full
+	pendingFailedMeter    = metrics.NewRegisteredMeter("pair/mempool/failed", nil)    // Transactions that could not be simulated
+	pendingTouchingMeter  = metrics.NewRegisteredMeter("pair/mempool/touching", nil)  // Transactions touching tracked pairs
+	pendingCancelledMeter = metrics.NewRegisteredMeter("pair/mempool/cancelled", nil) // Evaluations superseded by a new head
+	pendingFoundMeter     = metrics.NewRegisteredMeter("pair/mempool/found", nil)     // Transactions with backrun opportunities
+	simulateTimer         = metrics.NewRegisteredTimer("pair/mempool/simulate", nil)
+	backrunTimer          = metrics.NewRegisteredTimer("pair/mempool/evaluate", nil)
+)
+
+// TxPoolBackend is the chain and transaction pool access of the mempool mode.
+type TxPoolBackend interface {
+	Backend
+	SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription
+	CurrentHeader() *types.Header
+}
+
+// BackrunSubmitter delivers the opportunities found behind a pending transaction.
+type BackrunSubmitter interface {
+	SubmitBackrun(ctx context.Context, number uint64, head common.Hash, target *types.Transaction, targetGas uint64, opportunities []pairtypes.Opportunity) error
+}
+
+// MempoolConfig contains the tunables of the mempool mode.
+type MempoolConfig struct {
+	QueueSize int           // Maximum number of pending transactions waiting for simulation
+	Workers   int           // Number of pending transactions evaluated concurrently
+	Budget    time.Duration // Time the evaluation behind a single transaction may take
+}
+
+// Mempool evaluates backrun arbitrage for pending transactions. Every incoming
+// contract call is simulated on top of the current head, the routes touched by
+// the logs it emits are resolved through the same topic map as imported blocks,
+// and evaluated against the state after the transaction. A new head cancels all
+// evaluations against the previous one.
+type Mempool struct {
+	backend   TxPoolBackend
+	api       pairtypes.PendingAPI
+	submitter BackrunSubmitter // Optional, opportunities are only logged if nil
+	cache     *pairtypes.PairCache
+	scheduler *scheduler.Scheduler
+	config    MempoolConfig
+
+	queue chan *types.Transaction
+
+	headLock   sync.RWMutex
+	head       *types.Header
+	headCtx    context.Context // Cancelled once head is superseded
+	headCancel context.CancelFunc
+	generation uint64 // Snapshot generation the scheduler history was last pruned at
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	txSub  event.Subscription
+	sub    event.Subscription
+	wg     sync.WaitGroup
+}
+
+// NewMempool creates the mempool mode evaluator. The returned value implements
+// node.Lifecycle. The submitter may be nil, in which case nothing is ever sent.
+func NewMempool(backend TxPoolBackend, api pairtypes.PendingAPI, submitter BackrunSubmitter, cache *pairtypes.PairCache, config MempoolConfig) *Mempool {
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultMempoolQueueSize
+	}
+	if config.Workers <= 0 {
+		config.Workers = DefaultMempoolWorkers
+	}
+	if config.Budget <= 0 {
+		config.Budget = DefaultMempoolBudget
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Mempool{
+		backend:   backend,
+		api:       api,
+		submitter: submitter,
+		cache:     cache,
+		scheduler: scheduler.New(scheduler.Config{Budget: config.Budget}),
+		config:    config,
+		queue:     make(chan *types.Transaction, config.QueueSize),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start subscribes to new heads and pending transactions and spawns the
+// dispatch loop and the evaluation workers.
+func (m *Mempool) Start() error {
+	m.setHead(m.backend.CurrentHeader())
+
+	txs := make(chan core.NewTxsEvent, txChanSize)
+	heads := make(chan core.ChainEvent, chainEventChanSize)
+	m.txSub = m.backend.SubscribeNewTxsEvent(txs)
+	m.sub = m.backend.SubscribeChainEvent(heads)
+
+	m.wg.Add(1 + m.config.Workers)
+	go m.dispatchLoop(txs, heads)
+	for i := 0; i < m.config.Workers; i++ {
+		go m.evaluateLoop()
+	}
+	log.Info("Started mempool arbitrage evaluation", "queue", m.config.QueueSize, "workers", m.config.Workers, "budget", m.config.Budget)
+	return nil
+}
+
+// Stop terminates the evaluation, aborting any in-flight simulation.
+func (m *Mempool) Stop() error {
+	m.txSub.Unsubscribe()
+	m.sub.Unsubscribe()
+	m.cancel()
+	m.wg.Wait()
+
+	log.Info("Mempool arbitrage evaluation stopped")
+	return nil
+}
+
+// dispatchLoop tracks the chain head and queues the pending transactions that
+// may be swaps. It never blocks on the workers, transactions arriving while the
+// queue is full are dropped.
+func (m *Mempool) dispatchLoop(txs chan core.NewTxsEvent, heads chan core.ChainEvent) {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case ev := <-heads:
+			m.setHead(ev.Block.Header())
+
+		case ev := <-txs:
+			for _, tx := range ev.Txs {
+				if !maybeSwap(tx) {
+					continue
+				}
+				select {
+				case m.queue <- tx:
+					pendingMeter.Mark(1)
+				default:
+					pendingDroppedMeter.Mark(1)
+				}
+			}
+
+		case err := <-m.txSub.Err():
+			if err != nil {
+				log.Error("Mempool arbitrage subscription failed", "err", err)
+			}
+			return
+		case err := <-m.sub.Err():
+			if err != nil {
+				log.Error("Mempool arbitrage subscription failed", "err", err)
+			}
+			return
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// setHead makes header the state pending transactions are simulated on, and
+// cancels everything still running against the previous head.
+func (m *Mempool) setHead(header *types.Header) {
+	m.headLock.Lock()
+	defer m.headLock.Unlock()
+
+	if m.headCancel != nil {
+		m.headCancel()
+	}
+	m.head = header
+	m.headCtx, m.headCancel = context.WithCancel(m.ctx)
+
+	snap := m.cache.Snapshot()
+	if snap.Generation() != m.generation {
+		m.scheduler.Forget(func(id int64) bool {
+			_, ok := snap.Route(strconv.FormatInt(id, 10))
+			return ok
+		})
+		m.generation = snap.Generation()
+	}
+}
+
+// current returns the head to simulate on, together with its context.
+func (m *Mempool) current() (*types.Header, context.Context) {
+	m.headLock.RLock()
+	defer m.headLock.RUnlock()
+
+	return m.head, m.headCtx
+}
+
+// evaluateLoop simulates and evaluates queued transactions one at a time.
+func (m *Mempool) evaluateLoop() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case tx := <-m.queue:
+			m.evaluate(tx)
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *Mempool) evaluate(tx *types.Transaction) {
+	head, ctx := m.current()
+	if head == nil || ctx.Err() != nil {
+		return
+	}
+	start := time.Now()
+	pending, err := m.api.SimulatePending(ctx, head.Hash(), tx)
+	if err != nil {
+		// Mostly transactions already included or replaced, not worth more than a trace
+		pendingFailedMeter.Mark(1)
+		log.Trace("Failed to simulate pending transaction", "hash", tx.Hash(), "head", head.Hash(), "err", err)
+		return
+	}
+	simulateTimer.UpdateSince(start)
+
+	candidates := resolveRoutes(m.cache.Snapshot(), pending.Logs())
+	if len(candidates) == 0 {
+		return
+	}
+	pendingTouchingMeter.Mark(1)
+
+	number := head.Number.Uint64()
+	routes := m.scheduler.Schedule(number, candidates)
+
+	start = time.Now()
+	evalCtx, cancel := m.scheduler.Deadline(ctx, start)
+	defer cancel()
+
+	batch, err := pending.PairCallBatch(evalCtx, routes)
+	switch {
+	case ctx.Err() != nil:
+		pendingCancelledMeter.Mark(1)
+		return
+	case errors.Is(evalCtx.Err(), context.DeadlineExceeded):
+		m.scheduler.Expire(len(routes), time.Since(start))
+		log.Debug("Backrun evaluation exceeded its deadline", "hash", tx.Hash(), "head", head.Hash(), "routes", len(routes))
+		return
+	case err != nil:
+		log.Debug("Failed to evaluate backrun routes", "hash", tx.Hash(), "head", head.Hash(), "err", err)
+		return
+	}
+	elapsed := time.Since(start)
+	backrunTimer.Update(elapsed)
+	m.scheduler.Update(number, routes, batch, elapsed)
+
+	if len(batch.Opportunities) == 0 {
+		return
+	}
+	pendingFoundMeter.Mark(1)
+	log.Info("Found backrun arbitrage", "hash", tx.Hash(), "head", head.Hash(), "routes", len(routes), "opportunities", len(batch.Opportunities))
+
+	if m.submitter == nil {
+		return
+	}
+	if err := m.submitter.SubmitBackrun(ctx, number, head.Hash(), tx, pending.GasUsed(), batch.Opportunities); err != nil {
+		log.Error("Failed to submit backrun bundle", "hash", tx.Hash(), "head", head.Hash(), "err", err)
+	}
+}
+
+// maybeSwap filters out the pending transactions that cannot trade on a pair,
+// plain transfers and contract creations.
+func maybeSwap(tx *types.Transaction) bool {
+	return tx.To() != nil && len(tx.Data()) >= 4
+}
@@ -0,0 +1,167 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+type testTxPool struct {
+	testBackend
+	txFeed event.Feed
+	head   *types.Header
+}
+
+func (b *testTxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.txFeed.Subscribe(ch)
+}
+
+func (b *testTxPool) CurrentHeader() *types.Header {
+	return b.head
+}
+
+// testPendingAPI simulates every transaction as emitting a swap on pairAddr,
+// unless its nonce is listed as unsimulatable. Evaluations block until their
+// context is cancelled or release is closed.
+type testPendingAPI struct {
+	failing   map[uint64]bool
+	heads     chan common.Hash
+	release   chan struct{}
+	evaluated chan error
+}
+
+func (api *testPendingAPI) SimulatePending(ctx context.Context, head common.Hash, tx *types.Transaction) (pairtypes.PendingState, error) {
+	if api.failing[tx.Nonce()] {
+		return nil, errors.New("nonce too low")
+	}
+	api.heads <- head
+	return &testPendingState{api: api}, nil
+}
+
+type testPendingState struct {
+	api *testPendingAPI
+}
+
+func (s *testPendingState) Logs() []*types.Log {
+	return []*types.Log{{Address: pairAddr, Topics: []common.Hash{swapTopic}}}
+}
+
+func (s *testPendingState) GasUsed() uint64 { return 50000 }
+
+func (s *testPendingState) PairCallBatch(ctx context.Context, routes []pairtypes.Route) (*pairtypes.BatchResult, error) {
+	select {
+	case <-ctx.Done():
+		s.api.evaluated <- ctx.Err()
+		return nil, ctx.Err()
+	case <-s.api.release:
+	}
+	batch := new(pairtypes.BatchResult)
+	for _, route := range routes {
+		batch.Results = append(batch.Results, pairtypes.RouteResult{Route: route.ID, Profit: big.NewInt(1), Chosen: true, Gas: 21000})
+		batch.Opportunities = append(batch.Opportunities, pairtypes.Opportunity{Route: route, Gas: 21000})
+	}
+	s.api.evaluated <- nil
+	return batch, nil
+}
+
+type backrun struct {
+	head      common.Hash
+	target    common.Hash
+	targetGas uint64
+	routes    int
+}
+
+type testBackrunSubmitter struct {
+	submitted chan backrun
+}
+
+func (s *testBackrunSubmitter) SubmitBackrun(ctx context.Context, number uint64, head common.Hash, target *types.Transaction, targetGas uint64, opportunities []pairtypes.Opportunity) error {
+	s.submitted <- backrun{head, target.Hash(), targetGas, len(opportunities)}
+	return nil
+}
+
+func pendingTx(nonce uint64, data []byte) *types.Transaction {
+	to := common.HexToAddress("0x10ed43c718714eb63d5aa57b78b54704e256024e")
+	return types.NewTx(&types.LegacyTx{Nonce: nonce, To: &to, Data: data})
+}
+
+func TestMempoolBackrun(t *testing.T) {
+	head := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10)})
+	backend := &testTxPool{head: head.Header()}
+	api := &testPendingAPI{
+		failing:   map[uint64]bool{2: true},
+		heads:     make(chan common.Hash, 16),
+		release:   make(chan struct{}),
+		evaluated: make(chan error, 16),
+	}
+	close(api.release)
+	submitter := &testBackrunSubmitter{submitted: make(chan backrun, 16)}
+
+	m := NewMempool(backend, api, submitter, newTestCache(), MempoolConfig{Workers: 1})
+	m.Start()
+	defer m.Stop()
+
+	// A plain transfer is never simulated, an unsimulatable transaction is skipped
+	swap := pendingTx(1, []byte{0x38, 0xed, 0x17, 0x39})
+	backend.txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{
+		pendingTx(0, nil),
+		pendingTx(2, []byte{0x38, 0xed, 0x17, 0x39}),
+		swap,
+	}})
+	select {
+	case b := <-submitter.submitted:
+		if b.head != head.Hash() || b.target != swap.Hash() || b.targetGas != 50000 || b.routes != 2 {
+			t.Fatalf("backrun mismatch: %+v", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("backrun not submitted")
+	}
+	select {
+	case h := <-api.heads:
+		if h != head.Hash() {
+			t.Fatalf("simulated on wrong head: %x", h)
+		}
+	default:
+		t.Fatal("swap not simulated")
+	}
+	if len(api.heads) != 0 {
+		t.Fatalf("unexpected simulations: %d", len(api.heads))
+	}
+}
+
+func TestMempoolCancelsOnNewHead(t *testing.T) {
+	first := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10)})
+	backend := &testTxPool{head: first.Header()}
+	api := &testPendingAPI{
+		heads:     make(chan common.Hash, 16),
+		release:   make(chan struct{}),
+		evaluated: make(chan error, 16),
+	}
+	m := NewMempool(backend, api, nil, newTestCache(), MempoolConfig{Workers: 1})
+	m.Start()
+	defer m.Stop()
+
+	backend.txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{pendingTx(1, []byte{0x1, 0x2, 0x3, 0x4})}})
+	select {
+	case <-api.heads:
+	case <-time.After(time.Second):
+		t.Fatal("pending transaction not simulated")
+	}
+	backend.feed.Send(chainEvent(11))
+	select {
+	case err := <-api.evaluated:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("evaluation not cancelled: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("evaluation not cancelled by new head")
+	}
+}
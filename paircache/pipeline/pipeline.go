@@ -0,0 +1,360 @@
+// Package pipeline evaluates arbitrage routes touched by newly imported blocks
+// asynchronously, so that block import is never held up by simulation.
+package pipeline
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+	"github.com/ethereum/go-ethereum/paircache/runstore"
+	"github.com/ethereum/go-ethereum/paircache/scheduler"
+)
+
+const (
+	// chainEventChanSize is the size of channel listening to ChainEvent.
+	chainEventChanSize = 10
+
+	// DefaultQueueSize is the default number of blocks waiting for evaluation.
+	DefaultQueueSize = 4
+)
+
+var (
+	queueGauge     = metrics.NewRegisteredGauge("pair/pipeline/queue", nil)
+	enqueuedMeter  = metrics.NewRegisteredMeter("pair/pipeline/enqueued", nil)
+	droppedMeter   = metrics.NewRegisteredMeter("pair/pipeline/dropped", nil)   // Queued jobs evicted because the queue was full
+	cancelledMeter = metrics.NewRegisteredMeter("pair/pipeline/cancelled", nil) // Jobs superseded by a newer head
+	failedMeter    = metrics.NewRegisteredMeter("pair/pipeline/failed", nil)
+	routesMeter    = metrics.NewRegisteredMeter("pair/pipeline/routes", nil)
+	waitTimer      = metrics.NewRegisteredTimer("pair/pipeline/wait", nil)     // Time from head arrival to evaluation start
+	evaluateTimer  = metrics.NewRegisteredTimer("pair/pipeline/evaluate", nil) // Time spent evaluating a block
+)
+
+// Backend is the chain event source the pipeline listens to.
+type Backend interface {
+	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+}
+
+// Submitter delivers the opportunities found on top of a block.
+type Submitter interface {
+	Submit(ctx context.Context, number uint64, head common.Hash, opportunities []pairtypes.Opportunity) error
+}
+
+// Recorder persists the outcome of every evaluation.
+type Recorder interface {
+	Write(run *runstore.Run) error
+}
+
+// Config contains the tunables of the pipeline.
+type Config struct {
+	QueueSize    int           // Maximum number of blocks waiting for evaluation
+	MaxTriangles int           // Maximum number of routes evaluated per block, 0 = budget only
+	Budget       time.Duration // Time a block may spend in evaluation, capped at the block interval
+}
+
+// job is the evaluation work of a single canonical block.
+type job struct {
+	number     uint64
+	hash       common.Hash
+	routes     []pairtypes.Route
+	candidates int // Routes touched by the block, including the unscheduled ones
+	arrived    time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Pipeline subscribes to canonical chain events, resolves the routes touched
+// by each block from its logs and hands them to the evaluator on a separate
+// goroutine. A newer head cancels the evaluation of all older blocks, and the
+// bounded queue evicts its oldest entry when full instead of blocking.
+type Pipeline struct {
+	backend   Backend
+	evaluator pairtypes.PairAPI
+	submitter Submitter // Optional, opportunities are only logged if nil
+	recorder  Recorder  // Optional, runs are not persisted if nil
+	cache     *pairtypes.PairCache
+	scheduler *scheduler.Scheduler
+	config    Config
+
+	generation uint64 // Snapshot generation the scheduler history was last pruned at
+
+	queue  chan *job
+	latest *job // Most recently enqueued job, cancelled when a newer head arrives
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	sub    event.Subscription
+	wg     sync.WaitGroup
+}
+
+// New creates an arbitrage pipeline. The returned value implements node.Lifecycle.
+// The submitter and recorder may be nil, in which case nothing is ever sent or
+// persisted respectively.
+func New(backend Backend, evaluator pairtypes.PairAPI, submitter Submitter, recorder Recorder, cache *pairtypes.PairCache, config Config) *Pipeline {
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.Budget <= 0 {
+		config.Budget = scheduler.DefaultBudget
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Pipeline{
+		backend:   backend,
+		evaluator: evaluator,
+		submitter: submitter,
+		recorder:  recorder,
+		cache:     cache,
+		scheduler: scheduler.New(scheduler.Config{Budget: config.Budget, MaxRoutes: config.MaxTriangles}),
+		config:    config,
+		queue:     make(chan *job, config.QueueSize),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start subscribes to chain events and spawns the dispatch and evaluation loops.
+func (p *Pipeline) Start() error {
+	events := make(chan core.ChainEvent, chainEventChanSize)
+	p.sub = p.backend.SubscribeChainEvent(events)
+
+	p.wg.Add(2)
+	go p.dispatchLoop(events)
+	go p.evaluateLoop()
+
+	log.Info("Started arbitrage pipeline", "queue", p.config.QueueSize, "budget", p.config.Budget, "routes", p.config.MaxTriangles)
+	return nil
+}
+
+// Stop terminates the pipeline, aborting any in-flight evaluation.
+func (p *Pipeline) Stop() error {
+	p.sub.Unsubscribe()
+	p.cancel()
+	p.wg.Wait()
+
+	log.Info("Arbitrage pipeline stopped")
+	return nil
+}
+
+// dispatchLoop turns chain events into evaluation jobs. It never blocks on the
+// evaluator, so a slow evaluation cannot stall the chain feed.
+func (p *Pipeline) dispatchLoop(events chan core.ChainEvent) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case ev := <-events:
+			p.dispatch(ev)
+		case err := <-p.sub.Err():
+			if err != nil {
+				log.Error("Arbitrage pipeline subscription failed", "err", err)
+			}
+			return
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Pipeline) dispatch(ev core.ChainEvent) {
+	number := ev.Block.NumberU64()
+	snap := p.cache.Snapshot()
+	if snap.Generation() != p.generation {
+		p.scheduler.Forget(func(id int64) bool {
+			_, ok := snap.Route(strconv.FormatInt(id, 10))
+			return ok
+		})
+		p.generation = snap.Generation()
+	}
+	candidates := resolveRoutes(snap, ev.Logs)
+	log.Debug("Resolved arbitrage routes", "number", number, "hash", ev.Hash, "routes", len(candidates))
+
+	// Any previous head is stale now, whether it is still queued or running
+	if p.latest != nil {
+		p.latest.cancel()
+		p.latest = nil
+	}
+	p.scheduler.Observe(number, ev.Block.Time())
+	if len(candidates) == 0 {
+		return
+	}
+	routes := p.scheduler.Schedule(number, candidates)
+	if len(routes) < len(candidates) {
+		log.Debug("Scheduled arbitrage routes", "number", number, "hash", ev.Hash, "scheduled", len(routes), "skipped", len(candidates)-len(routes))
+	}
+	arrived := time.Now()
+	ctx, cancel := p.scheduler.Deadline(p.ctx, arrived)
+	j := &job{
+		number:     number,
+		hash:       ev.Hash,
+		routes:     routes,
+		candidates: len(candidates),
+		arrived:    arrived,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	p.latest = j
+
+	for {
+		select {
+		case p.queue <- j:
+			enqueuedMeter.Mark(1)
+			queueGauge.Update(int64(len(p.queue)))
+			return
+		default:
+		}
+		// Queue full, evict the oldest job to make room for the new head
+		select {
+		case old := <-p.queue:
+			old.cancel()
+			droppedMeter.Mark(1)
+		default:
+		}
+	}
+}
+
+// evaluateLoop runs the queued jobs one at a time.
+func (p *Pipeline) evaluateLoop() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case j := <-p.queue:
+			queueGauge.Update(int64(len(p.queue)))
+			p.evaluate(j)
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Pipeline) evaluate(j *job) {
+	defer j.cancel()
+
+	if j.ctx.Err() != nil {
+		cancelledMeter.Mark(1)
+		return
+	}
+	wait := time.Since(j.arrived)
+	waitTimer.Update(wait)
+	routesMeter.Mark(int64(len(j.routes)))
+
+	start := time.Now()
+	batch, err := p.evaluator.PairCallBatch(j.ctx, j.hash, j.routes)
+
+	run := &runstore.Run{
+		Number:  j.number,
+		Hash:    j.hash,
+		Time:    start,
+		Routes:  len(j.routes),
+		Skipped: j.candidates - len(j.routes),
+		Wait:    wait,
+		Total:   time.Since(start),
+	}
+	switch {
+	case errors.Is(j.ctx.Err(), context.DeadlineExceeded):
+		p.scheduler.Expire(len(j.routes), run.Total)
+		log.Warn("Arbitrage evaluation exceeded its deadline", "number", j.number, "hash", j.hash, "routes", len(j.routes), "elapsed", common.PrettyDuration(time.Since(j.arrived)))
+		run.Error = j.ctx.Err().Error()
+	case j.ctx.Err() != nil:
+		cancelledMeter.Mark(1)
+		log.Debug("Arbitrage evaluation superseded", "number", j.number, "hash", j.hash, "elapsed", common.PrettyDuration(time.Since(start)))
+		run.Cancelled = true
+	case err != nil:
+		failedMeter.Mark(1)
+		log.Error("routes执行eth_call失败", "number", j.number, "hash", j.hash, "err", err)
+		run.Error = err.Error()
+	default:
+		evaluateTimer.UpdateSince(start)
+		p.scheduler.Update(j.number, j.routes, batch, run.Total)
+		run.Screened = batch.Screened
+		run.Quoted = batch.Quoted
+		run.Results = batch.Results
+		run.Query = batch.QueryTime
+		run.Estimate = batch.EstimateTime
+		for _, opp := range batch.Opportunities {
+			run.GasTotal += opp.Gas
+		}
+	}
+	if p.recorder != nil {
+		if err := p.recorder.Write(run); err != nil {
+			log.Error("Failed to persist arbitrage run", "number", j.number, "hash", j.hash, "err", err)
+		}
+	}
+	if batch == nil || p.submitter == nil || len(batch.Opportunities) == 0 || j.ctx.Err() != nil {
+		return
+	}
+	if err := p.submitter.Submit(j.ctx, j.number, j.hash, batch.Opportunities); err != nil {
+		log.Error("Failed to submit arbitrage bundle", "number", j.number, "hash", j.hash, "err", err)
+	}
+}
+
+// resolveRoutes returns the deduplicated routes whose pairs emitted one of the
+// tracked topics in the given logs, together with the liquidity the logs moved
+// through the pairs of each route.
+func resolveRoutes(snap *pairtypes.PairSnapshot, logs []*types.Log) []scheduler.Candidate {
+	var (
+		routes    []pairtypes.Route
+		seen      = make(map[string]bool)
+		liquidity = make(map[common.Address]float64)
+	)
+	for _, l := range logs {
+		if len(l.Topics) == 0 {
+			continue
+		}
+		topic0 := "0x" + hex.EncodeToString(l.Topics[0][:])
+		oper := snap.Topic(topic0)
+		if oper == "" {
+			continue
+		}
+		var address common.Address
+		if oper == "Balancer" {
+			if len(l.Topics) < 2 {
+				continue
+			}
+			address = common.BytesToAddress(l.Topics[1][0:20])
+		} else {
+			address = l.Address
+		}
+		liquidity[address] += magnitude(l.Data)
+		for _, id := range snap.PairRoutes(address.Hex()) {
+			if seen[id] {
+				continue
+			}
+			if route, ok := snap.Route(id); ok {
+				routes = append(routes, route)
+				seen[id] = true
+			}
+		}
+	}
+	candidates := make([]scheduler.Candidate, len(routes))
+	for i, route := range routes {
+		candidates[i].Route = route
+		for _, pair := range route.Pairs() {
+			candidates[i].Liquidity += liquidity[pair]
+		}
+	}
+	return candidates
+}
+
+// magnitude estimates the size of the amounts in a log as the bit length of its
+// largest 32 byte data word, scaled to [0, 1]. Amounts of different tokens are
+// not comparable in value, so only their order of magnitude is taken into account.
+func magnitude(data []byte) float64 {
+	var bits int
+	for i := 0; i+32 <= len(data); i += 32 {
+		bits = max(bits, new(big.Int).SetBytes(data[i:i+32]).BitLen())
+	}
+	return float64(bits) / 256
+}
@@ -0,0 +1,319 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+	"github.com/ethereum/go-ethereum/paircache/runstore"
+)
+
+var (
+	swapTopic = common.HexToHash("0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822")
+	pairAddr  = common.HexToAddress("0x170a4d2A29b30c6551f6a4C0CB527e7A9Cb7D526")
+)
+
+type testBackend struct {
+	feed event.Feed
+}
+
+func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return b.feed.Subscribe(ch)
+}
+
+type call struct {
+	head   common.Hash
+	routes []pairtypes.Route
+	err    error
+}
+
+// blockingEvaluator holds every evaluation until its context is cancelled or
+// it is explicitly released.
+type blockingEvaluator struct {
+	started chan common.Hash
+	release chan struct{}
+	done    chan call
+}
+
+func newBlockingEvaluator() *blockingEvaluator {
+	return &blockingEvaluator{
+		started: make(chan common.Hash, 128),
+		release: make(chan struct{}),
+		done:    make(chan call, 128),
+	}
+}
+
+func (e *blockingEvaluator) PairCallBatch(ctx context.Context, head common.Hash, routes []pairtypes.Route) (*pairtypes.BatchResult, error) {
+	e.started <- head
+	var err error
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-e.release:
+	}
+	e.done <- call{head: head, routes: routes, err: err}
+	if err != nil {
+		return nil, err
+	}
+	batch := new(pairtypes.BatchResult)
+	for _, route := range routes {
+		batch.Results = append(batch.Results, pairtypes.RouteResult{Route: route.ID, Profit: big.NewInt(1), Chosen: true, Gas: 21000})
+		batch.Opportunities = append(batch.Opportunities, pairtypes.Opportunity{Route: route, Gas: 21000})
+	}
+	return batch, nil
+}
+
+func (e *blockingEvaluator) CallBatch() (string, error) { return "", nil }
+
+type submission struct {
+	number        uint64
+	head          common.Hash
+	opportunities []pairtypes.Opportunity
+}
+
+type testSubmitter struct {
+	submitted chan submission
+}
+
+func (s *testSubmitter) Submit(ctx context.Context, number uint64, head common.Hash, opportunities []pairtypes.Opportunity) error {
+	s.submitted <- submission{number, head, opportunities}
+	return nil
+}
+
+func newTestCache() *pairtypes.PairCache {
+	cache := pairtypes.NewPairCache()
+	cache.Swap(pairtypes.NewPairSnapshot(1, []pairtypes.Triangle{
+		{ID: 1, Pair0: pairAddr.Hex(), Pair1: "0x01", Pair2: "0x02"},
+		{ID: 2, Pair0: "0x03", Pair1: pairAddr.Hex(), Pair2: "0x04"},
+		{ID: 3, Pair0: "0x05", Pair1: "0x06", Pair2: "0x07"},
+	}, map[string]string{swapTopic.Hex(): "Swap"}))
+	return cache
+}
+
+func chainEvent(number int64) core.ChainEvent {
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number)})
+	return core.ChainEvent{
+		Block: block,
+		Hash:  block.Hash(),
+		Logs:  []*types.Log{{Address: pairAddr, Topics: []common.Hash{swapTopic}}},
+	}
+}
+
+func TestResolveRoutes(t *testing.T) {
+	snap := newTestCache().Snapshot()
+	logs := []*types.Log{
+		{Address: pairAddr, Topics: []common.Hash{swapTopic}},
+		{Address: pairAddr, Topics: []common.Hash{swapTopic}},
+		{Address: common.HexToAddress("0x05"), Topics: []common.Hash{{0x1}}},
+		{Address: common.HexToAddress("0x05")},
+	}
+	routes := resolveRoutes(snap, logs)
+	if len(routes) != 2 {
+		t.Fatalf("route count mismatch: have %d, want 2", len(routes))
+	}
+	if routes[0].Route.ID != 1 || routes[1].Route.ID != 2 {
+		t.Fatalf("unexpected routes: %v", routes)
+	}
+}
+
+func TestResolveRoutesLiquidity(t *testing.T) {
+	snap := newTestCache().Snapshot()
+	data := make([]byte, 64)
+	data[31] = 0xff // 8 bit amount
+	data[32] = 0x01 // 249 bit amount
+	logs := []*types.Log{
+		{Address: pairAddr, Topics: []common.Hash{swapTopic}, Data: data},
+		{Address: common.HexToAddress("0x03"), Topics: []common.Hash{swapTopic}, Data: data[:32]},
+	}
+	routes := resolveRoutes(snap, logs)
+	if len(routes) != 2 {
+		t.Fatalf("route count mismatch: have %d, want 2", len(routes))
+	}
+	// Route 1 only goes through pairAddr, route 2 through both pairs
+	if have, want := routes[0].Liquidity, 249.0/256; have != want {
+		t.Fatalf("route 1 liquidity mismatch: have %v, want %v", have, want)
+	}
+	if have, want := routes[1].Liquidity, 249.0/256+8.0/256; have != want {
+		t.Fatalf("route 2 liquidity mismatch: have %v, want %v", have, want)
+	}
+}
+
+func TestPipelineCancelsOnNewHead(t *testing.T) {
+	backend := new(testBackend)
+	evaluator := newBlockingEvaluator()
+	p := New(backend, evaluator, nil, nil, newTestCache(), Config{})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	first, second := chainEvent(1), chainEvent(2)
+	backend.feed.Send(first)
+	select {
+	case head := <-evaluator.started:
+		if head != first.Hash {
+			t.Fatalf("evaluating wrong head: have %x, want %x", head, first.Hash)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("evaluation not started")
+	}
+	backend.feed.Send(second)
+
+	select {
+	case c := <-evaluator.done:
+		if c.head != first.Hash || c.err == nil {
+			t.Fatalf("first evaluation not cancelled: %+v", c)
+		}
+		if len(c.routes) != 2 {
+			t.Fatalf("route count mismatch: have %d, want 2", len(c.routes))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("first evaluation not cancelled by new head")
+	}
+	select {
+	case head := <-evaluator.started:
+		if head != second.Hash {
+			t.Fatalf("evaluating wrong head: have %x, want %x", head, second.Hash)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second evaluation not started")
+	}
+	close(evaluator.release)
+	if c := <-evaluator.done; c.err != nil {
+		t.Fatalf("second evaluation failed: %v", c.err)
+	}
+}
+
+func TestPipelineDoesNotBlockFeed(t *testing.T) {
+	backend := new(testBackend)
+	evaluator := newBlockingEvaluator()
+	p := New(backend, evaluator, nil, nil, newTestCache(), Config{QueueSize: 1})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	// The chain feed must never wait for the evaluator, however many heads arrive
+	sent := make(chan struct{})
+	go func() {
+		for i := int64(1); i <= 100; i++ {
+			backend.feed.Send(chainEvent(i))
+		}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("chain feed blocked by the pipeline")
+	}
+}
+
+func TestPipelineSubmitsOpportunities(t *testing.T) {
+	backend := new(testBackend)
+	evaluator := newBlockingEvaluator()
+	submitter := &testSubmitter{submitted: make(chan submission, 1)}
+	p := New(backend, evaluator, submitter, nil, newTestCache(), Config{})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	close(evaluator.release)
+	ev := chainEvent(7)
+	backend.feed.Send(ev)
+
+	select {
+	case s := <-submitter.submitted:
+		if s.number != 7 || s.head != ev.Hash {
+			t.Fatalf("submitted for wrong block: have %d/%x, want 7/%x", s.number, s.head, ev.Hash)
+		}
+		if len(s.opportunities) != 2 {
+			t.Fatalf("opportunity count mismatch: have %d, want 2", len(s.opportunities))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("opportunities not submitted")
+	}
+}
+
+type testRecorder struct {
+	runs chan *runstore.Run
+}
+
+func (r *testRecorder) Write(run *runstore.Run) error {
+	r.runs <- run
+	return nil
+}
+
+func TestPipelineRecordsRuns(t *testing.T) {
+	backend := new(testBackend)
+	evaluator := newBlockingEvaluator()
+	recorder := &testRecorder{runs: make(chan *runstore.Run, 2)}
+	p := New(backend, evaluator, nil, recorder, newTestCache(), Config{})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	// The first head is superseded while running, the second one completes
+	first, second := chainEvent(1), chainEvent(2)
+	backend.feed.Send(first)
+	<-evaluator.started
+	backend.feed.Send(second)
+	<-evaluator.started
+	close(evaluator.release)
+
+	for i, want := range []struct {
+		hash      common.Hash
+		cancelled bool
+		results   int
+	}{{first.Hash, true, 0}, {second.Hash, false, 2}} {
+		select {
+		case run := <-recorder.runs:
+			if run.Hash != want.hash || run.Cancelled != want.cancelled || len(run.Results) != want.results {
+				t.Fatalf("run %d mismatch: hash %x, cancelled %v, results %d", i, run.Hash, run.Cancelled, len(run.Results))
+			}
+			if run.Routes != 2 {
+				t.Fatalf("run %d route count mismatch: have %d, want 2", i, run.Routes)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("run %d not recorded", i)
+		}
+	}
+}
+
+func TestPipelineDeadline(t *testing.T) {
+	backend := new(testBackend)
+	evaluator := newBlockingEvaluator()
+	recorder := &testRecorder{runs: make(chan *runstore.Run, 1)}
+	p := New(backend, evaluator, nil, recorder, newTestCache(), Config{Budget: 50 * time.Millisecond})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	// The evaluator never finishes on its own, the deadline must cut it off
+	backend.feed.Send(chainEvent(1))
+	select {
+	case c := <-evaluator.done:
+		if !errors.Is(c.err, context.DeadlineExceeded) {
+			t.Fatalf("evaluation not cut off by the deadline: %v", c.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("slow evaluation still running")
+	}
+	select {
+	case run := <-recorder.runs:
+		if run.Cancelled || run.Error == "" {
+			t.Fatalf("expired run mismatch: cancelled %v, error %q", run.Cancelled, run.Error)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expired run not recorded")
+	}
+}
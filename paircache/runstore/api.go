@@ -0,0 +1,60 @@
+package runstore
+
+import (
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// defaultHistoryLimit is the number of entries pair_getTriangleHistory
+	// returns if no limit is given.
+	defaultHistoryLimit = 100
+
+	// maxHistoryLimit caps the number of entries of a single history query.
+	maxHistoryLimit = 10000
+)
+
+// API exposes the persisted arbitrage runs in the pair namespace.
+type API struct {
+	store *Store
+}
+
+// NewAPI creates the query API of a run store.
+func NewAPI(store *Store) *API {
+	return &API{store: store}
+}
+
+// APIs returns the RPC services of the run store.
+func APIs(store *Store) []rpc.API {
+	return []rpc.API{{
+		Namespace: "pair",
+		Service:   NewAPI(store),
+	}}
+}
+
+// GetRunsByBlock returns the arbitrage runs evaluated on top of the blocks with
+// the given number. Reorgs may leave more than one run per height.
+func (api *API) GetRunsByBlock(number hexutil.Uint64) ([]*Run, error) {
+	return api.store.RunsByBlock(uint64(number))
+}
+
+// GetTriangleHistory returns the outcome of a triangle in every run within the
+// inclusive block range, oldest first. The range defaults to the whole history.
+// Routes discovered from the pair graph are queried by their negative id.
+func (api *API) GetTriangleHistory(triangle int64, from, to *hexutil.Uint64, limit *int) ([]*RouteRun, error) {
+	var (
+		start uint64
+		end   = ^uint64(0)
+		max   = defaultHistoryLimit
+	)
+	if from != nil {
+		start = uint64(*from)
+	}
+	if to != nil {
+		end = uint64(*to)
+	}
+	if limit != nil && *limit > 0 {
+		max = min(*limit, maxHistoryLimit)
+	}
+	return api.store.RouteHistory(triangle, start, end, max)
+}
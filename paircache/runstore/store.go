@@ -0,0 +1,217 @@
+// Package runstore persists the outcome of every arbitrage evaluation so that
+// missed opportunities can be analysed after the fact.
+package runstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+// Database layout:
+//
+//	runPrefix + num (uint64 big endian) + hash -> Run JSON
+//	routeRunPrefix + route id (uint64 big endian) + num (uint64 big endian) + hash -> RouteRun JSON
+var (
+	runPrefix      = []byte("r")
+	routeRunPrefix = []byte("t")
+)
+
+// Run is the persisted outcome of the evaluation of a single block.
+type Run struct {
+	Number    uint64                  `json:"number"`
+	Hash      common.Hash             `json:"hash"`
+	Time      time.Time               `json:"time"`
+	Routes    int                     `json:"routes"`   // Routes scheduled for evaluation
+	Skipped   int                     `json:"skipped"`  // Routes touched by the block but left out by the scheduler
+	Screened  int                     `json:"screened"` // Routes dropped by native pricing
+	Quoted    int                     `json:"quoted"`   // Routes settled by native pricing without the contract
+	Results   []pairtypes.RouteResult `json:"results"`
+	GasTotal  uint64                  `json:"gasTotal"` // Estimated gas of the chosen set
+	Wait      time.Duration           `json:"wait"`     // Time from head arrival to evaluation start
+	Query     time.Duration           `json:"query"`
+	Estimate  time.Duration           `json:"estimate"`
+	Total     time.Duration           `json:"total"`
+	Cancelled bool                    `json:"cancelled"` // Superseded by a newer head before completion
+	Error     string                  `json:"error,omitempty"`
+}
+
+// RouteRun is the outcome of a single route in one run.
+type RouteRun struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Time   time.Time   `json:"time"`
+	Profit *big.Int    `json:"profit,omitempty"`
+	Gas    uint64      `json:"gas,omitempty"`
+	Chosen bool        `json:"chosen"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Store keeps the runs of the most recent blocks in a key-value store.
+type Store struct {
+	db      ethdb.KeyValueStore
+	history uint64 // Number of blocks to keep runs for, 0 keeps everything
+
+	tail uint64 // Lowest block number that may still have a run
+	lock sync.Mutex
+}
+
+// New creates a run store on top of db, retaining the runs of the last history
+// blocks.
+func New(db ethdb.KeyValueStore, history uint64) *Store {
+	s := &Store{db: db, history: history}
+
+	it := db.NewIterator(runPrefix, nil)
+	if it.Next() {
+		s.tail = binary.BigEndian.Uint64(it.Key()[len(runPrefix):])
+	}
+	it.Release()
+	return s
+}
+
+func runKey(number uint64, hash common.Hash) []byte {
+	key := make([]byte, 0, len(runPrefix)+8+common.HashLength)
+	key = append(key, runPrefix...)
+	key = binary.BigEndian.AppendUint64(key, number)
+	return append(key, hash.Bytes()...)
+}
+
+func routeRunKey(route int64, number uint64, hash common.Hash) []byte {
+	key := make([]byte, 0, len(routeRunPrefix)+16+common.HashLength)
+	key = append(key, routeRunPrefix...)
+	key = binary.BigEndian.AppendUint64(key, uint64(route))
+	key = binary.BigEndian.AppendUint64(key, number)
+	return append(key, hash.Bytes()...)
+}
+
+func routeRunPrefixOf(route int64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, routeRunPrefix...), uint64(route))
+}
+
+// Write persists a run together with its per-route index entries, dropping
+// the runs that fell out of the retention window.
+func (s *Store) Write(run *Run) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	blob, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	batch := s.db.NewBatch()
+	if err := batch.Put(runKey(run.Number, run.Hash), blob); err != nil {
+		return err
+	}
+	for _, result := range run.Results {
+		entry, err := json.Marshal(&RouteRun{
+			Number: run.Number,
+			Hash:   run.Hash,
+			Time:   run.Time,
+			Profit: result.Profit,
+			Gas:    result.Gas,
+			Chosen: result.Chosen,
+			Error:  result.Error,
+		})
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(routeRunKey(result.Route, run.Number, run.Hash), entry); err != nil {
+			return err
+		}
+	}
+	if s.history > 0 && run.Number >= s.history && s.tail <= run.Number-s.history {
+		if err := s.prune(batch, run.Number-s.history+1); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// prune deletes all runs below the given block number.
+func (s *Store) prune(batch ethdb.Batch, limit uint64) error {
+	it := s.db.NewIterator(runPrefix, nil)
+	defer it.Release()
+
+	var pruned int
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(runPrefix)+8+common.HashLength {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(runPrefix):])
+		if number >= limit {
+			break
+		}
+		var run Run
+		if err := json.Unmarshal(it.Value(), &run); err != nil {
+			log.Warn("Dropping corrupted arbitrage run", "number", number, "err", err)
+		}
+		for _, result := range run.Results {
+			if err := batch.Delete(routeRunKey(result.Route, number, run.Hash)); err != nil {
+				return err
+			}
+		}
+		if err := batch.Delete(common.CopyBytes(key)); err != nil {
+			return err
+		}
+		pruned++
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	s.tail = limit
+	if pruned > 0 {
+		log.Debug("Pruned arbitrage runs", "count", pruned, "below", limit)
+	}
+	return nil
+}
+
+// RunsByBlock returns the runs of all blocks with the given number, one per
+// block hash that was evaluated at that height.
+func (s *Store) RunsByBlock(number uint64) ([]*Run, error) {
+	prefix := binary.BigEndian.AppendUint64(append([]byte{}, runPrefix...), number)
+	it := s.db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var runs []*Run
+	for it.Next() {
+		run := new(Run)
+		if err := json.Unmarshal(it.Value(), run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, it.Error()
+}
+
+// RouteHistory returns at most limit outcomes of the given route within the
+// inclusive block range, oldest first.
+func (s *Store) RouteHistory(route int64, from, to uint64, limit int) ([]*RouteRun, error) {
+	if from > to {
+		return nil, errors.New("invalid block range")
+	}
+	prefix := routeRunPrefixOf(route)
+	it := s.db.NewIterator(prefix, binary.BigEndian.AppendUint64(nil, from))
+	defer it.Release()
+
+	var entries []*RouteRun
+	for it.Next() && len(entries) < limit {
+		if binary.BigEndian.Uint64(it.Key()[len(prefix):]) > to {
+			break
+		}
+		entry := new(RouteRun)
+		if err := json.Unmarshal(it.Value(), entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, it.Error()
+}
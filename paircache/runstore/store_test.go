@@ -0,0 +1,104 @@
+package runstore
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+func testRun(number uint64, hash common.Hash, routes ...int64) *Run {
+	run := &Run{Number: number, Hash: hash, Time: time.Unix(int64(number), 0), Routes: len(routes)}
+	for i, id := range routes {
+		run.Results = append(run.Results, pairtypes.RouteResult{
+			Route:  id,
+			Profit: big.NewInt(int64(number*10) + id),
+			Chosen: i == 0,
+		})
+	}
+	return run
+}
+
+func TestRunsByBlock(t *testing.T) {
+	store := New(memorydb.New(), 0)
+
+	// Two competing blocks at the same height, and one unrelated block
+	for _, run := range []*Run{
+		testRun(10, common.Hash{0x1}, 1, 2),
+		testRun(10, common.Hash{0x2}, 1),
+		testRun(11, common.Hash{0x3}, 3),
+	} {
+		if err := store.Write(run); err != nil {
+			t.Fatalf("failed to write run: %v", err)
+		}
+	}
+	runs, err := store.RunsByBlock(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].Hash != (common.Hash{0x1}) || runs[1].Hash != (common.Hash{0x2}) {
+		t.Fatalf("unexpected runs: %+v", runs)
+	}
+	if len(runs[0].Results) != 2 || runs[0].Results[1].Profit.Int64() != 102 {
+		t.Fatalf("run results mismatch: %+v", runs[0].Results)
+	}
+	if runs, _ := store.RunsByBlock(12); len(runs) != 0 {
+		t.Fatalf("unexpected runs for missing block: %+v", runs)
+	}
+}
+
+func TestRouteHistory(t *testing.T) {
+	store := New(memorydb.New(), 0)
+	for number := uint64(1); number <= 5; number++ {
+		if err := store.Write(testRun(number, common.Hash{byte(number)}, 7, 8)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := store.RouteHistory(7, 2, 4, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("history length mismatch: have %d, want 3", len(entries))
+	}
+	for i, entry := range entries {
+		number := uint64(i + 2)
+		if entry.Number != number || entry.Profit.Int64() != int64(number*10+7) || !entry.Chosen {
+			t.Fatalf("entry %d mismatch: %+v", i, entry)
+		}
+	}
+	if entries, _ := store.RouteHistory(8, 0, 10, 2); len(entries) != 2 || entries[0].Number != 1 || entries[0].Chosen {
+		t.Fatalf("limited history mismatch: %+v", entries)
+	}
+	if _, err := store.RouteHistory(7, 4, 2, 100); err == nil {
+		t.Fatal("expected error for inverted range")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	db := memorydb.New()
+	store := New(db, 3)
+	for number := uint64(1); number <= 6; number++ {
+		if err := store.Write(testRun(number, common.Hash{byte(number)}, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for number := uint64(1); number <= 6; number++ {
+		runs, _ := store.RunsByBlock(number)
+		if kept := number > 3; kept != (len(runs) == 1) {
+			t.Fatalf("block %d: have %d runs, kept %v", number, len(runs), kept)
+		}
+	}
+	entries, _ := store.RouteHistory(1, 0, 10, 100)
+	if len(entries) != 3 || entries[0].Number != 4 {
+		t.Fatalf("route index not pruned: %+v", entries)
+	}
+	// A reopened store must pick up pruning where it was left
+	store = New(db, 3)
+	if store.tail != 4 {
+		t.Fatalf("tail mismatch after reopen: have %d, want 4", store.tail)
+	}
+}
@@ -0,0 +1,272 @@
+// Package scheduler decides which of the arbitrage routes touched by a block are
+// evaluated, ranking them by past profitability, the liquidity moved through
+// their pools in the block and how long ago they were last evaluated, and
+// admitting as many as fit into a per-block time budget. The budget is enforced
+// with a deadline, never later than the next block is due.
+package scheduler
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+const (
+	// DefaultBudget is the default time a block may spend in evaluation.
+	DefaultBudget = time.Second
+
+	// DefaultRouteCost is the assumed evaluation time of a single route until
+	// the first block has been measured. With the default budget it admits the
+	// same 100 routes that used to be sampled per block.
+	DefaultRouteCost = 10 * time.Millisecond
+
+	// staleBlocks is the number of blocks after which a route that has not been
+	// evaluated gets the full staleness score.
+	staleBlocks = 100
+
+	// profitBits normalises the logarithmic profit score, profits are in wei.
+	profitBits = 128
+
+	// decay is the weight of the newest sample in the moving averages.
+	decay = 0.3
+
+	// Weights of the score components, each of which is in [0, 1].
+	profitWeight    = 0.5
+	liquidityWeight = 0.3
+	stalenessWeight = 0.2
+)
+
+var (
+	candidatesMeter = metrics.NewRegisteredMeter("pair/scheduler/candidates", nil)
+	scheduledMeter  = metrics.NewRegisteredMeter("pair/scheduler/scheduled", nil)
+	skippedMeter    = metrics.NewRegisteredMeter("pair/scheduler/skipped", nil) // Candidates left out by the budget
+	expiredMeter    = metrics.NewRegisteredMeter("pair/scheduler/expired", nil) // Evaluations cut off by their deadline
+	costGauge       = metrics.NewRegisteredGauge("pair/scheduler/cost", nil)    // Estimated evaluation time per route, in microseconds
+)
+
+// Config contains the tunables of the scheduler.
+type Config struct {
+	Budget    time.Duration // Time a block may spend in evaluation
+	MaxRoutes int           // Hard cap of routes evaluated per block, 0 = budget only
+}
+
+// Candidate is a route touched by a block.
+type Candidate struct {
+	Route     pairtypes.Route
+	Liquidity float64 // Magnitude of the amounts moved through the route's pools in the block
+}
+
+// stats is the evaluation history of a single route.
+type stats struct {
+	profit    float64 // Moving average of the normalised logarithmic profit
+	evaluated uint64  // Block number of the last evaluation
+}
+
+// Scheduler ranks candidate routes and picks the ones to evaluate. It is safe
+// for concurrent use.
+type Scheduler struct {
+	config Config
+
+	lock     sync.Mutex
+	stats    map[int64]*stats
+	cost     time.Duration // Moving average of the evaluation time per route
+	interval time.Duration // Interval between the last two consecutive heads, 0 until known
+	head     uint64        // Number of the last observed head
+	headTime uint64        // Timestamp of the last observed head
+}
+
+// New creates a scheduler with no history.
+func New(config Config) *Scheduler {
+	if config.Budget <= 0 {
+		config.Budget = DefaultBudget
+	}
+	return &Scheduler{
+		config: config,
+		stats:  make(map[int64]*stats),
+		cost:   DefaultRouteCost,
+	}
+}
+
+// Schedule returns the candidates to evaluate on top of the given block, best
+// first. Every candidate is returned at most once, no matter how many times it
+// appears in the input.
+func (s *Scheduler) Schedule(number uint64, candidates []Candidate) []pairtypes.Route {
+	candidates = dedup(candidates)
+	candidatesMeter.Mark(int64(len(candidates)))
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	limit := s.limit()
+	if len(candidates) <= limit {
+		scheduledMeter.Mark(int64(len(candidates)))
+		routes := make([]pairtypes.Route, len(candidates))
+		for i, c := range candidates {
+			routes[i] = c.Route
+		}
+		return routes
+	}
+	// Normalise profit and liquidity against the best candidate of the block
+	var maxProfit, maxLiquidity float64
+	for _, c := range candidates {
+		if st := s.stats[c.Route.ID]; st != nil {
+			maxProfit = math.Max(maxProfit, st.profit)
+		}
+		maxLiquidity = math.Max(maxLiquidity, c.Liquidity)
+	}
+	scores := make(map[int64]float64, len(candidates))
+	for _, c := range candidates {
+		var profit, liquidity float64
+		staleness := 1.0
+
+		if st := s.stats[c.Route.ID]; st != nil {
+			if maxProfit > 0 {
+				profit = st.profit / maxProfit
+			}
+			staleness = math.Min(float64(number-st.evaluated)/staleBlocks, 1)
+		}
+		if maxLiquidity > 0 {
+			liquidity = c.Liquidity / maxLiquidity
+		}
+		scores[c.Route.ID] = profitWeight*profit + liquidityWeight*liquidity + stalenessWeight*staleness
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i].Route.ID] > scores[candidates[j].Route.ID]
+	})
+	routes := make([]pairtypes.Route, limit)
+	for i := range routes {
+		routes[i] = candidates[i].Route
+	}
+	scheduledMeter.Mark(int64(limit))
+	skippedMeter.Mark(int64(len(candidates) - limit))
+	return routes
+}
+
+// Observe records the timestamp of a new head, deriving the block interval that
+// bounds the evaluation deadline from consecutive heads.
+func (s *Scheduler) Observe(number, timestamp uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if number == s.head+1 && timestamp > s.headTime {
+		s.interval = time.Duration(timestamp-s.headTime) * time.Second
+	}
+	s.head, s.headTime = number, timestamp
+}
+
+// Deadline derives the context of the evaluation of a block that arrived at the
+// given time. It expires once the budget is used up or, if sooner, the next
+// block is due, so a slow batch is cut off instead of running into the next
+// block.
+func (s *Scheduler) Deadline(parent context.Context, arrived time.Time) (context.Context, context.CancelFunc) {
+	s.lock.Lock()
+	timeout := s.timeout()
+	s.lock.Unlock()
+
+	return context.WithDeadline(parent, arrived.Add(timeout))
+}
+
+// timeout returns the time a block may spend in evaluation, the budget capped
+// at the block interval. The caller must hold the lock.
+func (s *Scheduler) timeout() time.Duration {
+	if s.interval > 0 && s.interval < s.config.Budget {
+		return s.interval
+	}
+	return s.config.Budget
+}
+
+// limit returns the number of routes fitting into the timeout, at least one.
+// The caller must hold the lock.
+func (s *Scheduler) limit() int {
+	limit := max(int(s.timeout()/s.cost), 1)
+	if s.config.MaxRoutes > 0 {
+		limit = min(limit, s.config.MaxRoutes)
+	}
+	return limit
+}
+
+// Update feeds the outcome of an evaluation on top of the given block back into
+// the history. Elapsed is the wall time the evaluation of the routes took.
+func (s *Scheduler) Update(number uint64, routes []pairtypes.Route, batch *pairtypes.BatchResult, elapsed time.Duration) {
+	if len(routes) == 0 {
+		return
+	}
+	profits := make(map[int64]float64, len(batch.Results))
+	for _, result := range batch.Results {
+		if result.Profit != nil && result.Profit.Sign() > 0 {
+			profits[result.Route] = math.Min(float64(result.Profit.BitLen())/profitBits, 1)
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, route := range routes {
+		st := s.stats[route.ID]
+		if st == nil {
+			// The first sample stands on its own rather than decaying from zero
+			st = &stats{profit: profits[route.ID]}
+			s.stats[route.ID] = st
+		} else {
+			st.profit = decay*profits[route.ID] + (1-decay)*st.profit
+		}
+		st.evaluated = number
+	}
+	s.updateCost(len(routes), elapsed)
+}
+
+// Expire feeds back an evaluation of the given number of routes cut off by its
+// deadline after elapsed. The history of the routes is left untouched as their
+// outcome is unknown, but the cost estimate grows so that fewer routes are
+// admitted to the following blocks.
+func (s *Scheduler) Expire(routes int, elapsed time.Duration) {
+	expiredMeter.Mark(1)
+	if routes == 0 {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.updateCost(routes, elapsed)
+}
+
+// updateCost folds the evaluation time of a batch into the per route cost. The
+// caller must hold the lock.
+func (s *Scheduler) updateCost(routes int, elapsed time.Duration) {
+	perRoute := elapsed / time.Duration(routes)
+	s.cost = time.Duration(decay*float64(perRoute) + (1-decay)*float64(s.cost))
+	if s.cost <= 0 {
+		s.cost = time.Nanosecond
+	}
+	costGauge.Update(s.cost.Microseconds())
+}
+
+// Forget drops the history of the routes not in the given set, so that routes
+// removed from the pair cache do not accumulate.
+func (s *Scheduler) Forget(keep func(id int64) bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id := range s.stats {
+		if !keep(id) {
+			delete(s.stats, id)
+		}
+	}
+}
+
+// dedup drops repeated routes, keeping the first occurrence.
+func dedup(candidates []Candidate) []Candidate {
+	seen := make(map[int64]bool, len(candidates))
+	unique := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if !seen[c.Route.ID] {
+			seen[c.Route.ID] = true
+			unique = append(unique, c)
+		}
+	}
+	return unique
+}
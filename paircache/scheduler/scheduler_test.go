@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+func candidates(liquidity ...float64) []Candidate {
+	cs := make([]Candidate, len(liquidity))
+	for i, l := range liquidity {
+		cs[i] = Candidate{Route: pairtypes.Route{ID: int64(i + 1)}, Liquidity: l}
+	}
+	return cs
+}
+
+func ids(routes []pairtypes.Route) []int64 {
+	ids := make([]int64, len(routes))
+	for i, route := range routes {
+		ids[i] = route.ID
+	}
+	return ids
+}
+
+func TestScheduleWithinBudget(t *testing.T) {
+	s := New(Config{Budget: time.Second})
+
+	// Duplicates must be evaluated once even when everything fits
+	cs := append(candidates(1, 2, 3), Candidate{Route: pairtypes.Route{ID: 2}})
+	if routes := s.Schedule(1, cs); len(routes) != 3 {
+		t.Fatalf("scheduled routes mismatch: have %v, want 3 routes", ids(routes))
+	}
+}
+
+func TestScheduleByLiquidity(t *testing.T) {
+	s := New(Config{Budget: 2 * DefaultRouteCost})
+
+	routes := s.Schedule(1, candidates(1, 5, 3))
+	if have := ids(routes); len(have) != 2 || have[0] != 2 || have[1] != 3 {
+		t.Fatalf("scheduled routes mismatch: have %v, want [2 3]", have)
+	}
+}
+
+func TestScheduleByHistory(t *testing.T) {
+	s := New(Config{Budget: DefaultRouteCost})
+
+	// Route 1 was profitable, route 2 was not, route 3 was never evaluated
+	evaluated := []pairtypes.Route{{ID: 1}, {ID: 2}}
+	s.Update(10, evaluated, &pairtypes.BatchResult{Results: []pairtypes.RouteResult{
+		{Route: 1, Profit: big.NewInt(1e18)},
+		{Route: 2},
+	}}, 2*DefaultRouteCost)
+
+	if have := ids(s.Schedule(11, candidates(1, 1, 1))); have[0] != 1 {
+		t.Fatalf("profitable route not preferred: have %v", have)
+	}
+	// Once route 2 has been idle long enough it overtakes a fresh unprofitable route
+	s.Update(200, []pairtypes.Route{{ID: 3}}, &pairtypes.BatchResult{}, DefaultRouteCost)
+	cs := []Candidate{{Route: pairtypes.Route{ID: 2}}, {Route: pairtypes.Route{ID: 3}}}
+	if have := ids(s.Schedule(201, cs)); have[0] != 2 {
+		t.Fatalf("stale route not preferred: have %v", have)
+	}
+}
+
+func TestScheduleAdaptsToCost(t *testing.T) {
+	s := New(Config{Budget: 100 * time.Millisecond, MaxRoutes: 50})
+	if limit := len(s.Schedule(1, candidates(make([]float64, 100)...))); limit != 10 {
+		t.Fatalf("initial limit mismatch: have %d, want 10", limit)
+	}
+	// Much faster evaluations raise the limit up to the hard cap
+	routes := make([]pairtypes.Route, 10)
+	for i := 0; i < 20; i++ {
+		s.Update(uint64(i), routes, &pairtypes.BatchResult{}, time.Millisecond)
+	}
+	if limit := len(s.Schedule(30, candidates(make([]float64, 100)...))); limit != 50 {
+		t.Fatalf("adapted limit mismatch: have %d, want 50", limit)
+	}
+}
+
+func TestForget(t *testing.T) {
+	s := New(Config{})
+	s.Update(1, []pairtypes.Route{{ID: 1}, {ID: 2}}, &pairtypes.BatchResult{}, time.Millisecond)
+	s.Forget(func(id int64) bool { return id == 2 })
+	if _, ok := s.stats[1]; ok {
+		t.Fatal("removed route still tracked")
+	}
+	if _, ok := s.stats[2]; !ok {
+		t.Fatal("kept route dropped")
+	}
+}
+
+func TestDeadlineCutsOffSlowJob(t *testing.T) {
+	s := New(Config{Budget: time.Hour})
+
+	// Heads three seconds apart bound the deadline well below the budget
+	s.Observe(1, 100)
+	s.Observe(2, 103)
+	if timeout := s.timeout(); timeout != 3*time.Second {
+		t.Fatalf("timeout mismatch: have %v, want 3s", timeout)
+	}
+	// A job that would take longer than the time left is cut off
+	ctx, cancel := s.Deadline(context.Background(), time.Now().Add(-3*time.Second+50*time.Millisecond))
+	defer cancel()
+
+	slow := time.NewTimer(time.Minute)
+	defer slow.Stop()
+	select {
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			t.Fatalf("unexpected context error: %v", ctx.Err())
+		}
+	case <-slow.C:
+		t.Fatal("slow job not cut off")
+	}
+	// Expired evaluations raise the cost without touching the route history
+	s.Expire(10, time.Second)
+	if s.cost <= DefaultRouteCost || len(s.stats) != 0 {
+		t.Fatalf("expired evaluation not accounted: cost %v, stats %d", s.cost, len(s.stats))
+	}
+	// A gap in the heads keeps the last known interval
+	s.Observe(5, 200)
+	if timeout := s.timeout(); timeout != 3*time.Second {
+		t.Fatalf("timeout changed by non-consecutive head: %v", timeout)
+	}
+}
@@ -0,0 +1,200 @@
+package paircache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/paircache/graph"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	generationGauge = metrics.NewRegisteredGauge("pair/generation", nil)
+	triangleGauge   = metrics.NewRegisteredGauge("pair/triangles", nil)
+	routeGauge      = metrics.NewRegisteredGauge("pair/routes", nil)
+	discoveredGauge = metrics.NewRegisteredGauge("pair/routes/discovered", nil)
+	pairGauge       = metrics.NewRegisteredGauge("pair/pairs", nil)
+	topicGauge      = metrics.NewRegisteredGauge("pair/topics", nil)
+
+	addedTriangleGauge   = metrics.NewRegisteredGauge("pair/diff/triangles/added", nil)
+	removedTriangleGauge = metrics.NewRegisteredGauge("pair/diff/triangles/removed", nil)
+	changedTriangleGauge = metrics.NewRegisteredGauge("pair/diff/triangles/changed", nil)
+	addedPairGauge       = metrics.NewRegisteredGauge("pair/diff/pairs/added", nil)
+	removedPairGauge     = metrics.NewRegisteredGauge("pair/diff/pairs/removed", nil)
+)
+
+// Service 负责从 TriangleSource 加载 triangle 与 topic 到 PairCache 并周期性刷新，
+// 实现了 node.Lifecycle 接口，由节点负责启动和停止
+type Service struct {
+	config *Config
+	source TriangleSource
+	cache  *pairtypes.PairCache
+
+	reloadLock sync.Mutex // 保证同一时间只有一个刷新任务在构建并替换快照
+	quit       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewService 根据配置创建数据源并返回对应的服务，服务加载的数据写入全局 PairCache
+func NewService(config *Config) (*Service, error) {
+	source, err := NewSource(config)
+	if err != nil {
+		return nil, err
+	}
+	return newService(config, source, pairCache), nil
+}
+
+func newService(config *Config, source TriangleSource, cache *pairtypes.PairCache) *Service {
+	return &Service{
+		config: config,
+		source: source,
+		cache:  cache,
+		quit:   make(chan struct{}),
+	}
+}
+
+// APIs 返回服务提供的 RPC 接口。pair_reload 会替换内存中的快照，
+// 因此只在 IPC 与需要 JWT 认证的 engine 端点上开放
+func (s *Service) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace:     "pair",
+		Service:       &API{service: s},
+		Authenticated: true,
+	}}
+}
+
+// Start 首次加载 triangle 与 topic 到内存，并开启协程周期更新
+func (s *Service) Start() error {
+	if _, _, err := s.Reload(); err != nil {
+		return err
+	}
+	s.wg.Add(2)
+	go s.loop(s.config.TriangleRefresh, s.refreshTriangles)
+	go s.loop(s.config.TopicRefresh, s.refreshTopics)
+
+	log.Info("Started pair cache service", "source", s.config.Source)
+	return nil
+}
+
+// Stop 停止周期更新协程并关闭数据源
+func (s *Service) Stop() error {
+	close(s.quit)
+	s.wg.Wait()
+
+	log.Info("Pair cache service stopped")
+	return s.source.Close()
+}
+
+// loop 按给定周期执行刷新任务，刷新失败时保留内存中已有的快照
+func (s *Service) loop(interval time.Duration, refresh func() error) {
+	defer s.wg.Done()
+
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := refresh(); err != nil {
+				log.Error("Failed to refresh pair cache", "source", s.config.Source, "err", err)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// Reload 从数据源同时重新加载 triangle 与 topic，构建新的快照并原子替换
+func (s *Service) Reload() (*pairtypes.PairSnapshot, *pairtypes.PairDiff, error) {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	start := time.Now()
+	triangles, err := s.source.Triangles()
+	if err != nil {
+		return nil, nil, err
+	}
+	topics, err := s.source.Topics()
+	if err != nil {
+		return nil, nil, err
+	}
+	next := pairtypes.NewRouteSnapshot(s.cache.Snapshot().Generation()+1, triangles, s.discover(triangles), topics)
+	diff := s.swap(next)
+	log.Info("刷新内存中triange与topic耗时", "time", time.Since(start), "generation", next.Generation(), "triange总数", next.TriangleCount(), "route总数", next.RouteCount(), "pair总数", next.PairCount(), "topic总数", next.TopicCount())
+	return next, diff, nil
+}
+
+func (s *Service) refreshTriangles() error {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	start := time.Now()
+	triangles, err := s.source.Triangles()
+	if err != nil {
+		return err
+	}
+	next := s.cache.Snapshot().WithTriangles(triangles, s.discover(triangles))
+	s.swap(next)
+	log.Info("刷新内存中triange耗时", "time", time.Since(start), "generation", next.Generation(), "triange总数", next.TriangleCount(), "route总数", next.RouteCount(), "pair总数", next.PairCount())
+	return nil
+}
+
+// discover 用 triangle 中出现的 pool 构建 pair 图，找出从 DiscoverBases 出发并回到起点的
+// route，DiscoverHops 小于最小跳数时不做发现
+func (s *Service) discover(triangles []pairtypes.Triangle) []pairtypes.Route {
+	if s.config.DiscoverHops < graph.MinHops || len(s.config.DiscoverBases) == 0 {
+		return nil
+	}
+	start := time.Now()
+	g := graph.FromTriangles(triangles)
+	routes := g.Cycles(s.config.DiscoverBases, graph.MinHops, min(s.config.DiscoverHops, graph.MaxHops), s.config.DiscoverLimit)
+	log.Info("从pair图中发现route耗时", "time", time.Since(start), "pool总数", g.Pools(), "route总数", len(routes))
+	return routes
+}
+
+func (s *Service) refreshTopics() error {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	start := time.Now()
+	topics, err := s.source.Topics()
+	if err != nil {
+		return err
+	}
+	next := s.cache.Snapshot().WithTopics(topics)
+	s.swap(next)
+	log.Info("刷新内存中topic耗时", "time", time.Since(start), "generation", next.Generation(), "topic总数", next.TopicCount())
+	return nil
+}
+
+// swap 计算新旧快照的差异，替换快照并更新监控指标，调用方需持有 reloadLock
+func (s *Service) swap(next *pairtypes.PairSnapshot) *pairtypes.PairDiff {
+	diff := s.cache.Snapshot().Diff(next)
+	s.cache.Swap(next)
+
+	generationGauge.Update(int64(next.Generation()))
+	triangleGauge.Update(int64(next.TriangleCount()))
+	routeGauge.Update(int64(next.RouteCount()))
+	discoveredGauge.Update(int64(next.DiscoveredCount()))
+	pairGauge.Update(int64(next.PairCount()))
+	topicGauge.Update(int64(next.TopicCount()))
+	addedTriangleGauge.Update(int64(len(diff.AddedTriangles)))
+	removedTriangleGauge.Update(int64(len(diff.RemovedTriangles)))
+	changedTriangleGauge.Update(int64(len(diff.ChangedTriangles)))
+	addedPairGauge.Update(int64(len(diff.AddedPairs)))
+	removedPairGauge.Update(int64(len(diff.RemovedPairs)))
+
+	if !diff.Empty() {
+		log.Debug("Pair cache snapshot swapped", "generation", next.Generation(),
+			"triangles.added", len(diff.AddedTriangles), "triangles.removed", len(diff.RemovedTriangles), "triangles.changed", len(diff.ChangedTriangles),
+			"pairs.added", len(diff.AddedPairs), "pairs.removed", len(diff.RemovedPairs),
+			"topics.added", len(diff.AddedTopics), "topics.removed", len(diff.RemovedTopics))
+	}
+	return diff
+}
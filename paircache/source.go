@@ -0,0 +1,266 @@
+package paircache
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/paircache/mysqldb"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+	"github.com/jmoiron/sqlx"
+)
+
+// 支持的 triangle 数据源类型
+const (
+	SourceMySQL  = "mysql"
+	SourceFile   = "file"
+	SourceMemory = "memory"
+)
+
+// TriangleSource 是 triangle 与 topic 数据的来源，PairCache 通过它周期性地刷新内存中的数据
+type TriangleSource interface {
+	// Triangles 返回数据源中当前全部的 triangle
+	Triangles() ([]pairtypes.Triangle, error)
+
+	// Topics 返回 topic0 到 pair 操作类型的映射
+	Topics() (map[string]string, error)
+
+	// Close 释放数据源持有的资源
+	Close() error
+}
+
+// NewSource 根据配置创建对应的 triangle 数据源
+func NewSource(config *Config) (TriangleSource, error) {
+	switch config.Source {
+	case SourceMySQL:
+		return NewMySQLSource(config.MySQLDSN, config.TopicFile)
+	case SourceFile:
+		return NewFileSource(config.TriangleFile, config.TopicFile)
+	case SourceMemory:
+		return NewMemorySource(nil, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown triangle source %q", config.Source)
+	}
+}
+
+// MySQLSource 从 arbitrage_triangle 表中读取 triangle，topic 从本地文件读取
+type MySQLSource struct {
+	db        *sqlx.DB
+	topicFile string
+}
+
+// NewMySQLSource 创建一个 MySQL 数据源
+func NewMySQLSource(dsn string, topicFile string) (*MySQLSource, error) {
+	db, err := mysqldb.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &MySQLSource{db: db, topicFile: topicFile}, nil
+}
+
+// Triangles 使用流式查询逐行读取全部 triangle
+func (s *MySQLSource) Triangles() ([]pairtypes.Triangle, error) {
+	rows, err := s.db.Queryx("select id, token0, router0, pair0, token1, router1, pair1, token2, router2, pair2 from arbitrage_triangle order by id asc")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var triangles []pairtypes.Triangle
+	for rows.Next() {
+		triangle := pairtypes.Triangle{}
+		if err := rows.StructScan(&triangle); err != nil {
+			return nil, err
+		}
+		triangles = append(triangles, triangle)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return triangles, nil
+}
+
+// Topics 从配置的 topic 文件中读取 topic
+func (s *MySQLSource) Topics() (map[string]string, error) {
+	return readTopicFile(s.topicFile)
+}
+
+// Close 关闭数据库连接池
+func (s *MySQLSource) Close() error {
+	return s.db.Close()
+}
+
+// FileSource 从本地 JSON 或 CSV 文件中读取 triangle，根据文件扩展名区分格式
+type FileSource struct {
+	triangleFile string
+	topicFile    string
+}
+
+// NewFileSource 创建一个文件数据源
+func NewFileSource(triangleFile string, topicFile string) (*FileSource, error) {
+	if triangleFile == "" {
+		return nil, errors.New("no triangle file specified")
+	}
+	return &FileSource{triangleFile: triangleFile, topicFile: topicFile}, nil
+}
+
+// Triangles 每次调用都重新读取 triangle 文件
+func (s *FileSource) Triangles() ([]pairtypes.Triangle, error) {
+	f, err := os.Open(s.triangleFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(s.triangleFile), ".csv") {
+		return decodeTrianglesCSV(f)
+	}
+	var triangles []pairtypes.Triangle
+	if err := json.NewDecoder(f).Decode(&triangles); err != nil {
+		return nil, err
+	}
+	return triangles, nil
+}
+
+// Topics 从配置的 topic 文件中读取 topic
+func (s *FileSource) Topics() (map[string]string, error) {
+	return readTopicFile(s.topicFile)
+}
+
+// Close 文件数据源不持有资源
+func (s *FileSource) Close() error {
+	return nil
+}
+
+// MemorySource 是一个纯内存的数据源，用于测试或不依赖外部存储的场景
+type MemorySource struct {
+	triangles []pairtypes.Triangle
+	topics    map[string]string
+	lock      sync.RWMutex
+}
+
+// NewMemorySource 使用给定的 triangle 和 topic 创建一个内存数据源
+func NewMemorySource(triangles []pairtypes.Triangle, topics map[string]string) *MemorySource {
+	s := new(MemorySource)
+	s.SetTriangles(triangles)
+	s.SetTopics(topics)
+	return s
+}
+
+// SetTriangles 替换数据源中的全部 triangle
+func (s *MemorySource) SetTriangles(triangles []pairtypes.Triangle) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.triangles = append([]pairtypes.Triangle(nil), triangles...)
+}
+
+// SetTopics 替换数据源中的全部 topic
+func (s *MemorySource) SetTopics(topics map[string]string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.topics = make(map[string]string, len(topics))
+	for topic, oper := range topics {
+		s.topics[topic] = oper
+	}
+}
+
+// Triangles 返回数据源中 triangle 的拷贝
+func (s *MemorySource) Triangles() ([]pairtypes.Triangle, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return append([]pairtypes.Triangle(nil), s.triangles...), nil
+}
+
+// Topics 返回数据源中 topic 的拷贝
+func (s *MemorySource) Topics() (map[string]string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	topics := make(map[string]string, len(s.topics))
+	for topic, oper := range s.topics {
+		topics[topic] = oper
+	}
+	return topics, nil
+}
+
+// Close 内存数据源不持有资源
+func (s *MemorySource) Close() error {
+	return nil
+}
+
+// readTopicFile 读取 JSON 格式的 topic 文件，未配置文件时返回空的映射
+func readTopicFile(path string) (map[string]string, error) {
+	topics := make(map[string]string)
+	if path == "" {
+		return topics, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &topics); err != nil {
+		return nil, err
+	}
+	return topics, nil
+}
+
+// csvColumns 是 CSV 文件首行必须包含的列名，列的顺序不限
+var csvColumns = []string{"id", "token0", "router0", "pair0", "token1", "router1", "pair1", "token2", "router2", "pair2"}
+
+// decodeTrianglesCSV 解析带表头的 CSV 格式 triangle 数据
+func decodeTrianglesCSV(r io.Reader) ([]pairtypes.Triangle, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("missing csv column %q", name)
+		}
+	}
+
+	var triangles []pairtypes.Triangle
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, err := strconv.ParseInt(record[index["id"]], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid triangle id %q: %w", record[index["id"]], err)
+		}
+		triangles = append(triangles, pairtypes.Triangle{
+			ID:      id,
+			Token0:  record[index["token0"]],
+			Router0: record[index["router0"]],
+			Pair0:   record[index["pair0"]],
+			Token1:  record[index["token1"]],
+			Router1: record[index["router1"]],
+			Pair1:   record[index["pair1"]],
+			Token2:  record[index["token2"]],
+			Router2: record[index["router2"]],
+			Pair2:   record[index["pair2"]],
+		})
+	}
+	return triangles, nil
+}
@@ -0,0 +1,149 @@
+package paircache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+)
+
+var testTriangle = pairtypes.Triangle{
+	ID:      1,
+	Token0:  "0xeBBAefF6217d22E7744394061D874015709b8141",
+	Router0: "0x0BFbCF9fa4f9C56B0F40a671Ad40E0805A091865",
+	Pair0:   "0x170a4d2A29b30c6551f6a4C0CB527e7A9Cb7D526",
+	Token1:  "0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c",
+	Router1: "0xdB1d10011AD0Ff90774D0C6Bb92e5C5c8b4461F7",
+	Pair1:   "0xCB99FE720124129520f7a09Ca3CBEF78D58Ed934",
+	Token2:  "0xe9e7CEA3DedcA5984780Bafc599bD69ADd087D56",
+	Router2: "0x10ED43C718714eb63d5aA57B78B54704E256024E",
+	Pair2:   "0xc1fE0336456a8D4550ab0E1e528a684Bcf7bD3F8",
+}
+
+func TestFileSourceJSON(t *testing.T) {
+	dir := t.TempDir()
+	triangleFile := filepath.Join(dir, "triangles.json")
+	topicFile := filepath.Join(dir, "topic.json")
+
+	content := `[{"id":1,"token0":"0xeBBAefF6217d22E7744394061D874015709b8141","router0":"0x0BFbCF9fa4f9C56B0F40a671Ad40E0805A091865","pair0":"0x170a4d2A29b30c6551f6a4C0CB527e7A9Cb7D526","token1":"0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c","router1":"0xdB1d10011AD0Ff90774D0C6Bb92e5C5c8b4461F7","pair1":"0xCB99FE720124129520f7a09Ca3CBEF78D58Ed934","token2":"0xe9e7CEA3DedcA5984780Bafc599bD69ADd087D56","router2":"0x10ED43C718714eb63d5aA57B78B54704E256024E","pair2":"0xc1fE0336456a8D4550ab0E1e528a684Bcf7bD3F8"}]`
+	if err := os.WriteFile(triangleFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(topicFile, []byte(`{"0x01":"Swap"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source, err := NewFileSource(triangleFile, topicFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	triangles, err := source.Triangles()
+	if err != nil {
+		t.Fatalf("failed to read triangles: %v", err)
+	}
+	if !reflect.DeepEqual(triangles, []pairtypes.Triangle{testTriangle}) {
+		t.Fatalf("triangle mismatch: have %v, want %v", triangles, testTriangle)
+	}
+	topics, err := source.Topics()
+	if err != nil {
+		t.Fatalf("failed to read topics: %v", err)
+	}
+	if topics["0x01"] != "Swap" {
+		t.Fatalf("topic mismatch: have %v", topics)
+	}
+}
+
+func TestFileSourceCSV(t *testing.T) {
+	triangleFile := filepath.Join(t.TempDir(), "triangles.csv")
+	content := "pair2,id,token0,router0,pair0,token1,router1,pair1,token2,router2\n" +
+		"0xc1fE0336456a8D4550ab0E1e528a684Bcf7bD3F8,1,0xeBBAefF6217d22E7744394061D874015709b8141,0x0BFbCF9fa4f9C56B0F40a671Ad40E0805A091865,0x170a4d2A29b30c6551f6a4C0CB527e7A9Cb7D526,0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c,0xdB1d10011AD0Ff90774D0C6Bb92e5C5c8b4461F7,0xCB99FE720124129520f7a09Ca3CBEF78D58Ed934,0xe9e7CEA3DedcA5984780Bafc599bD69ADd087D56,0x10ED43C718714eb63d5aA57B78B54704E256024E\n"
+	if err := os.WriteFile(triangleFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source, err := NewFileSource(triangleFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	triangles, err := source.Triangles()
+	if err != nil {
+		t.Fatalf("failed to read triangles: %v", err)
+	}
+	if !reflect.DeepEqual(triangles, []pairtypes.Triangle{testTriangle}) {
+		t.Fatalf("triangle mismatch: have %v, want %v", triangles, testTriangle)
+	}
+	if topics, err := source.Topics(); err != nil || len(topics) != 0 {
+		t.Fatalf("expected no topics, have %v (err %v)", topics, err)
+	}
+}
+
+func TestFileSourceCSVMissingColumn(t *testing.T) {
+	triangleFile := filepath.Join(t.TempDir(), "triangles.csv")
+	if err := os.WriteFile(triangleFile, []byte("id,token0\n1,0x01\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source, err := NewFileSource(triangleFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Triangles(); err == nil {
+		t.Fatal("expected error for missing csv columns")
+	}
+}
+
+func TestServiceLoadsMemorySource(t *testing.T) {
+	source := NewMemorySource([]pairtypes.Triangle{testTriangle}, map[string]string{"0x01": "Swap"})
+	cache := pairtypes.NewPairCache()
+	service := newService(&Config{Source: SourceMemory}, source, cache)
+	if err := service.Start(); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	defer service.Stop()
+
+	if _, ok := cache.GetTriangle("1"); !ok {
+		t.Fatal("triangle not loaded")
+	}
+	if ids := cache.GetPairTriangles(testTriangle.Pair1); len(ids) != 1 || ids[0] != "1" {
+		t.Fatalf("pair index not loaded: %v", ids)
+	}
+	if cache.GetTopic("0x01") != "Swap" {
+		t.Fatal("topics not loaded")
+	}
+}
+
+func TestNewSourceUnknown(t *testing.T) {
+	if _, err := NewSource(&Config{Source: "redis"}); err == nil {
+		t.Fatal("expected error for unknown source")
+	}
+}
+
+func TestServiceReloadRemovesTriangles(t *testing.T) {
+	source := NewMemorySource([]pairtypes.Triangle{testTriangle}, nil)
+	cache := pairtypes.NewPairCache()
+	service := newService(&Config{Source: SourceMemory}, source, cache)
+	if err := service.Start(); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	defer service.Stop()
+
+	source.SetTriangles(nil)
+	result, err := (&API{service: service}).Reload()
+	if err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	if result.Generation != 2 || result.Triangles != 0 || result.RemovedTriangles != 1 || result.RemovedPairs != 3 {
+		t.Fatalf("unexpected reload result: %+v", result)
+	}
+	if _, ok := cache.GetTriangle("1"); ok {
+		t.Fatal("removed triangle still cached")
+	}
+}
+
+func TestServiceReloadAuthenticated(t *testing.T) {
+	service := newService(&Config{Source: SourceMemory}, NewMemorySource(nil, nil), pairtypes.NewPairCache())
+	for _, api := range service.APIs() {
+		if !api.Authenticated {
+			t.Fatalf("%s namespace exposed without authentication", api.Namespace)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package submit
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// ModeLocal hands the bundle as a bid to the local miner, the node must be
+	// a validator with the submitting account registered as one of its builders.
+	ModeLocal = "local"
+
+	// ModeBuilder sends the bundle as a bid to the configured endpoints with
+	// mev_sendBid.
+	ModeBuilder = "builder"
+
+	// ModePrivate sends every transaction of the bundle to the configured
+	// endpoints with eth_sendRawTransaction, without ever touching the public
+	// transaction pool.
+	ModePrivate = "private"
+)
+
+// Config contains the settings of the profit bundle submission.
+type Config struct {
+	Mode      string         `toml:",omitempty"` // Submission route: local, builder or private, disabled if empty
+	Account   common.Address `toml:",omitempty"` // Unlocked keystore account signing the bundles
+	Endpoints []string       `toml:",omitempty"` // RPC endpoints of the builder and private routes
+	GasPrice  *big.Int       `toml:",omitempty"` // Gas price of the arbitrage transactions
+	GasMargin uint64         // Extra gas on top of the estimate, in percent
+	DryRun    bool           // Build, sign and journal the bundles without sending them
+	Journal   string         `toml:",omitempty"` // JSONL file recording every bundle, relative to the datadir, disabled if empty
+}
+
+// DefaultConfig contains the default submission settings.
+var DefaultConfig = Config{
+	GasPrice:  big.NewInt(params.GWei),
+	GasMargin: 20,
+	Journal:   "pair-submissions.jsonl",
+}
+
+// Enabled reports whether profit bundles should be submitted.
+func (c *Config) Enabled() bool {
+	return c.Mode != ""
+}
+
+// Validate checks the consistency of the submission settings.
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case ModeLocal:
+	case ModeBuilder, ModePrivate:
+		if len(c.Endpoints) == 0 {
+			return fmt.Errorf("submission mode %q needs at least one endpoint", c.Mode)
+		}
+	default:
+		return fmt.Errorf("unknown submission mode %q", c.Mode)
+	}
+	if c.Account == (common.Address{}) {
+		return fmt.Errorf("submission account not configured")
+	}
+	if c.GasPrice == nil || c.GasPrice.Sign() <= 0 {
+		return fmt.Errorf("invalid submission gas price %v", c.GasPrice)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package submit
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Entry is the journal record of the bundle built for a single block.
+type Entry struct {
+	Time        time.Time      `json:"time"`
+	BlockNumber uint64         `json:"blockNumber"` // Block the bundle targets
+	ParentHash  common.Hash    `json:"parentHash"`
+	Mode        string         `json:"mode"`
+	DryRun      bool           `json:"dryRun"`
+	Account     common.Address `json:"account"`
+	Backrun     *common.Hash   `json:"backrun,omitempty"` // Pending transaction the bundle is placed behind
+	Txs         []EntryTx      `json:"txs"`
+	BidHash     *common.Hash   `json:"bidHash,omitempty"`
+	Results     []EntryResult  `json:"results,omitempty"`
+}
+
+// EntryTx is a single arbitrage transaction of a journaled bundle.
+type EntryTx struct {
+	Hash   common.Hash    `json:"hash"`
+	Route  int64          `json:"route"`
+	Nonce  uint64         `json:"nonce"`
+	Gas    uint64         `json:"gas"`
+	Profit *hexutil.Big   `json:"profit"`
+	To     common.Address `json:"to"`
+	Data   hexutil.Bytes  `json:"data"`
+}
+
+// EntryResult is the response of one route the bundle was sent to.
+type EntryResult struct {
+	Target string      `json:"target"`
+	Hash   common.Hash `json:"hash,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// journal appends entries to a file as JSON lines.
+type journal struct {
+	file *os.File
+	lock sync.Mutex
+}
+
+// openJournal opens the journal file for appending, creating it if needed.
+func openJournal(path string) (*journal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &journal{file: file}, nil
+}
+
+// write appends an entry to the journal.
+func (j *journal) write(entry *Entry) error {
+	blob, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	_, err = j.file.Write(append(blob, '\n'))
+	return err
+}
+
+// close flushes and closes the journal file.
+func (j *journal) close() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
@@ -0,0 +1,402 @@
+// Package submit turns the profitable triangles found for a block into signed
+// transactions and delivers them, either as a bid to the local miner or to
+// remote builders, or as private transactions.
+package submit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/miner/builderclient"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// dialTimeout is the maximum time spent connecting to a single endpoint.
+const dialTimeout = 10 * time.Second
+
+var (
+	bundlesMeter = metrics.NewRegisteredMeter("pair/submit/bundles", nil)
+	txsMeter     = metrics.NewRegisteredMeter("pair/submit/txs", nil)
+	dryRunMeter  = metrics.NewRegisteredMeter("pair/submit/dryrun", nil)
+	failedMeter  = metrics.NewRegisteredMeter("pair/submit/failed", nil) // Routes rejecting a bundle
+)
+
+// Backend is the chain access needed to build bundles.
+type Backend interface {
+	ChainConfig() *params.ChainConfig
+	StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error)
+}
+
+// BidSender is the local miner bid path, implemented by ethapi.MevAPI.
+type BidSender interface {
+	SendBid(ctx context.Context, args types.BidArgs) (common.Hash, error)
+}
+
+// Signer signs with unlocked accounts, implemented by keystore.KeyStore.
+type Signer interface {
+	SignTx(a accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	SignHash(a accounts.Account, hash []byte) ([]byte, error)
+}
+
+// Submitter signs and delivers the arbitrage bundle of each evaluated block.
+type Submitter struct {
+	config  Config
+	backend Backend
+	bids    BidSender
+	signer  Signer
+	account accounts.Account
+
+	endpoints []*builderclient.Client
+	journal   *journal
+
+	// The bundles are never in the local transaction pool, so the nonces of
+	// the account are tracked here. The local nonce is resynced from the chain
+	// state once a bundle failed or its block has been imported.
+	nonceLock  sync.Mutex
+	nonce      uint64 // Next nonce of the account, if nonceValid
+	nonceValid bool
+	nonceBlock uint64 // Block targeted by the last bundle using the local nonce
+}
+
+// New creates a bundle submitter. The returned value implements node.Lifecycle,
+// endpoints are dialled and the journal opened on Start.
+func New(config Config, backend Backend, bids BidSender, signer Signer) (*Submitter, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Mode == ModeLocal && bids == nil {
+		return nil, errors.New("local submission needs the miner bid path")
+	}
+	return &Submitter{
+		config:  config,
+		backend: backend,
+		bids:    bids,
+		signer:  signer,
+		account: accounts.Account{Address: config.Account},
+	}, nil
+}
+
+// Start opens the journal and connects to the configured endpoints.
+func (s *Submitter) Start() error {
+	if s.config.Journal != "" {
+		journal, err := openJournal(s.config.Journal)
+		if err != nil {
+			return err
+		}
+		s.journal = journal
+	}
+	if s.config.Mode != ModeLocal {
+		for _, url := range s.config.Endpoints {
+			ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+			client, err := builderclient.DialOptions(ctx, url)
+			cancel()
+			if err != nil {
+				s.Stop()
+				return fmt.Errorf("failed to dial submission endpoint %s: %v", url, err)
+			}
+			s.endpoints = append(s.endpoints, client)
+		}
+	}
+	log.Info("Started arbitrage bundle submission", "mode", s.config.Mode, "account", s.config.Account,
+		"endpoints", len(s.endpoints), "dryrun", s.config.DryRun, "journal", s.config.Journal)
+	return nil
+}
+
+// Stop disconnects from the endpoints and closes the journal.
+func (s *Submitter) Stop() error {
+	for _, client := range s.endpoints {
+		client.Close()
+	}
+	s.endpoints = nil
+
+	if s.journal != nil {
+		if err := s.journal.close(); err != nil {
+			log.Error("Failed to close submission journal", "err", err)
+		}
+		s.journal = nil
+	}
+	return nil
+}
+
+// backrun is a pending transaction the arbitrage transactions are placed right
+// behind in the bundle.
+type backrun struct {
+	tx  *types.Transaction
+	gas uint64 // Gas used by tx in simulation
+}
+
+// Submit signs the opportunities found on top of head, in order, as one bundle
+// for the next block and delivers it on the configured route. Opportunities
+// without a gas estimate are left out, since they are expected to revert.
+func (s *Submitter) Submit(ctx context.Context, number uint64, head common.Hash, opportunities []pairtypes.Opportunity) error {
+	return s.submit(ctx, number, head, nil, opportunities)
+}
+
+// SubmitBackrun is like Submit, but the opportunities were found in the state
+// after the pending target transaction, which used targetGas in simulation. The
+// bundle starts with the target so that the arbitrage lands right behind it.
+// Private submission only sends the arbitrage transactions, the target is
+// expected to reach the validators on its own.
+func (s *Submitter) SubmitBackrun(ctx context.Context, number uint64, head common.Hash, target *types.Transaction, targetGas uint64, opportunities []pairtypes.Opportunity) error {
+	return s.submit(ctx, number, head, &backrun{tx: target, gas: targetGas}, opportunities)
+}
+
+func (s *Submitter) submit(ctx context.Context, number uint64, head common.Hash, target *backrun, opportunities []pairtypes.Opportunity) error {
+	entry, txs, err := s.sign(ctx, number, head, opportunities)
+	if err != nil || len(txs) == 0 {
+		return err
+	}
+	if target != nil {
+		hash := target.tx.Hash()
+		entry.Backrun = &hash
+	}
+	var bid *types.BidArgs
+	if s.config.Mode != ModePrivate {
+		if bid, err = s.bid(number, head, target, opportunities, txs); err != nil {
+			s.resetNonce()
+			return err
+		}
+		hash := bid.RawBid.Hash()
+		entry.BidHash = &hash
+	}
+	bundlesMeter.Mark(1)
+	txsMeter.Mark(int64(len(txs)))
+
+	if s.config.DryRun {
+		// Nothing was sent, the next bundle may use the same nonces
+		s.resetNonce()
+		dryRunMeter.Mark(1)
+		log.Info("Built arbitrage bundle (dry run)", "number", entry.BlockNumber, "txs", len(txs), "mode", s.config.Mode)
+	} else {
+		entry.Results = s.send(ctx, bid, txs)
+		for _, result := range entry.Results {
+			if result.Error != "" {
+				failedMeter.Mark(1)
+				log.Warn("Arbitrage bundle rejected", "number", entry.BlockNumber, "target", result.Target, "err", result.Error)
+			}
+		}
+		if failed(entry.Results) {
+			s.resetNonce()
+		}
+		log.Info("Submitted arbitrage bundle", "number", entry.BlockNumber, "txs", len(txs), "mode", s.config.Mode)
+	}
+	if s.journal != nil {
+		if err := s.journal.write(entry); err != nil {
+			log.Error("Failed to write submission journal", "err", err)
+		}
+	}
+	return nil
+}
+
+// failed reports whether any route rejected the bundle.
+func failed(results []EntryResult) bool {
+	for _, result := range results {
+		if result.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// reserveNonces returns the nonce of the first of count transactions sent for
+// the block after head, advancing the local nonce past them. The bundles sent
+// for earlier blocks have been included or dropped by then, so the local nonce
+// is resynced from the state of head.
+func (s *Submitter) reserveNonces(ctx context.Context, number uint64, head common.Hash, count uint64) (uint64, error) {
+	s.nonceLock.Lock()
+	defer s.nonceLock.Unlock()
+
+	if !s.nonceValid || number >= s.nonceBlock {
+		statedb, _, err := s.backend.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(head, false))
+		if err != nil {
+			return 0, err
+		}
+		s.nonce, s.nonceValid = statedb.GetNonce(s.config.Account), true
+	}
+	nonce := s.nonce
+	s.nonce += count
+	s.nonceBlock = number + 1
+	return nonce, nil
+}
+
+// resetNonce drops the local nonce after a bundle that was not delivered, the
+// next bundle resyncs it from the chain state.
+func (s *Submitter) resetNonce() {
+	s.nonceLock.Lock()
+	defer s.nonceLock.Unlock()
+
+	s.nonceValid = false
+}
+
+// sign creates one transaction per opportunity with consecutive nonces.
+func (s *Submitter) sign(ctx context.Context, number uint64, head common.Hash, opportunities []pairtypes.Opportunity) (*Entry, []*types.Transaction, error) {
+	var count uint64
+	for _, opp := range opportunities {
+		if opp.Gas != 0 {
+			count++
+		}
+	}
+	if count == 0 {
+		return nil, nil, nil
+	}
+	if s.config.Mode != ModePrivate {
+		count++ // The PayBidTx follows the arbitrage transactions
+	}
+	nonce, err := s.reserveNonces(ctx, number, head, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		chainID = s.backend.ChainConfig().ChainID
+		txs     = make([]*types.Transaction, 0, len(opportunities))
+		entry   = &Entry{
+			Time:        time.Now(),
+			BlockNumber: number + 1,
+			ParentHash:  head,
+			Mode:        s.config.Mode,
+			DryRun:      s.config.DryRun,
+			Account:     s.config.Account,
+		}
+	)
+	for _, opp := range opportunities {
+		if opp.Gas == 0 {
+			continue
+		}
+		to := opp.To
+		gas := opp.Gas * (100 + s.config.GasMargin) / 100
+		tx, err := s.signer.SignTx(s.account, types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: s.config.GasPrice,
+			Gas:      gas,
+			To:       &to,
+			Data:     opp.CallData,
+		}), chainID)
+		if err != nil {
+			s.resetNonce()
+			return nil, nil, err
+		}
+		txs = append(txs, tx)
+		entry.Txs = append(entry.Txs, EntryTx{
+			Hash:   tx.Hash(),
+			Route:  opp.Route.ID,
+			Nonce:  nonce,
+			Gas:    gas,
+			Profit: (*hexutil.Big)(opp.Profit),
+			To:     to,
+			Data:   opp.CallData,
+		})
+		nonce++
+	}
+	return entry, txs, nil
+}
+
+// bid wraps the signed transactions, preceded by the backrun target if any, into
+// a bid for the block after head. None of the transactions may revert, and since
+// the submitting account acts as its own builder the mandatory PayBidTx is an
+// empty self transfer.
+func (s *Submitter) bid(number uint64, head common.Hash, target *backrun, opportunities []pairtypes.Opportunity, txs []*types.Transaction) (*types.BidArgs, error) {
+	raw := &types.RawBid{
+		BlockNumber:  number + 1,
+		ParentHash:   head,
+		Txs:          make([]hexutil.Bytes, 0, len(txs)),
+		UnRevertible: make([]common.Hash, 0, len(txs)),
+		BuilderFee:   new(big.Int),
+	}
+	// The gas margin only raises the gas limit of the transactions. The bid
+	// states the gas expected to be used, which the validator checks the
+	// packed block against, so it is left out here.
+	for _, opp := range opportunities {
+		raw.GasUsed += opp.Gas
+	}
+	raw.GasFee = new(big.Int).Mul(new(big.Int).SetUint64(raw.GasUsed), s.config.GasPrice)
+
+	if target != nil {
+		blob, err := target.tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		raw.Txs = append(raw.Txs, blob)
+		raw.UnRevertible = append(raw.UnRevertible, target.tx.Hash())
+		raw.GasUsed += target.gas
+		raw.GasFee.Add(raw.GasFee, new(big.Int).Mul(new(big.Int).SetUint64(target.gas), target.tx.GasPrice()))
+	}
+	for _, tx := range txs {
+		blob, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		raw.Txs = append(raw.Txs, blob)
+		raw.UnRevertible = append(raw.UnRevertible, tx.Hash())
+	}
+	to := s.config.Account
+	payBidTx, err := s.signer.SignTx(s.account, types.NewTx(&types.LegacyTx{
+		Nonce:    txs[len(txs)-1].Nonce() + 1,
+		GasPrice: s.config.GasPrice,
+		Gas:      params.TxGas,
+		To:       &to,
+		Value:    new(big.Int),
+	}), s.backend.ChainConfig().ChainID)
+	if err != nil {
+		return nil, err
+	}
+	payBidBlob, err := payBidTx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := s.signer.SignHash(s.account, raw.Hash().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &types.BidArgs{
+		RawBid:          raw,
+		Signature:       signature,
+		PayBidTx:        payBidBlob,
+		PayBidTxGasUsed: params.TxGas,
+	}, nil
+}
+
+// send delivers the bundle on the configured route and collects the response
+// of every target.
+func (s *Submitter) send(ctx context.Context, bid *types.BidArgs, txs []*types.Transaction) []EntryResult {
+	var results []EntryResult
+	record := func(target string, hash common.Hash, err error) {
+		result := EntryResult{Target: target, Hash: hash}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	switch s.config.Mode {
+	case ModeLocal:
+		hash, err := s.bids.SendBid(ctx, *bid)
+		record(ModeLocal, hash, err)
+
+	case ModeBuilder:
+		for i, client := range s.endpoints {
+			hash, err := client.SendBid(ctx, bid)
+			record(s.config.Endpoints[i], hash, err)
+		}
+
+	case ModePrivate:
+		for i, client := range s.endpoints {
+			for _, tx := range txs {
+				hash, err := client.SendRawTransaction(ctx, tx)
+				record(s.config.Endpoints[i], hash, err)
+			}
+		}
+	}
+	return results
+}
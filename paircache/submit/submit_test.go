@@ -0,0 +1,305 @@
+package submit
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/paircache/pairtypes"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type testSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *testSigner) SignTx(a accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+func (s *testSigner) SignHash(a accounts.Account, hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, s.key)
+}
+
+type testBackend struct {
+	nonce uint64
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return params.BSCChainConfig }
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetNonce(testAccount, b.nonce)
+	return statedb, &types.Header{}, nil
+}
+
+type testBids struct {
+	bids []types.BidArgs
+	err  error
+}
+
+func (b *testBids) SendBid(ctx context.Context, args types.BidArgs) (common.Hash, error) {
+	b.bids = append(b.bids, args)
+	return args.RawBid.Hash(), b.err
+}
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAccount = crypto.PubkeyToAddress(testKey.PublicKey)
+	testHead    = common.HexToHash("0x01")
+	testTarget  = common.HexToAddress("0xc0ffee")
+)
+
+func testOpportunities() []pairtypes.Opportunity {
+	return []pairtypes.Opportunity{
+		{Route: pairtypes.Route{ID: 1}, To: testTarget, CallData: []byte{0x1}, Profit: big.NewInt(300), Gas: 100000},
+		{Route: pairtypes.Route{ID: 2}, To: testTarget, CallData: []byte{0x2}, Profit: big.NewInt(200)},
+		{Route: pairtypes.Route{ID: 3}, To: testTarget, CallData: []byte{0x3}, Profit: big.NewInt(100), Gas: 50000},
+	}
+}
+
+func newTestSubmitter(t *testing.T, config Config, bids BidSender) *Submitter {
+	config.Account = testAccount
+	if config.GasPrice == nil {
+		config.GasPrice = DefaultConfig.GasPrice
+	}
+	s, err := New(config, &testBackend{nonce: 5}, bids, &testSigner{testKey})
+	if err != nil {
+		t.Fatalf("failed to create submitter: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start submitter: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+	return s
+}
+
+func readJournal(t *testing.T, path string) []Entry {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("malformed journal line: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSubmitLocalBid(t *testing.T) {
+	bids := new(testBids)
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	s := newTestSubmitter(t, Config{Mode: ModeLocal, GasMargin: 10, Journal: journal}, bids)
+
+	if err := s.Submit(context.Background(), 99, testHead, testOpportunities()); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if len(bids.bids) != 1 {
+		t.Fatalf("bid count mismatch: have %d, want 1", len(bids.bids))
+	}
+	bid := bids.bids[0]
+	if sender, err := bid.EcrecoverSender(); err != nil || sender != testAccount {
+		t.Fatalf("bid signer mismatch: have %v (%v), want %v", sender, err, testAccount)
+	}
+	raw := bid.RawBid
+	if raw.BlockNumber != 100 || raw.ParentHash != testHead {
+		t.Fatalf("bid targets wrong block: %d/%x", raw.BlockNumber, raw.ParentHash)
+	}
+	if raw.GasUsed != 150000 || raw.GasFee.Cmp(new(big.Int).Mul(big.NewInt(150000), DefaultConfig.GasPrice)) != 0 {
+		t.Fatalf("bid gas mismatch: used %d, fee %v", raw.GasUsed, raw.GasFee)
+	}
+	txs, err := raw.DecodeTxs(types.LatestSigner(params.BSCChainConfig))
+	if err != nil {
+		t.Fatalf("failed to decode bid txs: %v", err)
+	}
+	// The opportunity without gas estimate must be left out
+	if len(txs) != 2 || len(raw.UnRevertible) != 2 {
+		t.Fatalf("bid tx count mismatch: have %d/%d, want 2", len(txs), len(raw.UnRevertible))
+	}
+	for i, want := range []struct {
+		nonce, gas uint64
+	}{{5, 110000}, {6, 55000}} {
+		if txs[i].Nonce() != want.nonce || txs[i].Gas() != want.gas || *txs[i].To() != testTarget {
+			t.Fatalf("tx %d mismatch: nonce %d gas %d to %v", i, txs[i].Nonce(), txs[i].Gas(), txs[i].To())
+		}
+		if raw.UnRevertible[i] != txs[i].Hash() {
+			t.Fatalf("tx %d not marked unrevertible", i)
+		}
+	}
+	payBidTx := new(types.Transaction)
+	if err := payBidTx.UnmarshalBinary(bid.PayBidTx); err != nil {
+		t.Fatalf("failed to decode pay bid tx: %v", err)
+	}
+	if payBidTx.Nonce() != 7 || bid.PayBidTxGasUsed > params.PayBidTxGasLimit {
+		t.Fatalf("pay bid tx mismatch: nonce %d, gas used %d", payBidTx.Nonce(), bid.PayBidTxGasUsed)
+	}
+
+	entries := readJournal(t, journal)
+	if len(entries) != 1 {
+		t.Fatalf("journal entry count mismatch: have %d, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.BlockNumber != 100 || entry.DryRun || len(entry.Txs) != 2 || entry.Txs[1].Route != 3 {
+		t.Fatalf("journal entry mismatch: %+v", entry)
+	}
+	if entry.BidHash == nil || *entry.BidHash != raw.Hash() {
+		t.Fatalf("journal bid hash mismatch: have %v, want %x", entry.BidHash, raw.Hash())
+	}
+	if len(entry.Results) != 1 || entry.Results[0].Error != "" || entry.Results[0].Hash != raw.Hash() {
+		t.Fatalf("journal results mismatch: %+v", entry.Results)
+	}
+}
+
+func TestSubmitBackrun(t *testing.T) {
+	bids := new(testBids)
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	s := newTestSubmitter(t, Config{Mode: ModeLocal, Journal: journal}, bids)
+
+	key, _ := crypto.GenerateKey()
+	target, err := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(2), Gas: 200000, To: &testTarget}), types.LatestSigner(params.BSCChainConfig), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SubmitBackrun(context.Background(), 99, testHead, target, 80000, testOpportunities()); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if len(bids.bids) != 1 {
+		t.Fatalf("bid count mismatch: have %d, want 1", len(bids.bids))
+	}
+	raw := bids.bids[0].RawBid
+	txs, err := raw.DecodeTxs(types.LatestSigner(params.BSCChainConfig))
+	if err != nil {
+		t.Fatalf("failed to decode bid txs: %v", err)
+	}
+	if len(txs) != 3 || txs[0].Hash() != target.Hash() || raw.UnRevertible[0] != target.Hash() {
+		t.Fatalf("backrun target not leading the bundle: %d txs", len(txs))
+	}
+	wantFee := new(big.Int).Mul(big.NewInt(150000), DefaultConfig.GasPrice)
+	wantFee.Add(wantFee, big.NewInt(80000*2))
+	if raw.GasUsed != 230000 || raw.GasFee.Cmp(wantFee) != 0 {
+		t.Fatalf("bid gas mismatch: used %d, fee %v", raw.GasUsed, raw.GasFee)
+	}
+	entries := readJournal(t, journal)
+	if len(entries) != 1 || entries[0].Backrun == nil || *entries[0].Backrun != target.Hash() || len(entries[0].Txs) != 2 {
+		t.Fatalf("journal entry mismatch: %+v", entries)
+	}
+}
+
+func TestSubmitNonces(t *testing.T) {
+	bids := new(testBids)
+	s := newTestSubmitter(t, Config{Mode: ModeLocal}, bids)
+	backend := s.backend.(*testBackend)
+
+	firstNonce := func() uint64 {
+		txs, err := bids.bids[len(bids.bids)-1].RawBid.DecodeTxs(types.LatestSigner(params.BSCChainConfig))
+		if err != nil {
+			t.Fatalf("failed to decode bid txs: %v", err)
+		}
+		return txs[0].Nonce()
+	}
+	submit := func(number uint64) {
+		if err := s.Submit(context.Background(), number, testHead, testOpportunities()); err != nil {
+			t.Fatalf("failed to submit: %v", err)
+		}
+	}
+	// Bundles for the same block follow each other, including the PayBidTx
+	submit(99)
+	submit(99)
+	if nonce := firstNonce(); nonce != 8 {
+		t.Fatalf("second bundle nonce mismatch: have %d, want 8", nonce)
+	}
+	// Once the block is imported the nonce follows the chain state
+	backend.nonce = 8
+	submit(100)
+	if nonce := firstNonce(); nonce != 8 {
+		t.Fatalf("nonce after inclusion mismatch: have %d, want 8", nonce)
+	}
+	// A rejected bundle releases its nonces
+	bids.err = errors.New("rejected")
+	submit(100)
+	bids.err = nil
+	submit(100)
+	if nonce := firstNonce(); nonce != 8 {
+		t.Fatalf("nonce after rejection mismatch: have %d, want 8", nonce)
+	}
+}
+
+func TestSubmitDryRun(t *testing.T) {
+	bids := new(testBids)
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	s := newTestSubmitter(t, Config{Mode: ModeLocal, DryRun: true, Journal: journal}, bids)
+
+	for number := uint64(1); number <= 2; number++ {
+		if err := s.Submit(context.Background(), number, testHead, testOpportunities()); err != nil {
+			t.Fatalf("failed to submit: %v", err)
+		}
+	}
+	if len(bids.bids) != 0 {
+		t.Fatalf("dry run sent %d bids", len(bids.bids))
+	}
+	entries := readJournal(t, journal)
+	if len(entries) != 2 {
+		t.Fatalf("journal entry count mismatch: have %d, want 2", len(entries))
+	}
+	for i, entry := range entries {
+		if !entry.DryRun || entry.BlockNumber != uint64(i+2) || len(entry.Results) != 0 || entry.BidHash == nil {
+			t.Fatalf("journal entry %d mismatch: %+v", i, entry)
+		}
+	}
+}
+
+func TestSubmitNothingToSend(t *testing.T) {
+	bids := new(testBids)
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	s := newTestSubmitter(t, Config{Mode: ModeLocal, Journal: journal}, bids)
+
+	opportunities := []pairtypes.Opportunity{{Route: pairtypes.Route{ID: 1}, To: testTarget, Profit: big.NewInt(1)}}
+	if err := s.Submit(context.Background(), 1, testHead, opportunities); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if len(bids.bids) != 0 {
+		t.Fatalf("sent %d bids without transactions", len(bids.bids))
+	}
+	if entries := readJournal(t, journal); len(entries) != 0 {
+		t.Fatalf("journaled %d empty bundles", len(entries))
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		config Config
+		ok     bool
+	}{
+		{Config{Mode: ModeLocal, Account: testAccount, GasPrice: big.NewInt(1)}, true},
+		{Config{Mode: ModeBuilder, Account: testAccount, GasPrice: big.NewInt(1), Endpoints: []string{"http://localhost"}}, true},
+		{Config{Mode: ModePrivate, Account: testAccount, GasPrice: big.NewInt(1)}, false},
+		{Config{Mode: "public", Account: testAccount, GasPrice: big.NewInt(1)}, false},
+		{Config{Mode: ModeLocal, GasPrice: big.NewInt(1)}, false},
+		{Config{Mode: ModeLocal, Account: testAccount}, false},
+	}
+	for i, test := range tests {
+		if err := test.config.Validate(); (err == nil) != test.ok {
+			t.Errorf("test %d: validation mismatch, have %v, want ok=%v", i, err, test.ok)
+		}
+	}
+}
@@ -69,6 +69,8 @@ type handler struct {
 	allowSubscribe       bool
 	batchRequestLimit    int
 	batchResponseMaxSize int
+	limiter              *limiter  // request limits, nil if unlimited
+	recorder             *Recorder // call recorder, nil if not recording
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -474,7 +476,20 @@ func (h *handler) handleCallMsg(ctx *callProc, reqCtx context.Context, msg *json
 		return nil
 
 	case msg.isCall():
+		var (
+			recorder = h.recorder
+			head     uint64
+		)
+		if recorder != nil && !recorder.recorded(msg.Method) {
+			recorder = nil
+		}
+		if recorder != nil {
+			head = recorder.currentHead()
+		}
 		resp := h.handleCall(ctx, msg)
+		if recorder != nil {
+			recorder.record(PeerInfoFromContext(ctx.ctx), head, start, msg, resp)
+		}
 		var ctx []interface{}
 		ctx = append(ctx, "reqid", idForLog{msg.ID}, "duration", time.Since(start))
 		if resp.Error != nil {
@@ -506,6 +521,13 @@ func (h *handler) handleCallMsg(ctx *callProc, reqCtx context.Context, msg *json
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if h.limiter != nil && !msg.isUnsubscribe() {
+		release, err := h.limiter.acquire(cp.ctx, msg.Method)
+		if err != nil {
+			return msg.errorResponse(err)
+		}
+		defer release()
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -0,0 +1,217 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// apiKeyHeader and apiKeyParam are the request header and URL query parameter
+	// a client may pass its API key in.
+	apiKeyHeader = "X-API-Key"
+	apiKeyParam  = "apikey"
+
+	// anonymousClient is the name anonymous clients are reported under in metrics.
+	anonymousClient = "anonymous"
+
+	// maxLimitBuckets is the maximum number of token buckets kept around. Clients
+	// whose buckets are evicted start over with a full bucket.
+	maxLimitBuckets = 16384
+
+	// defaultConcurrencyTimeout is how long a call waits for a free slot of its
+	// concurrency cap if the config does not say otherwise.
+	defaultConcurrencyTimeout = 5 * time.Second
+)
+
+var errMissingAPIKey = errors.New("missing or invalid API key")
+
+// RateLimit configures a token bucket. The bucket is refilled with Rate tokens
+// per second up to Burst tokens, and every request takes one.
+type RateLimit struct {
+	Rate  float64 // Requests per second, zero means unlimited
+	Burst int     // Maximum number of requests at once, defaults to the rate rounded up
+}
+
+func (l RateLimit) newLimiter() *rate.Limiter {
+	burst := l.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(l.Rate))
+	}
+	return rate.NewLimiter(rate.Limit(l.Rate), burst)
+}
+
+// LimitConfig configures the API keys and the request limits of a server.
+//
+// Limits are applied per client. Clients are identified by the name of their
+// API key, or by their remote host if they have none. Method limits and
+// concurrency caps are looked up by method name first and by namespace next,
+// i.e. an entry for "eth_getLogs" takes precedence over one for "eth".
+type LimitConfig struct {
+	APIKeys        map[string]string    // API key => client name
+	AllowAnonymous bool                 // Whether clients without API key are served if keys are configured
+	Rate           RateLimit            // Request limit of every client
+	ClientRates    map[string]RateLimit // Request limit overrides by client name
+	MethodRates    map[string]RateLimit // Request limits of every client by method or namespace
+	Concurrency    map[string]int       // Maximum number of requests in progress by method or namespace, across all clients
+
+	ConcurrencyTimeout time.Duration // Maximum time a call waits for a free concurrency slot, defaults to 5s
+}
+
+// limitExceededError is returned if a client exceeds its request limits.
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return errcodeLimitExceeded }
+
+func (e *limitExceededError) Error() string { return e.message }
+
+// limiter enforces the limits of a LimitConfig across all connections of a server.
+type limiter struct {
+	config LimitConfig
+	slots  map[string]chan struct{} // Concurrency semaphores by method or namespace
+
+	lock    sync.Mutex
+	buckets lru.BasicLRU[string, *rate.Limiter]
+}
+
+func newLimiter(config *LimitConfig) *limiter {
+	l := &limiter{
+		config:  *config,
+		slots:   make(map[string]chan struct{}),
+		buckets: lru.NewBasicLRU[string, *rate.Limiter](maxLimitBuckets),
+	}
+	if l.config.ConcurrencyTimeout <= 0 {
+		l.config.ConcurrencyTimeout = defaultConcurrencyTimeout
+	}
+	for name, n := range config.Concurrency {
+		if n > 0 {
+			l.slots[name] = make(chan struct{}, n)
+		}
+	}
+	return l
+}
+
+// authenticate resolves the API key of an HTTP or WebSocket request to the name
+// of its client. Requests without a key are accepted as anonymous if allowed.
+func (l *limiter) authenticate(r *http.Request) (string, error) {
+	key := r.Header.Get(apiKeyHeader)
+	if key == "" {
+		key = r.URL.Query().Get(apiKeyParam)
+	}
+	if key != "" {
+		if name, ok := l.config.APIKeys[key]; ok {
+			return name, nil
+		}
+		return "", errMissingAPIKey
+	}
+	if len(l.config.APIKeys) > 0 && !l.config.AllowAnonymous {
+		return "", errMissingAPIKey
+	}
+	return "", nil
+}
+
+// bucket returns the token bucket with the given id, creating it if needed.
+func (l *limiter) bucket(id string, limit RateLimit) *rate.Limiter {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if b, ok := l.buckets.Get(id); ok {
+		return b
+	}
+	b := limit.newLimiter()
+	l.buckets.Add(id, b)
+	return b
+}
+
+// lookup returns the entry of the method in m, or else that of its namespace,
+// together with the key it was found under.
+func lookup[V any](m map[string]V, method string) (string, V, bool) {
+	if v, ok := m[method]; ok {
+		return method, v, true
+	}
+	if elem := strings.SplitN(method, serviceMethodSeparator, 2); len(elem) == 2 {
+		if v, ok := m[elem[0]]; ok {
+			return elem[0], v, true
+		}
+	}
+	var v V
+	return "", v, false
+}
+
+// acquire checks a call of the given method against the limits of the calling
+// client and waits for a free slot if the method has a concurrency cap. The
+// wait is bounded by the concurrency timeout of the config. The returned
+// function must be called once the call is done.
+func (l *limiter) acquire(ctx context.Context, method string) (func(), error) {
+	var (
+		info   = PeerInfoFromContext(ctx)
+		client = info.KeyName
+		id     = "key:" + client
+		name   = client
+	)
+	if client == "" {
+		host, _, err := net.SplitHostPort(info.RemoteAddr)
+		if err != nil {
+			host = info.RemoteAddr
+		}
+		id, name = "host:"+host, anonymousClient
+	}
+	clientRequestCounter(name).Inc(1)
+
+	limit := l.config.Rate
+	if override, ok := l.config.ClientRates[client]; ok && client != "" {
+		limit = override
+	}
+	if limit.Rate > 0 && !l.bucket(id, limit).Allow() {
+		clientLimitedCounter(name).Inc(1)
+		return nil, &limitExceededError{fmt.Sprintf("request rate limit of %v/s exceeded", limit.Rate)}
+	}
+	if key, limit, ok := lookup(l.config.MethodRates, method); ok && limit.Rate > 0 {
+		if !l.bucket(id+"/"+key, limit).Allow() {
+			clientLimitedCounter(name).Inc(1)
+			return nil, &limitExceededError{fmt.Sprintf("request rate limit of %v/s exceeded for %s", limit.Rate, key)}
+		}
+	}
+	_, slots, ok := lookup(l.slots, method)
+	if !ok {
+		return func() {}, nil
+	}
+	timer := time.NewTimer(l.config.ConcurrencyTimeout)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-timer.C:
+		clientLimitedCounter(name).Inc(1)
+		return nil, &limitExceededError{fmt.Sprintf("too many concurrent %s requests", method)}
+	case <-ctx.Done():
+		clientLimitedCounter(name).Inc(1)
+		return nil, &limitExceededError{fmt.Sprintf("too many concurrent %s requests", method)}
+	}
+}
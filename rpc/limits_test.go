@@ -0,0 +1,174 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer()
+	defer srv.Stop()
+	srv.SetLimits(&LimitConfig{APIKeys: map[string]string{"secret": "alice"}})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"test_peerInfo","params":[]}`
+	post := func(url string, header http.Header) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set("content-type", contentType)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	confirmStatusCode(t, post(ts.URL, nil).StatusCode, http.StatusUnauthorized)
+	confirmStatusCode(t, post(ts.URL, http.Header{"X-Api-Key": {"wrong"}}).StatusCode, http.StatusUnauthorized)
+	confirmStatusCode(t, post(ts.URL+"?apikey=secret", nil).StatusCode, http.StatusOK)
+
+	// The key name is visible to the method handlers
+	c, err := DialOptions(context.Background(), ts.URL, WithHeader("X-API-Key", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var info PeerInfo
+	if err := c.Call(&info, "test_peerInfo"); err != nil {
+		t.Fatal(err)
+	}
+	if info.KeyName != "alice" {
+		t.Fatalf("wrong key name %q, want %q", info.KeyName, "alice")
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer()
+	defer srv.Stop()
+	srv.SetLimits(&LimitConfig{
+		APIKeys:        map[string]string{"secret": "alice"},
+		AllowAnonymous: true,
+		Rate:           RateLimit{Rate: 0.001, Burst: 5},
+		ClientRates:    map[string]RateLimit{"alice": {Rate: 0.001, Burst: 10}},
+		MethodRates: map[string]RateLimit{
+			"test":            {Rate: 0.001, Burst: 2},
+			"test_noArgsRets": {Rate: 0.001, Burst: 1},
+		},
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	call := func(c *Client, method string) error {
+		return c.Call(nil, method)
+	}
+	isLimited := func(err error) bool {
+		var rpcErr Error
+		return errors.As(err, &rpcErr) && rpcErr.ErrorCode() == errcodeLimitExceeded
+	}
+	anon, err := DialHTTP(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer anon.Close()
+
+	// Method limits take precedence over namespace limits
+	if err := call(anon, "test_noArgsRets"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call(anon, "test_noArgsRets"); !isLimited(err) {
+		t.Fatalf("expected method limit error, got %v", err)
+	}
+	if err := call(anon, "test_null"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call(anon, "test_rets"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call(anon, "test_null"); !isLimited(err) {
+		t.Fatalf("expected namespace limit error, got %v", err)
+	}
+	// Rejected calls count against the client limit as well, which is
+	// exhausted now for all namespaces
+	if err := call(anon, "rpc_modules"); !isLimited(err) {
+		t.Fatalf("expected client limit error, got %v", err)
+	}
+	// Clients with key have their own buckets and limits
+	alice, err := DialOptions(context.Background(), ts.URL, WithHeader("X-API-Key", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	for i := 0; i < 4; i++ {
+		if err := call(alice, "rpc_modules"); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+}
+
+func TestConcurrencyLimits(t *testing.T) {
+	t.Parallel()
+
+	l := newLimiter(&LimitConfig{Concurrency: map[string]int{"debug": 1}})
+	release, err := l.acquire(context.Background(), "debug_traceBlock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Methods of other namespaces are not affected
+	if _, err := l.acquire(context.Background(), "eth_getLogs"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, "debug_traceCall"); err == nil {
+		t.Fatal("expected concurrency limit error")
+	}
+	release()
+	if _, err := l.acquire(context.Background(), "debug_traceCall"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrencyTimeout(t *testing.T) {
+	t.Parallel()
+
+	l := newLimiter(&LimitConfig{
+		Concurrency:        map[string]int{"debug": 1},
+		ConcurrencyTimeout: 50 * time.Millisecond,
+	})
+	if _, err := l.acquire(context.Background(), "debug_traceBlock"); err != nil {
+		t.Fatal(err)
+	}
+	// The wait for a slot ends even if the caller never cancels
+	_, err := l.acquire(context.Background(), "debug_traceCall")
+	if _, ok := err.(*limitExceededError); !ok {
+		t.Fatalf("expected concurrency limit error, got %v", err)
+	}
+}
@@ -53,3 +53,14 @@ func newRPCRequestGauge(method string) metrics.Gauge {
 	m := fmt.Sprintf("rpc/count/%s", method)
 	return metrics.GetOrRegisterGauge(m, nil)
 }
+
+// clientRequestCounter counts the requests of an API key client, anonymous
+// clients are counted together.
+func clientRequestCounter(client string) metrics.Counter {
+	return metrics.GetOrRegisterCounter(fmt.Sprintf("rpc/client/%s/requests", client), nil)
+}
+
+// clientLimitedCounter counts the requests of a client rejected by the limits.
+func clientLimitedCounter(client string) metrics.Counter {
+	return metrics.GetOrRegisterCounter(fmt.Sprintf("rpc/client/%s/limited", client), nil)
+}
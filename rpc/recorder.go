@@ -0,0 +1,163 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// RecordEntry is a call served by the server together with its response, as
+// written by a Recorder. Recordings are files of one JSON encoded entry per line.
+type RecordEntry struct {
+	Time      time.Time       `json:"time"`
+	Transport string          `json:"transport"`
+	Remote    string          `json:"remote"`
+	Head      uint64          `json:"head"`     // Head block number when the call was received
+	Duration  time.Duration   `json:"duration"` // Time taken to serve the call
+	Request   json.RawMessage `json:"request"`
+	Response  json.RawMessage `json:"response"`
+}
+
+// unrecordedMethods are the methods whose calls carry passwords or produce
+// signatures, they are never recorded. The personal namespace is left out as a
+// whole.
+var unrecordedMethods = map[string]bool{
+	"eth_sign":            true,
+	"eth_signTransaction": true,
+	"eth_signTypedData":   true,
+}
+
+// Recorder writes the calls served by a server and their responses to a
+// recording. Notifications and subscription messages are not recorded, neither
+// are the calls of the personal namespace and the signing methods.
+type Recorder struct {
+	head    atomic.Pointer[func() uint64]
+	methods map[string]bool // Recorded methods and namespaces, all if empty
+	failed  atomic.Bool
+
+	lock sync.Mutex
+	out  io.WriteCloser
+}
+
+// NewRecorder creates a recorder writing to the given output.
+func NewRecorder(out io.WriteCloser) *Recorder {
+	return &Recorder{out: out}
+}
+
+// NewFileRecorder creates a recorder writing to the given file, which is
+// rotated once it reaches maxSize megabytes. At most maxBackups rotated files
+// are kept, zero keeps all of them. The recording is only accessible by the
+// owner of the file.
+func NewFileRecorder(path string, maxSize, maxBackups int) (*Recorder, error) {
+	// Rotated files are created with the permissions of the current one
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+	if err := os.Chmod(path, 0600); err != nil {
+		return nil, err
+	}
+	return NewRecorder(&lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+	}), nil
+}
+
+// SetMethods limits the recording to the given methods. Entries without an
+// underscore select a whole namespace, e.g. "eth". All methods are recorded
+// if none are given. It must be called before the recorder is in use.
+func (r *Recorder) SetMethods(methods []string) {
+	r.methods = make(map[string]bool, len(methods))
+	for _, method := range methods {
+		r.methods[method] = true
+	}
+}
+
+// SetHeadFunc sets the function reporting the head block number recorded with
+// every call. Calls are recorded with a zero head until it is set.
+func (r *Recorder) SetHeadFunc(fn func() uint64) {
+	r.head.Store(&fn)
+}
+
+// Close closes the output of the recorder.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.out.Close()
+}
+
+// recorded reports whether calls of the given method are recorded.
+func (r *Recorder) recorded(method string) bool {
+	namespace, _, _ := strings.Cut(method, serviceMethodSeparator)
+	if namespace == "personal" || unrecordedMethods[method] {
+		return false
+	}
+	return len(r.methods) == 0 || r.methods[method] || r.methods[namespace]
+}
+
+// currentHead returns the current head block number.
+func (r *Recorder) currentHead() uint64 {
+	if fn := r.head.Load(); fn != nil {
+		return (*fn)()
+	}
+	return 0
+}
+
+// record writes a served call to the recording.
+func (r *Recorder) record(info PeerInfo, head uint64, start time.Time, req, resp *jsonrpcMessage) {
+	blob, err := encodeRecord(info, head, start, req, resp)
+	if err == nil {
+		r.lock.Lock()
+		_, err = r.out.Write(append(blob, '\n'))
+		r.lock.Unlock()
+	}
+	// Only report the first failure, as it is likely to persist
+	if err != nil && r.failed.CompareAndSwap(false, true) {
+		log.Warn("Failed to record RPC call", "method", req.Method, "err", err)
+	}
+}
+
+// encodeRecord encodes a served call as a recording line.
+func encodeRecord(info PeerInfo, head uint64, start time.Time, req, resp *jsonrpcMessage) ([]byte, error) {
+	entry := RecordEntry{
+		Time:      start,
+		Transport: info.Transport,
+		Remote:    info.RemoteAddr,
+		Head:      head,
+		Duration:  time.Since(start),
+	}
+	var err error
+	if entry.Request, err = json.Marshal(req); err != nil {
+		return nil, err
+	}
+	if entry.Response, err = json.Marshal(resp); err != nil {
+		return nil, err
+	}
+	return json.Marshal(entry)
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// recordBuffer is a thread-safe in-memory recording.
+type recordBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *recordBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *recordBuffer) Close() error { return nil }
+
+func (b *recordBuffer) entries(t *testing.T) []RecordEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var entries []RecordEntry
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		var entry RecordEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid recording line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	var (
+		out      = new(recordBuffer)
+		recorder = NewRecorder(out)
+		server   = newTestServer()
+	)
+	defer server.Stop()
+	recorder.SetHeadFunc(func() uint64 { return 42 })
+	server.SetRecorder(recorder)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(nil, "test_returnError"); err == nil {
+		t.Fatal("expected error")
+	}
+	// Notifications are not recorded
+	if err := client.Notify(context.Background(), "test_noArgsRets"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatal(err)
+	}
+	entries := out.entries(t)
+	if len(entries) != 3 {
+		t.Fatalf("wrong number of entries: have %d, want 3", len(entries))
+	}
+	for i, method := range []string{"test_echo", "test_returnError", "test_noArgsRets"} {
+		var (
+			entry = entries[i]
+			req   jsonrpcMessage
+			resp  jsonrpcMessage
+		)
+		if err := json.Unmarshal(entry.Request, &req); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(entry.Response, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if req.Method != method || string(req.ID) != string(resp.ID) {
+			t.Fatalf("entry %d: wrong request %s", i, entry.Request)
+		}
+		if entry.Head != 42 || entry.Transport != "ipc" {
+			t.Fatalf("entry %d: wrong head %d or transport %q", i, entry.Head, entry.Transport)
+		}
+	}
+	var echo echoResult
+	if err := json.Unmarshal(mustResult(t, entries[0].Response), &echo); err != nil || !reflect.DeepEqual(echo, result) {
+		t.Fatalf("wrong recorded result %+v, want %+v", echo, result)
+	}
+	var resp jsonrpcMessage
+	json.Unmarshal(entries[1].Response, &resp)
+	if resp.Error == nil || resp.Error.Code != (testError{}).ErrorCode() {
+		t.Fatalf("wrong recorded error %s", entries[1].Response)
+	}
+}
+
+func TestRecorderMethods(t *testing.T) {
+	t.Parallel()
+
+	recorder := NewRecorder(new(recordBuffer))
+	for _, method := range []string{"personal_unlockAccount", "personal_sign", "eth_sign", "eth_signTransaction"} {
+		if recorder.recorded(method) {
+			t.Errorf("%s recorded", method)
+		}
+	}
+	if !recorder.recorded("eth_call") || !recorder.recorded("debug_traceCall") {
+		t.Error("methods not recorded without filter")
+	}
+	recorder.SetMethods([]string{"debug", "eth_call", "personal"})
+	for method, want := range map[string]bool{
+		"eth_call":                 true,
+		"debug_traceCall":          true,
+		"eth_getBalance":           false,
+		"personal_sendTransaction": false,
+	} {
+		if have := recorder.recorded(method); have != want {
+			t.Errorf("%s: recorded %v, want %v", method, have, want)
+		}
+	}
+}
+
+func TestFileRecorderPermissions(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	recorder, err := NewFileRecorder(path, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("wrong recording permissions %v, want 0600", perm)
+	}
+}
+
+func mustResult(t *testing.T, response json.RawMessage) json.RawMessage {
+	var resp jsonrpcMessage
+	if err := json.Unmarshal(response, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Result
+}
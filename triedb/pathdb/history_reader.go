@@ -0,0 +1,216 @@
+package pathdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/exp/slices"
+)
+
+// maxHistoricDistance is the maximum number of state histories between a
+// requested historic state and the disk layer. Every lookup may scan them all.
+const maxHistoricDistance = 8192
+
+var (
+	// ErrHistoryUnavailable is returned if the state histories are not kept
+	// by the database, e.g. it has no ancient store or is opened read only.
+	ErrHistoryUnavailable = errors.New("state history is not available")
+
+	// ErrHistoryPruned is returned if the state histories needed to rebuild
+	// the requested state have been pruned.
+	ErrHistoryPruned = errors.New("state history has been pruned")
+
+	// ErrHistoryIncomplete is returned if the storage of an account can not be
+	// rebuilt, as the history of its destruction does not hold all the slots.
+	ErrHistoryIncomplete = errors.New("state history is incomplete")
+
+	// ErrHistoryTooDeep is returned if the requested state is too far behind
+	// the disk layer to be rebuilt at a bounded cost.
+	ErrHistoryTooDeep = errors.New("state history is too deep")
+)
+
+// HistoricalStateReader reads the values of a state older than the disk layer.
+// Every state history holds the values its block modified as they were before
+// the block, so the first history after the requested state that modified a
+// value holds the value in the requested state. Values no history modified are
+// the same as in the state of the disk layer the reader was created on.
+//
+// Lookups scan the histories one by one, their cost grows with the distance
+// between the requested state and the disk layer, which is capped at
+// maxHistoricDistance.
+type HistoricalStateReader struct {
+	freezer *rawdb.ResettableFreezer
+	id      uint64      // Id of the requested state
+	head    uint64      // Id of the disk layer when the reader was created
+	root    common.Hash // Root of the disk layer when the reader was created
+}
+
+// HistoricReader creates a reader for the state with the given root, which
+// must be older than the disk layer.
+func (db *Database) HistoricReader(root common.Hash) (*HistoricalStateReader, error) {
+	return db.historicReader(root, maxHistoricDistance)
+}
+
+// historicReader creates a reader for the state with the given root, at most
+// limit state histories behind the disk layer.
+func (db *Database) historicReader(root common.Hash, limit uint64) (*HistoricalStateReader, error) {
+	if db.freezer == nil {
+		return nil, ErrHistoryUnavailable
+	}
+	db.lock.RLock()
+	if db.waitSync {
+		db.lock.RUnlock()
+		return nil, errDatabaseWaitSync
+	}
+	dl := db.tree.bottom()
+	db.lock.RUnlock()
+
+	tail, err := db.freezer.Tail()
+	if err != nil {
+		return nil, err
+	}
+	id := rawdb.ReadStateID(db.diskdb, root)
+	if id == nil || *id < tail {
+		// The lookups of pruned states are deleted together with the histories
+		if blob := rawdb.ReadStateHistoryMeta(db.freezer, tail+1); len(blob) > 0 {
+			var m meta
+			if err := m.decode(blob); err == nil {
+				return nil, fmt.Errorf("%w: state %x not found, oldest available state is of block %d", ErrHistoryPruned, root, m.block-1)
+			}
+		}
+		return nil, fmt.Errorf("%w: state %x not found", ErrHistoryPruned, root)
+	}
+	if *id >= dl.stateID() {
+		return nil, fmt.Errorf("state %x is not older than the disk layer", root)
+	}
+	if distance := dl.stateID() - *id; distance > limit {
+		return nil, fmt.Errorf("%w: state %x is %d states behind the disk layer, limit %d", ErrHistoryTooDeep, root, distance, limit)
+	}
+	return &HistoricalStateReader{
+		freezer: db.freezer,
+		id:      *id,
+		head:    dl.stateID(),
+		root:    dl.rootHash(),
+	}, nil
+}
+
+// Root returns the root of the disk layer state that holds all the values no
+// history modified.
+func (r *HistoricalStateReader) Root() common.Hash {
+	return r.root
+}
+
+// Account returns the account in slim RLP encoding, empty if the account did
+// not exist. The second value is false if no history modified the account, it
+// must then be read from the state of Root.
+func (r *HistoricalStateReader) Account(addr common.Address) ([]byte, bool, error) {
+	for id := r.id + 1; id <= r.head; id++ {
+		index, found, err := r.accountIndex(id, addr)
+		if err != nil {
+			return nil, false, err
+		}
+		if !found {
+			continue
+		}
+		data := rawdb.ReadStateAccountHistory(r.freezer, id)
+		if uint32(len(data)) < index.offset+uint32(index.length) {
+			return nil, false, fmt.Errorf("state history %d: account data is corrupted", id)
+		}
+		return common.CopyBytes(data[index.offset : index.offset+uint32(index.length)]), true, nil
+	}
+	return nil, false, nil
+}
+
+// Storage returns the storage slot in prefix-zero trimmed RLP encoding, empty
+// if the slot was not set. The second value is false if no history modified the
+// slot, it must then be read from the state of Root.
+func (r *HistoricalStateReader) Storage(addr common.Address, key common.Hash) ([]byte, bool, error) {
+	return r.storage(addr, crypto.Keccak256Hash(key.Bytes()))
+}
+
+// storage looks up a storage slot by the hash of its key.
+func (r *HistoricalStateReader) storage(addr common.Address, hash common.Hash) ([]byte, bool, error) {
+	for id := r.id + 1; id <= r.head; id++ {
+		index, found, err := r.accountIndex(id, addr)
+		if err != nil {
+			return nil, false, err
+		}
+		if !found {
+			continue
+		}
+		if index.storageSlots > 0 {
+			slot, found, err := r.slotIndex(id, index, hash)
+			if err != nil {
+				return nil, false, err
+			}
+			if found {
+				data := rawdb.ReadStateStorageHistory(r.freezer, id)
+				if uint32(len(data)) < slot.offset+uint32(slot.length) {
+					return nil, false, fmt.Errorf("state history %d: storage data is corrupted", id)
+				}
+				return common.CopyBytes(data[slot.offset : slot.offset+uint32(slot.length)]), true, nil
+			}
+		}
+		// The slot may be missing from the history of a destruction that
+		// was too large to record in full
+		var m meta
+		if err := m.decode(rawdb.ReadStateHistoryMeta(r.freezer, id)); err != nil {
+			return nil, false, fmt.Errorf("state history %d: %w", id, err)
+		}
+		if slices.Contains(m.incomplete, addr) {
+			return nil, false, fmt.Errorf("%w: storage of %x in block %d", ErrHistoryIncomplete, addr, m.block)
+		}
+	}
+	return nil, false, nil
+}
+
+// accountIndex looks up the index of an account in the given state history.
+func (r *HistoricalStateReader) accountIndex(id uint64, addr common.Address) (accountIndex, bool, error) {
+	blob := rawdb.ReadStateAccountIndex(r.freezer, id)
+	if len(blob) == 0 {
+		return accountIndex{}, false, fmt.Errorf("%w: state history %d not found", ErrHistoryPruned, id)
+	}
+	if len(blob)%accountIndexSize != 0 {
+		return accountIndex{}, false, fmt.Errorf("state history %d: invalid account index, len: %d", id, len(blob))
+	}
+	n := len(blob) / accountIndexSize
+	pos := sort.Search(n, func(i int) bool {
+		return bytes.Compare(blob[i*accountIndexSize:i*accountIndexSize+common.AddressLength], addr.Bytes()) >= 0
+	})
+	if pos == n {
+		return accountIndex{}, false, nil
+	}
+	var index accountIndex
+	index.decode(blob[pos*accountIndexSize : (pos+1)*accountIndexSize])
+	return index, index.address == addr, nil
+}
+
+// slotIndex looks up the index of a storage slot among the slots of an account
+// in the given state history.
+func (r *HistoricalStateReader) slotIndex(id uint64, account accountIndex, hash common.Hash) (slotIndex, bool, error) {
+	var (
+		blob  = rawdb.ReadStateStorageIndex(r.freezer, id)
+		start = int(account.storageOffset) * slotIndexSize
+		end   = int(account.storageOffset+account.storageSlots) * slotIndexSize
+	)
+	if len(blob) < end {
+		return slotIndex{}, false, fmt.Errorf("state history %d: storage index is corrupted", id)
+	}
+	blob = blob[start:end]
+
+	n := int(account.storageSlots)
+	pos := sort.Search(n, func(i int) bool {
+		return bytes.Compare(blob[i*slotIndexSize:i*slotIndexSize+common.HashLength], hash.Bytes()) >= 0
+	})
+	if pos == n {
+		return slotIndex{}, false, nil
+	}
+	var index slotIndex
+	index.decode(blob[pos*slotIndexSize : (pos+1)*slotIndexSize])
+	return index, index.hash == hash, nil
+}
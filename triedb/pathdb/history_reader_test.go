@@ -0,0 +1,105 @@
+package pathdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestHistoricReader(t *testing.T) {
+	tester := newTester(t, 0)
+	defer tester.release()
+
+	if err := tester.db.Commit(tester.lastHash(), false); err != nil {
+		t.Fatalf("Failed to commit database, err: %v", err)
+	}
+	if _, err := tester.db.HistoricReader(tester.lastHash()); err == nil {
+		t.Fatal("Disk layer is served as historic state")
+	}
+	for i := 0; i < len(tester.roots)-1; i += 17 {
+		root := tester.roots[i]
+		reader, err := tester.db.HistoricReader(root)
+		if err != nil {
+			t.Fatalf("Failed to create reader of state %d, err: %v", i, err)
+		}
+		for addrHash, account := range tester.snapAccounts[root] {
+			addr := tester.preimages[addrHash]
+			blob, found, err := reader.Account(addr)
+			if err != nil {
+				t.Fatalf("Failed to read account, err: %v", err)
+			}
+			if !found {
+				blob = tester.accounts[addrHash]
+			}
+			if !bytes.Equal(blob, account) {
+				t.Fatalf("State %d: account %x mismatch, want %x, got %x", i, addr, account, blob)
+			}
+		}
+		for addrHash, slots := range tester.snapStorages[root] {
+			addr := tester.preimages[addrHash]
+			if _, ok := tester.snapAccounts[root][addrHash]; !ok {
+				continue // Storage of accounts deleted before the state
+			}
+			for hash, slot := range slots {
+				blob, found, err := reader.storage(addr, hash)
+				if err != nil {
+					t.Fatalf("Failed to read slot, err: %v", err)
+				}
+				if !found {
+					blob = tester.storages[addrHash][hash]
+				}
+				if !bytes.Equal(blob, slot) {
+					t.Fatalf("State %d: slot %x of %x mismatch, want %x, got %x", i, hash, addr, slot, blob)
+				}
+			}
+		}
+	}
+	// Accounts created after the state did not exist in it
+	for addrHash := range tester.accounts {
+		if _, ok := tester.snapAccounts[tester.roots[0]][addrHash]; ok {
+			continue
+		}
+		reader, _ := tester.db.HistoricReader(tester.roots[0])
+		blob, found, err := reader.Account(tester.preimages[addrHash])
+		if err != nil || !found || len(blob) != 0 {
+			t.Fatalf("Account %x existed before its creation: %x %v %v", addrHash, blob, found, err)
+		}
+		break
+	}
+	if _, err := tester.db.HistoricReader(crypto.Keccak256Hash([]byte{0x01})); !errors.Is(err, ErrHistoryPruned) {
+		t.Fatalf("Unexpected error for unknown state: %v", err)
+	}
+}
+
+func TestHistoricReaderPruned(t *testing.T) {
+	tester := newTester(t, 10)
+	defer tester.release()
+
+	if err := tester.db.Commit(tester.lastHash(), false); err != nil {
+		t.Fatalf("Failed to commit database, err: %v", err)
+	}
+	if _, err := tester.db.HistoricReader(tester.roots[0]); !errors.Is(err, ErrHistoryPruned) {
+		t.Fatalf("Unexpected error for pruned state: %v", err)
+	}
+	if _, err := tester.db.HistoricReader(tester.roots[len(tester.roots)-5]); err != nil {
+		t.Fatalf("Failed to create reader of recent state, err: %v", err)
+	}
+}
+
+func TestHistoricReaderTooDeep(t *testing.T) {
+	tester := newTester(t, 0)
+	defer tester.release()
+
+	if err := tester.db.Commit(tester.lastHash(), false); err != nil {
+		t.Fatalf("Failed to commit database, err: %v", err)
+	}
+	// The last root is the disk layer, the one before is a single history behind
+	if _, err := tester.db.historicReader(tester.roots[len(tester.roots)-3], 1); !errors.Is(err, ErrHistoryTooDeep) {
+		t.Fatalf("Unexpected error for deep state: %v", err)
+	}
+	if _, err := tester.db.historicReader(tester.roots[len(tester.roots)-2], 1); err != nil {
+		t.Fatalf("Failed to create reader of recent state, err: %v", err)
+	}
+}